@@ -218,6 +218,30 @@ func Run(window *app.Window) error {
 
 			c.Restore()
 
+			// Example 9: Boolean operations (difference, intersect, union,
+			// xor, reverse difference) of a square and a circle
+			opSquare := impl.NewSkPath(enums.PathFillTypeWinding)
+			opSquare.AddRect(models.Rect{Left: -30, Top: -30, Right: 10, Bottom: 10}, enums.PathDirectionCW, 0)
+			opCircle := impl.NewSkPath(enums.PathFillTypeWinding)
+			opCircle.AddCircle(base.Scalar(10), base.Scalar(10), base.Scalar(22), enums.PathDirectionCW)
+			opPaint := skia.NewPaintFill(color.NRGBA{R: 255, G: 140, B: 60, A: 255})
+			for i, pathOp := range []skia.PathOp{
+				skia.PathOpDifference,
+				skia.PathOpIntersect,
+				skia.PathOpUnion,
+				skia.PathOpXOR,
+				skia.PathOpReverseDifference,
+			} {
+				result, ok := skia.Op(opSquare, opCircle, pathOp)
+				if !ok {
+					continue
+				}
+				c.Save()
+				c.Translate(startX+spacing*2, startY+spacing*float32(i)*0.8)
+				c.DrawPath(result, opPaint)
+				c.Restore()
+			}
+
 			frameEvent.Frame(&ops)
 		}
 	}
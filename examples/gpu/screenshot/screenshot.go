@@ -1,62 +1,52 @@
 package main
 
 import (
-	"bytes"
-	"image"
+	"errors"
 	"image/color"
-	"image/png"
 	"log"
 	"os"
 
-	"gioui.org/gpu/headless"
-	"gioui.org/op"
-	"gioui.org/op/paint"
 	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/gio-skia/skia/codec"
+	"github.com/zodimo/gio-skia/skia/surface"
 )
 
 func main() {
-	w, err := headless.NewWindow(1000, 1000)
+	s, err := surface.NewGPU(1000, 1000)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer w.Release()
-	if err := Run(w); err != nil {
+	defer s.Release()
+	if err := Run(s); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func Run(window *headless.Window) error {
-	var ops op.Ops
-	// Reset ops for new frame
-	ops.Reset()
+func Run(s surface.Surface) error {
+	c := s.GetCanvas()
 
-	// White background — no path, no clip
-	paint.ColorOp{Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}}.Add(&ops)
-	paint.PaintOp{}.Add(&ops)
+	// White background
+	c.Clear(skia.ColorToColor4f(color.NRGBA{R: 255, G: 255, B: 255, A: 255}))
 
 	// Draw test rectangle
-	c := skia.NewCanvas(&ops)
 	p := skia.NewPath()
 	skia.PathAddRect(p, 10, 10, 100, 50)
 	paint := skia.NewPaintStroke(color.NRGBA{R: 255, A: 255}, 3)
 	paint.SetStrokeMiter(4)
 	c.DrawPath(p, paint)
 
-	window.Frame(&ops)
 	//save screenshot
-	img := image.NewRGBA(image.Rectangle{Max: image.Point{X: 1000, Y: 1000}})
-	var buf bytes.Buffer
-	err := window.Screenshot(img)
-	if err != nil {
+	if err := s.Flush(); err != nil {
 		return err
 	}
-	err = png.Encode(&buf, img)
-	if err != nil {
-		return err
+	img := s.MakeImageSnapshot()
+	if img == nil {
+		return errors.New("screenshot: no snapshot of the surface")
 	}
-	err = os.WriteFile("screenshot.png", buf.Bytes(), 0644)
+	f, err := os.Create("screenshot.png")
 	if err != nil {
 		return err
 	}
-	return nil
+	defer f.Close()
+	return codec.EncodePNG(f, img)
 }
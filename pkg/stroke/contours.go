@@ -4,12 +4,23 @@ import (
 	"math"
 
 	"gioui.org/f32"
-	"github.com/andybalholm/stroke"
+	"gioui.org/op"
+	"gioui.org/op/clip"
 	andyStroke "github.com/andybalholm/stroke"
 )
 
-// strokedContours computes stroked path segments
+// StrokedContours computes stroked path segments
 func StrokedContours(s Path, opts StrokeOpts) [][]andyStroke.Segment {
+	path := contours(s)
+	if len(opts.Dash) > 0 {
+		path = dashContours(path, opts.Dash, opts.Dash0)
+	}
+	return andyStroke.Stroke(path, options(opts.Width, opts.Miter, opts.Cap, opts.Join))
+}
+
+// contours splits s into contours of stroker segments. It is the single
+// walker behind Stroke.Op, StrokedContours and ExpandStroke.
+func contours(s Path) [][]andyStroke.Segment {
 	var path [][]andyStroke.Segment
 	var contour []andyStroke.Segment
 	var pen f32.Point
@@ -50,7 +61,7 @@ func StrokedContours(s Path, opts StrokeOpts) [][]andyStroke.Segment {
 				contour = andyStroke.AppendArc(contour, start, center, angle)
 				pen = f32.Point(contour[len(contour)-1].End)
 			} else {
-				out := stroke.ArcSegment(start, center, angle)
+				out := andyStroke.ArcSegment(start, center, angle)
 				contour = append(contour, out)
 				pen = f32.Point(out.End)
 			}
@@ -59,16 +70,37 @@ func StrokedContours(s Path, opts StrokeOpts) [][]andyStroke.Segment {
 	if len(contour) > 0 {
 		path = append(path, contour)
 	}
+	return path
+}
 
-	if len(opts.Dash) > 0 {
-		path = stroke.Dash(path, opts.Dash, opts.Dash0)
+// options converts stroke settings to the stroker's options.
+func options(width, miter float32, cap CapStyle, join JoinStyle) andyStroke.Options {
+	return andyStroke.Options{
+		Width:      width,
+		MiterLimit: miter,
+		Cap:        cap,
+		Join:       join,
 	}
+}
 
-	var opt stroke.Options
-	opt.Width = opts.Width
-	opt.MiterLimit = opts.Miter
-	opt.Cap = opts.Cap
-	opt.Join = opts.Join
-
-	return stroke.Stroke(path, opt)
+// outline converts stroked contours to a clip path. Gaps between
+// consecutive segments are bridged with lines.
+func outline(ops *op.Ops, stroked [][]andyStroke.Segment) clip.PathSpec {
+	var p clip.Path
+	p.Begin(ops)
+	var pen f32.Point
+	for _, contour := range stroked {
+		for i, seg := range contour {
+			if i == 0 {
+				p.MoveTo(f32.Point(seg.Start))
+				pen = f32.Point(seg.Start)
+			}
+			if pen != f32.Point(seg.Start) {
+				p.LineTo(f32.Point(seg.Start))
+			}
+			p.CubeTo(f32.Point(seg.CP1), f32.Point(seg.CP2), f32.Point(seg.End))
+			pen = f32.Point(seg.End)
+		}
+	}
+	return p.End()
 }
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package stroke
+
+import (
+	"testing"
+
+	"gioui.org/f32"
+)
+
+func TestContours(t *testing.T) {
+	p := Path{Segments: []Segment{
+		MoveTo(f32.Pt(0, 0)),
+		MoveTo(f32.Pt(10, 10)), // empty contour
+		LineTo(f32.Pt(20, 10)),
+		QuadTo(f32.Pt(30, 10), f32.Pt(30, 20)),
+		MoveTo(f32.Pt(0, 50)),
+		CubeTo(f32.Pt(10, 40), f32.Pt(20, 60), f32.Pt(30, 50)),
+	}}
+	got := contours(p)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 contours, got %d", len(got))
+	}
+	if len(got[0]) != 2 || len(got[1]) != 1 {
+		t.Fatalf("unexpected segment counts %d, %d", len(got[0]), len(got[1]))
+	}
+	// Segments continue from the pen.
+	if f32.Point(got[0][1].Start) != f32.Pt(20, 10) || f32.Point(got[1][0].Start) != f32.Pt(0, 50) {
+		t.Errorf("segments do not start at the pen: %v, %v", got[0][1].Start, got[1][0].Start)
+	}
+}
+
+func TestStrokedContoursClosed(t *testing.T) {
+	square := Path{Segments: []Segment{
+		MoveTo(f32.Pt(0, 0)),
+		LineTo(f32.Pt(10, 0)),
+		LineTo(f32.Pt(10, 10)),
+		LineTo(f32.Pt(0, 10)),
+		LineTo(f32.Pt(0, 0)),
+	}}
+	opts := StrokeOpts{Width: 2, Miter: 4, Join: MiterJoin}
+	if n := len(StrokedContours(square, opts)); n != 2 {
+		t.Errorf("closed contour should stroke to an outer and inner outline, got %d", n)
+	}
+	square.Segments = square.Segments[:4]
+	if n := len(StrokedContours(square, opts)); n != 1 {
+		t.Errorf("open contour should stroke to one outline, got %d", n)
+	}
+}
+
+func TestDashedContoursJoinClosed(t *testing.T) {
+	square := Path{Segments: []Segment{
+		MoveTo(f32.Pt(0, 0)),
+		LineTo(f32.Pt(40, 0)),
+		LineTo(f32.Pt(40, 40)),
+		LineTo(f32.Pt(0, 40)),
+		LineTo(f32.Pt(0, 0)),
+	}}
+	// The dashes of the 160 long outline are [0,50], [60,110] and
+	// [120,160]; the last runs into the first across the start corner.
+	dashes := DashedContours(square, []float32{50, 10}, 0)
+	if len(dashes) != 2 {
+		t.Fatalf("expected 2 dashes, got %d", len(dashes))
+	}
+	joined := dashes[1]
+	near := func(p, q f32.Point) bool {
+		d := p.Sub(q)
+		return d.X*d.X+d.Y*d.Y < 1e-6
+	}
+	if !near(f32.Point(joined[0].Start), f32.Pt(0, 40)) || !near(f32.Point(joined[len(joined)-1].End), f32.Pt(40, 10)) {
+		t.Errorf("joined dash runs from %v to %v", joined[0].Start, joined[len(joined)-1].End)
+	}
+
+	// Open contours keep both ends.
+	square.Segments = square.Segments[:4]
+	if n := len(DashedContours(square, []float32{50, 10}, 0)); n != 2 {
+		t.Errorf("open contour should have 2 dashes, got %d", n)
+	}
+}
@@ -0,0 +1,61 @@
+package stroke
+
+import (
+	andyStroke "github.com/andybalholm/stroke"
+)
+
+// DashedContours splits s into the dashes of pattern, starting phase into
+// the pattern. It is the dashing behind Stroke.Op, StrokedContours and
+// ExpandStroke, exposed for path effects that dash without stroking.
+func DashedContours(s Path, pattern []float32, phase float32) [][]andyStroke.Segment {
+	return dashContours(contours(s), pattern, phase)
+}
+
+// dashContours splits path into dashes. On a closed contour, one ending at its
+// start, a dash running into the closing point continues into the dash
+// leaving it, so the corner is joined instead of capped twice.
+func dashContours(path [][]andyStroke.Segment, pattern []float32, phase float32) [][]andyStroke.Segment {
+	var patternLen float32
+	for _, d := range pattern {
+		if d < 0 {
+			return path
+		}
+		patternLen += d
+	}
+	if patternLen == 0 {
+		return path
+	}
+	for phase < 0 {
+		// Multiply by two in case the pattern has an odd number of elements.
+		phase += patternLen * 2
+	}
+	// Find whether the pattern is on at the start of each contour, as the
+	// stroker's Dash does.
+	ph, i := phase, 0
+	for ph > pattern[i%len(pattern)] {
+		ph -= pattern[i%len(pattern)]
+		i++
+	}
+	startOn := i%2 == 0
+
+	var result [][]andyStroke.Segment
+	for _, contour := range path {
+		dashes := andyStroke.Dash([][]andyStroke.Segment{contour}, pattern, phase)
+		if n := len(dashes); n >= 2 && startOn && isClosed(contour) {
+			first, last := dashes[0], dashes[n-1]
+			if len(first) > 0 && len(last) > 0 &&
+				first[0].Start == contour[0].Start && last[len(last)-1].End == contour[len(contour)-1].End {
+				dashes[n-1] = append(last[:len(last):len(last)], first...)
+				dashes = dashes[1:]
+			}
+		}
+		result = append(result, dashes...)
+	}
+	return result
+}
+
+// isClosed reports whether contour ends at its start, which the stroker
+// treats as closed.
+func isClosed(contour []andyStroke.Segment) bool {
+	return len(contour) > 0 && contour[0].Start == contour[len(contour)-1].End
+}
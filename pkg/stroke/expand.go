@@ -3,7 +3,6 @@ package stroke
 import (
 	"math"
 
-	"gioui.org/f32"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	andyStroke "github.com/andybalholm/stroke"
@@ -13,95 +12,17 @@ import (
 // This is used internally by canvas.DrawPath for stroke operations.
 func ExpandStroke(s Path, width float32, join JoinStyle, cap CapStyle,
 	miter float32, dash []float32, dash0 float32) clip.PathSpec {
-	// Use the andybalholm/stroke library to expand the path
-	var opt andyStroke.Options
-	opt.Width = width
-	opt.MiterLimit = miter
-	opt.Cap = cap
-	opt.Join = join
-
-	// Convert stroke.Path to [][]stroke.Segment
-	var path [][]andyStroke.Segment
-	var contour []andyStroke.Segment
-	var pen f32.Point
-
-	for _, seg := range s.Segments {
-		switch seg.Op {
-		case segOpMoveTo:
-			if len(contour) > 0 {
-				path = append(path, contour)
-				contour = nil
-			}
-			pen = seg.Args[0]
-		case segOpLineTo:
-			contour = append(contour, andyStroke.LinearSegment(
-				andyStroke.Point(pen),
-				andyStroke.Point(seg.Args[0]),
-			))
-			pen = seg.Args[0]
-		case segOpQuadTo:
-			contour = append(contour, andyStroke.QuadraticSegment(
-				andyStroke.Point(pen),
-				andyStroke.Point(seg.Args[0]),
-				andyStroke.Point(seg.Args[1]),
-			))
-			pen = seg.Args[1]
-		case segOpCubeTo:
-			contour = append(contour, andyStroke.Segment{
-				Start: andyStroke.Point(pen),
-				CP1:   andyStroke.Point(seg.Args[0]),
-				CP2:   andyStroke.Point(seg.Args[1]),
-				End:   andyStroke.Point(seg.Args[2]),
-			})
-			pen = seg.Args[2]
-		case segOpArcTo:
-			// ArcTo is not used in the basic Skia API, but handle it for completeness
-			var (
-				start  = andyStroke.Point(pen)
-				center = andyStroke.Point(seg.Args[0])
-				angle  = seg.Args[1].X
-			)
-			if absF32(angle) > math.Pi {
-				contour = andyStroke.AppendArc(contour, start, center, angle)
-				pen = f32.Point(contour[len(contour)-1].End)
-			} else {
-				out := andyStroke.ArcSegment(start, center, angle)
-				contour = append(contour, out)
-				pen = f32.Point(out.End)
-			}
-		}
-	}
-	if len(contour) > 0 {
-		path = append(path, contour)
-	}
+	path := contours(s)
 
 	// Apply dashing if provided
 	if len(dash) > 0 {
-		path = andyStroke.Dash(path, dash, dash0)
+		path = dashContours(path, dash, dash0)
 	}
 
-	// Stroke the path
-	stroked := andyStroke.Stroke(path, opt)
-
-	// Convert back to clip.Path
+	// Stroke the path and convert back to clip.Path
+	stroked := andyStroke.Stroke(path, options(width, miter, cap, join))
 	var ops op.Ops
-	var outline clip.Path
-	outline.Begin(&ops)
-
-	for _, contour := range stroked {
-		for i, seg := range contour {
-			if i == 0 {
-				outline.MoveTo(f32.Point(seg.Start))
-			}
-			outline.CubeTo(
-				f32.Point(seg.CP1),
-				f32.Point(seg.CP2),
-				f32.Point(seg.End),
-			)
-		}
-	}
-
-	return outline.End()
+	return outline(&ops, stroked)
 }
 
 func absF32(x float32) float32 {
@@ -4,8 +4,6 @@
 package stroke
 
 import (
-	"math"
-
 	"gioui.org/f32"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -113,97 +111,12 @@ func (s Stroke) Op(ops *op.Ops) clip.Op {
 	}
 
 	// Use the stroke package to find the outline of the andyStroke.
-	var path [][]andyStroke.Segment
-	var contour []andyStroke.Segment
-	var pen f32.Point
-
-	for _, seg := range s.Path.Segments {
-		switch seg.Op {
-		case segOpMoveTo:
-			if len(contour) > 0 {
-				path = append(path, contour)
-				contour = nil
-			}
-			pen = seg.Args[0]
-		case segOpLineTo:
-			contour = append(contour, andyStroke.LinearSegment(andyStroke.Point(pen), andyStroke.Point(seg.Args[0])))
-			pen = seg.Args[0]
-		case segOpQuadTo:
-			contour = append(contour, andyStroke.QuadraticSegment(andyStroke.Point(pen), andyStroke.Point(seg.Args[0]), andyStroke.Point(seg.Args[1])))
-			pen = seg.Args[1]
-		case segOpCubeTo:
-			contour = append(contour, andyStroke.Segment{
-				Start: andyStroke.Point(pen),
-				CP1:   andyStroke.Point(seg.Args[0]),
-				CP2:   andyStroke.Point(seg.Args[1]),
-				End:   andyStroke.Point(seg.Args[2]),
-			})
-			pen = seg.Args[2]
-		case segOpArcTo:
-			var (
-				start  = andyStroke.Point(pen)
-				center = andyStroke.Point(seg.Args[0])
-				angle  = seg.Args[1].X
-			)
-			switch {
-			case absF32(angle) > math.Pi:
-				contour = andyStroke.AppendArc(contour, start, center, angle)
-				pen = f32.Point(contour[len(contour)-1].End)
-			default:
-				out := andyStroke.ArcSegment(start, center, angle)
-				contour = append(contour, out)
-				pen = f32.Point(out.End)
-			}
-		}
-	}
-	if len(contour) > 0 {
-		path = append(path, contour)
-	}
-
+	path := contours(s.Path)
 	if len(s.Dashes.Dashes) > 0 {
-		path = andyStroke.Dash(path, s.Dashes.Dashes, s.Dashes.Phase)
-	}
-
-	var opt andyStroke.Options
-	opt.Width = s.Width
-	opt.MiterLimit = s.Miter
-	switch s.Cap {
-	case RoundCap:
-		opt.Cap = andyStroke.RoundCap
-	case SquareCap:
-		opt.Cap = andyStroke.SquareCap
-	case FlatCap:
-		opt.Cap = andyStroke.FlatCap
-	case TriangularCap:
-		opt.Cap = andyStroke.TriangularCap
-	}
-	switch s.Join {
-	case RoundJoin:
-		opt.Join = andyStroke.RoundJoin
-	case BevelJoin:
-		opt.Join = andyStroke.BevelJoin
-	case MiterJoin:
-		opt.Join = andyStroke.MiterJoin
+		path = dashContours(path, s.Dashes.Dashes, s.Dashes.Phase)
 	}
-
-	stroked := andyStroke.Stroke(path, opt)
+	stroked := andyStroke.Stroke(path, options(s.Width, s.Miter, s.Cap, s.Join))
 
 	// Output path data.
-	var outline clip.Path
-	outline.Begin(ops)
-	for _, contour := range stroked {
-		for i, seg := range contour {
-			if i == 0 {
-				outline.MoveTo(f32.Point(seg.Start))
-				pen = f32.Point(seg.Start)
-			}
-			if pen != f32.Point(seg.Start) {
-				outline.LineTo(f32.Point(seg.Start))
-			}
-			outline.CubeTo(f32.Point(seg.CP1), f32.Point(seg.CP2), f32.Point(seg.End))
-			pen = f32.Point(seg.End)
-		}
-	}
-
-	return clip.Outline{Path: outline.End()}.Op()
+	return clip.Outline{Path: outline(ops, stroked)}.Op()
 }
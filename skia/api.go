@@ -2,8 +2,12 @@
 package skia
 
 import (
+	"image/color"
+
 	"github.com/zodimo/go-skia-support/skia/base"
+	"github.com/zodimo/go-skia-support/skia/enums"
 	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
 	"github.com/zodimo/go-skia-support/skia/shaper"
 )
 
@@ -63,6 +67,91 @@ type SkPaint = interfaces.SkPaint
 type SkMatrix = interfaces.SkMatrix
 
 // Canvas defines a Skia-style immediate-mode drawing context.
-// All operations are GPU-accelerated via Gio's renderer.
+// NewCanvas draws with Gio's GPU renderer, NewRasterCanvas on the CPU.
 // This interface matches SkCanvas method signatures for the methods we implement,
 type Canvas = interfaces.SkCanvas
+
+// Extended is implemented by the Gio canvases of this package. It adds the
+// draws SkCanvas lacks and the controls specific to the Gio backend to
+// Canvas; obtain it with a type assertion, c.(skia.Extended).
+type Extended interface {
+	Canvas
+
+	// SetTextRenderMode selects between path and mask rendering of glyphs.
+	SetTextRenderMode(mode TextRenderMode)
+
+	// SetStrokeMode selects whether strokes are expanded in local or device
+	// space.
+	SetStrokeMode(mode StrokeMode)
+
+	// SetGlyphMaskOptions sets the gamma and contrast used for glyph masks.
+	SetGlyphMaskOptions(opts GlyphMaskOptions)
+
+	// DrawTextOnPath draws text along path, starting offset units from its
+	// start.
+	DrawTextOnPath(text string, path SkPath, offset Scalar, font interfaces.SkFont, paint SkPaint)
+
+	// DrawTextOnPathWithOptions draws text along path with alignment and
+	// overflow options.
+	DrawTextOnPathWithOptions(text string, path SkPath, offset Scalar, font interfaces.SkFont, paint SkPaint, opts TextOnPathOptions)
+
+	// DrawVertices draws a triangle mesh, coloring it with the paint's
+	// shader and its vertex colors blended with mode.
+	DrawVertices(vertices *Vertices, mode enums.BlendMode, paint SkPaint)
+
+	// DrawAtlas draws sprites from atlas, each placed by its RSXform and
+	// optionally blended with its color by mode.
+	DrawAtlas(atlas interfaces.SkImage, xforms []models.RSXform, texRects []models.Rect, colors []color.NRGBA, mode enums.BlendMode, sampling models.SamplingOptions, cullRect *models.Rect, paint SkPaint)
+
+	// DrawImageWithSampling draws image at (left, top) sampled with
+	// sampling.
+	DrawImageWithSampling(image interfaces.SkImage, left, top Scalar, sampling models.SamplingOptions, paint SkPaint)
+
+	// DrawImageRectWithSampling draws the src part of image scaled into
+	// dst, sampled with sampling; constraint selects whether filtering may
+	// sample outside src.
+	DrawImageRectWithSampling(image interfaces.SkImage, src *models.Rect, dst models.Rect, sampling models.SamplingOptions, paint SkPaint, constraint enums.SrcRectConstraint)
+
+	// DrawImageNine draws image stretched into dst, keeping the corners
+	// outside center at their size.
+	DrawImageNine(image interfaces.SkImage, center models.IRect, dst models.Rect, filter enums.FilterMode, paint SkPaint)
+
+	// DrawImageLattice draws image divided by lattice and stretched into
+	// dst.
+	DrawImageLattice(image interfaces.SkImage, lattice Lattice, dst models.Rect, filter enums.FilterMode, paint SkPaint)
+
+	// DrawPatch draws the Coons patch bounded by four cubics, with optional
+	// corner colors and texture coordinates.
+	DrawPatch(cubics [12]models.Point, colors *[4]color.NRGBA, texCoords *[4]models.Point, mode enums.BlendMode, paint SkPaint)
+
+	// DrawPicture replays picture transformed by matrix, if not nil.
+	// Pictures hold draws resolved at recording time and are meant to be
+	// replayed at identity scale.
+	DrawPicture(picture *Picture, matrix SkMatrix, paint SkPaint)
+
+	// GetTotalMatrix returns a copy of the current transform.
+	GetTotalMatrix() SkMatrix
+
+	// GetLocalClipBounds returns the bounds of the clip in local
+	// coordinates.
+	GetLocalClipBounds() models.Rect
+
+	// GetDeviceClipBounds returns the bounds of the clip in device pixels.
+	GetDeviceClipBounds() models.IRect
+
+	// IsClipEmpty reports whether the clip excludes everything.
+	IsClipEmpty() bool
+
+	// IsClipRect reports whether the clip is an axis aligned rectangle.
+	IsClipRect() bool
+
+	// QuickReject reports whether rect is certainly outside the clip.
+	QuickReject(rect models.Rect) bool
+
+	// QuickRejectPath reports whether path is certainly outside the clip.
+	QuickRejectPath(path SkPath) bool
+
+	// CulledDraws returns the number of draws skipped because they were
+	// outside the clip.
+	CulledDraws() int
+}
@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// DrawAtlas draws sprites from atlas, mirroring SkCanvas::drawAtlas. Sprite
+// i is the texRects[i] part of atlas, with its top-left corner mapped to
+// the origin and then transformed by xforms[i]. colors is optional; if
+// present each sprite is blended with its color by mode, the sprite being
+// the source. cullRect, if not nil, bounds all sprites in local coordinates
+// and spares computing their bounds. The paint's alpha applies to every
+// sprite and paint may be nil. Nothing is drawn if the lengths of the
+// slices do not match. The paint's color filter applies to the result.
+//
+// The atlas is converted once and all sprites share one image op. Colors
+// blended by modulate or src-in tint the sprites: each sprite is drawn from
+// a copy of its part of the atlas tinted with its color, cached per color.
+// Sprites with colors blended by other modes, or under perspective, are
+// rasterized on the CPU one at a time.
+func (c *canvas) DrawAtlas(atlas interfaces.SkImage, xforms []models.RSXform, texRects []models.Rect, colors []color.NRGBA, mode enums.BlendMode, sampling models.SamplingOptions, cullRect *models.Rect, paint SkPaint) {
+	if atlas == nil || len(xforms) == 0 || len(texRects) != len(xforms) || (colors != nil && len(colors) != len(xforms)) {
+		return
+	}
+	var bounds models.Rect
+	if cullRect != nil {
+		bounds = *cullRect
+	} else {
+		bounds = atlasBounds(xforms, texRects)
+	}
+	if c.cullDraw(bounds, 0) {
+		return
+	}
+	alpha := float32(1)
+	if paint != nil {
+		if paint.GetBlendModeOr(enums.BlendModeSrcOver) == enums.BlendModeDst {
+			return
+		}
+		alpha = float32(paint.GetAlphaf())
+	}
+
+	_, tintable := atlasSpriteTint(color.NRGBA{}, mode)
+	tinted := len(colors) > 0 && tintable
+	if (len(colors) > 0 && !tintable) || c.stack[len(c.stack)-1].persp != nil {
+		// The color filter applies after the colors are blended.
+		img, _ := cachedImage(atlas, paint)
+		if img == nil {
+			return
+		}
+		shader := newImageShader(img, enums.TileModeClamp, enums.TileModeClamp, sampling, nil)
+		mesh := NewPaint()
+		mesh.SetShader(shader)
+		mesh.SetAlphaf(Scalar(alpha))
+		if f, ok := paintColorFilter(paint); ok {
+			mesh.SetColorFilter(f)
+		}
+		// Sprites are rasterized one at a time so that the images cover
+		// the sprites rather than their union.
+		for i := range xforms {
+			var cols []color.NRGBA
+			if len(colors) > 0 {
+				cols = colors[i : i+1]
+			}
+			c.drawVerticesRaster(atlasVertices(xforms[i:i+1], texRects[i:i+1], cols), mode, mesh, false)
+		}
+		return
+	}
+
+	var img *image.RGBA
+	var imgOp gpaint.ImageOp
+	if tinted {
+		// Sprites are tinted before the color filter applies.
+		img, _ = cachedImage(atlas, paint)
+	} else {
+		img, imgOp = cachedFilteredImage(atlas, paint)
+	}
+	if img == nil {
+		return
+	}
+	pop := c.pushContext()
+	defer pop()
+	if alpha < 1 {
+		opacity := gpaint.PushOpacity(c.ops, alpha)
+		defer opacity.Pop()
+	}
+	nearest := sampling.FilterMode != enums.FilterModeLinear && !sampling.UseCubic
+	for i, xf := range xforms {
+		tex := texRects[i]
+		w, h := float32(tex.Right-tex.Left), float32(tex.Bottom-tex.Top)
+		if w <= 0 || h <= 0 {
+			continue
+		}
+		spriteOp, origin := imgOp, image.Point{}
+		if tinted {
+			tint, _ := atlasSpriteTint(colors[i], mode)
+			if tint.A == 0 {
+				continue
+			}
+			var ok bool
+			spriteOp, origin, ok = atlasSpriteImage(atlas, img, paint, tex, tint)
+			if !ok {
+				continue
+			}
+		}
+		if nearest {
+			spriteOp.Filter = gpaint.FilterNearest
+		}
+		sprite := op.Affine(f32.NewAffine2D(
+			float32(xf.SCos), float32(-xf.SSin), float32(xf.Tx),
+			float32(xf.SSin), float32(xf.SCos), float32(xf.Ty),
+		)).Push(c.ops)
+		var path clip.Path
+		path.Begin(c.ops)
+		path.MoveTo(f32.Pt(0, 0))
+		path.LineTo(f32.Pt(w, 0))
+		path.LineTo(f32.Pt(w, h))
+		path.LineTo(f32.Pt(0, h))
+		path.Close()
+		cl := clip.Outline{Path: path.End()}.Op().Push(c.ops)
+		offset := op.Affine(f32.Affine2D{}.Offset(f32.Pt(float32(origin.X)-float32(tex.Left), float32(origin.Y)-float32(tex.Top)))).Push(c.ops)
+		spriteOp.Add(c.ops)
+		gpaint.PaintOp{}.Add(c.ops)
+		offset.Pop()
+		cl.Pop()
+		sprite.Pop()
+	}
+}
+
+// atlasSpriteTint returns the color a sprite blended with col by mode is
+// multiplied with. It reports false if mode does not reduce to a tint.
+func atlasSpriteTint(col color.NRGBA, mode enums.BlendMode) (color.NRGBA, bool) {
+	switch mode {
+	case enums.BlendModeModulate:
+		return col, true
+	case enums.BlendModeSrcIn:
+		return color.NRGBA{R: 255, G: 255, B: 255, A: col.A}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// atlasSpriteImage returns an image op of the part of img, the converted
+// atlas, around tex multiplied by tint, with the paint's color filter
+// applied, and the position of that part in the atlas. A pixel margin is
+// kept so that filtering at the sprite edges samples the atlas as a whole
+// image would.
+func atlasSpriteImage(atlas interfaces.SkImage, img *image.RGBA, paint SkPaint, tex models.Rect, tint color.NRGBA) (gpaint.ImageOp, image.Point, bool) {
+	crop := image.Rect(
+		int(math.Floor(float64(tex.Left)))-1, int(math.Floor(float64(tex.Top)))-1,
+		int(math.Ceil(float64(tex.Right)))+1, int(math.Ceil(float64(tex.Bottom)))+1,
+	).Intersect(img.Bounds())
+	if crop.Empty() {
+		return gpaint.ImageOp{}, image.Point{}, false
+	}
+	key := imageBaseKey(atlas, paint)
+	key.variant.crop = crop
+	key.variant.tint = tint
+	f, filtered := paintColorFilter(paint)
+	if filtered {
+		key.variant.filter = f.filterID()
+	}
+	_, imgOp := cachedImageKey(key, func() *image.RGBA {
+		sprite := tintImage(cropImage(img, crop), tint)
+		if filtered {
+			sprite = filterImage(sprite, f)
+		}
+		return sprite
+	})
+	return imgOp, crop.Min, true
+}
+
+// tintImage multiplies the premultiplied pixels of img with tint in place
+// and returns img.
+func tintImage(img *image.RGBA, tint color.NRGBA) *image.RGBA {
+	t := premulRGBA(tint)
+	mul := [4]uint32{uint32(t.R), uint32(t.G), uint32(t.B), uint32(t.A)}
+	for i := range img.Pix {
+		img.Pix[i] = uint8((uint32(img.Pix[i])*mul[i%4] + 127) / 255)
+	}
+	return img
+}
+
+// atlasSpriteCorners returns the corners of the sprite tex placed by xf, in
+// clockwise order from its top-left.
+func atlasSpriteCorners(xf models.RSXform, tex models.Rect) [4]models.Point {
+	w, h := tex.Right-tex.Left, tex.Bottom-tex.Top
+	at := func(x, y Scalar) models.Point {
+		return models.Point{X: xf.SCos*x - xf.SSin*y + xf.Tx, Y: xf.SSin*x + xf.SCos*y + xf.Ty}
+	}
+	return [4]models.Point{at(0, 0), at(w, 0), at(w, h), at(0, h)}
+}
+
+// atlasBounds returns the bounds of the placed sprites.
+func atlasBounds(xforms []models.RSXform, texRects []models.Rect) models.Rect {
+	var bounds models.Rect
+	for i, xf := range xforms {
+		for j, p := range atlasSpriteCorners(xf, texRects[i]) {
+			if i == 0 && j == 0 {
+				bounds = models.Rect{Left: p.X, Top: p.Y, Right: p.X, Bottom: p.Y}
+				continue
+			}
+			bounds = models.Rect{
+				Left:   min(bounds.Left, p.X),
+				Top:    min(bounds.Top, p.Y),
+				Right:  max(bounds.Right, p.X),
+				Bottom: max(bounds.Bottom, p.Y),
+			}
+		}
+	}
+	return bounds
+}
+
+// atlasVertices returns the sprites as a mesh of two triangles each,
+// textured with their part of the atlas and colored with their colors.
+func atlasVertices(xforms []models.RSXform, texRects []models.Rect, colors []color.NRGBA) *Vertices {
+	n := 6 * len(xforms)
+	pos := make([]models.Point, 0, n)
+	tex := make([]models.Point, 0, n)
+	var cols []color.NRGBA
+	if len(colors) > 0 {
+		cols = make([]color.NRGBA, 0, n)
+	}
+	for i, xf := range xforms {
+		r := texRects[i]
+		p := atlasSpriteCorners(xf, r)
+		t := [4]models.Point{{X: r.Left, Y: r.Top}, {X: r.Right, Y: r.Top}, {X: r.Right, Y: r.Bottom}, {X: r.Left, Y: r.Bottom}}
+		for _, k := range [6]int{0, 1, 2, 0, 2, 3} {
+			pos = append(pos, p[k])
+			tex = append(tex, t[k])
+			if cols != nil {
+				cols = append(cols, colors[i])
+			}
+		}
+	}
+	return NewVertices(VertexModeTriangles, pos, tex, cols, nil)
+}
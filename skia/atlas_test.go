@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestAtlasBounds(t *testing.T) {
+	xforms := []models.RSXform{
+		{SCos: 1, Tx: 10, Ty: 20},
+		// A quarter turn scaled by two.
+		{SSin: 2, Tx: 50, Ty: 0},
+	}
+	texRects := []models.Rect{{Left: 4, Top: 4, Right: 8, Bottom: 6}, {Right: 4, Bottom: 2}}
+	want := models.Rect{Left: 10, Top: 0, Right: 50, Bottom: 22}
+	if got := atlasBounds(xforms, texRects); got != want {
+		t.Errorf("atlasBounds = %+v, want %+v", got, want)
+	}
+}
+
+func TestAtlasVertices_Colors(t *testing.T) {
+	// Two 2x2 sprites side by side in the atlas.
+	atlas := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			atlas.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+	xforms := []models.RSXform{{SCos: 2}, {SCos: 2, Tx: 4}}
+	texRects := []models.Rect{{Right: 2, Bottom: 2}, {Left: 2, Right: 4, Bottom: 2}}
+	colors := []color.NRGBA{{R: 255, A: 255}, {B: 255, A: 255}}
+	paint := NewPaint()
+	paint.SetShader(newImageShader(atlas, enums.TileModeClamp, enums.TileModeClamp, models.SamplingOptions{}, nil))
+
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	rasterVertices(dst, atlasVertices(xforms, texRects, colors), enums.BlendModeModulate, paint, impl.NewMatrixIdentity())
+	if got := dst.RGBAAt(1, 1); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("first sprite = %v, want red", got)
+	}
+	if got := dst.RGBAAt(6, 2); got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("second sprite = %v, want blue", got)
+	}
+}
+
+func TestCanvas_DrawAtlas(t *testing.T) {
+	defer PurgeImageCache()
+	info := models.NewImageInfo(4, 2, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	atlas := impl.NewRasterImage(info, make([]byte, 4*2*4), 4*4)
+	xforms := []models.RSXform{{SCos: 1}, {SCos: 1, Tx: 10}}
+	texRects := []models.Rect{{Right: 2, Bottom: 2}, {Left: 2, Right: 4, Bottom: 2}}
+	red := color.NRGBA{R: 255, A: 255}
+
+	c := NewCanvasWithSize(new(op.Ops), 20, 20).(Extended)
+	c.DrawAtlas(atlas, xforms, texRects, nil, enums.BlendModeModulate, models.SamplingOptions{}, nil, nil)
+	c.DrawAtlas(atlas, xforms, texRects, []color.NRGBA{red, red}, enums.BlendModeModulate, models.SamplingOptions{}, nil, NewPaint())
+	if c.CulledDraws() != 0 {
+		t.Errorf("visible sprites culled")
+	}
+
+	cull := models.Rect{Left: 100, Top: 100, Right: 110, Bottom: 110}
+	c.DrawAtlas(atlas, xforms, texRects, nil, enums.BlendModeModulate, models.SamplingOptions{}, &cull, nil)
+	if c.CulledDraws() != 1 {
+		t.Errorf("sprites outside the cull rect should be culled")
+	}
+}
+
+func TestCanvas_DrawAtlas_TintedSprites(t *testing.T) {
+	PurgeImageCache()
+	defer PurgeImageCache()
+	info := models.NewImageInfo(4, 2, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	pix := make([]byte, 4*2*4)
+	for i := range pix {
+		pix[i] = 255
+	}
+	atlas := impl.NewRasterImage(info, pix, 4*4)
+	xforms := []models.RSXform{{SCos: 1}, {SCos: 1, Tx: 10}, {SCos: 1, Tx: 20}}
+	texRects := []models.Rect{{Right: 2, Bottom: 2}, {Left: 2, Right: 4, Bottom: 2}, {Right: 2, Bottom: 2}}
+	red := color.NRGBA{R: 255, A: 255}
+	colors := []color.NRGBA{red, red, red}
+
+	c := NewCanvasWithSize(new(op.Ops), 40, 20).(Extended)
+	c.DrawAtlas(atlas, xforms, texRects, colors, enums.BlendModeModulate, models.SamplingOptions{}, nil, nil)
+	// The converted atlas and one tinted copy of each distinct sprite, with
+	// a pixel margin: 3x2 pixels each.
+	if got, want := ImageCacheUsage(), 4*2*4+2*3*2*4; got != want {
+		t.Errorf("image cache usage = %d, want %d", got, want)
+	}
+	c.DrawAtlas(atlas, xforms, texRects, colors, enums.BlendModeModulate, models.SamplingOptions{}, nil, nil)
+	if got, want := ImageCacheUsage(), 4*2*4+2*3*2*4; got != want {
+		t.Errorf("image cache usage after redraw = %d, want %d", got, want)
+	}
+}
+
+func TestTintImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	tint, ok := atlasSpriteTint(color.NRGBA{R: 255, A: 128}, enums.BlendModeModulate)
+	if !ok {
+		t.Fatal("modulate does not tint")
+	}
+	if got, want := tintImage(img, tint).RGBAAt(0, 0), (color.RGBA{R: 128, A: 128}); got != want {
+		t.Errorf("modulate = %v, want %v", got, want)
+	}
+
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	tint, _ = atlasSpriteTint(color.NRGBA{R: 255, A: 128}, enums.BlendModeSrcIn)
+	if got, want := tintImage(img, tint).RGBAAt(0, 0), (color.RGBA{R: 128, G: 128, B: 128, A: 128}); got != want {
+		t.Errorf("src-in = %v, want %v", got, want)
+	}
+	if _, ok := atlasSpriteTint(color.NRGBA{}, enums.BlendModeScreen); ok {
+		t.Error("screen should not reduce to a tint")
+	}
+}
@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+)
+
+// CPU blending. Gio composites with source-over only; draws rendered on the
+// CPU blend with Skia's modes here, on premultiplied colors.
+
+// rgbaF is a premultiplied color with components in [0, 1].
+type rgbaF struct {
+	r, g, b, a float32
+}
+
+func toRGBAF(c color.RGBA) rgbaF {
+	return rgbaF{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255, float32(c.A) / 255}
+}
+
+func (c rgbaF) rgba() color.RGBA {
+	q := func(v float32) uint8 { return uint8(clampUnit(v)*255 + 0.5) }
+	a := q(c.a)
+	// Keep the color premultiplied after rounding.
+	return color.RGBA{R: min(q(c.r), a), G: min(q(c.g), a), B: min(q(c.b), a), A: a}
+}
+
+func (c rgbaF) scale(s float32) rgbaF {
+	return rgbaF{c.r * s, c.g * s, c.b * s, c.a * s}
+}
+
+// blendRGBA returns src blended onto dst with mode.
+func blendRGBA(mode enums.BlendMode, src, dst color.RGBA) color.RGBA {
+	switch {
+	case mode == enums.BlendModeSrcOver && src.A == 255:
+		return src
+	case mode == enums.BlendModeSrcOver && src.A == 0:
+		return dst
+	}
+	return blendRGBAF(mode, toRGBAF(src), toRGBAF(dst)).rgba()
+}
+
+func blendRGBAF(mode enums.BlendMode, s, d rgbaF) rgbaF {
+	// Porter-Duff modes are s·F + d·G.
+	pd := func(f, g float32) rgbaF {
+		return rgbaF{s.r*f + d.r*g, s.g*f + d.g*g, s.b*f + d.b*g, s.a*f + d.a*g}
+	}
+	switch mode {
+	case enums.BlendModeClear:
+		return rgbaF{}
+	case enums.BlendModeSrc:
+		return s
+	case enums.BlendModeDst:
+		return d
+	case enums.BlendModeSrcOver:
+		return pd(1, 1-s.a)
+	case enums.BlendModeDstOver:
+		return pd(1-d.a, 1)
+	case enums.BlendModeSrcIn:
+		return pd(d.a, 0)
+	case enums.BlendModeDstIn:
+		return pd(0, s.a)
+	case enums.BlendModeSrcOut:
+		return pd(1-d.a, 0)
+	case enums.BlendModeDstOut:
+		return pd(0, 1-s.a)
+	case enums.BlendModeSrcATop:
+		return pd(d.a, 1-s.a)
+	case enums.BlendModeDstATop:
+		return pd(1-d.a, s.a)
+	case enums.BlendModeXor:
+		return pd(1-d.a, 1-s.a)
+	case enums.BlendModePlus:
+		return rgbaF{min(s.r+d.r, 1), min(s.g+d.g, 1), min(s.b+d.b, 1), min(s.a+d.a, 1)}
+	case enums.BlendModeModulate:
+		return rgbaF{s.r * d.r, s.g * d.g, s.b * d.b, s.a * d.a}
+	case enums.BlendModeScreen:
+		return rgbaF{s.r + d.r - s.r*d.r, s.g + d.g - s.g*d.g, s.b + d.b - s.b*d.b, s.a + d.a - s.a*d.a}
+	}
+	a := s.a + d.a - s.a*d.a
+	if mode > enums.BlendModeLastSeparableMode {
+		return blendNonSeparable(mode, s, d, a)
+	}
+	f := func(sc, dc float32) float32 {
+		return blendSeparable(mode, sc, dc, s.a, d.a)
+	}
+	return rgbaF{f(s.r, d.r), f(s.g, d.g), f(s.b, d.b), a}
+}
+
+// blendSeparable blends one premultiplied component with a separable mode,
+// with Skia's formulas.
+func blendSeparable(mode enums.BlendMode, s, d, sa, da float32) float32 {
+	// Every mode adds the parts covered by only one of the colors.
+	rest := s*(1-da) + d*(1-sa)
+	hardLight := func(s, d, sa, da float32) float32 {
+		if 2*s <= sa {
+			return 2 * s * d
+		}
+		return sa*da - 2*(da-d)*(sa-s)
+	}
+	switch mode {
+	case enums.BlendModeOverlay:
+		return rest + hardLight(d, s, da, sa)
+	case enums.BlendModeDarken:
+		return s + d - max(s*da, d*sa)
+	case enums.BlendModeLighten:
+		return s + d - min(s*da, d*sa)
+	case enums.BlendModeColorDodge:
+		switch {
+		case d == 0:
+			return s * (1 - da)
+		case s >= sa:
+			return sa*da + rest
+		}
+		return sa*min(da, d*sa/(sa-s)) + rest
+	case enums.BlendModeColorBurn:
+		switch {
+		case d >= da:
+			return d + s*(1-da)
+		case s == 0:
+			return d * (1 - sa)
+		}
+		return sa*(da-min(da, (da-d)*sa/s)) + rest
+	case enums.BlendModeHardLight:
+		return rest + hardLight(s, d, sa, da)
+	case enums.BlendModeSoftLight:
+		m := float32(0)
+		if da > 0 {
+			m = d / da
+		}
+		s2, m4 := 2*s, 4*m
+		darkSrc := d * (sa + (s2-sa)*(1-m))
+		darkDst := (m4*m4+m4)*(m-1) + 7*m
+		liteDst := float32(math.Sqrt(float64(m))) - m
+		liteSrc := d*sa + da*(s2-sa)*liteDst
+		if 4*d <= da {
+			liteSrc = d*sa + da*(s2-sa)*darkDst
+		}
+		if s2 <= sa {
+			return rest + darkSrc
+		}
+		return rest + liteSrc
+	case enums.BlendModeDifference:
+		return s + d - 2*min(s*da, d*sa)
+	case enums.BlendModeExclusion:
+		return s + d - 2*s*d
+	case enums.BlendModeMultiply:
+		return rest + s*d
+	}
+	// Unknown modes draw source-over.
+	return s + d*(1-sa)
+}
+
+// blendNonSeparable blends with the hue, saturation, color and luminosity
+// modes, which mix the components of unpremultiplied colors.
+func blendNonSeparable(mode enums.BlendMode, s, d rgbaF, a float32) rgbaF {
+	unpremul := func(c rgbaF) [3]float32 {
+		if c.a <= 0 {
+			return [3]float32{}
+		}
+		return [3]float32{c.r / c.a, c.g / c.a, c.b / c.a}
+	}
+	cs, cb := unpremul(s), unpremul(d)
+	var b [3]float32
+	switch mode {
+	case enums.BlendModeHue:
+		b = setLum(setSat(cs, sat(cb)), lum(cb))
+	case enums.BlendModeSaturation:
+		b = setLum(setSat(cb, sat(cs)), lum(cb))
+	case enums.BlendModeColor:
+		b = setLum(cs, lum(cb))
+	default:
+		b = setLum(cb, lum(cs))
+	}
+	f := func(sc, dc, bc float32) float32 {
+		return sc*(1-d.a) + dc*(1-s.a) + s.a*d.a*bc
+	}
+	return rgbaF{f(s.r, d.r, b[0]), f(s.g, d.g, b[1]), f(s.b, d.b, b[2]), a}
+}
+
+func lum(c [3]float32) float32 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func sat(c [3]float32) float32 {
+	return max(c[0], c[1], c[2]) - min(c[0], c[1], c[2])
+}
+
+func setLum(c [3]float32, l float32) [3]float32 {
+	d := l - lum(c)
+	c = [3]float32{c[0] + d, c[1] + d, c[2] + d}
+	// Clip the color into gamut, keeping its luminosity.
+	l = lum(c)
+	lo, hi := min(c[0], c[1], c[2]), max(c[0], c[1], c[2])
+	for i := range c {
+		if lo < 0 && l != lo {
+			c[i] = l + (c[i]-l)*l/(l-lo)
+		}
+		if hi > 1 && hi != l {
+			c[i] = l + (c[i]-l)*(1-l)/(hi-l)
+		}
+	}
+	return c
+}
+
+func setSat(c [3]float32, s float32) [3]float32 {
+	lo, hi := min(c[0], c[1], c[2]), max(c[0], c[1], c[2])
+	if hi <= lo {
+		return [3]float32{}
+	}
+	for i := range c {
+		c[i] = (c[i] - lo) * s / (hi - lo)
+	}
+	return c
+}
@@ -8,7 +8,6 @@ import (
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	gpaint "gioui.org/op/paint"
-	"github.com/zodimo/gio-skia/pkg/stroke"
 	"github.com/zodimo/go-skia-support/skia/enums"
 	"github.com/zodimo/go-skia-support/skia/impl"
 	"github.com/zodimo/go-skia-support/skia/interfaces"
@@ -17,25 +16,61 @@ import (
 )
 
 // Compile-time check that canvas implements Canvas interface
-var _ Canvas = (*canvas)(nil)
+var _ Extended = (*canvas)(nil)
 
 type canvas struct {
 	ops   *op.Ops
 	stack []context
+	// bounds is the device area of the canvas.
+	bounds models.Rect
+	// culled counts the draws skipped by culling.
+	culled int
+
+	textRenderMode   TextRenderMode
+	glyphMaskOptions GlyphMaskOptions
+	strokeMode       StrokeMode
 }
 
 type context struct {
 	xform f32.Affine2D
-	clips []clip.Op
+	// persp is the full transform if it has perspective, in which case
+	// xform holds only its affine part.
+	persp SkMatrix
+	clips []clipEntry
+}
+
+// clipEntry is a clip with its conservative device space bounds.
+type clipEntry struct {
+	op     clip.Op
+	bounds models.Rect
+	// isRect is set if the clip is exactly its bounds.
+	isRect bool
 }
 
+// wideOpenExtent bounds the device area of canvases of unknown size.
+const wideOpenExtent = 1 << 29
+
 // NewCanvas returns a Canvas implementation backed by Gio's GPU renderer.
+// The device area is unbounded; use NewCanvasWithSize to clip queries and
+// culling to the window.
 func NewCanvas(ops *op.Ops) Canvas {
+	return newCanvas(ops, models.Rect{Left: -wideOpenExtent, Top: -wideOpenExtent, Right: wideOpenExtent, Bottom: wideOpenExtent})
+}
+
+// NewCanvasWithSize returns a Canvas whose device area is width by height
+// pixels.
+func NewCanvasWithSize(ops *op.Ops, width, height int) Canvas {
+	return newCanvas(ops, models.Rect{Right: Scalar(width), Bottom: Scalar(height)})
+}
+
+func newCanvas(ops *op.Ops, bounds models.Rect) *canvas {
 	return &canvas{
-		ops: ops,
+		ops:    ops,
+		bounds: bounds,
 		stack: []context{{
 			xform: f32.Affine2D{},
 		}},
+		glyphMaskOptions: DefaultGlyphMaskOptions,
 	}
 }
 
@@ -44,11 +79,12 @@ func NewCanvas(ops *op.Ops) Canvas {
 func (c *canvas) Save() int {
 	top := c.stack[len(c.stack)-1]
 	// Deep copy the clips slice to ensure isolation
-	newClips := make([]clip.Op, len(top.clips))
+	newClips := make([]clipEntry, len(top.clips))
 	copy(newClips, top.clips)
 
 	newCtx := context{
 		xform: top.xform,
+		persp: top.persp,
 		clips: newClips,
 	}
 	c.stack = append(c.stack, newCtx)
@@ -63,14 +99,18 @@ func (c *canvas) Restore() {
 
 func (c *canvas) Concat(matrix SkMatrix) {
 	top := &c.stack[len(c.stack)-1]
-	// Convert current f32.Affine2D to SkMatrix, concat, then convert back
-	currentMatrix := affine2DToSkMatrix(top.xform)
+	// Convert current transform to SkMatrix, concat, then convert back
+	currentMatrix := top.matrix()
 	// Create a new matrix for the result
 	resultMatrix := impl.NewMatrixIdentity()
 	// Apply new matrix AFTER current matrix (Post-concatenation)
 	// NewCTM = CTM * Matrix
 	resultMatrix.SetConcat(currentMatrix, matrix)
 	top.xform = skMatrixToAffine2D(resultMatrix)
+	top.persp = nil
+	if resultMatrix.HasPerspective() {
+		top.persp = resultMatrix
+	}
 }
 
 func (c *canvas) Translate(dx, dy Scalar) {
@@ -103,159 +143,54 @@ func (c *canvas) RotateFloat32(degrees float32) {
 }
 
 // drawPathInternal is the internal implementation that handles the actual drawing.
-func (c *canvas) drawPathInternal(path SkPath, paint SkPaint) {
+// Converted paths are cached for reuse if cache is set.
+func (c *canvas) drawPathInternal(path SkPath, paint SkPaint, cache bool) {
 	// Convert SkPaint to our internal Paint type for rendering
 	internalPaint := skPaintToPaint(paint)
+	if paint.GetPathEffect() != nil {
+		rec := NewStrokeRec(paint, c.resScale())
+		path = applyPathEffect(path, paint, &rec)
+		internalPaint = rec.applyTo(internalPaint)
+	}
+	if path.IsEmpty() {
+		return
+	}
+	deviceOpts, device := c.deviceStroke(internalPaint)
+	inflate := strokeInflation(internalPaint)
+	if device {
+		inflate = c.localInflation(strokeInflation(Paint{Stroke: deviceOpts}))
+	}
+	if !path.IsInverseFillType() && c.cullDraw(path.Bounds(), inflate) {
+		return
+	}
+	if device {
+		c.drawDeviceStroke(path, internalPaint, deviceOpts)
+		return
+	}
+	ctx := &c.stack[len(c.stack)-1]
+	if ctx.persp != nil {
+		c.drawPathPerspective(path, internalPaint, cache)
+		return
+	}
 	// Apply all clips in the current context
 	// These are stored in device space, so apply them BEFORE the current transform
-	ctx := &c.stack[len(c.stack)-1]
 	for _, cl := range ctx.clips {
-		stack := cl.Push(c.ops)
+		stack := cl.op.Push(c.ops)
 		defer stack.Pop()
 	}
 
 	transformSave := op.Affine(c.stack[len(c.stack)-1].xform).Push(c.ops)
 	defer transformSave.Pop()
 
-	if path.IsEmpty() {
-		return
-	}
-
-	// Get path data for iteration
-	verbCount := path.CountVerbs()
-	verbs := make([]enums.PathVerb, verbCount)
-	path.GetVerbs(verbs)
-
-	pointCount := path.CountPoints()
-	points := make([]models.Point, pointCount)
-	path.GetPoints(points)
-
-	conicWeights := path.ConicWeights()
-
-	// Use go-skia-support's PathIter for proper path iteration
-	iter := impl.NewPathIter(points, verbs, conicWeights)
-
-	// Build GioUI paths
 	var b clip.Path
 	b.Begin(c.ops)
-	// Build stroke.Path in parallel
-	var s stroke.Path
-	var start f32.Point
-	var current f32.Point
-	hasStart := false
-
-	for rec := iter.Next(); rec != nil; rec = iter.Next() {
-		verb := rec.Verb
-		pts := rec.Points
-
-		if len(pts) == 0 {
-			continue
-		}
-
-		switch verb {
-		case enums.PathVerbMove:
-			pt := f32.Pt(float32(pts[0].X), float32(pts[0].Y))
-			b.MoveTo(pt)
-			s.Segments = append(s.Segments, stroke.MoveTo(pt))
-			start = pt
-			current = pt
-			hasStart = true
-
-		case enums.PathVerbLine:
-			if len(pts) >= 2 {
-				pt := f32.Pt(float32(pts[1].X), float32(pts[1].Y))
-				b.LineTo(pt)
-				s.Segments = append(s.Segments, stroke.LineTo(pt))
-				current = pt
-			}
-
-		case enums.PathVerbQuad:
-			if len(pts) >= 3 {
-				ctrl := f32.Pt(float32(pts[1].X), float32(pts[1].Y))
-				end := f32.Pt(float32(pts[2].X), float32(pts[2].Y))
-				// Convert quadratic to cubic: CP1 = current + 2/3*(ctrl - current), CP2 = end + 2/3*(ctrl - end)
-				cp1 := f32.Pt(
-					current.X+2.0/3.0*(ctrl.X-current.X),
-					current.Y+2.0/3.0*(ctrl.Y-current.Y),
-				)
-				cp2 := f32.Pt(
-					end.X+2.0/3.0*(ctrl.X-end.X),
-					end.Y+2.0/3.0*(ctrl.Y-end.Y),
-				)
-				b.CubeTo(cp1, cp2, end)
-				s.Segments = append(s.Segments, stroke.QuadTo(ctrl, end))
-				current = end
-			}
-
-		case enums.PathVerbConic:
-			if len(pts) >= 3 {
-				ctrl := f32.Pt(float32(pts[1].X), float32(pts[1].Y))
-				end := f32.Pt(float32(pts[2].X), float32(pts[2].Y))
-				weight := rec.ConicWeight
-				if weight < 0 {
-					weight = 1.0 // Default weight
-				}
-				// Convert conic to cubic using the weight
-				// For now, treat as quadratic (weight=1) - can be enhanced later
-				cp1 := f32.Pt(
-					current.X+2.0/3.0*(ctrl.X-current.X),
-					current.Y+2.0/3.0*(ctrl.Y-current.Y),
-				)
-				cp2 := f32.Pt(
-					end.X+2.0/3.0*(ctrl.X-end.X),
-					end.Y+2.0/3.0*(ctrl.Y-end.Y),
-				)
-				b.CubeTo(cp1, cp2, end)
-				s.Segments = append(s.Segments, stroke.QuadTo(ctrl, end))
-				current = end
-			}
-
-		case enums.PathVerbCubic:
-			if len(pts) >= 4 {
-				c1 := f32.Pt(float32(pts[1].X), float32(pts[1].Y))
-				c2 := f32.Pt(float32(pts[2].X), float32(pts[2].Y))
-				end := f32.Pt(float32(pts[3].X), float32(pts[3].Y))
-				b.CubeTo(c1, c2, end)
-				s.Segments = append(s.Segments, stroke.CubeTo(c1, c2, end))
-				current = end
-			}
-
-		case enums.PathVerbClose:
-			if hasStart {
-				b.LineTo(start)
-				s.Segments = append(s.Segments, stroke.LineTo(start))
-				current = start
-			}
-		}
-	}
-
-	pathSpec := b.End()
-
-	if internalPaint.Fill {
-		clipSave := clip.Outline{Path: pathSpec}.Op().Push(c.ops)
-		gpaint.ColorOp{Color: internalPaint.Color}.Add(c.ops)
-		gpaint.PaintOp{}.Add(c.ops)
-		clipSave.Pop()
-	} else {
-		contours := stroke.StrokedContours(s, internalPaint.Stroke)
-		var stroked clip.Path
-		stroked.Begin(c.ops)
-		for _, contour := range contours {
-			for i, seg := range contour {
-				if i == 0 {
-					stroked.MoveTo(f32.Point(seg.Start))
-				}
-				stroked.CubeTo(f32.Point(seg.CP1), f32.Point(seg.CP2), f32.Point(seg.End))
-			}
-		}
-		strokePathSpec := stroked.End()
-		gpaint.FillShape(c.ops, internalPaint.Color, clip.Outline{Path: strokePathSpec}.Op())
-	}
+	convertPaintPath(path, internalPaint, c.pathTolerance(conicTolerance), cache, &b)
+	gpaint.FillShape(c.ops, internalPaint.Color, clip.Outline{Path: b.End()}.Op())
 }
 
 // DrawPath implements SkCanvas.DrawPath - matches SkCanvas signature.
 func (c *canvas) DrawPath(path SkPath, paint SkPaint) {
-	c.drawPathInternal(path, paint)
+	c.drawPathInternal(path, paint, true)
 }
 
 // ── State Management (additional methods) ───────────────────────────────────
@@ -286,6 +221,7 @@ func (c *canvas) Skew(sx, sy Scalar) {
 func (c *canvas) ResetMatrix() {
 	if len(c.stack) > 0 {
 		c.stack[len(c.stack)-1].xform = f32.Affine2D{}
+		c.stack[len(c.stack)-1].persp = nil
 	}
 }
 
@@ -307,7 +243,7 @@ func (c *canvas) DrawPaint(paint SkPaint) {
 	// Apply all clips in the current context
 	ctx := &c.stack[len(c.stack)-1]
 	for _, cl := range ctx.clips {
-		stack := cl.Push(c.ops)
+		stack := cl.op.Push(c.ops)
 		defer stack.Pop()
 	}
 
@@ -350,6 +286,11 @@ func (c *canvas) DrawArc(oval models.Rect, startAngle, sweepAngle Scalar, useCen
 	if sweepAngle == 0 {
 		return
 	}
+	c.DrawPath(arcPath(oval, startAngle, sweepAngle, useCenter), paint)
+}
+
+// arcPath returns the path DrawArc draws.
+func arcPath(oval models.Rect, startAngle, sweepAngle Scalar, useCenter bool) SkPath {
 	path := impl.NewSkPath(enums.PathFillTypeWinding)
 	if useCenter {
 		// Wedge: start from center
@@ -362,7 +303,7 @@ func (c *canvas) DrawArc(oval models.Rect, startAngle, sweepAngle Scalar, useCen
 		// Arc only
 		path.AddArc(oval, startAngle, sweepAngle)
 	}
-	c.DrawPath(path, paint)
+	return path
 }
 
 func (c *canvas) DrawCircle(center models.Point, radius Scalar, paint SkPaint) {
@@ -375,7 +316,11 @@ func (c *canvas) DrawPoints(mode enums.PointMode, points []models.Point, paint S
 	if len(points) < 1 {
 		return
 	}
+	c.DrawPath(pointsPath(mode, points, paint), paint)
+}
 
+// pointsPath returns the path DrawPoints draws.
+func pointsPath(mode enums.PointMode, points []models.Point, paint SkPaint) SkPath {
 	path := impl.NewSkPath(enums.PathFillTypeWinding)
 	switch mode {
 	case enums.PointModePoints:
@@ -402,7 +347,7 @@ func (c *canvas) DrawPoints(mode enums.PointMode, points []models.Point, paint S
 			}
 		}
 	}
-	c.DrawPath(path, paint)
+	return path
 }
 
 func (c *canvas) DrawLine(p0, p1 models.Point, paint SkPaint) {
@@ -411,67 +356,29 @@ func (c *canvas) DrawLine(p0, p1 models.Point, paint SkPaint) {
 
 // ── Image Drawing ───────────────────────────────────────────────────
 
+// DrawImage draws image at (left, top) at its natural size, filtered
+// linearly.
 func (c *canvas) DrawImage(image interfaces.SkImage, left, top Scalar, paint SkPaint) {
-	if image == nil {
-		return
-	}
-
-	// Convert SkImage to Go image.RGBA
-	goImage := c.skImageToGoImage(image)
-	if goImage == nil {
-		return
-	}
-
-	// Apply all clips
-	ctx := &c.stack[len(c.stack)-1]
-	for _, cl := range ctx.clips {
-		stack := cl.Push(c.ops)
-		defer stack.Pop()
-	}
-
-	// Apply current transformation
-	transformSave := op.Affine(c.stack[len(c.stack)-1].xform).Push(c.ops)
-	defer transformSave.Pop()
-
-	// Translate to the target position
-	translateOp := op.Affine(f32.Affine2D{}.Offset(f32.Pt(float32(left), float32(top)))).Push(c.ops)
-	defer translateOp.Pop()
-
-	// Create the image op
-	imgOp := gpaint.NewImageOp(goImage)
-	imgOp.Add(c.ops)
-
-	// Clip to image bounds and paint
-	imgSize := goImage.Bounds().Size()
-	clipRect := clip.Rect{Max: imgSize}.Push(c.ops)
-	gpaint.PaintOp{}.Add(c.ops)
-	clipRect.Pop()
+	c.DrawImageWithSampling(image, left, top, defaultImageSampling, paint)
 }
 
+// DrawImageRect draws the src part of image, or all of it if src is nil,
+// scaled into dst. It is filtered linearly and may sample pixels just
+// outside src, so that sprites of one image share its upload; use
+// DrawImageRectWithSampling with SrcRectConstraintStrict to prevent that.
 func (c *canvas) DrawImageRect(skImg interfaces.SkImage, src *models.Rect, dst models.Rect, paint SkPaint) {
-	if skImg == nil {
-		return
-	}
-
-	// Convert SkImage to Go image.RGBA
-	goImage := c.skImageToGoImage(skImg)
-	if goImage == nil {
-		return
-	}
+	c.DrawImageRectWithSampling(skImg, src, dst, defaultImageSampling, paint, enums.SrcRectConstraintFast)
+}
 
-	// Calculate the source rect (if nil, use full image)
-	var srcRect models.Rect
-	if src != nil {
-		srcRect = *src
-	} else {
-		srcRect = models.Rect{
-			Left:   0,
-			Top:    0,
-			Right:  Scalar(skImg.Width()),
-			Bottom: Scalar(skImg.Height()),
-		}
-	}
+// drawRGBAImageRect draws the src part of img scaled into dst, under the
+// current clips and transform.
+func (c *canvas) drawRGBAImageRect(img *image.RGBA, srcRect, dst models.Rect) {
+	c.drawImageOpRect(gpaint.NewImageOp(img), srcRect, dst)
+}
 
+// drawImageOpRect draws the src part of the image of imgOp scaled into dst,
+// under the current clips and transform.
+func (c *canvas) drawImageOpRect(imgOp gpaint.ImageOp, srcRect, dst models.Rect) {
 	srcWidth := srcRect.Right - srcRect.Left
 	srcHeight := srcRect.Bottom - srcRect.Top
 	dstWidth := dst.Right - dst.Left
@@ -480,17 +387,18 @@ func (c *canvas) DrawImageRect(skImg interfaces.SkImage, src *models.Rect, dst m
 	if srcWidth <= 0 || srcHeight <= 0 || dstWidth <= 0 || dstHeight <= 0 {
 		return
 	}
+	if c.cullDraw(dst, 0) {
+		return
+	}
 
-	// Apply all clips
-	ctx := &c.stack[len(c.stack)-1]
-	for _, cl := range ctx.clips {
-		stack := cl.Push(c.ops)
-		defer stack.Pop()
+	if c.stack[len(c.stack)-1].persp != nil {
+		c.drawImagePerspective(imgOp, srcRect, dst)
+		return
 	}
 
-	// Apply current transformation
-	transformSave := op.Affine(c.stack[len(c.stack)-1].xform).Push(c.ops)
-	defer transformSave.Pop()
+	// Apply all clips and the current transformation
+	pop := c.pushContext()
+	defer pop()
 
 	// Translate to destination position
 	translateOp := op.Affine(f32.Affine2D{}.Offset(f32.Pt(float32(dst.Left), float32(dst.Top)))).Push(c.ops)
@@ -521,35 +429,26 @@ func (c *canvas) DrawImageRect(skImg interfaces.SkImage, src *models.Rect, dst m
 		defer srcOffsetOp.Pop()
 	}
 
-	// Create the image op
-	imgOp := gpaint.NewImageOp(goImage)
 	imgOp.Add(c.ops)
 
 	gpaint.PaintOp{}.Add(c.ops)
 }
 
-// skImageToGoImage converts a SkImage to Go's image.RGBA
-func (c *canvas) skImageToGoImage(skImg interfaces.SkImage) *image.RGBA {
-	width := skImg.Width()
-	height := skImg.Height()
-	if width <= 0 || height <= 0 {
-		return nil
+// pushContext pushes the clips and transformation of the current context.
+// The returned function pops them again.
+func (c *canvas) pushContext() (pop func()) {
+	ctx := &c.stack[len(c.stack)-1]
+	stacks := make([]clip.Stack, 0, len(ctx.clips))
+	for _, cl := range ctx.clips {
+		stacks = append(stacks, cl.op.Push(c.ops))
 	}
-
-	// Create destination image
-	goImg := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// Try to read pixels from SkImage
-	info := skImg.ImageInfo()
-	dstRowBytes := width * 4 // RGBA = 4 bytes per pixel
-
-	// Read pixels into the Go image's buffer
-	success := skImg.ReadPixels(info, goImg.Pix, dstRowBytes, 0, 0)
-	if !success {
-		return nil
+	transformSave := op.Affine(ctx.xform).Push(c.ops)
+	return func() {
+		transformSave.Pop()
+		for i := len(stacks) - 1; i >= 0; i-- {
+			stacks[i].Pop()
+		}
 	}
-
-	return goImg
 }
 
 // ── Clipping ───────────────────────────────────────────────────
@@ -558,100 +457,63 @@ func (c *canvas) ClipRect(rect models.Rect, clipOp enums.ClipOp, doAntiAlias boo
 	// Build the clip path
 	path := impl.NewSkPath(enums.PathFillTypeWinding)
 	path.AddRect(rect, enums.PathDirectionCW, 0)
-	c.clipPathInternal(path, clipOp, doAntiAlias)
+	// The clip stays a rect if the transform keeps rects axis aligned.
+	ctx := &c.stack[len(c.stack)-1]
+	isRect := ctx.persp == nil && ctx.matrix().RectStaysRect()
+	c.clipPathInternal(path, clipOp, isRect)
 }
 
 func (c *canvas) ClipRRect(rrect models.RRect, clipOp enums.ClipOp, doAntiAlias bool) {
 	// Build clip path from RRect
 	path := impl.NewSkPath(enums.PathFillTypeWinding)
 	path.AddRRect(rrect, enums.PathDirectionCW)
-	c.clipPathInternal(path, clipOp, doAntiAlias)
+	c.clipPathInternal(path, clipOp, false)
 }
 
 func (c *canvas) ClipPath(path SkPath, clipOp enums.ClipOp, doAntiAlias bool) {
-	c.clipPathInternal(path, clipOp, doAntiAlias)
+	c.clipPathInternal(path, clipOp, false)
 }
 
-func (c *canvas) clipPathInternal(path SkPath, clipOp enums.ClipOp, doAntiAlias bool) {
+func (c *canvas) clipPathInternal(path SkPath, clipOp enums.ClipOp, isRect bool) {
 	// 1. Transform path to device space (current context transform)
 	// We need to bake the current transform into the clip path because
 	// we will apply these clips *before* applying the transform stack during draw.
 	ctx := &c.stack[len(c.stack)-1]
-	currentMatrix := affine2DToSkMatrix(ctx.xform)
-
-	// Copy path to avoid modifying the input
-	// TODO: Clone path? For now assuming input path can be modified or we should make a new one?
-	// SkCanvas ClipPath takes const SkPath&, so we should arguably copy.
-	// But go-skia-support Path might be mutable ref.
-	// Let's create a new path and AddPath(path, matrix)
-	devicePath := impl.NewSkPath(path.FillType())
-	devicePath.AddPathMatrix(path, currentMatrix, enums.AddPathModeAppend)
-
-	clipPath := c.buildPathClip(devicePath)
-	c.applyClip(clipPath, clipOp)
+	var devicePath SkPath
+	if ctx.persp != nil {
+		devicePath = mapPathPerspective(path, ctx.persp)
+	} else {
+		// Create a new path so the input is not modified
+		devicePath = impl.NewSkPath(path.FillType())
+		devicePath.AddPathMatrix(path, affine2DToSkMatrix(ctx.xform), enums.AddPathModeAppend)
+	}
+
+	entry := clipEntry{
+		bounds: devicePath.Bounds(),
+		isRect: isRect,
+	}
+	if clipOp == enums.ClipOpDifference {
+		// Gio clips only intersect, so clip to the current clip bounds
+		// minus the path. Subtracting cannot grow the clip, so those
+		// bounds stay conservative.
+		entry.bounds = c.deviceClipBounds()
+		entry.isRect = false
+		if isEmptyRect(entry.bounds) {
+			devicePath = impl.NewSkPath(enums.PathFillTypeWinding)
+		} else if diff, ok := Op(pathOpsRect(entry.bounds), devicePath, PathOpDifference); ok {
+			devicePath = diff
+		}
+	}
+	entry.op = c.buildPathClip(devicePath)
+	c.applyClip(entry)
 }
 
 // buildPathClip converts a SkPath to Gio clip.Path
 func (c *canvas) buildPathClip(path SkPath) clip.Op {
 	var clipPath clip.Path
 	clipPath.Begin(c.ops)
-
-	verbs := make([]enums.PathVerb, path.CountVerbs())
-	path.GetVerbs(verbs)
-
-	points := make([]models.Point, path.CountPoints())
-	path.GetPoints(points)
-	conicWeights := path.ConicWeights()
-
-	pointIdx := 0
-	conicIdx := 0
-
-	for _, verb := range verbs {
-		switch verb {
-		case enums.PathVerbMove:
-			pt := points[pointIdx]
-			clipPath.MoveTo(f32.Pt(float32(pt.X), float32(pt.Y)))
-			pointIdx++
-		case enums.PathVerbLine:
-			pt := points[pointIdx]
-			clipPath.LineTo(f32.Pt(float32(pt.X), float32(pt.Y)))
-			pointIdx++
-		case enums.PathVerbQuad:
-			ctrl := points[pointIdx]
-			end := points[pointIdx+1]
-			clipPath.QuadTo(
-				f32.Pt(float32(ctrl.X), float32(ctrl.Y)),
-				f32.Pt(float32(end.X), float32(end.Y)),
-			)
-			pointIdx += 2
-		case enums.PathVerbConic:
-			ctrl := points[pointIdx]
-			end := points[pointIdx+1]
-			weight := conicWeights[conicIdx]
-			// Gio doesn't have native conic support, approximate with quad
-			// For weight = 1, conic = quad
-			_ = weight
-			clipPath.QuadTo(
-				f32.Pt(float32(ctrl.X), float32(ctrl.Y)),
-				f32.Pt(float32(end.X), float32(end.Y)),
-			)
-			pointIdx += 2
-			conicIdx++
-		case enums.PathVerbCubic:
-			c1 := points[pointIdx]
-			c2 := points[pointIdx+1]
-			end := points[pointIdx+2]
-			clipPath.CubeTo(
-				f32.Pt(float32(c1.X), float32(c1.Y)),
-				f32.Pt(float32(c2.X), float32(c2.Y)),
-				f32.Pt(float32(end.X), float32(end.Y)),
-			)
-			pointIdx += 3
-		case enums.PathVerbClose:
-			clipPath.Close()
-		}
-	}
-
+	// Clip paths are in device space.
+	convertPath(path, conicTolerance, &clipPath)
 	return clip.Outline{Path: clipPath.End()}.Op()
 }
 
@@ -659,17 +521,24 @@ func (c *canvas) buildPathClip(path SkPath) clip.Op {
 // Note: Gio applies clips at draw time, so we track them in the context
 // applyClip stores the clip operation in the context
 // Note: Gio applies clips at draw time, so we track them in the context
-func (c *canvas) applyClip(clipOp clip.Op, op enums.ClipOp) {
-	// Append the new clip to the current context
-	// We currently treat all clips as Intersect (the default behavior of sequential clips)
-	// TODO: Handle ClipOp.Difference if needed (requires more complex masking)
+func (c *canvas) applyClip(entry clipEntry) {
+	// Append the new clip to the current context. Sequential clips
+	// intersect; difference clips arrive already subtracted.
 	ctx := &c.stack[len(c.stack)-1]
-	ctx.clips = append(ctx.clips, clipOp)
+	ctx.clips = append(ctx.clips, entry)
 }
 
 // ── Text Drawing ───────────────────────────────────────────────────
 
 func (c *canvas) DrawTextBlob(blob interfaces.SkTextBlob, x, y Scalar, paint SkPaint) {
+	forEachGlyph(blob, x, y, func(font interfaces.SkFont, glyphID uint16, matrix SkMatrix) {
+		c.drawGlyph(font, glyphID, matrix, paint)
+	})
+}
+
+// forEachGlyph calls fn with every glyph of blob drawn at (x, y) and the
+// matrix mapping the glyph's font units to canvas coordinates.
+func forEachGlyph(blob interfaces.SkTextBlob, x, y Scalar, fn func(font interfaces.SkFont, glyphID uint16, matrix SkMatrix)) {
 	if blob == nil {
 		return
 	}
@@ -699,12 +568,10 @@ func (c *canvas) DrawTextBlob(blob interfaces.SkTextBlob, x, y Scalar, paint SkP
 			unitsPerEm = 2048 // Default if not available
 		}
 		scaleFactor := fontSize / Scalar(unitsPerEm)
-		scaleX := scaleFactor * run.Font.ScaleX()
-		scaleY := scaleFactor
 		skewX := run.Font.SkewX()
 
-		// Helper helper to clean up matrix creation
 		// baseMatrix = Skew * Scale(units->pixels)
+		// Note: Font units have Y pointing up, so Y is flipped
 		scaleM := impl.NewMatrixScale(scaleFactor*run.Font.ScaleX(), -scaleFactor)
 		skewM := impl.NewMatrixSkew(skewX, 0)
 		baseMatrix := impl.NewMatrixIdentity()
@@ -718,12 +585,6 @@ func (c *canvas) DrawTextBlob(blob interfaces.SkTextBlob, x, y Scalar, paint SkP
 				}
 				xform := run.RSXforms[glyphIdx]
 
-				// Get glyph path
-				glyphPath, err := typeface.GetGlyphPath(uint16(glyphID))
-				if err != nil || glyphPath == nil {
-					continue
-				}
-
 				// Create RSXform matrix
 				// [ SCos  -SSin  Tx ]
 				// [ SSin   SCos  Ty ]
@@ -737,13 +598,7 @@ func (c *canvas) DrawTextBlob(blob interfaces.SkTextBlob, x, y Scalar, paint SkP
 				// Combine: Final = RS * Base
 				matrix := impl.NewMatrixIdentity()
 				matrix.SetConcat(rsMatrix, baseMatrix)
-
-				// Transform path
-				transformedPath := impl.NewSkPath(glyphPath.FillType())
-				transformedPath.AddPathMatrix(glyphPath, matrix, enums.AddPathModeAppend)
-
-				// Draw
-				c.DrawPath(transformedPath, paint)
+				fn(run.Font, uint16(glyphID), matrix)
 			}
 			continue
 		}
@@ -753,77 +608,67 @@ func (c *canvas) DrawTextBlob(blob interfaces.SkTextBlob, x, y Scalar, paint SkP
 			}
 			pos := run.Positions[glyphIdx]
 
-			// Get glyph path from typeface
-			glyphPath, err := typeface.GetGlyphPath(uint16(glyphID))
-			if err != nil || glyphPath == nil {
-				continue
-			}
-
-			// Scale and position the glyph
+			// Combine: Final = T * Base
 			// Note: We bake the blob origin (x, y) into the glyph position
-			c.drawGlyphPath(glyphPath, pos.X+x, pos.Y+y, scaleX, scaleY, skewX, paint)
+			transM := impl.NewMatrixTranslate(pos.X+x, pos.Y+y)
+			matrix := impl.NewMatrixIdentity()
+			matrix.SetConcat(transM, baseMatrix)
+			fn(run.Font, uint16(glyphID), matrix)
 		}
 	}
 }
 
-// drawGlyphPath draws a single glyph path at the specified position with scaling and skew
-func (c *canvas) drawGlyphPath(path interfaces.SkPath, posX, posY, scaleX, scaleY, skewX Scalar, paint SkPaint) {
-	// Create transform matrix for this glyph: Translate * Skew * Scale
-	// Note: Font paths are typically in font units with Y pointing up,
-	// so we need to flip Y (scaleY is likely passed as positive, so we flip it here)
-	scaleM := impl.NewMatrixScale(scaleX, -scaleY)
-	skewM := impl.NewMatrixSkew(skewX, 0)
-	transM := impl.NewMatrixTranslate(posX, posY)
-
-	// Combine: T * Sk * Sc
-	matrix := impl.NewMatrixIdentity()
-	matrix.SetConcat(skewM, scaleM)
-	tmp := impl.NewMatrixIdentity() // Helper because SetConcat takes (A, B) -> A*B
-	tmp.SetConcat(transM, matrix)   // T * (Sk * Sc)
-	matrix = tmp
-
-	// Transform the path
-	transformedPath := impl.NewSkPath(path.FillType())
-	transformedPath.AddPathMatrix(path, matrix, enums.AddPathModeAppend)
-
-	// Draw the transformed path using DrawPath
-	// This ensures proper CTM application and clipping
-	c.DrawPath(transformedPath, paint)
-}
-
 func (c *canvas) DrawSimpleText(text []byte, encoding enums.TextEncoding, x, y Scalar, font interfaces.SkFont, paint SkPaint) {
 	if len(text) == 0 || font == nil {
 		return
 	}
 
-	// Convert to string (assume UTF-8 for now)
-	var textStr string
-	switch encoding {
-	case enums.TextEncodingUTF8:
-		textStr = string(text)
-	case enums.TextEncodingUTF16, enums.TextEncodingUTF32:
-		// For now, fall back to simple byte-to-string
-		// Full implementation would decode properly
-		textStr = string(text)
-	case enums.TextEncodingGlyphID:
-		// Glyph IDs can't be shaped - would need different path
-		return
-	default:
-		textStr = string(text)
+	if blob := simpleTextBlob(text, encoding, font); blob != nil {
+		c.DrawTextBlob(blob, x, y, paint)
+	}
+}
+
+// simpleTextBlob returns the blob DrawSimpleText draws, or nil. Glyph IDs
+// are drawn as given; everything else is decoded and shaped, reusing the
+// shaping cache shared with MeasureText.
+func simpleTextBlob(text []byte, encoding enums.TextEncoding, font interfaces.SkFont) interfaces.SkTextBlob {
+	if encoding == enums.TextEncodingGlyphID {
+		blob, _ := glyphIDBlob(glyphIDs(text), font)
+		return blob
 	}
+	textStr, _ := decodeText(text, encoding)
+	return shapeTextCached(textStr, font).blob
+}
 
+// shapeTextAdvance shapes text on a single left-to-right line at the
+// origin, returning the blob and its advance.
+func shapeTextAdvance(text string, font interfaces.SkFont) (interfaces.SkTextBlob, Scalar) {
 	// Create shaper and handler
 	hbShaper := shaper.NewHarfbuzzShaper()
-	handler := shaper.NewTextBlobBuilderRunHandler(textStr, models.Point{X: 0, Y: 0})
+	handler := &advanceRunHandler{
+		TextBlobBuilderRunHandler: shaper.NewTextBlobBuilderRunHandler(text, models.Point{X: 0, Y: 0}),
+	}
 
 	// Shape the text (left-to-right, no width limit)
-	hbShaper.Shape(textStr, font, true, 0, handler, nil)
+	hbShaper.Shape(text, font, true, 0, handler, nil)
 
 	// Build the text blob
 	blob := handler.MakeBlob()
-	if blob != nil {
-		c.DrawTextBlob(blob, x, y, paint)
+	if blob == nil {
+		return nil, 0
 	}
+	return blob, handler.advance
+}
+
+// advanceRunHandler builds a text blob and sums the advances of its runs.
+type advanceRunHandler struct {
+	*shaper.TextBlobBuilderRunHandler
+	advance Scalar
+}
+
+func (h *advanceRunHandler) CommitRunBuffer(info shaper.RunInfo) {
+	h.advance += info.Advance.X
+	h.TextBlobBuilderRunHandler.CommitRunBuffer(info)
 }
 
 func (c *canvas) DrawString(str string, x, y Scalar, font interfaces.SkFont, paint SkPaint) {
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// ── Queries ───────────────────────────────────────────────────
+
+// GetTotalMatrix returns a copy of the current transform.
+func (c *canvas) GetTotalMatrix() SkMatrix {
+	e := c.stack[len(c.stack)-1].matrix().Get9()
+	return impl.NewMatrixAll(e[0], e[1], e[2], e[3], e[4], e[5], e[6], e[7], e[8])
+}
+
+// deviceClipBounds returns the conservative bounds of the clip in device
+// space. It is empty if nothing can be drawn.
+func (c *canvas) deviceClipBounds() models.Rect {
+	bounds := c.bounds
+	for _, cl := range c.stack[len(c.stack)-1].clips {
+		bounds = intersectRect(bounds, cl.bounds)
+	}
+	return bounds
+}
+
+// GetDeviceClipBounds returns the bounds of the clip in device pixels,
+// rounded out.
+func (c *canvas) GetDeviceClipBounds() models.IRect {
+	b := c.deviceClipBounds()
+	if isEmptyRect(b) {
+		return models.IRect{}
+	}
+	return models.IRect{
+		Left:   int32(math.Floor(float64(b.Left))),
+		Top:    int32(math.Floor(float64(b.Top))),
+		Right:  int32(math.Ceil(float64(b.Right))),
+		Bottom: int32(math.Ceil(float64(b.Bottom))),
+	}
+}
+
+// GetLocalClipBounds returns the bounds of the clip in local coordinates.
+// Like Skia, the device bounds are outset by a pixel to cover antialiased
+// edges. It is empty if the clip is empty or the transform is singular.
+func (c *canvas) GetLocalClipBounds() models.Rect {
+	b := c.deviceClipBounds()
+	if isEmptyRect(b) {
+		return models.Rect{}
+	}
+	inv, ok := c.stack[len(c.stack)-1].matrix().Invert()
+	if !ok {
+		return models.Rect{}
+	}
+	return inv.MapRect(b.MakeOutset(1, 1))
+}
+
+// IsClipEmpty reports whether the clip excludes everything.
+func (c *canvas) IsClipEmpty() bool {
+	return isEmptyRect(c.deviceClipBounds())
+}
+
+// IsClipRect reports whether the clip is a non-empty axis aligned
+// rectangle in device space.
+func (c *canvas) IsClipRect() bool {
+	if c.IsClipEmpty() {
+		return false
+	}
+	for _, cl := range c.stack[len(c.stack)-1].clips {
+		if !cl.isRect {
+			return false
+		}
+	}
+	return true
+}
+
+// QuickReject reports whether rect, in local coordinates, is certainly
+// outside the clip. False does not mean that rect is visible.
+func (c *canvas) QuickReject(rect models.Rect) bool {
+	clipBounds := c.deviceClipBounds()
+	if isEmptyRect(clipBounds) || !isFiniteRect(rect) {
+		return true
+	}
+	// Outset the clip for antialiased edges.
+	dev := c.stack[len(c.stack)-1].matrix().MapRect(sortRect(rect))
+	return !rectsIntersect(dev, clipBounds.MakeOutset(1, 1))
+}
+
+// QuickRejectPath reports whether path is certainly outside the clip.
+// Inverse filled paths are only rejected by an empty clip.
+func (c *canvas) QuickRejectPath(path SkPath) bool {
+	if path == nil {
+		return true
+	}
+	if path.IsInverseFillType() {
+		return c.IsClipEmpty()
+	}
+	if path.IsEmpty() {
+		return true
+	}
+	return c.QuickReject(path.Bounds())
+}
+
+func isEmptyRect(r models.Rect) bool {
+	return !(r.Left < r.Right && r.Top < r.Bottom)
+}
+
+func isFiniteRect(r models.Rect) bool {
+	for _, v := range [4]Scalar{r.Left, r.Top, r.Right, r.Bottom} {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortRect returns r with left <= right and top <= bottom.
+func sortRect(r models.Rect) models.Rect {
+	return models.Rect{
+		Left:   min(r.Left, r.Right),
+		Top:    min(r.Top, r.Bottom),
+		Right:  max(r.Left, r.Right),
+		Bottom: max(r.Top, r.Bottom),
+	}
+}
+
+// rectsIntersect reports whether a and b overlap. Rects of zero width or
+// height, such as the bounds of a horizontal line, still intersect.
+func rectsIntersect(a, b models.Rect) bool {
+	return a.Left <= b.Right && b.Left <= a.Right && a.Top <= b.Bottom && b.Top <= a.Bottom
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestCanvas_GetTotalMatrix(t *testing.T) {
+	canvas := NewCanvas(new(op.Ops)).(Extended)
+	canvas.Translate(10, 20)
+	canvas.Scale(2, 3)
+
+	m := canvas.GetTotalMatrix()
+	if x, y := m.MapXY(1, 1); !near(x, 12, 1e-5) || !near(y, 23, 1e-5) {
+		t.Errorf("MapXY(1, 1) = (%v, %v), want (12, 23)", x, y)
+	}
+	// The result is a copy.
+	m.SetTranslateX(100)
+	if x, _ := canvas.GetTotalMatrix().MapXY(0, 0); !near(x, 10, 1e-5) {
+		t.Errorf("modifying the result changed the canvas: x = %v", x)
+	}
+}
+
+func TestCanvas_ClipBounds(t *testing.T) {
+	canvas := NewCanvasWithSize(new(op.Ops), 200, 100).(Extended)
+	if got := canvas.GetDeviceClipBounds(); got != (models.IRect{Right: 200, Bottom: 100}) {
+		t.Errorf("initial device bounds %+v", got)
+	}
+	if !canvas.IsClipRect() || canvas.IsClipEmpty() {
+		t.Error("initial clip should be a non-empty rect")
+	}
+
+	canvas.Save()
+	canvas.Translate(10, 10)
+	canvas.Scale(2, 2)
+	canvas.ClipRect(models.Rect{Left: 0, Top: 0, Right: 20.25, Bottom: 30}, enums.ClipOpIntersect, true)
+	if got, want := canvas.GetDeviceClipBounds(), (models.IRect{Left: 10, Top: 10, Right: 51, Bottom: 70}); got != want {
+		t.Errorf("device bounds %+v, want %+v", got, want)
+	}
+	local := canvas.GetLocalClipBounds()
+	if !near(local.Left, -0.5, 1e-5) || !near(local.Top, -0.5, 1e-5) || !near(local.Right, 20.75, 1e-5) || !near(local.Bottom, 30.5, 1e-5) {
+		t.Errorf("local bounds %+v", local)
+	}
+	if !canvas.IsClipRect() {
+		t.Error("scaled rect clip should stay a rect")
+	}
+
+	canvas.Rotate(30)
+	canvas.ClipRect(models.Rect{Left: 0, Top: 0, Right: 10, Bottom: 10}, enums.ClipOpIntersect, true)
+	if canvas.IsClipRect() {
+		t.Error("rotated rect clip is not a rect")
+	}
+	canvas.Restore()
+	if !canvas.IsClipRect() {
+		t.Error("Restore should restore the rect clip")
+	}
+
+	canvas.Save()
+	canvas.ClipRect(models.Rect{Left: 300, Top: 0, Right: 400, Bottom: 10}, enums.ClipOpIntersect, true)
+	if !canvas.IsClipEmpty() || canvas.IsClipRect() {
+		t.Error("clip outside the canvas should be empty")
+	}
+	if got := canvas.GetLocalClipBounds(); got != (models.Rect{}) {
+		t.Errorf("empty clip has local bounds %+v", got)
+	}
+	canvas.Restore()
+
+	oval := impl.NewSkPath(enums.PathFillTypeWinding)
+	oval.AddOval(models.Rect{Left: 0, Top: 0, Right: 50, Bottom: 50}, enums.PathDirectionCW)
+	canvas.ClipPath(oval, enums.ClipOpIntersect, true)
+	if canvas.IsClipRect() {
+		t.Error("oval clip is not a rect")
+	}
+	if got := canvas.GetDeviceClipBounds(); got != (models.IRect{Right: 50, Bottom: 50}) {
+		t.Errorf("oval device bounds %+v", got)
+	}
+}
+
+func TestCanvas_QuickReject(t *testing.T) {
+	canvas := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+	canvas.ClipRect(models.Rect{Left: 10, Top: 10, Right: 50, Bottom: 50}, enums.ClipOpIntersect, true)
+
+	tests := []struct {
+		name string
+		rect models.Rect
+		want bool
+	}{
+		{"inside", models.Rect{Left: 20, Top: 20, Right: 30, Bottom: 30}, false},
+		{"overlapping", models.Rect{Left: 40, Top: 40, Right: 80, Bottom: 80}, false},
+		{"outside", models.Rect{Left: 60, Top: 60, Right: 80, Bottom: 80}, true},
+		{"unsorted", models.Rect{Left: 30, Top: 30, Right: 20, Bottom: 20}, false},
+		{"horizontal line", models.Rect{Left: 0, Top: 20, Right: 100, Bottom: 20}, false},
+	}
+	for _, tt := range tests {
+		if got := canvas.QuickReject(tt.rect); got != tt.want {
+			t.Errorf("%s: QuickReject(%+v) = %v, want %v", tt.name, tt.rect, got, tt.want)
+		}
+	}
+
+	canvas.Translate(100, 0)
+	if !canvas.QuickReject(models.Rect{Left: 20, Top: 20, Right: 30, Bottom: 30}) {
+		t.Error("translated rect should be rejected")
+	}
+
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(0, 30, 5, enums.PathDirectionCW)
+	if !canvas.QuickRejectPath(path) {
+		t.Error("path outside the clip should be rejected")
+	}
+	path.SetFillType(enums.PathFillTypeInverseWinding)
+	if canvas.QuickRejectPath(path) {
+		t.Error("inverse filled path should not be rejected")
+	}
+	if !canvas.QuickRejectPath(impl.NewSkPath(enums.PathFillTypeWinding)) {
+		t.Error("empty path should be rejected")
+	}
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+)
+
+const (
+	// minFrameDuration is the shortest frame duration honored. Shorter
+	// frames last defaultFrameDuration instead, as browsers do, since
+	// encoders write zero to mean unspecified.
+	minFrameDuration     = 20 * time.Millisecond
+	defaultFrameDuration = 100 * time.Millisecond
+)
+
+// Frame is a frame of an animation, fully composited.
+type Frame struct {
+	Image interfaces.SkImage
+	// Duration is how long the frame is shown.
+	Duration time.Duration
+}
+
+// Animation is a decoded animated image. Still images decode to an
+// animation of one frame.
+type Animation struct {
+	Frames []Frame
+	// LoopCount is the number of times the animation plays, or 0 to play
+	// forever.
+	LoopCount int
+}
+
+// DecodeAnimation decodes all frames of a GIF or WebP animation. Other
+// images decode to a single frame of zero duration.
+func DecodeAnimation(data []byte) (*Animation, error) {
+	frames, loops, err := decodeFrames(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 1 {
+		frames[0].Duration = 0
+	}
+	return &Animation{Frames: frames, LoopCount: loops}, nil
+}
+
+// DecodeAnimationReader is like DecodeAnimation but reads the encoded image
+// from r.
+func DecodeAnimationReader(r io.Reader) (*Animation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAnimation(data)
+}
+
+// Duration returns the time to play the animation once.
+func (a *Animation) Duration() time.Duration {
+	var d time.Duration
+	for _, f := range a.Frames {
+		d += f.Duration
+	}
+	return d
+}
+
+// FrameAt returns the image to draw at time t from the start of playback.
+// After the last loop, the last frame stays.
+func (a *Animation) FrameAt(t time.Duration) interfaces.SkImage {
+	if len(a.Frames) == 0 {
+		return nil
+	}
+	total := a.Duration()
+	if t <= 0 || total <= 0 {
+		return a.Frames[0].Image
+	}
+	if a.LoopCount > 0 && t >= total*time.Duration(a.LoopCount) {
+		return a.Frames[len(a.Frames)-1].Image
+	}
+	t %= total
+	for _, f := range a.Frames {
+		if t < f.Duration {
+			return f.Image
+		}
+		t -= f.Duration
+	}
+	return a.Frames[len(a.Frames)-1].Image
+}
+
+// frameDuration returns the duration of a frame encoded as d.
+func frameDuration(d time.Duration) time.Duration {
+	if d < minFrameDuration {
+		return defaultFrameDuration
+	}
+	return d
+}
+
+// gifFrames composites up to maxFrames frames of g, or all if maxFrames is
+// 0, and returns them with the number of times to play them.
+func gifFrames(g *gif.GIF, maxFrames int) ([]Frame, int) {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	var frames []Frame
+	for i, m := range g.Image {
+		if maxFrames > 0 && i == maxFrames {
+			break
+		}
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var prev *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			prev = image.NewRGBA(bounds)
+			copy(prev.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, m.Bounds(), m, m.Bounds().Min, draw.Over)
+		frames = append(frames, Frame{
+			Image:    newImage(toNRGBA(canvas), metadata{}),
+			Duration: frameDuration(time.Duration(g.Delay[i]) * 10 * time.Millisecond),
+		})
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, m.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prev
+		}
+	}
+	// The GIF loop count is the number of repeats, with -1 for none.
+	loops := g.LoopCount
+	if loops != 0 {
+		loops = max(loops+1, 1)
+	}
+	return frames, loops
+}
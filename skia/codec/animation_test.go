@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestDecodeAnimation_GIF(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}}
+	red := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	for i := range red.Pix {
+		red.Pix[i] = 1
+	}
+	blue := image.NewPaletted(image.Rect(2, 2, 4, 4), palette)
+	for i := range blue.Pix {
+		blue.Pix[i] = 2
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:     []*image.Paletted{red, blue},
+		Delay:     []int{5, 0},
+		Disposal:  []byte{gif.DisposalBackground, gif.DisposalNone},
+		LoopCount: 2,
+		Config:    image.Config{ColorModel: palette, Width: 4, Height: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := DecodeAnimation(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Frames) != 2 || a.LoopCount != 3 {
+		t.Fatalf("%d frames, %d loops, want 2 frames, 3 loops", len(a.Frames), a.LoopCount)
+	}
+	if a.Frames[0].Duration != 50*time.Millisecond || a.Frames[1].Duration != defaultFrameDuration {
+		t.Errorf("durations %v, %v", a.Frames[0].Duration, a.Frames[1].Duration)
+	}
+	// The first frame is disposed to transparency before the second.
+	second := a.Frames[1].Image
+	if second.Width() != 4 || rgbaAt(second, 0, 0).A != 0 || rgbaAt(second, 3, 3) != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("second frame %v, %v", rgbaAt(second, 0, 0), rgbaAt(second, 3, 3))
+	}
+	if rgbaAt(a.Frames[0].Image, 0, 0) != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("first frame %v", rgbaAt(a.Frames[0].Image, 0, 0))
+	}
+
+	// Decode keeps the first frame only.
+	img, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rgbaAt(img, 3, 3) != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("decoded image %v", rgbaAt(img, 3, 3))
+	}
+}
+
+// animatedWebP returns an animation of the still lossless WebP image data,
+// drawn once at each offset.
+func animatedWebP(t *testing.T, data []byte, offsets []image.Point, durations []int, loops uint16) []byte {
+	t.Helper()
+	still, err := decodeWebPStill(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, h := still.Rect.Dx(), still.Rect.Dy()
+	frame := riffChunks(data[12:])[0]
+	if frame.id != "VP8L" {
+		t.Fatalf("chunk %q, want VP8L", frame.id)
+	}
+	chunk := func(b []byte, id string, data []byte) []byte {
+		b = append(b, id...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+		b = append(b, data...)
+		if len(data)%2 == 1 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	cw, ch := w, h
+	for _, o := range offsets {
+		cw, ch = max(cw, o.X+w), max(ch, o.Y+h)
+	}
+	vp8x := []byte{1 << 1, 0, 0, 0}
+	vp8x = appendUint24(appendUint24(vp8x, uint32(cw-1)), uint32(ch-1))
+	body := chunk(nil, "VP8X", vp8x)
+	body = chunk(body, "ANIM", binary.LittleEndian.AppendUint16([]byte{0, 0, 0, 0}, loops))
+	for i, o := range offsets {
+		f := appendUint24(appendUint24(nil, uint32(o.X/2)), uint32(o.Y/2))
+		f = appendUint24(appendUint24(f, uint32(w-1)), uint32(h-1))
+		f = appendUint24(f, uint32(durations[i]))
+		f = append(f, 0)
+		f = chunk(f, "VP8L", frame.data)
+		body = chunk(body, "ANMF", f)
+	}
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(body)))...)
+	return append(append(out, "WEBP"...), body...)
+}
+
+func TestDecodeAnimation_WebP(t *testing.T) {
+	data, err := os.ReadFile("testdata/gopher-doc.1bpp.lossless.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	still, err := decodeWebPStill(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := still.Rect.Size()
+	anim := animatedWebP(t, data, []image.Point{{}, {X: 10, Y: 4}}, []int{40, 0}, 3)
+
+	a, err := DecodeAnimation(anim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Frames) != 2 || a.LoopCount != 3 {
+		t.Fatalf("%d frames, %d loops, want 2 frames, 3 loops", len(a.Frames), a.LoopCount)
+	}
+	if a.Frames[0].Duration != 40*time.Millisecond || a.Frames[1].Duration != defaultFrameDuration {
+		t.Errorf("durations %v, %v", a.Frames[0].Duration, a.Frames[1].Duration)
+	}
+	for i, f := range a.Frames {
+		if f.Image.Width() != size.X+10 || f.Image.Height() != size.Y+4 {
+			t.Errorf("frame %d: size %dx%d", i, f.Image.Width(), f.Image.Height())
+		}
+	}
+	// The second frame is drawn over the first, and the first frame leaves
+	// the offset corner empty.
+	c := still.NRGBAAt(size.X-1, size.Y-1)
+	if got := rgbaAt(a.Frames[1].Image, size.X+9, size.Y+3); got.A != c.A {
+		t.Errorf("second frame corner %v, want alpha %d", got, c.A)
+	}
+	if got := rgbaAt(a.Frames[0].Image, size.X+9, size.Y+3); got.A != 0 {
+		t.Errorf("first frame corner %v, want transparent", got)
+	}
+
+	img, err := Decode(anim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width() != size.X+10 {
+		t.Errorf("first frame width %d", img.Width())
+	}
+}
+
+func TestAnimation_FrameAt(t *testing.T) {
+	images := make([]interfaces.SkImage, 3)
+	for i := range images {
+		images[i] = impl.NewRasterImage(models.NewImageInfo(1, 1, enums.ColorTypeRGBA8888, enums.AlphaTypePremul), make([]byte, 4), 4)
+	}
+	a := &Animation{
+		Frames: []Frame{
+			{images[0], 100 * time.Millisecond},
+			{images[1], 50 * time.Millisecond},
+			{images[2], 50 * time.Millisecond},
+		},
+		LoopCount: 2,
+	}
+	if d := a.Duration(); d != 200*time.Millisecond {
+		t.Errorf("duration %v", d)
+	}
+	for _, tc := range []struct {
+		t    time.Duration
+		want int
+	}{
+		{-time.Second, 0},
+		{0, 0},
+		{99 * time.Millisecond, 0},
+		{100 * time.Millisecond, 1},
+		{170 * time.Millisecond, 2},
+		{210 * time.Millisecond, 0},
+		{360 * time.Millisecond, 2},
+		// Played twice; the last frame stays.
+		{time.Second, 2},
+	} {
+		if got := a.FrameAt(tc.t); got != images[tc.want] {
+			t.Errorf("FrameAt(%v) = frame %v, want %d", tc.t, got, tc.want)
+		}
+	}
+	a.LoopCount = 0
+	if got := a.FrameAt(time.Second + 10*time.Millisecond); got != images[0] {
+		t.Errorf("endless animation at 1.01s = %v, want frame 0", got)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package codec decodes PNG, JPEG, GIF and WebP images into SkImages for
+// drawing on a canvas, and encodes SkImages to PNG and JPEG.
+//
+// Decoded images are converted to sRGB using their embedded ICC profile or
+// PNG gamma, and turned upright according to their EXIF orientation.
+// Animated GIF and WebP images decode into frames with durations.
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+	"golang.org/x/image/webp"
+)
+
+// Format is an encoded image format.
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatPNG
+	FormatJPEG
+	FormatGIF
+	FormatWebP
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPNG:
+		return "PNG"
+	case FormatJPEG:
+		return "JPEG"
+	case FormatGIF:
+		return "GIF"
+	case FormatWebP:
+		return "WebP"
+	}
+	return "unknown"
+}
+
+// ErrUnknownFormat is returned when decoding data in none of the supported
+// formats.
+var ErrUnknownFormat = errors.New("codec: unknown image format")
+
+// DetectFormat returns the format of the encoded image data from its
+// signature.
+func DetectFormat(data []byte) Format {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8, 0xff}):
+		return FormatJPEG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP
+	}
+	return FormatUnknown
+}
+
+// Decode decodes a PNG, JPEG, GIF or WebP image. Animated images decode to
+// their first frame.
+func Decode(data []byte) (interfaces.SkImage, error) {
+	frames, _, err := decodeFrames(data, 1)
+	if err != nil {
+		return nil, err
+	}
+	return frames[0].Image, nil
+}
+
+// DecodeReader is like Decode but reads the encoded image from r.
+func DecodeReader(r io.Reader) (interfaces.SkImage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// decodeFrames decodes up to maxFrames frames of data, or all of them if
+// maxFrames is 0, and returns them with the animation's loop count.
+func decodeFrames(data []byte, maxFrames int) ([]Frame, int, error) {
+	format := DetectFormat(data)
+	var (
+		frames []Frame
+		loops  int
+		err    error
+	)
+	switch format {
+	case FormatPNG, FormatJPEG:
+		var img image.Image
+		if format == FormatPNG {
+			img, err = png.Decode(bytes.NewReader(data))
+		} else {
+			img, err = jpeg.Decode(bytes.NewReader(data))
+		}
+		if err == nil {
+			frames = []Frame{{Image: newImage(toNRGBA(img), readMetadata(format, data))}}
+		}
+	case FormatGIF:
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err == nil {
+			frames, loops = gifFrames(g, maxFrames)
+		}
+	case FormatWebP:
+		frames, loops, err = decodeWebP(data, maxFrames)
+	default:
+		return nil, 0, ErrUnknownFormat
+	}
+	if err == nil && len(frames) == 0 {
+		err = errors.New("no frames")
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("codec: decoding %v: %w", format, err)
+	}
+	return frames, loops, nil
+}
+
+// decodeWebPStill decodes a still WebP image, or the single frame of an
+// animation wrapped as one.
+func decodeWebPStill(data []byte) (*image.NRGBA, error) {
+	img, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA(img), nil
+}
+
+// toNRGBA returns img as an unpremultiplied image with its origin at zero.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if m, ok := img.(*image.NRGBA); ok && m.Rect.Min == (image.Point{}) {
+		return m
+	}
+	b := img.Bounds()
+	m := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(m, m.Rect, img, b.Min, draw.Src)
+	return m
+}
+
+// newImage converts img to sRGB and orients it as described by md, and
+// returns it as an SkImage. img may be modified.
+func newImage(img *image.NRGBA, md metadata) interfaces.SkImage {
+	if t := md.colorTransform(); t != nil {
+		t.apply(img)
+	}
+	img = orient(img, md.orientation)
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	alphaType := enums.AlphaTypeUnpremul
+	if img.Opaque() {
+		alphaType = enums.AlphaTypeOpaque
+	}
+	info := models.NewImageInfo(w, h, enums.ColorTypeRGBA8888, alphaType)
+	return impl.NewRasterImage(info, img.Pix, img.Stride)
+}
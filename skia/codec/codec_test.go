@@ -0,0 +1,274 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func nearColor(a, b color.RGBA, tol int) bool {
+	return absDiff(a.R, b.R) <= tol && absDiff(a.G, b.G) <= tol && absDiff(a.B, b.B) <= tol && absDiff(a.A, b.A) <= tol
+}
+
+// pngWithChunk returns img encoded as PNG with an extra chunk after IHDR.
+func pngWithChunk(t *testing.T, img image.Image, typ string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	enc := buf.Bytes()
+	// The signature and the 25 bytes of IHDR.
+	const ihdrEnd = 8 + 25
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	return append(append(append([]byte(nil), enc[:ihdrEnd]...), chunk...), enc[ihdrEnd:]...)
+}
+
+// exifWithOrientation returns little-endian EXIF data holding orientation o.
+func exifWithOrientation(o uint16) []byte {
+	b := []byte("II*\x00")
+	b = binary.LittleEndian.AppendUint32(b, 8)
+	b = binary.LittleEndian.AppendUint16(b, 1)
+	b = binary.LittleEndian.AppendUint16(b, 0x0112)
+	b = binary.LittleEndian.AppendUint16(b, 3)
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = binary.LittleEndian.AppendUint16(b, o)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	return binary.LittleEndian.AppendUint32(b, 0)
+}
+
+func rgbaAt(img interfaces.SkImage, x, y int) color.RGBA {
+	return skia.ImageToRGBA(img).RGBAAt(x, y)
+}
+
+func TestDetectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want Format
+	}{
+		{"\x89PNG\r\n\x1a\n....", FormatPNG},
+		{"\xff\xd8\xff\xe0", FormatJPEG},
+		{"GIF89a", FormatGIF},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", FormatWebP},
+		{"RIFF\x00\x00\x00\x00WAVE", FormatUnknown},
+		{"", FormatUnknown},
+	} {
+		if got := DetectFormat([]byte(tc.data)); got != tc.want {
+			t.Errorf("DetectFormat(%q) = %v, want %v", tc.data, got, tc.want)
+		}
+	}
+	if _, err := Decode([]byte("not an image")); err != ErrUnknownFormat {
+		t.Errorf("Decode of garbage returned %v", err)
+	}
+}
+
+func TestEncodeDecode_PNG(t *testing.T) {
+	info := models.NewImageInfo(2, 1, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	src := impl.NewRasterImage(info, []byte{255, 0, 0, 255, 0, 64, 0, 128}, 8)
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	img, err := DecodeReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width() != 2 || img.Height() != 1 || img.AlphaType() != enums.AlphaTypeUnpremul {
+		t.Fatalf("decoded %dx%d, alpha type %v", img.Width(), img.Height(), img.AlphaType())
+	}
+	if got := rgbaAt(img, 0, 0); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("opaque pixel %v", got)
+	}
+	if got := rgbaAt(img, 1, 0); !nearColor(got, color.RGBA{G: 64, A: 128}, 1) {
+		t.Errorf("translucent pixel %v", got)
+	}
+}
+
+func TestEncodeDecode_JPEG(t *testing.T) {
+	info := models.NewImageInfo(16, 16, enums.ColorTypeRGBA8888, enums.AlphaTypeOpaque)
+	pix := make([]byte, 16*16*4)
+	for i := 0; i < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2], pix[i+3] = 200, 100, 50, 255
+	}
+	var buf bytes.Buffer
+	if err := EncodeJPEG(&buf, impl.NewRasterImage(info, pix, 16*4), 95); err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.AlphaType() != enums.AlphaTypeOpaque {
+		t.Errorf("alpha type %v", img.AlphaType())
+	}
+	if got := rgbaAt(img, 8, 8); !nearColor(got, color.RGBA{R: 200, G: 100, B: 50, A: 255}, 3) {
+		t.Errorf("pixel %v", got)
+	}
+}
+
+func TestDecode_JPEGOrientation(t *testing.T) {
+	// Left half red, right half blue, stored rotated: orientation 6 turns
+	// it clockwise, bringing the left half to the top.
+	src := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 16 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	exif := append([]byte("Exif\x00\x00"), exifWithOrientation(6)...)
+	seg := []byte{0xff, 0xe1, byte((len(exif) + 2) >> 8), byte(len(exif) + 2)}
+	data := append(append(append([]byte{0xff, 0xd8}, seg...), exif...), buf.Bytes()[2:]...)
+
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width() != 16 || img.Height() != 32 {
+		t.Fatalf("size %dx%d, want 16x32", img.Width(), img.Height())
+	}
+	if got := rgbaAt(img, 8, 4); !nearColor(got, color.RGBA{R: 255, A: 255}, 8) {
+		t.Errorf("top %v, want red", got)
+	}
+	if got := rgbaAt(img, 8, 28); !nearColor(got, color.RGBA{B: 255, A: 255}, 8) {
+		t.Errorf("bottom %v, want blue", got)
+	}
+}
+
+func TestDecode_PNGGamma(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 1, 1))
+	src.Pix[0] = 128
+	// A gamma of 1 means linear values.
+	linear := pngWithChunk(t, src, "gAMA", binary.BigEndian.AppendUint32(nil, 100000))
+	img, err := Decode(linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rgbaAt(img, 0, 0); !nearColor(got, color.RGBA{R: 188, G: 188, B: 188, A: 255}, 1) {
+		t.Errorf("linear gray %v, want sRGB 188", got)
+	}
+	// The sRGB chunk overrides gamma.
+	md := pngMetadata(pngWithChunk(t, src, "sRGB", []byte{0}))
+	md.gamma = 1
+	if !md.srgb || md.colorTransform() != nil {
+		t.Error("sRGB image transformed")
+	}
+}
+
+func TestDecode_WebP(t *testing.T) {
+	data, err := os.ReadFile("testdata/gopher-doc.1bpp.lossless.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := decodeWebPStill(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width() != want.Rect.Dx() || img.Height() != want.Rect.Dy() {
+		t.Errorf("size %dx%d, want %v", img.Width(), img.Height(), want.Rect.Size())
+	}
+}
+
+func TestDecode_WebPCanvasSize(t *testing.T) {
+	// An animation header with the given canvas size and no frames.
+	header := func(w, h uint32) []byte {
+		vp8x := []byte{'V', 'P', '8', 'X', 10, 0, 0, 0, 1 << 1, 0, 0, 0}
+		vp8x = appendUint24(vp8x, w-1)
+		vp8x = appendUint24(vp8x, h-1)
+		b := []byte("RIFF")
+		b = binary.LittleEndian.AppendUint32(b, uint32(4+len(vp8x)))
+		b = append(b, "WEBP"...)
+		return append(b, vp8x...)
+	}
+	for _, data := range [][]byte{
+		header(1<<24, 1<<24),
+		header(1<<16, 1<<12),
+		header(16, 16)[:28],
+	} {
+		if _, err := Decode(data); err == nil {
+			t.Errorf("decoding %d byte header %x succeeded", len(data), data)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image whose pixels hold their index.
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Pix[4*i] = uint8(i)
+	}
+	for _, tc := range []struct {
+		o    int
+		want []uint8
+	}{
+		{1, []uint8{0, 1, 2, 3, 4, 5}},
+		{2, []uint8{2, 1, 0, 5, 4, 3}},
+		{3, []uint8{5, 4, 3, 2, 1, 0}},
+		{4, []uint8{3, 4, 5, 0, 1, 2}},
+		{5, []uint8{0, 3, 1, 4, 2, 5}},
+		{6, []uint8{3, 0, 4, 1, 5, 2}},
+		{7, []uint8{5, 2, 4, 1, 3, 0}},
+		{8, []uint8{2, 5, 1, 4, 0, 3}},
+	} {
+		dst := orient(src, tc.o)
+		if tc.o >= 5 && dst.Rect.Size() != image.Pt(2, 3) {
+			t.Errorf("orientation %d: size %v", tc.o, dst.Rect.Size())
+		}
+		for i, w := range tc.want {
+			if got := dst.Pix[4*i]; got != w {
+				t.Errorf("orientation %d: pixel %d = %d, want %d", tc.o, i, got, w)
+				break
+			}
+		}
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	if got := exifOrientation(exifWithOrientation(8)); got != 8 {
+		t.Errorf("little-endian orientation %d", got)
+	}
+	be := []byte{'M', 'M', 0, '*', 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 3, 0, 0, 0, 0, 0, 0}
+	if got := exifOrientation(be); got != 3 {
+		t.Errorf("big-endian orientation %d", got)
+	}
+	if got := exifOrientation(exifWithOrientation(9)); got != 0 {
+		t.Errorf("invalid orientation %d", got)
+	}
+	if got := exifOrientation([]byte("II*\x00\xff\xff\xff\xff")); got != 0 {
+		t.Errorf("truncated EXIF orientation %d", got)
+	}
+}
@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+)
+
+const (
+	// encodeTableSize is the number of entries of the table encoding linear
+	// values to sRGB.
+	encodeTableSize = 4096
+	// profileTolerance is how close a profile must be to sRGB to be
+	// treated as sRGB.
+	profileTolerance = 0.002
+)
+
+// srgbToXYZD50 converts linear sRGB to the D50 XYZ space ICC profiles use,
+// as chromatically adapted by the sRGB profile.
+var srgbToXYZD50 = [9]float64{
+	0.4360747, 0.3850649, 0.1430804,
+	0.2225045, 0.7168786, 0.0606169,
+	0.0139322, 0.0971045, 0.7141733,
+}
+
+// colorTransform converts 8-bit colors to sRGB.
+type colorTransform struct {
+	// linear maps each encoded channel value to linear light.
+	linear [3][256]float32
+	// matrix converts linear colors to linear sRGB, if not nil.
+	matrix *[9]float32
+	// encode maps linear values in [0, 1] to sRGB.
+	encode [encodeTableSize]uint8
+}
+
+// curve is a transfer function from encoded values to linear light.
+type curve func(v float64) float64
+
+// colorTransform returns the transform of colors described by md to sRGB,
+// or nil if they are sRGB or the description is not understood. ICC
+// profiles take precedence over PNG gamma, and only matrix and curve
+// profiles of RGB and gray images are supported.
+func (md metadata) colorTransform() *colorTransform {
+	switch {
+	case md.icc != nil:
+		curves, matrix, ok := parseICCProfile(md.icc)
+		if !ok {
+			return nil
+		}
+		return newColorTransform(curves, matrix)
+	case md.srgb, md.gamma <= 0:
+		return nil
+	}
+	// gAMA holds the exponent encoding linear values, with sRGB primaries.
+	g := 1 / md.gamma
+	c := func(v float64) float64 { return math.Pow(v, g) }
+	return newColorTransform([3]curve{c, c, c}, nil)
+}
+
+// newColorTransform returns the transform applying curves and then the
+// matrix from profile connection space to sRGB, or nil if it is close to
+// the identity.
+func newColorTransform(curves [3]curve, toXYZ *[9]float64) *colorTransform {
+	t := new(colorTransform)
+	identity := true
+	for c, f := range curves {
+		for i := range t.linear[c] {
+			v := float64(i) / 255
+			l := clampUnit(f(v))
+			t.linear[c][i] = float32(l)
+			if math.Abs(l-srgbToLinear(v)) > profileTolerance {
+				identity = false
+			}
+		}
+	}
+	if toXYZ != nil {
+		m := mulMatrix(invertMatrix(srgbToXYZD50), *toXYZ)
+		var mf [9]float32
+		for i, v := range m {
+			id := 0.0
+			if i%4 == 0 {
+				id = 1
+			}
+			if math.Abs(v-id) > 10*profileTolerance {
+				identity = false
+			}
+			mf[i] = float32(v)
+		}
+		t.matrix = &mf
+	}
+	if identity {
+		return nil
+	}
+	for i := range t.encode {
+		t.encode[i] = uint8(linearToSRGB(float64(i)/(encodeTableSize-1))*255 + 0.5)
+	}
+	return t
+}
+
+// apply converts the colors of img in place.
+func (t *colorTransform) apply(img *image.NRGBA) {
+	enc := func(v float32) uint8 {
+		return t.encode[int(float32(clampUnit(float64(v)))*(encodeTableSize-1)+0.5)]
+	}
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, y):][:4*img.Rect.Dx()]
+		for i := 0; i < len(row); i += 4 {
+			r, g, b := t.linear[0][row[i]], t.linear[1][row[i+1]], t.linear[2][row[i+2]]
+			if m := t.matrix; m != nil {
+				r, g, b = m[0]*r+m[1]*g+m[2]*b, m[3]*r+m[4]*g+m[5]*b, m[6]*r+m[7]*g+m[8]*b
+			}
+			row[i], row[i+1], row[i+2] = enc(r), enc(g), enc(b)
+		}
+	}
+}
+
+// parseICCProfile returns the per-channel curves and the matrix to D50 XYZ
+// of an RGB profile, or the gray curve and no matrix of a gray profile. It
+// reports false for other profiles.
+func parseICCProfile(p []byte) (curves [3]curve, toXYZ *[9]float64, ok bool) {
+	if len(p) < 132 || string(p[36:40]) != "acsp" || string(p[20:24]) != "XYZ " {
+		return curves, nil, false
+	}
+	tags := make(map[string][]byte)
+	n := binary.BigEndian.Uint32(p[128:])
+	for i := uint32(0); i < n; i++ {
+		e := p[132+12*i:]
+		if len(e) < 12 {
+			return curves, nil, false
+		}
+		off, size := binary.BigEndian.Uint32(e[4:]), binary.BigEndian.Uint32(e[8:])
+		if uint64(off)+uint64(size) > uint64(len(p)) {
+			return curves, nil, false
+		}
+		tags[string(e[:4])] = p[off : off+size]
+	}
+	switch string(p[16:20]) {
+	case "GRAY":
+		k, ok := parseICCCurve(tags["kTRC"])
+		return [3]curve{k, k, k}, nil, ok
+	case "RGB ":
+		var m [9]float64
+		for c, name := range [3]string{"r", "g", "b"} {
+			if curves[c], ok = parseICCCurve(tags[name+"TRC"]); !ok {
+				return curves, nil, false
+			}
+			xyz := tags[name+"XYZ"]
+			if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+				return curves, nil, false
+			}
+			// The colorant is a column of the matrix.
+			for row := 0; row < 3; row++ {
+				m[3*row+c] = s15Fixed16(xyz[8+4*row:])
+			}
+		}
+		return curves, &m, true
+	}
+	return curves, nil, false
+}
+
+// parseICCCurve parses a curv or para tag.
+func parseICCCurve(t []byte) (curve, bool) {
+	if len(t) < 12 {
+		return nil, false
+	}
+	switch string(t[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(t[8:]))
+		switch {
+		case n == 0:
+			return func(v float64) float64 { return v }, true
+		case n == 1 && len(t) >= 14:
+			g := float64(binary.BigEndian.Uint16(t[12:])) / 256
+			return func(v float64) float64 { return math.Pow(v, g) }, true
+		case n > 1 && len(t) >= 12+2*n:
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(t[12+2*i:])) / 65535
+			}
+			return func(v float64) float64 {
+				// Interpolate the table linearly.
+				x := v * float64(n-1)
+				i := min(int(x), n-2)
+				f := x - float64(i)
+				return table[i]*(1-f) + table[i+1]*f
+			}, true
+		}
+	case "para":
+		// The number of parameters of each function type.
+		counts := [5]int{1, 3, 4, 5, 7}
+		typ := int(binary.BigEndian.Uint16(t[8:]))
+		if typ >= len(counts) || len(t) < 12+4*counts[typ] {
+			return nil, false
+		}
+		var prm [7]float64
+		for i := 0; i < counts[typ]; i++ {
+			prm[i] = s15Fixed16(t[12+4*i:])
+		}
+		g, a, b, c, d, e, f := prm[0], prm[1], prm[2], prm[3], prm[4], prm[5], prm[6]
+		switch typ {
+		case 0:
+			return func(v float64) float64 { return math.Pow(v, g) }, true
+		case 1, 2:
+			if a == 0 {
+				return nil, false
+			}
+			return func(v float64) float64 {
+				if v >= -b/a {
+					return math.Pow(a*v+b, g) + c
+				}
+				return c
+			}, true
+		case 3, 4:
+			return func(v float64) float64 {
+				if v >= d {
+					return math.Pow(a*v+b, g) + e
+				}
+				return c*v + f
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// s15Fixed16 decodes an ICC signed 15.16 fixed point number.
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// srgbToLinear decodes an sRGB value to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear value to sRGB.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// clampUnit clamps v to [0, 1], mapping NaN to 0.
+func clampUnit(v float64) float64 {
+	if !(v > 0) {
+		return 0
+	}
+	return min(v, 1)
+}
+
+// mulMatrix returns the product of the row-major 3x3 matrices a and b.
+func mulMatrix(a, b [9]float64) [9]float64 {
+	var m [9]float64
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			m[3*r+c] = a[3*r]*b[c] + a[3*r+1]*b[3+c] + a[3*r+2]*b[6+c]
+		}
+	}
+	return m
+}
+
+// invertMatrix returns the inverse of the invertible row-major 3x3 matrix m.
+func invertMatrix(m [9]float64) [9]float64 {
+	a, b, c, d, e, f, g, h, i := m[0], m[1], m[2], m[3], m[4], m[5], m[6], m[7], m[8]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	return [9]float64{
+		(e*i - f*h) / det, (c*h - b*i) / det, (b*f - c*e) / det,
+		(f*g - d*i) / det, (a*i - c*g) / det, (c*d - a*f) / det,
+		(d*h - e*g) / det, (b*g - a*h) / det, (a*e - b*d) / det,
+	}
+}
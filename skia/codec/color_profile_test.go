@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+	"testing"
+)
+
+// iccProfile returns an ICC profile of the color space with the tags.
+func iccProfile(space string, tags map[string][]byte) []byte {
+	var names []string
+	for name := range tags {
+		names = append(names, name)
+	}
+	p := make([]byte, 128)
+	copy(p[16:], space)
+	copy(p[20:], "XYZ ")
+	copy(p[36:], "acsp")
+	p = binary.BigEndian.AppendUint32(p, uint32(len(names)))
+	off := len(p) + 12*len(names)
+	var data []byte
+	for _, name := range names {
+		p = append(p, name...)
+		p = binary.BigEndian.AppendUint32(p, uint32(off+len(data)))
+		p = binary.BigEndian.AppendUint32(p, uint32(len(tags[name])))
+		data = append(data, tags[name]...)
+	}
+	p = append(p, data...)
+	binary.BigEndian.PutUint32(p, uint32(len(p)))
+	return p
+}
+
+func fixed(v float64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(v*65536))))
+}
+
+// paraCurve returns a parametric curve tag with the parameters.
+func paraCurve(typ uint16, params ...float64) []byte {
+	b := append([]byte("para\x00\x00\x00\x00"), byte(typ>>8), byte(typ), 0, 0)
+	for _, v := range params {
+		b = append(b, fixed(v)...)
+	}
+	return b
+}
+
+func xyzTag(x, y, z float64) []byte {
+	b := []byte("XYZ \x00\x00\x00\x00")
+	return append(append(append(b, fixed(x)...), fixed(y)...), fixed(z)...)
+}
+
+// rgbProfile returns an RGB profile with the same curve for all channels
+// and the colorants of the columns of m.
+func rgbProfile(trc []byte, m [9]float64) []byte {
+	return iccProfile("RGB ", map[string][]byte{
+		"rTRC": trc, "gTRC": trc, "bTRC": trc,
+		"rXYZ": xyzTag(m[0], m[3], m[6]),
+		"gXYZ": xyzTag(m[1], m[4], m[7]),
+		"bXYZ": xyzTag(m[2], m[5], m[8]),
+	})
+}
+
+func TestColorTransform_SRGBProfile(t *testing.T) {
+	srgbCurve := paraCurve(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+	md := metadata{icc: rgbProfile(srgbCurve, srgbToXYZD50)}
+	if _, _, ok := parseICCProfile(md.icc); !ok {
+		t.Fatal("sRGB profile not parsed")
+	}
+	if md.colorTransform() != nil {
+		t.Error("sRGB profile transforms colors")
+	}
+}
+
+func TestColorTransform_Gray(t *testing.T) {
+	// A linear gray profile, as a one-entry gamma curve of 1.0.
+	linear := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01\x01\x00")
+	md := metadata{icc: iccProfile("GRAY", map[string][]byte{"kTRC": linear})}
+	tr := md.colorTransform()
+	if tr == nil {
+		t.Fatal("linear gray profile not transformed")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	copy(img.Pix, []byte{128, 128, 128, 77})
+	tr.apply(img)
+	if got := img.Pix[:4]; got[0] != 188 || got[1] != 188 || got[2] != 188 || got[3] != 77 {
+		t.Errorf("linear gray 128 = %v, want sRGB 188 with alpha kept", got)
+	}
+}
+
+func TestColorTransform_WideGamut(t *testing.T) {
+	// Display P3 primaries adapted to D50, with the sRGB curve as a table.
+	table := []byte("curv\x00\x00\x00\x00")
+	table = binary.BigEndian.AppendUint32(table, 1024)
+	for i := 0; i < 1024; i++ {
+		table = binary.BigEndian.AppendUint16(table, uint16(srgbToLinear(float64(i)/1023)*65535+0.5))
+	}
+	p3 := [9]float64{
+		0.5151, 0.2920, 0.1571,
+		0.2412, 0.6922, 0.0666,
+		-0.0011, 0.0419, 0.7841,
+	}
+	md := metadata{icc: rgbProfile(table, p3)}
+	tr := md.colorTransform()
+	if tr == nil {
+		t.Fatal("P3 profile not transformed")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	copy(img.Pix, []byte{0, 255, 0, 255, 255, 255, 255, 255})
+	tr.apply(img)
+	// P3 green is out of the sRGB gamut and clips; white stays white.
+	if got := img.Pix[:4]; got[0] != 0 || got[1] != 255 || got[2] != 0 {
+		t.Errorf("P3 green = %v", got)
+	}
+	if got := img.Pix[4:8]; got[0] < 254 || got[1] < 254 || got[2] < 254 {
+		t.Errorf("P3 white = %v", got)
+	}
+	// A darker P3 red maps to a more saturated sRGB red.
+	copy(img.Pix, []byte{200, 50, 50, 255})
+	tr.apply(img)
+	if got := img.Pix[:4]; got[0] <= 200 || got[1] >= 50 {
+		t.Errorf("P3 red = %v", got)
+	}
+}
+
+func TestParseICCProfile_Invalid(t *testing.T) {
+	for name, p := range map[string][]byte{
+		"short":     make([]byte, 100),
+		"cmyk":      iccProfile("CMYK", nil),
+		"no curves": iccProfile("RGB ", nil),
+		"bad tag":   iccProfile("GRAY", map[string][]byte{"kTRC": []byte("sf32\x00\x00\x00\x00\x00\x00\x00\x00")}),
+	} {
+		if _, _, ok := parseICCProfile(p); ok {
+			t.Errorf("%s profile parsed", name)
+		}
+	}
+}
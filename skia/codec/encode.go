@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"errors"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+)
+
+// errUnreadable is returned when encoding an image whose pixels cannot be
+// read.
+var errUnreadable = errors.New("codec: cannot read image pixels")
+
+// EncodePNG writes img to w as a PNG image.
+func EncodePNG(w io.Writer, img interfaces.SkImage) error {
+	m := skia.ImageToRGBA(img)
+	if m == nil {
+		return errUnreadable
+	}
+	return png.Encode(w, m)
+}
+
+// EncodeJPEG writes img to w as a JPEG image of quality 1 to 100, higher
+// being better. JPEG has no alpha; translucent pixels are composited onto
+// black.
+func EncodeJPEG(w io.Writer, img interfaces.SkImage, quality int) error {
+	m := skia.ImageToRGBA(img)
+	if m == nil {
+		return errUnreadable
+	}
+	return jpeg.Encode(w, m, &jpeg.Options{Quality: quality})
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// maxICCProfileSize bounds the size of an embedded ICC profile.
+const maxICCProfileSize = 4 << 20
+
+// metadata is the color and orientation information embedded in an encoded
+// image.
+type metadata struct {
+	// icc is the ICC profile, if any.
+	icc []byte
+	// srgb reports whether the image is marked as sRGB, overriding gamma.
+	srgb bool
+	// gamma is the PNG encoding gamma, or 0 if unknown.
+	gamma float64
+	// orientation is the EXIF orientation, from 1 to 8, or 0 if unknown.
+	orientation int
+}
+
+// readMetadata returns the metadata of data in format. Malformed metadata
+// is ignored.
+func readMetadata(format Format, data []byte) metadata {
+	switch format {
+	case FormatPNG:
+		return pngMetadata(data)
+	case FormatJPEG:
+		return jpegMetadata(data)
+	case FormatWebP:
+		return webpMetadata(data)
+	}
+	return metadata{}
+}
+
+// pngMetadata reads the iCCP, sRGB, gAMA and eXIf chunks of a PNG image.
+func pngMetadata(data []byte) metadata {
+	var md metadata
+	for p := data[8:]; len(p) >= 12; {
+		n := binary.BigEndian.Uint32(p)
+		if uint64(n)+12 > uint64(len(p)) {
+			break
+		}
+		typ, chunk := string(p[4:8]), p[8:8+n]
+		p = p[12+n:]
+		switch typ {
+		case "iCCP":
+			// A profile name, a compression method and the compressed
+			// profile.
+			i := bytes.IndexByte(chunk, 0)
+			if i < 0 || i+2 > len(chunk) || chunk[i+1] != 0 {
+				continue
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(chunk[i+2:]))
+			if err != nil {
+				continue
+			}
+			icc, err := io.ReadAll(io.LimitReader(zr, maxICCProfileSize))
+			if err == nil {
+				md.icc = icc
+			}
+		case "sRGB":
+			md.srgb = true
+		case "gAMA":
+			if len(chunk) == 4 {
+				md.gamma = float64(binary.BigEndian.Uint32(chunk)) / 100000
+			}
+		case "eXIf":
+			md.orientation = exifOrientation(chunk)
+		case "IEND":
+			return md
+		}
+	}
+	return md
+}
+
+// jpegMetadata reads the EXIF and ICC profile segments of a JPEG image. ICC
+// profiles larger than a segment are split across several.
+func jpegMetadata(data []byte) metadata {
+	var md metadata
+	type iccChunk struct {
+		seq  byte
+		data []byte
+	}
+	var chunks []iccChunk
+	for p := data[2:]; len(p) >= 4 && p[0] == 0xff; {
+		marker := p[1]
+		if marker == 0xff {
+			// Fill byte.
+			p = p[1:]
+			continue
+		}
+		if marker == 0x01 || marker >= 0xd0 && marker <= 0xd7 {
+			// Markers without a segment.
+			p = p[2:]
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(p[2:]))
+		if n < 2 || n+2 > len(p) {
+			break
+		}
+		seg := p[4 : 2+n]
+		p = p[2+n:]
+		switch {
+		case marker == 0xda:
+			// Start of scan; the metadata comes before.
+			p = nil
+		case marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")):
+			md.orientation = exifOrientation(seg[6:])
+		case marker == 0xe2 && bytes.HasPrefix(seg, []byte("ICC_PROFILE\x00")) && len(seg) >= 14:
+			chunks = append(chunks, iccChunk{seq: seg[12], data: seg[14:]})
+		}
+	}
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].seq < chunks[j].seq })
+	for _, c := range chunks {
+		md.icc = append(md.icc, c.data...)
+	}
+	return md
+}
+
+// webpMetadata reads the ICCP and EXIF chunks of an extended WebP image.
+func webpMetadata(data []byte) metadata {
+	var md metadata
+	for _, c := range riffChunks(data[12:]) {
+		switch c.id {
+		case "ICCP":
+			md.icc = c.data
+		case "EXIF":
+			// Some encoders keep the JPEG segment header.
+			md.orientation = exifOrientation(bytes.TrimPrefix(c.data, []byte("Exif\x00\x00")))
+		}
+	}
+	return md
+}
+
+// exifOrientation returns the orientation tag of the EXIF data exif, which
+// starts with a TIFF header, or 0 if it has none.
+func exifOrientation(exif []byte) int {
+	if len(exif) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(exif[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := order.Uint32(exif[4:])
+	if uint64(ifd)+2 > uint64(len(exif)) {
+		return 0
+	}
+	n := int(order.Uint16(exif[ifd:]))
+	entries := exif[ifd+2:]
+	for i := 0; i < n && 12*i+12 <= len(entries); i++ {
+		e := entries[12*i:]
+		const (
+			tagOrientation = 0x0112
+			typeShort      = 3
+		)
+		if order.Uint16(e) == tagOrientation && order.Uint16(e[2:]) == typeShort {
+			if o := int(order.Uint16(e[8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
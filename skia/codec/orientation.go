@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import "image"
+
+// orient returns img turned upright from the EXIF orientation o. The
+// orientation describes where the stored rows and columns belong: 2 to 4
+// flip or rotate by 180 degrees, 5 to 8 also swap width and height.
+// Orientations 0 and 1 return img unchanged.
+func orient(img *image.NRGBA, o int) *image.NRGBA {
+	if o < 2 || o > 8 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			// The source pixel of the destination pixel (x, y).
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			s := img.PixOffset(img.Rect.Min.X+sx, img.Rect.Min.Y+sy)
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], img.Pix[s:s+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"time"
+)
+
+// riffChunk is a chunk of a RIFF container.
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// riffChunks splits p into chunks, stopping at the first truncated one.
+func riffChunks(p []byte) []riffChunk {
+	var chunks []riffChunk
+	for len(p) >= 8 {
+		n := binary.LittleEndian.Uint32(p[4:])
+		if uint64(n) > uint64(len(p)-8) {
+			break
+		}
+		chunks = append(chunks, riffChunk{id: string(p[:4]), data: p[8 : 8+n]})
+		// Chunks are padded to an even size.
+		p = p[min(8+int(n)+int(n&1), len(p)):]
+	}
+	return chunks
+}
+
+// maxCanvasPixels limits the canvas size of WebP animations, which is read
+// from the file and allocated before any frame is decoded.
+const maxCanvasPixels = 1 << 26
+
+// decodeWebP decodes up to maxFrames frames of a WebP image, or all of them
+// if maxFrames is 0, and returns them with the number of times to play
+// them.
+func decodeWebP(data []byte, maxFrames int) ([]Frame, int, error) {
+	md := webpMetadata(data)
+	chunks := riffChunks(data[12:])
+	const animationFlag = 1 << 1
+	if len(chunks) == 0 || chunks[0].id != "VP8X" || len(chunks[0].data) < 10 || chunks[0].data[0]&animationFlag == 0 {
+		img, err := decodeWebPStill(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []Frame{{Image: newImage(img, md)}}, 0, nil
+	}
+
+	vp8x := chunks[0].data
+	cw, ch := int(uint24(vp8x[4:]))+1, int(uint24(vp8x[7:]))+1
+	if cw*ch > maxCanvasPixels {
+		return nil, 0, fmt.Errorf("canvas size %dx%d is too large", cw, ch)
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, cw, ch))
+	var (
+		frames []Frame
+		loops  int
+	)
+	for _, c := range chunks[1:] {
+		switch c.id {
+		case "ANIM":
+			// The background color is a hint; frames are composited onto
+			// transparency as other decoders do.
+			if len(c.data) >= 6 {
+				loops = int(binary.LittleEndian.Uint16(c.data[4:]))
+			}
+		case "ANMF":
+			if maxFrames > 0 && len(frames) == maxFrames {
+				// ANIM comes before the frames.
+				return frames, loops, nil
+			}
+			if len(c.data) < 16 {
+				return nil, 0, errors.New("truncated frame")
+			}
+			f := c.data
+			x, y := 2*int(uint24(f)), 2*int(uint24(f[3:]))
+			w, h := int(uint24(f[6:]))+1, int(uint24(f[9:]))+1
+			duration := time.Duration(uint24(f[12:])) * time.Millisecond
+			const (
+				disposeFlag = 1 << 0
+				noBlendFlag = 1 << 1
+			)
+			flags := f[15]
+			r := image.Rect(x, y, x+w, y+h)
+			if !r.In(canvas.Rect) {
+				return nil, 0, errors.New("frame outside the canvas")
+			}
+			img, err := decodeWebPStill(webpFrameImage(f[16:], w, h))
+			if err != nil {
+				return nil, 0, err
+			}
+			op := draw.Over
+			if flags&noBlendFlag != 0 {
+				op = draw.Src
+			}
+			draw.Draw(canvas, r, img, image.Point{}, op)
+			frames = append(frames, Frame{
+				Image:    newImage(toNRGBA(canvas), md),
+				Duration: frameDuration(duration),
+			})
+			if flags&disposeFlag != 0 {
+				draw.Draw(canvas, r, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return frames, loops, nil
+}
+
+// webpFrameImage wraps the image chunks of an animation frame of size w, h
+// into a still WebP image. Lossy frames with alpha need an extended header.
+func webpFrameImage(data []byte, w, h int) []byte {
+	var header []byte
+	for _, c := range riffChunks(data) {
+		if c.id == "ALPH" {
+			const alphaFlag = 1 << 4
+			header = []byte{'V', 'P', '8', 'X', 10, 0, 0, 0, alphaFlag, 0, 0, 0}
+			header = appendUint24(header, uint32(w-1))
+			header = appendUint24(header, uint32(h-1))
+			break
+		}
+	}
+	b := make([]byte, 0, 12+len(header)+len(data))
+	b = append(b, "RIFF"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(4+len(header)+len(data)))
+	b = append(b, "WEBP"...)
+	b = append(b, header...)
+	return append(b, data...)
+}
+
+// uint24 decodes a little-endian 24-bit integer.
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+// appendUint24 appends v as a little-endian 24-bit integer.
+func appendUint24(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16))
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"sync/atomic"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+)
+
+// colorFilterer is implemented by color filters the canvas can evaluate on
+// the CPU.
+type colorFilterer interface {
+	interfaces.ColorFilter
+	// filterColor returns the filtered premultiplied color c.
+	filterColor(c rgbaF) rgbaF
+	// filterID identifies the filter in image cache keys.
+	filterID() uint32
+}
+
+// paintColorFilter returns the color filter of paint, if it has one the
+// canvas can evaluate.
+func paintColorFilter(paint SkPaint) (colorFilterer, bool) {
+	if paint == nil {
+		return nil, false
+	}
+	f, ok := paint.GetColorFilter().(colorFilterer)
+	return f, ok
+}
+
+// filterNRGBA returns col filtered by the color filter of paint, if it
+// has one the canvas can evaluate.
+func filterNRGBA(paint SkPaint, col color.NRGBA) color.NRGBA {
+	f, ok := paintColorFilter(paint)
+	if !ok {
+		return col
+	}
+	return unpremulRGBA(f.filterColor(toRGBAF(premulRGBA(col))).rgba())
+}
+
+// colorFilterIDs hands out color filter IDs.
+var colorFilterIDs atomic.Uint32
+
+// matrixColorFilter transforms unpremultiplied colors with a 4x5 matrix.
+type matrixColorFilter struct {
+	m  [20]float32
+	id uint32
+}
+
+// NewColorMatrixFilter returns a color filter that transforms colors with
+// the row-major 4x5 matrix m, as SkColorFilters::Matrix does. Each output
+// component is the dot product of a row with the unpremultiplied input
+// (r, g, b, a, 1), with components in [0, 1].
+func NewColorMatrixFilter(m [20]float32) interfaces.ColorFilter {
+	return &matrixColorFilter{m: m, id: colorFilterIDs.Add(1)}
+}
+
+func (f *matrixColorFilter) IsAlphaUnchanged() bool {
+	m := f.m
+	return m[15] == 0 && m[16] == 0 && m[17] == 0 && m[18] == 1 && m[19] == 0
+}
+
+func (f *matrixColorFilter) filterColor(c rgbaF) rgbaF {
+	var r, g, b float32
+	if c.a > 0 {
+		r, g, b = c.r/c.a, c.g/c.a, c.b/c.a
+	}
+	m := f.m
+	row := func(i int) float32 {
+		return m[i]*r + m[i+1]*g + m[i+2]*b + m[i+3]*c.a + m[i+4]
+	}
+	a := clampUnit(row(15))
+	return rgbaF{clampUnit(row(0)) * a, clampUnit(row(5)) * a, clampUnit(row(10)) * a, a}
+}
+
+func (f *matrixColorFilter) filterID() uint32 {
+	return f.id
+}
+
+// blendColorFilter blends a constant color onto colors.
+type blendColorFilter struct {
+	color rgbaF
+	mode  enums.BlendMode
+	id    uint32
+}
+
+// NewBlendModeColorFilter returns a color filter that blends c onto colors
+// with mode, c being the source, as SkColorFilters::Blend does.
+func NewBlendModeColorFilter(c color.NRGBA, mode enums.BlendMode) interfaces.ColorFilter {
+	return &blendColorFilter{color: toRGBAF(premulRGBA(c)), mode: mode, id: colorFilterIDs.Add(1)}
+}
+
+func (f *blendColorFilter) IsAlphaUnchanged() bool {
+	switch f.mode {
+	case enums.BlendModeDst, enums.BlendModeSrcATop:
+		return true
+	}
+	return false
+}
+
+func (f *blendColorFilter) filterColor(c rgbaF) rgbaF {
+	return blendRGBAF(f.mode, f.color, c).clamp()
+}
+
+func (f *blendColorFilter) filterID() uint32 {
+	return f.id
+}
+
+// filterImage returns a copy of img with every pixel filtered by f.
+func filterImage(img *image.RGBA, f colorFilterer) *image.RGBA {
+	dst := image.NewRGBA(img.Rect)
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		c := f.filterColor(toRGBAF(color.RGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: img.Pix[i+3]})).rgba()
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return dst
+}
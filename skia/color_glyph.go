@@ -0,0 +1,820 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"sync"
+
+	"gioui.org/f32"
+	gpaint "gioui.org/op/paint"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Color glyph rendering.
+//
+// Glyphs are drawn in font units (y up) through a matrix that maps them to
+// canvas coordinates. COLR glyphs are drawn by walking their paint graph with
+// the regular canvas clip and paint primitives; composites Gio cannot blend
+// are rendered on the CPU in a layer. Variable paints are resolved at the
+// face's variation coordinates. CBDT and sbix bitmaps are drawn through the
+// image path. Everything else falls back to the outline.
+
+// foregroundPaletteIndex is the CPAL index that selects the text color.
+const foregroundPaletteIndex = 0xFFFF
+
+// maxColrDepth bounds the recursion of COLRv1 paint graphs.
+const maxColrDepth = 64
+
+// maxColrLayerSize bounds the size of COLR paints rendered on the CPU.
+const maxColrLayerSize = 1024
+
+// drawGlyph draws a glyph, picking the color, bitmap or outline
+// representation. matrix maps font units to canvas coordinates.
+func (c *canvas) drawGlyph(skFont interfaces.SkFont, glyphID uint16, matrix SkMatrix, paint SkPaint) {
+	typeface := skFont.Typeface()
+	if face := goTextFace(typeface); face != nil {
+		switch data := face.GlyphData(font.GID(glyphID)).(type) {
+		case font.GlyphColor:
+			// The paint alpha scales every color of the glyph, the
+			// foreground included.
+			foreground := skPaintToPaint(paint).Color
+			foreground.A = 255
+			p := colrPainter{
+				c:          c,
+				typeface:   typeface,
+				face:       face,
+				palette:    resolvePalette(typeface),
+				foreground: foreground,
+				alpha:      float32(paint.GetAlphaf()),
+			}
+			p.drawBaseGlyph(glyphID, data.Paint, matrix)
+			return
+		case font.GlyphBitmap:
+			if c.drawBitmapGlyph(face, glyphID, data, matrix, paint) {
+				return
+			}
+		}
+	}
+
+	if c.textRenderMode == TextRenderModeMask && c.drawGlyphMask(skFont, glyphID, matrix, paint) {
+		return
+	}
+
+	glyphPath, err := typeface.GetGlyphPath(glyphID)
+	if err != nil || glyphPath == nil {
+		return
+	}
+	transformedPath := impl.NewSkPath(glyphPath.FillType())
+	transformedPath.AddPathMatrix(glyphPath, matrix, enums.AddPathModeAppend)
+	// Glyph paths are baked at their position, so caching their strokes
+	// would only evict reusable entries.
+	c.drawPathInternal(transformedPath, paint, false)
+}
+
+// ── Bitmap glyphs (CBDT, sbix) ───────────────────────────────────────────
+
+type bitmapGlyphKey struct {
+	face    *font.Face
+	glyphID uint16
+}
+
+// bitmapGlyphCacheSize bounds the number of decoded bitmap glyphs kept around.
+const bitmapGlyphCacheSize = 256
+
+var bitmapGlyphCache = struct {
+	sync.Mutex
+	images map[bitmapGlyphKey]*image.RGBA
+}{images: make(map[bitmapGlyphKey]*image.RGBA)}
+
+// drawBitmapGlyph draws an embedded bitmap glyph. It reports false if the
+// bitmap cannot be decoded, so the caller can fall back to the outline.
+func (c *canvas) drawBitmapGlyph(face *font.Face, glyphID uint16, data font.GlyphBitmap, matrix SkMatrix, paint SkPaint) bool {
+	extents, ok := face.GlyphExtents(font.GID(glyphID))
+	if !ok || extents.Width == 0 || extents.Height == 0 {
+		return false
+	}
+
+	var img *image.RGBA
+	alpha := float32(1)
+	if data.Format == font.BlackAndWhite {
+		// Monochrome bitmaps are masks colored by the paint, alpha
+		// included.
+		img = decodeMonoBitmap(data, skPaintToPaint(paint).Color)
+	} else {
+		img = decodeColorBitmap(face, glyphID, data)
+		alpha = float32(paint.GetAlphaf())
+	}
+	if img == nil {
+		return false
+	}
+
+	// The extents are in font units with y up; draw the image in a y-down
+	// space so it is not mirrored.
+	c.Save()
+	defer c.Restore()
+	c.Concat(matrix)
+	c.Scale(1, -1)
+	dst := models.Rect{
+		Left:   Scalar(extents.XBearing),
+		Top:    Scalar(-extents.YBearing),
+		Right:  Scalar(extents.XBearing + extents.Width),
+		Bottom: Scalar(-extents.YBearing - extents.Height),
+	}
+	size := img.Bounds().Size()
+	src := models.Rect{Right: Scalar(size.X), Bottom: Scalar(size.Y)}
+	if alpha < 1 {
+		opacity := gpaint.PushOpacity(c.ops, alpha)
+		defer opacity.Pop()
+	}
+	c.drawRGBAImageRect(img, src, dst)
+	return true
+}
+
+// decodeColorBitmap decodes a PNG or JPEG glyph image, caching the result.
+func decodeColorBitmap(face *font.Face, glyphID uint16, data font.GlyphBitmap) *image.RGBA {
+	key := bitmapGlyphKey{face: face, glyphID: glyphID}
+	bitmapGlyphCache.Lock()
+	img, ok := bitmapGlyphCache.images[key]
+	bitmapGlyphCache.Unlock()
+	if ok {
+		return img
+	}
+
+	if data.Format != font.PNG && data.Format != font.JPG {
+		return nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data.Data))
+	if err != nil {
+		return nil
+	}
+	b := src.Bounds()
+	img = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+
+	bitmapGlyphCache.Lock()
+	if len(bitmapGlyphCache.images) >= bitmapGlyphCacheSize {
+		bitmapGlyphCache.images = make(map[bitmapGlyphKey]*image.RGBA)
+	}
+	bitmapGlyphCache.images[key] = img
+	bitmapGlyphCache.Unlock()
+	return img
+}
+
+// decodeMonoBitmap expands a bit-aligned 1bpp glyph into an image of col.
+func decodeMonoBitmap(data font.GlyphBitmap, col color.NRGBA) *image.RGBA {
+	if data.Width <= 0 || data.Height <= 0 || len(data.Data)*8 < data.Width*data.Height {
+		return nil
+	}
+	img := image.NewRGBA(image.Rect(0, 0, data.Width, data.Height))
+	premul := premulRGBA(col)
+	for y := 0; y < data.Height; y++ {
+		for x := 0; x < data.Width; x++ {
+			bit := y*data.Width + x
+			if data.Data[bit/8]&(0x80>>(bit%8)) != 0 {
+				img.SetRGBA(x, y, premul)
+			}
+		}
+	}
+	return img
+}
+
+// ── COLR glyphs ───────────────────────────────────────────────────────────
+
+// colrPainter walks a COLR paint graph and draws it on the canvas.
+type colrPainter struct {
+	c          *canvas
+	typeface   interfaces.SkTypeface
+	face       *font.Face
+	palette    []color.NRGBA
+	foreground color.NRGBA
+	// alpha is the paint alpha, applied to every resolved color.
+	alpha float32
+	depth int
+}
+
+// drawBaseGlyph draws the color glyph glyphID whose root paint is root.
+// The drawing is clipped to the glyph's clip box or extents.
+func (p *colrPainter) drawBaseGlyph(glyphID uint16, root tables.PaintTable, m SkMatrix) {
+	if p.depth > maxColrDepth {
+		return
+	}
+	area, ok := p.clipBox(glyphID)
+	if !ok {
+		return
+	}
+	area = m.MapRect(area)
+
+	p.c.Save()
+	defer p.c.Restore()
+	p.c.ClipRect(area, enums.ClipOpIntersect, true)
+	p.depth++
+	p.paint(root, m, area)
+	p.depth--
+}
+
+// clipBox returns the area covered by a color glyph, in font units.
+func (p *colrPainter) clipBox(glyphID uint16) (models.Rect, bool) {
+	if p.face.COLR != nil {
+		if box, ok := p.face.COLR.ClipList.Search(tables.GlyphID(glyphID)); ok {
+			switch box := box.(type) {
+			case tables.ClipBoxFormat1:
+				return fontUnitsRect(box.XMin, box.YMin, box.XMax, box.YMax), true
+			case tables.ClipBoxFormat2:
+				return fontUnitsRect(box.XMin, box.YMin, box.XMax, box.YMax), true
+			}
+		}
+	}
+	extents, ok := p.face.GlyphExtents(font.GID(glyphID))
+	if !ok {
+		return models.Rect{}, false
+	}
+	return fontUnitsRect(
+		int16(extents.XBearing), int16(extents.YBearing+extents.Height),
+		int16(math.Ceil(float64(extents.XBearing+extents.Width))), int16(math.Ceil(float64(extents.YBearing))),
+	), true
+}
+
+func fontUnitsRect(xMin, yMin, xMax, yMax int16) models.Rect {
+	return models.Rect{Left: Scalar(xMin), Top: Scalar(yMin), Right: Scalar(xMax), Bottom: Scalar(yMax)}
+}
+
+// paint draws pt. m maps the current paint space to canvas coordinates and
+// area bounds the current clip in canvas coordinates.
+func (p *colrPainter) paint(pt tables.PaintTable, m SkMatrix, area models.Rect) {
+	if p.depth > maxColrDepth {
+		return
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	pt = p.resolveVariations(pt)
+	if child, cm, ok := paintTransform(pt, m); ok {
+		p.paint(child, cm, area)
+		return
+	}
+	switch v := pt.(type) {
+	case tables.PaintColrLayersResolved:
+		// COLRv0: each layer is a glyph outline filled with a palette color.
+		for _, layer := range v {
+			p.fillGlyph(uint16(layer.GlyphID), p.color(layer.PaletteIndex, 1), m)
+		}
+	case tables.PaintColrLayers:
+		layers, err := p.face.COLR.LayerList.Resolve(v)
+		if err != nil {
+			return
+		}
+		for _, layer := range layers {
+			p.paint(layer, m, area)
+		}
+	case tables.PaintSolid:
+		p.c.DrawPaint(NewPaintFill(p.color(v.PaletteIndex, f2dot14(v.Alpha))))
+	case tables.PaintLinearGradient:
+		p.rasterGradient(p.colorLine(v.ColorLine), m, area, linearGradientEval(v.X0, v.Y0, v.X1, v.Y1, v.X2, v.Y2))
+	case tables.PaintRadialGradient:
+		p.rasterGradient(p.colorLine(v.ColorLine), m, area, radialGradientEval(v.X0, v.Y0, v.Radius0, v.X1, v.Y1, v.Radius1))
+	case tables.PaintSweepGradient:
+		p.rasterGradient(p.colorLine(v.ColorLine), m, area, sweepGradientEval(v.CenterX, v.CenterY, v.StartAngle, v.EndAngle))
+	case tables.PaintGlyph:
+		glyphPath, err := p.typeface.GetGlyphPath(v.GlyphID)
+		if err != nil || glyphPath == nil {
+			return
+		}
+		clipPath := impl.NewSkPath(glyphPath.FillType())
+		clipPath.AddPathMatrix(glyphPath, m, enums.AddPathModeAppend)
+		p.c.Save()
+		p.c.ClipPath(clipPath, enums.ClipOpIntersect, true)
+		p.paint(v.Paint, m, intersectRect(area, clipPath.Bounds()))
+		p.c.Restore()
+	case tables.PaintColrGlyph:
+		if child, ok := p.face.COLR.Search(tables.GlyphID(v.GlyphID)); ok {
+			p.drawBaseGlyph(v.GlyphID, child, m)
+		}
+	case tables.PaintComposite:
+		p.composite(v, m, area)
+	}
+}
+
+// paintTransform returns the child of a transform paint and m concatenated
+// with its transform. It reports false for other paints. Variable paints
+// must be resolved first.
+func paintTransform(pt tables.PaintTable, m SkMatrix) (tables.PaintTable, SkMatrix, bool) {
+	switch v := pt.(type) {
+	case tables.PaintTransform:
+		t := v.Transform
+		return v.Paint, concatFontMatrix(m, t.Xx, t.Yx, t.Xy, t.Yy, t.Dx, t.Dy), true
+	case tables.PaintTranslate:
+		return v.Paint, concatFontMatrix(m, 1, 0, 0, 1, float32(v.Dx), float32(v.Dy)), true
+	case tables.PaintScale:
+		return v.Paint, scaleAround(m, f2dot14(v.ScaleX), f2dot14(v.ScaleY), 0, 0), true
+	case tables.PaintScaleAroundCenter:
+		return v.Paint, scaleAround(m, f2dot14(v.ScaleX), f2dot14(v.ScaleY), v.CenterX, v.CenterY), true
+	case tables.PaintScaleUniform:
+		return v.Paint, scaleAround(m, f2dot14(v.Scale), f2dot14(v.Scale), 0, 0), true
+	case tables.PaintScaleUniformAroundCenter:
+		return v.Paint, scaleAround(m, f2dot14(v.Scale), f2dot14(v.Scale), v.CenterX, v.CenterY), true
+	case tables.PaintRotate:
+		return v.Paint, rotateAround(m, f2dot14(v.Angle), 0, 0), true
+	case tables.PaintRotateAroundCenter:
+		return v.Paint, rotateAround(m, f2dot14(v.Angle), v.CenterX, v.CenterY), true
+	case tables.PaintSkew:
+		return v.Paint, skewAround(m, f2dot14(v.XSkewAngle), f2dot14(v.YSkewAngle), 0, 0), true
+	case tables.PaintSkewAroundCenter:
+		return v.Paint, skewAround(m, f2dot14(v.XSkewAngle), f2dot14(v.YSkewAngle), v.CenterX, v.CenterY), true
+	}
+	return nil, nil, false
+}
+
+// composite draws a PaintComposite. Modes that reduce to a drawing order
+// are drawn directly; Gio composites with source-over only, so the others
+// are blended on the CPU in a layer covering area.
+func (p *colrPainter) composite(v tables.PaintComposite, m SkMatrix, area models.Rect) {
+	switch v.CompositeMode {
+	case tables.CompositeClear:
+	case tables.CompositeSrc:
+		p.paint(v.SourcePaint, m, area)
+	case tables.CompositeDest:
+		p.paint(v.BackdropPaint, m, area)
+	case tables.CompositeSrcOver:
+		p.paint(v.BackdropPaint, m, area)
+		p.paint(v.SourcePaint, m, area)
+	case tables.CompositeDestOver:
+		p.paint(v.SourcePaint, m, area)
+		p.paint(v.BackdropPaint, m, area)
+	default:
+		l, ok := p.newLayer(area)
+		if !ok {
+			return
+		}
+		img := l.image()
+		l.composite(v, m, img, nil)
+		l.draw(img)
+	}
+}
+
+// fillGlyph fills the outline of glyphID with col.
+func (p *colrPainter) fillGlyph(glyphID uint16, col color.NRGBA, m SkMatrix) {
+	glyphPath, err := p.typeface.GetGlyphPath(glyphID)
+	if err != nil || glyphPath == nil {
+		return
+	}
+	path := impl.NewSkPath(glyphPath.FillType())
+	path.AddPathMatrix(glyphPath, m, enums.AddPathModeAppend)
+	p.c.DrawPath(path, NewPaintFill(col))
+}
+
+// color resolves a palette entry, scaled by alpha and the paint alpha.
+func (p *colrPainter) color(index uint16, alpha float32) color.NRGBA {
+	var col color.NRGBA
+	switch {
+	case index == foregroundPaletteIndex:
+		col = p.foreground
+	case int(index) < len(p.palette):
+		col = p.palette[index]
+	default:
+		return color.NRGBA{}
+	}
+	col.A = uint8(clampUnit(float32(col.A)/255*alpha*p.alpha)*255 + 0.5)
+	return col
+}
+
+// ── Gradients ─────────────────────────────────────────────────────────────
+
+// colorStop is a resolved COLR color stop.
+type colorStop struct {
+	offset float32
+	color  color.NRGBA
+}
+
+// colorLine is a resolved COLR color line.
+type colorLine struct {
+	extend tables.Extend
+	stops  []colorStop
+}
+
+func (p *colrPainter) colorLine(cl tables.ColorLine) colorLine {
+	line := colorLine{extend: cl.Extend}
+	for _, s := range cl.ColorStops {
+		line.stops = append(line.stops, colorStop{offset: f2dot14(s.StopOffset), color: p.color(s.PaletteIndex, f2dot14(s.Alpha))})
+	}
+	line.sort()
+	return line
+}
+
+// sort orders the stops by offset, keeping the order of equal offsets.
+func (l *colorLine) sort() {
+	for i := 1; i < len(l.stops); i++ {
+		for j := i; j > 0 && l.stops[j].offset < l.stops[j-1].offset; j-- {
+			l.stops[j], l.stops[j-1] = l.stops[j-1], l.stops[j]
+		}
+	}
+}
+
+// at returns the premultiplied color of the line at t.
+func (l colorLine) at(t float32) color.RGBA {
+	n := len(l.stops)
+	if n == 0 {
+		return color.RGBA{}
+	}
+	first, last := l.stops[0].offset, l.stops[n-1].offset
+	if span := last - first; span > 0 {
+		switch l.extend {
+		case tables.ExtendRepeat:
+			t = first + span*fract((t-first)/span)
+		case tables.ExtendReflect:
+			u := (t - first) / span
+			u = float32(math.Abs(float64(u - 2*float32(math.Floor(float64(u/2+0.5))))))
+			t = first + span*u
+		}
+	}
+	if t <= first {
+		return premulRGBA(l.stops[0].color)
+	}
+	if t >= last {
+		return premulRGBA(l.stops[n-1].color)
+	}
+	for i := 1; i < n; i++ {
+		s0, s1 := l.stops[i-1], l.stops[i]
+		if t > s1.offset {
+			continue
+		}
+		w := float32(0)
+		if d := s1.offset - s0.offset; d > 0 {
+			w = (t - s0.offset) / d
+		}
+		return lerpRGBA(premulRGBA(s0.color), premulRGBA(s1.color), w)
+	}
+	return premulRGBA(l.stops[n-1].color)
+}
+
+// linearGradientAxis returns the start and direction of a COLR linear
+// gradient: p1 projected onto the line through p0 perpendicular to p0p2. It
+// reports false for degenerate gradients.
+func linearGradientAxis(x0, y0, x1, y1, x2, y2 int16) (p0, d f32.Point, ok bool) {
+	p0 = f32.Pt(float32(x0), float32(y0))
+	d1 := f32.Pt(float32(x1), float32(y1)).Sub(p0)
+	n := f32.Pt(float32(y2-y0), -float32(x2-x0))
+	nn := n.X*n.X + n.Y*n.Y
+	var p3 f32.Point
+	if nn == 0 {
+		p3 = p0.Add(d1)
+	} else {
+		p3 = p0.Add(n.Mul((d1.X*n.X + d1.Y*n.Y) / nn))
+	}
+	d = p3.Sub(p0)
+	return p0, d, d.X*d.X+d.Y*d.Y != 0
+}
+
+// linearGradientEval returns the color line position function of a COLR
+// linear gradient, or nil if it is degenerate.
+func linearGradientEval(x0, y0, x1, y1, x2, y2 int16) func(x, y float32) (float32, bool) {
+	p0, d, ok := linearGradientAxis(x0, y0, x1, y1, x2, y2)
+	if !ok {
+		return nil
+	}
+	dd := d.X*d.X + d.Y*d.Y
+	return func(x, y float32) (float32, bool) {
+		return ((x-p0.X)*d.X + (y-p0.Y)*d.Y) / dd, true
+	}
+}
+
+// radialGradientEval returns the color line position function of a COLR
+// radial gradient.
+func radialGradientEval(x0, y0 int16, r0 uint16, x1, y1 int16, r1 uint16) func(x, y float32) (float32, bool) {
+	c0 := f32.Pt(float32(x0), float32(y0))
+	cd := f32.Pt(float32(x1), float32(y1)).Sub(c0)
+	fr0 := float32(r0)
+	dr := float32(r1) - fr0
+	a := cd.X*cd.X + cd.Y*cd.Y - dr*dr
+	return func(x, y float32) (float32, bool) {
+		return twoPointConical(x-c0.X, y-c0.Y, cd, fr0, dr, a)
+	}
+}
+
+// twoPointConical solves for the largest t such that (px, py), relative to
+// the start center, lies on the circle interpolated at t with a
+// non-negative radius.
+func twoPointConical(px, py float32, cd f32.Point, r0, dr, a float32) (float32, bool) {
+	b := px*cd.X + py*cd.Y + r0*dr
+	c := px*px + py*py - r0*r0
+	if a == 0 {
+		if b == 0 {
+			return 0, false
+		}
+		t := c / (2 * b)
+		return t, r0+t*dr >= 0
+	}
+	disc := b*b - a*c
+	if disc < 0 {
+		return 0, false
+	}
+	s := float32(math.Sqrt(float64(disc)))
+	t1, t2 := (b+s)/a, (b-s)/a
+	if t1 < t2 {
+		t1, t2 = t2, t1
+	}
+	if r0+t1*dr >= 0 {
+		return t1, true
+	}
+	if r0+t2*dr >= 0 {
+		return t2, true
+	}
+	return 0, false
+}
+
+// sweepGradientEval returns the color line position function of a COLR
+// sweep gradient.
+func sweepGradientEval(cx, cy int16, start, end tables.Fixed214) func(x, y float32) (float32, bool) {
+	startDeg := (f2dot14(start) + 1) * 180
+	endDeg := (f2dot14(end) + 1) * 180
+	span := endDeg - startDeg
+	return func(x, y float32) (float32, bool) {
+		// Font units are y up, so atan2 gives counter-clockwise degrees.
+		deg := float32(math.Atan2(float64(y-float32(cy)), float64(x-float32(cx))) * 180 / math.Pi)
+		if deg < 0 {
+			deg += 360
+		}
+		if span == 0 {
+			if deg < startDeg {
+				return -1, true
+			}
+			return 2, true
+		}
+		return (deg - startDeg) / span, true
+	}
+}
+
+// rasterGradient rasterizes a gradient over area and draws it under the
+// current clip. eval maps a point in paint space to a color line position.
+func (p *colrPainter) rasterGradient(line colorLine, m SkMatrix, area models.Rect, eval func(x, y float32) (float32, bool)) {
+	if len(line.stops) == 0 || eval == nil {
+		return
+	}
+	l, ok := p.newLayer(area)
+	if !ok {
+		return
+	}
+	img := l.image()
+	l.fillGradient(img, nil, line, m, eval)
+	l.draw(img)
+}
+
+// ── Variations ────────────────────────────────────────────────────────────
+
+// noVariation is the VarIndexBase of values that do not vary.
+const noVariation = 0xFFFFFFFF
+
+// delta returns the delta of the value at base+i of a variable paint at the
+// face's variation coordinates, in the units of the value.
+func (p *colrPainter) delta(base, i uint32) float32 {
+	if base == noVariation || p.face == nil {
+		return 0
+	}
+	colr := p.face.COLR
+	coords := p.face.Coords()
+	if colr == nil || colr.ItemVariationStore == nil || len(coords) == 0 {
+		return 0
+	}
+	idx := base + i
+	var index tables.VariationStoreIndex
+	if m := colr.VarIndexMap; m != nil && len(m.Map) > 0 {
+		index = m.Map[min(int(idx), len(m.Map)-1)]
+	} else {
+		// Without a map the index holds the outer and inner indices.
+		index = tables.VariationStoreIndex{DeltaSetOuter: uint16(idx >> 16), DeltaSetInner: uint16(idx)}
+	}
+	return colr.ItemVariationStore.GetDelta(index, coords)
+}
+
+// vary adds the delta at base+i to v, rounded to v's units.
+func vary[T ~int16](p *colrPainter, v T, base, i uint32) T {
+	d := p.delta(base, i)
+	if d == 0 {
+		return v
+	}
+	return T(min(max(math.Round(float64(v)+float64(d)), math.MinInt16), math.MaxInt16))
+}
+
+// varyUnsigned is vary for unsigned values such as radii.
+func varyUnsigned(p *colrPainter, v uint16, base, i uint32) uint16 {
+	d := p.delta(base, i)
+	if d == 0 {
+		return v
+	}
+	return uint16(min(max(math.Round(float64(v)+float64(d)), 0), math.MaxUint16))
+}
+
+// resolveVariations returns the static paint a variable paint resolves to
+// at the face's variation coordinates. Other paints are returned as they
+// are. Only the paint itself is resolved, not its children.
+func (p *colrPainter) resolveVariations(pt tables.PaintTable) tables.PaintTable {
+	switch v := pt.(type) {
+	case tables.PaintVarSolid:
+		return tables.PaintSolid{PaletteIndex: v.PaletteIndex, Alpha: vary(p, v.Alpha, v.VarIndexBase, 0)}
+	case tables.PaintVarLinearGradient:
+		b := v.VarIndexBase
+		return tables.PaintLinearGradient{
+			ColorLine: p.resolveColorLine(v.ColorLine),
+			X0:        vary(p, v.X0, b, 0),
+			Y0:        vary(p, v.Y0, b, 1),
+			X1:        vary(p, v.X1, b, 2),
+			Y1:        vary(p, v.Y1, b, 3),
+			X2:        vary(p, v.X2, b, 4),
+			Y2:        vary(p, v.Y2, b, 5),
+		}
+	case tables.PaintVarRadialGradient:
+		b := v.VarIndexBase
+		return tables.PaintRadialGradient{
+			ColorLine: p.resolveColorLine(v.ColorLine),
+			X0:        vary(p, v.X0, b, 0),
+			Y0:        vary(p, v.Y0, b, 1),
+			Radius0:   varyUnsigned(p, v.Radius0, b, 2),
+			X1:        vary(p, v.X1, b, 3),
+			Y1:        vary(p, v.Y1, b, 4),
+			Radius1:   varyUnsigned(p, v.Radius1, b, 5),
+		}
+	case tables.PaintVarSweepGradient:
+		b := v.VarIndexBase
+		return tables.PaintSweepGradient{
+			ColorLine:  p.resolveColorLine(v.ColorLine),
+			CenterX:    vary(p, v.CenterX, b, 0),
+			CenterY:    vary(p, v.CenterY, b, 1),
+			StartAngle: vary(p, v.StartAngle, b, 2),
+			EndAngle:   vary(p, v.EndAngle, b, 3),
+		}
+	case tables.PaintVarTransform:
+		// The matrix is 16.16 fixed point, and so are its deltas.
+		t, b := v.Transform, v.Transform.VarIndexBase
+		fixed := func(v float32, i uint32) float32 { return v + p.delta(b, i)/(1<<16) }
+		return tables.PaintTransform{Paint: v.Paint, Transform: tables.Affine2x3{
+			Xx: fixed(t.Xx, 0),
+			Yx: fixed(t.Yx, 1),
+			Xy: fixed(t.Xy, 2),
+			Yy: fixed(t.Yy, 3),
+			Dx: fixed(t.Dx, 4),
+			Dy: fixed(t.Dy, 5),
+		}}
+	case tables.PaintVarTranslate:
+		b := v.VarIndexBase
+		return tables.PaintTranslate{Paint: v.Paint, Dx: vary(p, v.Dx, b, 0), Dy: vary(p, v.Dy, b, 1)}
+	case tables.PaintVarScale:
+		b := v.VarIndexBase
+		return tables.PaintScale{Paint: v.Paint, ScaleX: vary(p, v.ScaleX, b, 0), ScaleY: vary(p, v.ScaleY, b, 1)}
+	case tables.PaintVarScaleAroundCenter:
+		b := v.VarIndexBase
+		return tables.PaintScaleAroundCenter{
+			Paint:   v.Paint,
+			ScaleX:  vary(p, v.ScaleX, b, 0),
+			ScaleY:  vary(p, v.ScaleY, b, 1),
+			CenterX: vary(p, v.CenterX, b, 2),
+			CenterY: vary(p, v.CenterY, b, 3),
+		}
+	case tables.PaintVarScaleUniform:
+		return tables.PaintScaleUniform{Paint: v.Paint, Scale: vary(p, v.Scale, v.VarIndexBase, 0)}
+	case tables.PaintVarScaleUniformAroundCenter:
+		b := v.VarIndexBase
+		return tables.PaintScaleUniformAroundCenter{
+			Paint:   v.Paint,
+			Scale:   vary(p, v.Scale, b, 0),
+			CenterX: vary(p, v.CenterX, b, 1),
+			CenterY: vary(p, v.CenterY, b, 2),
+		}
+	case tables.PaintVarRotate:
+		return tables.PaintRotate{Paint: v.Paint, Angle: vary(p, v.Angle, v.VarIndexBase, 0)}
+	case tables.PaintVarRotateAroundCenter:
+		b := v.VarIndexBase
+		return tables.PaintRotateAroundCenter{
+			Paint:   v.Paint,
+			Angle:   vary(p, v.Angle, b, 0),
+			CenterX: vary(p, v.CenterX, b, 1),
+			CenterY: vary(p, v.CenterY, b, 2),
+		}
+	case tables.PaintVarSkew:
+		b := v.VarIndexBase
+		return tables.PaintSkew{Paint: v.Paint, XSkewAngle: vary(p, v.XSkewAngle, b, 0), YSkewAngle: vary(p, v.YSkewAngle, b, 1)}
+	case tables.PaintVarSkewAroundCenter:
+		b := v.VarIndexBase
+		return tables.PaintSkewAroundCenter{
+			Paint:      v.Paint,
+			XSkewAngle: vary(p, v.XSkewAngle, b, 0),
+			YSkewAngle: vary(p, v.YSkewAngle, b, 1),
+			CenterX:    vary(p, v.CenterX, b, 2),
+			CenterY:    vary(p, v.CenterY, b, 3),
+		}
+	}
+	return pt
+}
+
+// resolveColorLine resolves the stop offsets and alphas of a variable
+// color line.
+func (p *colrPainter) resolveColorLine(cl tables.VarColorLine) tables.ColorLine {
+	line := tables.ColorLine{Extend: cl.Extend, ColorStops: make([]tables.ColorStop, len(cl.ColorStops))}
+	for i, s := range cl.ColorStops {
+		line.ColorStops[i] = tables.ColorStop{
+			StopOffset:   vary(p, s.StopOffset, s.VarIndexBase, 0),
+			PaletteIndex: s.PaletteIndex,
+			Alpha:        vary(p, s.Alpha, s.VarIndexBase, 1),
+		}
+	}
+	return line
+}
+
+// ── Helpers ───────────────────────────────────────────────────────────────
+
+// f2dot14 converts a F2DOT14 value to float32.
+func f2dot14(v tables.Fixed214) float32 {
+	return float32(v) / (1 << 14)
+}
+
+// concatFontMatrix returns m * [xx xy dx; yx yy dy].
+func concatFontMatrix(m SkMatrix, xx, yx, xy, yy, dx, dy float32) SkMatrix {
+	t := impl.NewMatrixAll(
+		Scalar(xx), Scalar(xy), Scalar(dx),
+		Scalar(yx), Scalar(yy), Scalar(dy),
+		0, 0, 1,
+	)
+	result := impl.NewMatrixIdentity()
+	result.SetConcat(m, t)
+	return result
+}
+
+// aroundCenter wraps the linear transform [a c; b d] so it is applied around
+// (cx, cy).
+func aroundCenter(m SkMatrix, a, b, c, d float32, cx, cy int16) SkMatrix {
+	x, y := float32(cx), float32(cy)
+	dx := x - (a*x + c*y)
+	dy := y - (b*x + d*y)
+	return concatFontMatrix(m, a, b, c, d, dx, dy)
+}
+
+func scaleAround(m SkMatrix, sx, sy float32, cx, cy int16) SkMatrix {
+	return aroundCenter(m, sx, 0, 0, sy, cx, cy)
+}
+
+// rotateAround rotates counter-clockwise by angle half-turns, in y-up space.
+func rotateAround(m SkMatrix, angle float32, cx, cy int16) SkMatrix {
+	s, c := math.Sincos(float64(angle) * math.Pi)
+	return aroundCenter(m, float32(c), float32(s), float32(-s), float32(c), cx, cy)
+}
+
+// skewAround skews by the given angles in half-turns, in y-up space.
+func skewAround(m SkMatrix, xAngle, yAngle float32, cx, cy int16) SkMatrix {
+	xy := float32(-math.Tan(float64(xAngle) * math.Pi))
+	yx := float32(math.Tan(float64(yAngle) * math.Pi))
+	return aroundCenter(m, 1, yx, xy, 1, cx, cy)
+}
+
+// intersectRect returns the intersection of a and b, or an empty rect.
+func intersectRect(a, b models.Rect) models.Rect {
+	r := models.Rect{
+		Left:   max(a.Left, b.Left),
+		Top:    max(a.Top, b.Top),
+		Right:  min(a.Right, b.Right),
+		Bottom: min(a.Bottom, b.Bottom),
+	}
+	if r.Right < r.Left || r.Bottom < r.Top {
+		return models.Rect{}
+	}
+	return r
+}
+
+// affineDeterminant returns the determinant of the linear part of t.
+func affineDeterminant(t f32.Affine2D) float32 {
+	sx, hx, _, hy, sy, _ := t.Elems()
+	return sx*sy - hx*hy
+}
+
+func premulRGBA(c color.NRGBA) color.RGBA {
+	a := uint32(c.A)
+	return color.RGBA{
+		R: uint8((uint32(c.R)*a + 127) / 255),
+		G: uint8((uint32(c.G)*a + 127) / 255),
+		B: uint8((uint32(c.B)*a + 127) / 255),
+		A: c.A,
+	}
+}
+
+func lerpRGBA(a, b color.RGBA, t float32) color.RGBA {
+	l := func(x, y uint8) uint8 {
+		return uint8(float32(x) + (float32(y)-float32(x))*t + 0.5)
+	}
+	return color.RGBA{R: l(a.R, b.R), G: l(a.G, b.G), B: l(a.B, b.B), A: l(a.A, b.A)}
+}
+
+func clampUnit(v float32) float32 {
+	return min(max(v, 0), 1)
+}
+
+func fract(v float32) float32 {
+	return v - float32(math.Floor(float64(v)))
+}
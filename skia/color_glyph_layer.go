@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/f32"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+	"golang.org/x/image/vector"
+)
+
+// colrLayer renders COLR paints on the CPU into an image covering an area
+// of the canvas, at device resolution. It serves gradients Gio cannot draw
+// and composites Gio cannot blend.
+type colrLayer struct {
+	p    *colrPainter
+	area models.Rect
+	// toPixel maps canvas coordinates to layer pixels.
+	toPixel SkMatrix
+	w, h    int
+}
+
+// compositeBlendModes maps COLR composite modes to blend modes.
+var compositeBlendModes = [...]enums.BlendMode{
+	tables.CompositeClear:         enums.BlendModeClear,
+	tables.CompositeSrc:           enums.BlendModeSrc,
+	tables.CompositeDest:          enums.BlendModeDst,
+	tables.CompositeSrcOver:       enums.BlendModeSrcOver,
+	tables.CompositeDestOver:      enums.BlendModeDstOver,
+	tables.CompositeSrcIn:         enums.BlendModeSrcIn,
+	tables.CompositeDestIn:        enums.BlendModeDstIn,
+	tables.CompositeSrcOut:        enums.BlendModeSrcOut,
+	tables.CompositeDestOut:       enums.BlendModeDstOut,
+	tables.CompositeSrcAtop:       enums.BlendModeSrcATop,
+	tables.CompositeDestAtop:      enums.BlendModeDstATop,
+	tables.CompositeXor:           enums.BlendModeXor,
+	tables.CompositePlus:          enums.BlendModePlus,
+	tables.CompositeScreen:        enums.BlendModeScreen,
+	tables.CompositeOverlay:       enums.BlendModeOverlay,
+	tables.CompositeDarken:        enums.BlendModeDarken,
+	tables.CompositeLighten:       enums.BlendModeLighten,
+	tables.CompositeColorDodge:    enums.BlendModeColorDodge,
+	tables.CompositeColorBurn:     enums.BlendModeColorBurn,
+	tables.CompositeHardLight:     enums.BlendModeHardLight,
+	tables.CompositeSoftLight:     enums.BlendModeSoftLight,
+	tables.CompositeDifference:    enums.BlendModeDifference,
+	tables.CompositeExclusion:     enums.BlendModeExclusion,
+	tables.CompositeMultiply:      enums.BlendModeMultiply,
+	tables.CompositeHslHue:        enums.BlendModeHue,
+	tables.CompositeHslSaturation: enums.BlendModeSaturation,
+	tables.CompositeHslColor:      enums.BlendModeColor,
+	tables.CompositeHslLuminosity: enums.BlendModeLuminosity,
+}
+
+// newLayer returns a layer covering area, in canvas coordinates. It
+// reports false if area is empty.
+func (p *colrPainter) newLayer(area models.Rect) (*colrLayer, bool) {
+	if area.Right <= area.Left || area.Bottom <= area.Top {
+		return nil, false
+	}
+	scale := float32(math.Sqrt(math.Abs(float64(affineDeterminant(p.c.stack[len(p.c.stack)-1].xform)))))
+	if scale <= 0 {
+		scale = 1
+	}
+	aw, ah := float32(area.Right-area.Left), float32(area.Bottom-area.Top)
+	w := min(max(int(math.Ceil(float64(aw*scale))), 1), maxColrLayerSize)
+	h := min(max(int(math.Ceil(float64(ah*scale))), 1), maxColrLayerSize)
+	sx, sy := Scalar(float32(w)/aw), Scalar(float32(h)/ah)
+	return &colrLayer{
+		p:    p,
+		area: area,
+		toPixel: impl.NewMatrixAll(
+			sx, 0, -area.Left*sx,
+			0, sy, -area.Top*sy,
+			0, 0, 1,
+		),
+		w: w,
+		h: h,
+	}, true
+}
+
+// image returns a transparent image of the layer's size.
+func (l *colrLayer) image() *image.RGBA {
+	return image.NewRGBA(image.Rect(0, 0, l.w, l.h))
+}
+
+// draw draws img, an image of the layer, over the layer's area under the
+// canvas clip.
+func (l *colrLayer) draw(img *image.RGBA) {
+	l.p.c.drawRGBAImageRect(img, models.Rect{Right: Scalar(l.w), Bottom: Scalar(l.h)}, l.area)
+}
+
+// pixelMatrix maps paint space to layer pixels, given m mapping paint space
+// to canvas coordinates.
+func (l *colrLayer) pixelMatrix(m SkMatrix) SkMatrix {
+	pm := impl.NewMatrixIdentity()
+	pm.SetConcat(l.toPixel, m)
+	return pm
+}
+
+// paint draws pt into dst, masked by mask if not nil. m maps paint space to
+// canvas coordinates.
+func (l *colrLayer) paint(pt tables.PaintTable, m SkMatrix, dst *image.RGBA, mask *image.Alpha) {
+	p := l.p
+	if p.depth > maxColrDepth {
+		return
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	pt = p.resolveVariations(pt)
+	if child, cm, ok := paintTransform(pt, m); ok {
+		l.paint(child, cm, dst, mask)
+		return
+	}
+	switch v := pt.(type) {
+	case tables.PaintColrLayersResolved:
+		for _, layer := range v {
+			if cov := l.glyphCoverage(uint16(layer.GlyphID), m, mask); cov != nil {
+				l.fillColor(dst, cov, p.color(layer.PaletteIndex, 1))
+			}
+		}
+	case tables.PaintColrLayers:
+		layers, err := p.face.COLR.LayerList.Resolve(v)
+		if err != nil {
+			return
+		}
+		for _, layer := range layers {
+			l.paint(layer, m, dst, mask)
+		}
+	case tables.PaintSolid:
+		l.fillColor(dst, mask, p.color(v.PaletteIndex, f2dot14(v.Alpha)))
+	case tables.PaintLinearGradient:
+		l.fillGradient(dst, mask, p.colorLine(v.ColorLine), m, linearGradientEval(v.X0, v.Y0, v.X1, v.Y1, v.X2, v.Y2))
+	case tables.PaintRadialGradient:
+		l.fillGradient(dst, mask, p.colorLine(v.ColorLine), m, radialGradientEval(v.X0, v.Y0, v.Radius0, v.X1, v.Y1, v.Radius1))
+	case tables.PaintSweepGradient:
+		l.fillGradient(dst, mask, p.colorLine(v.ColorLine), m, sweepGradientEval(v.CenterX, v.CenterY, v.StartAngle, v.EndAngle))
+	case tables.PaintGlyph:
+		if cov := l.glyphCoverage(v.GlyphID, m, mask); cov != nil {
+			l.paint(v.Paint, m, dst, cov)
+		}
+	case tables.PaintColrGlyph:
+		child, ok := p.face.COLR.Search(tables.GlyphID(v.GlyphID))
+		if !ok {
+			return
+		}
+		box, ok := p.clipBox(v.GlyphID)
+		if !ok {
+			return
+		}
+		l.paint(child, m, dst, l.rectCoverage(m.MapRect(box), mask))
+	case tables.PaintComposite:
+		l.composite(v, m, dst, mask)
+	}
+}
+
+// composite renders the source and backdrop of v in layers of their own,
+// blends them with v's mode and draws the result into dst.
+func (l *colrLayer) composite(v tables.PaintComposite, m SkMatrix, dst *image.RGBA, mask *image.Alpha) {
+	mode := enums.BlendModeSrcOver
+	if int(v.CompositeMode) < len(compositeBlendModes) {
+		mode = compositeBlendModes[v.CompositeMode]
+	}
+	src, backdrop := l.image(), l.image()
+	l.paint(v.SourcePaint, m, src, nil)
+	l.paint(v.BackdropPaint, m, backdrop, nil)
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			c := blendRGBA(mode, src.RGBAAt(x, y), backdrop.RGBAAt(x, y))
+			l.blend(dst, mask, x, y, c)
+		}
+	}
+}
+
+// fillColor fills the layer with col, masked by mask if not nil.
+func (l *colrLayer) fillColor(dst *image.RGBA, mask *image.Alpha, col color.NRGBA) {
+	if col.A == 0 {
+		return
+	}
+	c := premulRGBA(col)
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			l.blend(dst, mask, x, y, c)
+		}
+	}
+}
+
+// fillGradient fills the layer with a gradient, masked by mask if not nil.
+// eval maps a point in paint space to a color line position, and m maps
+// paint space to canvas coordinates.
+func (l *colrLayer) fillGradient(dst *image.RGBA, mask *image.Alpha, line colorLine, m SkMatrix, eval func(x, y float32) (float32, bool)) {
+	if len(line.stops) == 0 || eval == nil {
+		return
+	}
+	inv, ok := l.pixelMatrix(m).Invert()
+	if !ok {
+		return
+	}
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			if mask != nil && mask.Pix[y*mask.Stride+x] == 0 {
+				continue
+			}
+			fx, fy := inv.MapXY(Scalar(float32(x)+0.5), Scalar(float32(y)+0.5))
+			if t, ok := eval(float32(fx), float32(fy)); ok {
+				l.blend(dst, mask, x, y, line.at(t))
+			}
+		}
+	}
+}
+
+// blend draws the premultiplied color c source-over onto the pixel at x, y
+// of dst, scaled by the mask coverage.
+func (l *colrLayer) blend(dst *image.RGBA, mask *image.Alpha, x, y int, c color.RGBA) {
+	if mask != nil {
+		cov := uint32(mask.Pix[y*mask.Stride+x])
+		if cov == 0 {
+			return
+		}
+		if cov < 255 {
+			scale := func(v uint8) uint8 { return uint8((uint32(v)*cov + 127) / 255) }
+			c = color.RGBA{R: scale(c.R), G: scale(c.G), B: scale(c.B), A: scale(c.A)}
+		}
+	}
+	if c.A == 0 {
+		return
+	}
+	dst.SetRGBA(x, y, blendRGBA(enums.BlendModeSrcOver, c, dst.RGBAAt(x, y)))
+}
+
+// glyphCoverage rasterizes the outline of glyphID, intersected with mask if
+// not nil. It returns nil for glyphs without an outline.
+func (l *colrLayer) glyphCoverage(glyphID uint16, m SkMatrix, mask *image.Alpha) *image.Alpha {
+	glyphPath, err := l.p.typeface.GetGlyphPath(glyphID)
+	if err != nil || glyphPath == nil {
+		return nil
+	}
+	pm := l.pixelMatrix(m)
+	// Glyph outlines are in font units; scale the tolerance to pixels.
+	scale := max(
+		math.Hypot(float64(pm.GetScaleX()), float64(pm.GetSkewY())),
+		math.Hypot(float64(pm.GetSkewX()), float64(pm.GetScaleY())),
+	)
+	r := vector.NewRasterizer(l.w, l.h)
+	convertPath(glyphPath, conicTolerance/Scalar(max(scale, 1e-6)), rasterSink{r: r, xform: func(p f32.Point) (float32, float32) {
+		x, y := pm.MapXY(Scalar(p.X), Scalar(p.Y))
+		return float32(x), float32(y)
+	}})
+	return l.coverage(r, mask)
+}
+
+// rectCoverage rasterizes rect, in canvas coordinates, intersected with
+// mask if not nil.
+func (l *colrLayer) rectCoverage(rect models.Rect, mask *image.Alpha) *image.Alpha {
+	rect = l.toPixel.MapRect(rect)
+	r := vector.NewRasterizer(l.w, l.h)
+	r.MoveTo(float32(rect.Left), float32(rect.Top))
+	r.LineTo(float32(rect.Right), float32(rect.Top))
+	r.LineTo(float32(rect.Right), float32(rect.Bottom))
+	r.LineTo(float32(rect.Left), float32(rect.Bottom))
+	r.ClosePath()
+	return l.coverage(r, mask)
+}
+
+// coverage draws r into a new coverage image, multiplied by mask if not
+// nil.
+func (l *colrLayer) coverage(r *vector.Rasterizer, mask *image.Alpha) *image.Alpha {
+	cov := image.NewAlpha(image.Rect(0, 0, l.w, l.h))
+	r.Draw(cov, cov.Bounds(), image.Opaque, image.Point{})
+	if mask != nil {
+		for i, a := range mask.Pix {
+			cov.Pix[i] = uint8((uint32(cov.Pix[i])*uint32(a) + 127) / 255)
+		}
+	}
+	return cov
+}
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestColorLine_Extend(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	line := colorLine{stops: []colorStop{{offset: 0, color: red}, {offset: 1, color: blue}}}
+
+	line.extend = tables.ExtendPad
+	if got := line.at(-0.5); got != premulRGBA(red) {
+		t.Errorf("pad before start: got %v, want %v", got, red)
+	}
+	if got := line.at(1.5); got != premulRGBA(blue) {
+		t.Errorf("pad after end: got %v, want %v", got, blue)
+	}
+
+	line.extend = tables.ExtendRepeat
+	if got := line.at(1.25); got != line.at(0.25) {
+		t.Errorf("repeat: got %v, want %v", got, line.at(0.25))
+	}
+
+	line.extend = tables.ExtendReflect
+	if got := line.at(1.25); got != line.at(0.75) {
+		t.Errorf("reflect: got %v, want %v", got, line.at(0.75))
+	}
+}
+
+func TestColorLine_SortsStops(t *testing.T) {
+	line := colorLine{stops: []colorStop{
+		{offset: 1, color: color.NRGBA{B: 255, A: 255}},
+		{offset: 0, color: color.NRGBA{R: 255, A: 255}},
+	}}
+	line.sort()
+	if line.stops[0].offset != 0 || line.stops[1].offset != 1 {
+		t.Errorf("stops not sorted: %v", line.stops)
+	}
+}
+
+func TestTwoPointConical_Concentric(t *testing.T) {
+	// Concentric circles from radius 10 to 20: a point at distance 15 is
+	// halfway.
+	cd := f32.Pt(0, 0)
+	tt, ok := twoPointConical(15, 0, cd, 10, 10, -100)
+	if !ok {
+		t.Fatal("expected a solution")
+	}
+	if tt < 0.49 || tt > 0.51 {
+		t.Errorf("got t=%v, want 0.5", tt)
+	}
+}
+
+func TestColrPainter_Color(t *testing.T) {
+	p := colrPainter{
+		palette:    []color.NRGBA{{R: 10, G: 20, B: 30, A: 255}},
+		foreground: color.NRGBA{R: 1, G: 2, B: 3, A: 255},
+		alpha:      1,
+	}
+	if got := p.color(0, 1); got != p.palette[0] {
+		t.Errorf("palette color: got %v, want %v", got, p.palette[0])
+	}
+	if got := p.color(foregroundPaletteIndex, 0.5); got.R != 1 || got.A != 128 {
+		t.Errorf("foreground color: got %v", got)
+	}
+	if got := p.color(5, 1); got != (color.NRGBA{}) {
+		t.Errorf("out of range index: got %v, want transparent", got)
+	}
+	// The paint alpha scales every color.
+	p.alpha = 0.5
+	if got := p.color(0, 1); got.A != 128 {
+		t.Errorf("palette color with paint alpha: got %v", got)
+	}
+	if got := p.color(foregroundPaletteIndex, 0.5); got.A != 64 {
+		t.Errorf("foreground color with paint alpha: got %v", got)
+	}
+}
+
+func TestDecodeMonoBitmap(t *testing.T) {
+	data := font.GlyphBitmap{Data: []byte{0b10100000}, Format: font.BlackAndWhite, Width: 2, Height: 2}
+	img := decodeMonoBitmap(data, color.NRGBA{R: 255, A: 255})
+	if img == nil {
+		t.Fatal("decodeMonoBitmap returned nil")
+	}
+	if img.RGBAAt(0, 0).A != 255 || img.RGBAAt(1, 0).A != 0 || img.RGBAAt(0, 1).A != 255 || img.RGBAAt(1, 1).A != 0 {
+		t.Errorf("unexpected mask: %v", img.Pix)
+	}
+}
+
+func TestMakeTypefaceWithPalette(t *testing.T) {
+	if MakeTypefaceWithPalette(nil, FontPalette{}) != nil {
+		t.Error("expected nil for nil typeface")
+	}
+
+	typeface := impl.NewTypeface("sans-serif", models.FontStyle{})
+	tf := MakeTypefaceWithPalette(typeface, FontPalette{Index: 1})
+	tf = MakeTypefaceWithPalette(tf, FontPalette{Index: 2})
+	pt, ok := tf.(*paletteTypeface)
+	if !ok {
+		t.Fatalf("expected *paletteTypeface, got %T", tf)
+	}
+	if pt.SkTypeface != typeface {
+		t.Error("palette typefaces should not nest")
+	}
+	if pt.palette.Index != 2 {
+		t.Errorf("palette index: got %d, want 2", pt.palette.Index)
+	}
+
+	// Typefaces without color glyphs draw through the outline path.
+	ops := new(op.Ops)
+	canvas := NewCanvas(ops)
+	font := impl.NewFontWithTypefaceAndSize(tf, 24)
+	canvas.DrawString("Hello", 10, 50, font, NewPaintFill(color.NRGBA{A: 255}))
+}
+
+func TestColrPainter_ResolveVariations(t *testing.T) {
+	// One axis at its peak; the deltas of items 0, 1 and 2 apply in full.
+	store := tables.ItemVarStore{
+		VariationRegionList: tables.VariationRegionList{VariationRegions: []tables.VariationRegion{
+			{RegionAxes: []tables.RegionAxisCoordinates{{StartCoord: 0, PeakCoord: 1 << 14, EndCoord: 1 << 14}}},
+		}},
+		ItemVariationDatas: []tables.ItemVariationData{
+			{RegionIndexes: []uint16{0}, DeltaSets: [][]int16{{-1 << 13}, {30}, {-1 << 12}}},
+		},
+	}
+	face := &font.Face{Font: &font.Font{COLR: &tables.COLR1{ItemVariationStore: &store}}}
+	face.SetCoords([]tables.Coord{1 << 14})
+	p := colrPainter{face: face}
+
+	solid, ok := p.resolveVariations(tables.PaintVarSolid{Alpha: 1 << 14, VarIndexBase: 0}).(tables.PaintSolid)
+	if !ok || solid.Alpha != 1<<13 {
+		t.Errorf("solid alpha: got %v, want %v", solid.Alpha, 1<<13)
+	}
+	translate, ok := p.resolveVariations(tables.PaintVarTranslate{Dx: 10, Dy: 5, VarIndexBase: 1}).(tables.PaintTranslate)
+	if !ok || translate.Dx != 40 || translate.Dy != 5-1<<12 {
+		t.Errorf("translate: got %d, %d", translate.Dx, translate.Dy)
+	}
+	gradient, ok := p.resolveVariations(tables.PaintVarLinearGradient{
+		ColorLine:    tables.VarColorLine{ColorStops: []tables.VarColorStop{{StopOffset: 1 << 14, Alpha: 1 << 14, VarIndexBase: 0}}},
+		VarIndexBase: noVariation,
+	}).(tables.PaintLinearGradient)
+	if !ok || gradient.ColorLine.ColorStops[0].StopOffset != 1<<13 || gradient.ColorLine.ColorStops[0].Alpha != 1<<14+30 {
+		t.Errorf("color stop: got %+v", gradient.ColorLine.ColorStops)
+	}
+
+	// At the default instance nothing varies.
+	face.SetCoords(nil)
+	if solid := p.resolveVariations(tables.PaintVarSolid{Alpha: 1 << 14}).(tables.PaintSolid); solid.Alpha != 1<<14 {
+		t.Errorf("default instance alpha: got %v", solid.Alpha)
+	}
+}
+
+func TestColrLayer_Composite(t *testing.T) {
+	typeface := goRegularTypeface(t)
+	face := goTextFace(typeface)
+	gid, ok := face.NominalGlyph('H')
+	if !ok {
+		t.Fatal("no glyph for H")
+	}
+	c := NewCanvas(new(op.Ops)).(*canvas)
+	red := color.NRGBA{R: 255, A: 255}
+	p := colrPainter{c: c, typeface: typeface, face: face, palette: []color.NRGBA{red, {B: 255, A: 255}}, alpha: 1}
+	l, ok := p.newLayer(models.Rect{Right: 100, Bottom: 100})
+	if !ok {
+		t.Fatal("no layer")
+	}
+	// Font units to canvas pixels, y up.
+	m := impl.NewMatrixAll(0.05, 0, 10, 0, -0.05, 90, 0, 0, 1)
+
+	// Find a pixel inside the glyph and one outside.
+	cov := l.glyphCoverage(uint16(gid), m, nil)
+	inside, outside := -1, -1
+	for i, a := range cov.Pix {
+		switch {
+		case a == 255 && inside < 0:
+			inside = i
+		case a == 0 && outside < 0:
+			outside = i
+		}
+	}
+	if inside < 0 || outside < 0 {
+		t.Fatal("glyph coverage has no inside or outside")
+	}
+	at := func(img *image.RGBA, i int) color.RGBA {
+		return img.RGBAAt(i%l.w, i/l.w)
+	}
+
+	source := tables.PaintSolid{PaletteIndex: 0, Alpha: 1 << 14}
+	backdrop := tables.PaintGlyph{GlyphID: uint16(gid), Paint: tables.PaintSolid{PaletteIndex: 1, Alpha: 1 << 14}}
+	tests := []struct {
+		mode            tables.CompositeMode
+		inside, outside color.RGBA
+	}{
+		{tables.CompositeSrcIn, premulRGBA(red), color.RGBA{}},
+		{tables.CompositeSrcOut, color.RGBA{}, premulRGBA(red)},
+		{tables.CompositeDestIn, color.RGBA{B: 255, A: 255}, color.RGBA{}},
+		{tables.CompositeDestOut, color.RGBA{}, color.RGBA{}},
+	}
+	for _, tt := range tests {
+		img := l.image()
+		l.composite(tables.PaintComposite{SourcePaint: source, CompositeMode: tt.mode, BackdropPaint: backdrop}, m, img, nil)
+		if got := at(img, inside); got != tt.inside {
+			t.Errorf("mode %d inside: got %v, want %v", tt.mode, got, tt.inside)
+		}
+		if got := at(img, outside); got != tt.outside {
+			t.Errorf("mode %d outside: got %v, want %v", tt.mode, got, tt.outside)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Draws that cannot touch the clip are skipped before any geometry is
+// built. The bounds tested are conservative, so nothing visible is culled.
+
+// CulledDraws returns the number of draws skipped because they were
+// outside the clip.
+func (c *canvas) CulledDraws() int {
+	return c.culled
+}
+
+// cullDraw reports whether a draw covering bounds, in local coordinates,
+// outset by inflate, is outside the clip. Culled draws are counted.
+func (c *canvas) cullDraw(bounds models.Rect, inflate Scalar) bool {
+	if inflate > 0 {
+		bounds = sortRect(bounds).MakeOutset(inflate, inflate)
+	}
+	if !c.QuickReject(bounds) {
+		return false
+	}
+	c.culled++
+	return true
+}
+
+// strokeInflation returns how far the stroke of paint can reach beyond the
+// path, following SkStrokeRec::GetInflationRadius. Fills do not reach
+// beyond the path.
+func strokeInflation(paint Paint) Scalar {
+	if paint.Stroke.Width <= 0 {
+		return 0
+	}
+	radius := Scalar(paint.Stroke.Width) / 2
+	multiplier := Scalar(1)
+	if paint.Stroke.Join == stroke.MiterJoin {
+		multiplier = max(multiplier, Scalar(paint.Stroke.Miter))
+	}
+	if paint.Stroke.Cap == stroke.SquareCap {
+		multiplier = max(multiplier, math.Sqrt2)
+	}
+	return radius * multiplier
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestCanvas_CullsDrawsOutsideClip(t *testing.T) {
+	canvas := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+	paint := NewPaint()
+
+	canvas.DrawRect(models.Rect{Left: 10, Top: 10, Right: 20, Bottom: 20}, paint)
+	if got := canvas.CulledDraws(); got != 0 {
+		t.Fatalf("visible draw culled, count %d", got)
+	}
+
+	canvas.DrawRect(models.Rect{Left: 200, Top: 10, Right: 220, Bottom: 20}, paint)
+	canvas.DrawCircle(models.Point{X: -50, Y: 50}, 10, paint)
+	if got := canvas.CulledDraws(); got != 2 {
+		t.Errorf("expected 2 culled draws, got %d", got)
+	}
+
+	// The stroke reaches into the canvas.
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(20)
+	canvas.DrawLine(models.Point{X: -5, Y: 0}, models.Point{X: -5, Y: 100}, paint)
+	if got := canvas.CulledDraws(); got != 2 {
+		t.Errorf("stroke overlapping the canvas was culled, count %d", got)
+	}
+
+	canvas.Save()
+	canvas.ClipRect(models.Rect{Left: 0, Top: 0, Right: 10, Bottom: 10}, enums.ClipOpIntersect, true)
+	canvas.DrawImageRect(impl.NewRasterImage(models.NewImageInfo(1, 1, enums.ColorTypeRGBA8888, enums.AlphaTypePremul), make([]byte, 4), 4),
+		nil, models.Rect{Left: 50, Top: 50, Right: 60, Bottom: 60}, paint)
+	canvas.Restore()
+	if got := canvas.CulledDraws(); got != 3 {
+		t.Errorf("image outside the clip should be culled, count %d", got)
+	}
+
+	// Inverse fills cover the whole clip.
+	inverse := impl.NewSkPath(enums.PathFillTypeInverseWinding)
+	inverse.AddRect(models.Rect{Left: 200, Top: 200, Right: 210, Bottom: 210}, enums.PathDirectionCW, 0)
+	canvas.DrawPath(inverse, NewPaint())
+	if got := canvas.CulledDraws(); got != 3 {
+		t.Errorf("inverse fill was culled, count %d", got)
+	}
+}
+
+func TestStrokeInflation(t *testing.T) {
+	tests := []struct {
+		name  string
+		paint Paint
+		want  Scalar
+	}{
+		{"fill", Paint{Fill: true}, 0},
+		{"round", Paint{Stroke: stroke.StrokeOpts{Width: 4, Join: stroke.RoundJoin, Cap: stroke.RoundCap}}, 2},
+		{"miter", Paint{Stroke: stroke.StrokeOpts{Width: 4, Join: stroke.MiterJoin, Miter: 4}}, 8},
+		{"square cap", Paint{Stroke: stroke.StrokeOpts{Width: 4, Cap: stroke.SquareCap}}, 2 * math.Sqrt2},
+	}
+	for _, tt := range tests {
+		if got := strokeInflation(tt.paint); !near(got, tt.want, 1e-5) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Registered variation axis tags.
+var (
+	AxisWeight      = FontTag("wght")
+	AxisWidth       = FontTag("wdth")
+	AxisOpticalSize = FontTag("opsz")
+	AxisSlant       = FontTag("slnt")
+	AxisItalic      = FontTag("ital")
+)
+
+// FontTag packs a four character OpenType tag, such as "wght" or "liga".
+// It panics if tag is not four bytes long.
+func FontTag(tag string) uint32 {
+	return uint32(ot.MustNewTag(tag))
+}
+
+// FontVariation sets a single variation axis to a value in design units,
+// for example AxisWeight to 650.
+type FontVariation struct {
+	Axis  uint32
+	Value float32
+}
+
+// FontArguments selects an instance of a variable font and the palette used
+// for its color glyphs. This mirrors SkFontArguments.
+//
+// The variations apply to shaping, metrics and glyph outlines alike, since
+// they all read from the same instanced face.
+type FontArguments struct {
+	Variations []FontVariation
+	// Palette, if set, selects the CPAL palette for color glyphs.
+	Palette *FontPalette
+}
+
+// WithAxis returns a copy of args with axis set to value, replacing an
+// earlier value for the same axis.
+func (args FontArguments) WithAxis(axis uint32, value float32) FontArguments {
+	vars := make([]FontVariation, 0, len(args.Variations)+1)
+	for _, v := range args.Variations {
+		if v.Axis != axis {
+			vars = append(vars, v)
+		}
+	}
+	args.Variations = append(vars, FontVariation{Axis: axis, Value: value})
+	return args
+}
+
+// WithWeight sets the weight (wght) axis, usually in the range 100-900.
+func (args FontArguments) WithWeight(weight float32) FontArguments {
+	return args.WithAxis(AxisWeight, weight)
+}
+
+// WithWidth sets the width (wdth) axis, in percent of the normal width.
+func (args FontArguments) WithWidth(width float32) FontArguments {
+	return args.WithAxis(AxisWidth, width)
+}
+
+// WithOpticalSize sets the optical size (opsz) axis, in points.
+func (args FontArguments) WithOpticalSize(size float32) FontArguments {
+	return args.WithAxis(AxisOpticalSize, size)
+}
+
+// toModel converts args to the support representation used by MakeClone.
+func (args FontArguments) toModel() models.FontArguments {
+	coords := make([]models.VariationCoordinate, len(args.Variations))
+	for i, v := range args.Variations {
+		coords[i] = models.VariationCoordinate{Axis: v.Axis, Value: v.Value}
+	}
+	return models.FontArguments{
+		VariationDesignPosition: models.VariationPosition{Coordinates: coords},
+	}
+}
+
+// MakeTypefaceWithArguments returns a clone of tf instanced at the given
+// variation coordinates. Axes not present in the font are ignored and values
+// are clamped to the axis range. Without variations the typeface itself is
+// reused, so only the palette changes.
+func MakeTypefaceWithArguments(tf interfaces.SkTypeface, args FontArguments) interfaces.SkTypeface {
+	if tf == nil {
+		return nil
+	}
+	if len(args.Variations) > 0 {
+		tf = tf.MakeClone(args.toModel())
+	}
+	if args.Palette != nil {
+		tf = MakeTypefaceWithPalette(tf, *args.Palette)
+	}
+	return tf
+}
+
+// TypefaceVariationCoordinates returns the normalized variation coordinates
+// of tf, in the range [-1, 1], one per axis of the font. It returns nil for
+// static fonts or typefaces at their default instance.
+func TypefaceVariationCoordinates(tf interfaces.SkTypeface) []float32 {
+	face := goTextFace(tf)
+	if face == nil {
+		return nil
+	}
+	coords := face.Coords()
+	if len(coords) == 0 {
+		return nil
+	}
+	out := make([]float32, len(coords))
+	for i, c := range coords {
+		out[i] = float32(c) / (1 << 14)
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFontArguments_WithAxis(t *testing.T) {
+	args := FontArguments{}.WithWeight(400).WithWidth(90).WithWeight(700)
+	if len(args.Variations) != 2 {
+		t.Fatalf("expected 2 variations, got %d", len(args.Variations))
+	}
+	for _, v := range args.Variations {
+		if v.Axis == AxisWeight && v.Value != 700 {
+			t.Errorf("weight should be replaced, got %v", v.Value)
+		}
+	}
+
+	model := args.toModel()
+	if len(model.VariationDesignPosition.Coordinates) != 2 {
+		t.Errorf("expected 2 coordinates, got %d", len(model.VariationDesignPosition.Coordinates))
+	}
+}
+
+func TestMakeTypefaceWithArguments(t *testing.T) {
+	if MakeTypefaceWithArguments(nil, FontArguments{}) != nil {
+		t.Error("expected nil for nil typeface")
+	}
+
+	face, err := font.ParseTTF(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatalf("Failed to parse goregular font: %v", err)
+	}
+	tf := impl.NewTypefaceWithTypefaceFace("Go", models.FontStyle{}, face)
+
+	// Without variations only the palette is applied.
+	palette := FontPalette{Index: 1}
+	got := MakeTypefaceWithArguments(tf, FontArguments{Palette: &palette})
+	if pt, ok := got.(*paletteTypeface); !ok || pt.SkTypeface != tf {
+		t.Errorf("expected palette wrapper around the original typeface, got %T", got)
+	}
+
+	// A static font ignores variations but still yields a usable clone.
+	got = MakeTypefaceWithArguments(tf, FontArguments{}.WithWeight(700))
+	if got == tf {
+		t.Error("expected a clone when variations are set")
+	}
+	if got.UnitsPerEm() != tf.UnitsPerEm() {
+		t.Errorf("clone units per em: got %d, want %d", got.UnitsPerEm(), tf.UnitsPerEm())
+	}
+	if _, err := got.GetGlyphPath(36); err != nil {
+		t.Errorf("clone should expose outlines: %v", err)
+	}
+	if coords := TypefaceVariationCoordinates(got); coords != nil {
+		t.Errorf("static font should have no coordinates, got %v", coords)
+	}
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+	"github.com/zodimo/go-skia-support/skia/shaper"
+)
+
+// PaletteOverride replaces a single CPAL palette entry.
+type PaletteOverride struct {
+	Index int
+	Color color.NRGBA
+}
+
+// FontPalette selects the CPAL palette used to draw color (COLR) glyphs.
+// This mirrors SkFontArguments::Palette.
+type FontPalette struct {
+	// Index is the CPAL palette to use. Out of range indices fall back to
+	// the default palette (0).
+	Index int
+	// Overrides replace individual entries of the selected palette.
+	Overrides []PaletteOverride
+}
+
+// paletteTypeface wraps a typeface with a palette selection.
+// It forwards everything else to the wrapped typeface, including the
+// go-text face used by the shaper.
+type paletteTypeface struct {
+	interfaces.SkTypeface
+	palette FontPalette
+}
+
+var _ shaper.UseGoTextFace = (*paletteTypeface)(nil)
+
+// MakeTypefaceWithPalette returns a typeface that draws color glyphs with the
+// given palette. The glyph outlines, metrics and shaping are unchanged.
+func MakeTypefaceWithPalette(tf interfaces.SkTypeface, palette FontPalette) interfaces.SkTypeface {
+	if tf == nil {
+		return nil
+	}
+	if pt, ok := tf.(*paletteTypeface); ok {
+		tf = pt.SkTypeface
+	}
+	return &paletteTypeface{SkTypeface: tf, palette: palette}
+}
+
+// GoTextFace returns the go-text face of the wrapped typeface.
+func (t *paletteTypeface) GoTextFace() *font.Face {
+	return goTextFace(t.SkTypeface)
+}
+
+// MakeClone clones the wrapped typeface and keeps the palette selection.
+func (t *paletteTypeface) MakeClone(args models.FontArguments) interfaces.SkTypeface {
+	return &paletteTypeface{SkTypeface: t.SkTypeface.MakeClone(args), palette: t.palette}
+}
+
+// TypefacePaletteCount returns the number of CPAL palettes in the typeface,
+// or 0 if it has no color glyphs.
+func TypefacePaletteCount(tf interfaces.SkTypeface) int {
+	face := goTextFace(tf)
+	if face == nil {
+		return 0
+	}
+	return len(face.CPAL)
+}
+
+// TypefacePaletteColors returns the colors of the CPAL palette at index,
+// or nil if the palette does not exist.
+func TypefacePaletteColors(tf interfaces.SkTypeface, index int) []color.NRGBA {
+	face := goTextFace(tf)
+	if face == nil || index < 0 || index >= len(face.CPAL) {
+		return nil
+	}
+	colors := make([]color.NRGBA, len(face.CPAL[index]))
+	for i, rec := range face.CPAL[index] {
+		colors[i] = color.NRGBA{R: rec.Red, G: rec.Green, B: rec.Blue, A: rec.Alpha}
+	}
+	return colors
+}
+
+// resolvePalette returns the palette colors selected for tf, with overrides
+// applied.
+func resolvePalette(tf interfaces.SkTypeface) []color.NRGBA {
+	var palette FontPalette
+	if pt, ok := tf.(*paletteTypeface); ok {
+		palette = pt.palette
+	}
+	colors := TypefacePaletteColors(tf, palette.Index)
+	if colors == nil {
+		colors = TypefacePaletteColors(tf, 0)
+	}
+	for _, o := range palette.Overrides {
+		if o.Index >= 0 && o.Index < len(colors) {
+			colors[o.Index] = o.Color
+		}
+	}
+	return colors
+}
+
+// goTextFace returns the go-text face backing tf, if any.
+func goTextFace(tf interfaces.SkTypeface) *font.Face {
+	if tf == nil {
+		return nil
+	}
+	if u, ok := tf.(shaper.UseGoTextFace); ok {
+		return u.GoTextFace()
+	}
+	return nil
+}
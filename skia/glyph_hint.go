@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"sort"
+
+	"gioui.org/f32"
+	"github.com/go-text/typesetting/font"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+)
+
+// Glyph mask hinting.
+//
+// Masks are grid-fitted by an autohinter in the manner of FreeType's:
+// TrueType instructions are not interpreted. The hinter finds the edges of
+// an outline along an axis, the flat runs and extrema where stems and
+// bowls begin and end, and moves them onto pixel boundaries. Edges near the
+// baseline, x-height and cap height snap with them, suppressing overshoots
+// too small to show. Every other point, control points included, is
+// interpolated between the edges around it, so curves keep their shape.
+
+const (
+	// hintEdgeMerge is the distance, in pixels, within which edges are
+	// treated as one.
+	hintEdgeMerge = 1.0 / 16
+	// hintOvershoot is the distance, in pixels, within which an edge
+	// snaps to a blue zone.
+	hintOvershoot = 0.5
+)
+
+// hintAxis maps device coordinates along one axis to grid-fitted ones by
+// linear interpolation between anchors. Beyond the outer anchors
+// coordinates move with the nearest one.
+type hintAxis struct {
+	// from holds the increasing original coordinates of the anchors and
+	// to their fitted coordinates.
+	from, to []float32
+}
+
+func (a hintAxis) apply(v float32) float32 {
+	n := len(a.from)
+	switch {
+	case n == 0:
+		return v
+	case v <= a.from[0]:
+		return v + a.to[0] - a.from[0]
+	case v >= a.from[n-1]:
+		return v + a.to[n-1] - a.from[n-1]
+	}
+	i := sort.Search(n, func(i int) bool { return a.from[i] >= v })
+	f0, f1 := a.from[i-1], a.from[i]
+	t0, t1 := a.to[i-1], a.to[i]
+	return t0 + (v-f0)*(t1-t0)/(f1-f0)
+}
+
+// fitHintAxis returns the axis that moves edges, sorted device
+// coordinates, onto pixel boundaries. Edges within hintOvershoot of a blue
+// zone snap with the zone. Other edges round to the nearest boundary, but
+// edges at least half a pixel apart stay at least a pixel apart so thin
+// stems do not vanish.
+func fitHintAxis(edges, blues []float32) hintAxis {
+	var a hintAxis
+	for _, e := range edges {
+		if n := len(a.from); n > 0 && e-a.from[n-1] < hintEdgeMerge {
+			continue
+		}
+		fit := float32(math.Round(float64(e)))
+		snapped := false
+		for _, b := range blues {
+			if abs32(e-b) < hintOvershoot {
+				fit = float32(math.Round(float64(b)))
+				snapped = true
+				break
+			}
+		}
+		if n := len(a.to); n > 0 {
+			prev, prevFit := a.from[n-1], a.to[n-1]
+			if !snapped && e-prev >= 0.5 && fit <= prevFit {
+				fit = prevFit + 1
+			}
+			// Fitting never reorders edges.
+			fit = max(fit, prevFit)
+		}
+		a.from = append(a.from, e)
+		a.to = append(a.to, fit)
+	}
+	return a
+}
+
+// outlineEdges returns the sorted coordinates along one axis of the edges
+// of outline, a device space outline. coord selects the axis; the edges of
+// an axis are the on-curve points whose neighbours do not cross it: flat
+// runs and extrema.
+func outlineEdges(outline recordedPath, coord func(f32.Point) float32) []float32 {
+	type point struct {
+		v  float32
+		on bool
+	}
+	var edges []float32
+	var contour []point
+	flush := func() {
+		n := len(contour)
+		// A closing point repeating the first is dropped.
+		if n > 1 && contour[n-1].v == contour[0].v && contour[n-1].on {
+			n--
+		}
+		for i := 0; i < n; i++ {
+			p := contour[i]
+			if !p.on {
+				continue
+			}
+			prev, next := contour[(i+n-1)%n].v, contour[(i+1)%n].v
+			if prev == p.v || next == p.v || (prev-p.v)*(next-p.v) > 0 {
+				edges = append(edges, p.v)
+			}
+		}
+		contour = contour[:0]
+	}
+	for _, s := range outline {
+		switch s.verb {
+		case enums.PathVerbMove:
+			flush()
+			contour = append(contour, point{coord(s.pts[0]), true})
+		case enums.PathVerbLine:
+			contour = append(contour, point{coord(s.pts[0]), true})
+		case enums.PathVerbQuad:
+			contour = append(contour, point{coord(s.pts[0]), false}, point{coord(s.pts[1]), true})
+		case enums.PathVerbCubic:
+			contour = append(contour, point{coord(s.pts[0]), false}, point{coord(s.pts[1]), false}, point{coord(s.pts[2]), true})
+		case enums.PathVerbClose:
+			flush()
+		}
+	}
+	flush()
+	sort.Slice(edges, func(i, j int) bool { return edges[i] < edges[j] })
+	return edges
+}
+
+// hintOutline grid-fits outline, an axis-aligned glyph outline in device
+// space. Slight hinting fits the vertical axis only, as FreeType's light
+// autohinter does; normal and full hinting fit both. origin is the device
+// position of the glyph origin and scaleY maps font units to device pixels
+// vertically. Horizontal edges are fitted relative to the origin, so stems
+// get whole pixel widths while subpixel positioning is kept.
+func hintOutline(outline recordedPath, typeface interfaces.SkTypeface, hinting enums.FontHinting, origin f32.Point, scaleY float32) recordedPath {
+	if hinting == enums.FontHintingNone || len(outline) == 0 {
+		return outline
+	}
+	blues := []float32{origin.Y}
+	if face := goTextFace(typeface); face != nil {
+		for _, metric := range []font.LineMetric{font.XHeight, font.CapHeight} {
+			if v := face.LineMetric(metric); v != 0 {
+				blues = append(blues, origin.Y+v*scaleY)
+			}
+		}
+	}
+	fy := fitHintAxis(outlineEdges(outline, func(p f32.Point) float32 { return p.Y }), blues)
+	var fx hintAxis
+	if hinting > enums.FontHintingSlight {
+		phase := origin.X - float32(math.Floor(float64(origin.X)))
+		fx = fitHintAxis(outlineEdges(outline, func(p f32.Point) float32 { return p.X - phase }), nil)
+		for i := range fx.from {
+			fx.from[i] += phase
+			fx.to[i] += phase
+		}
+	}
+	hinted := make(recordedPath, len(outline))
+	for i, s := range outline {
+		for j := range s.pts {
+			s.pts[j] = f32.Pt(fx.apply(s.pts[j].X), fy.apply(s.pts[j].Y))
+		}
+		hinted[i] = s
+	}
+	return hinted
+}
+
+func abs32(v float32) float32 {
+	return float32(math.Abs(float64(v)))
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+	"github.com/zodimo/go-skia-support/skia/enums"
+)
+
+func TestFitHintAxis(t *testing.T) {
+	// A thin stem, two edges closer than the merge distance and an edge
+	// near a blue zone.
+	a := fitHintAxis([]float32{2.3, 2.9, 2.92, 9.8}, []float32{10.2})
+	wantFrom := []float32{2.3, 2.9, 9.8}
+	wantTo := []float32{2, 3, 10}
+	if len(a.from) != len(wantFrom) {
+		t.Fatalf("anchors = %v, want %v", a.from, wantFrom)
+	}
+	for i := range wantFrom {
+		if a.from[i] != wantFrom[i] || a.to[i] != wantTo[i] {
+			t.Errorf("anchor %d = %v -> %v, want %v -> %v", i, a.from[i], a.to[i], wantFrom[i], wantTo[i])
+		}
+	}
+
+	// A stem narrower than a pixel that rounds to nothing keeps a pixel.
+	a = fitHintAxis([]float32{4.1, 4.7}, nil)
+	if a.to[0] != 4 || a.to[1] != 5 {
+		t.Errorf("thin stem fitted to %v, want [4 5]", a.to)
+	}
+}
+
+func TestHintAxis_Apply(t *testing.T) {
+	a := hintAxis{from: []float32{1.5, 3.5}, to: []float32{2, 3}}
+	tests := []struct{ v, want float32 }{
+		{0.5, 1},
+		{1.5, 2},
+		{2.5, 2.5},
+		{3.5, 3},
+		{5, 4.5},
+	}
+	for _, tt := range tests {
+		if got := a.apply(tt.v); got != tt.want {
+			t.Errorf("apply(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHintOutline_StemsOnPixelGrid(t *testing.T) {
+	typeface := goRegularTypeface(t)
+	glyphPath, err := typeface.GetGlyphPath(typeface.UnicharToGlyph('H'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := float32(13) / float32(typeface.UnitsPerEm())
+	var outline recordedPath
+	convertPath(glyphPath, conicTolerance/Scalar(scale), &outline)
+	origin := f32.Pt(0.25, 0)
+	for i := range outline {
+		for j, p := range outline[i].pts {
+			outline[i].pts[j] = f32.Pt(p.X*scale+origin.X, -p.Y*scale)
+		}
+	}
+
+	onGrid := func(v, phase float32) bool {
+		f := v - phase
+		return math.Abs(float64(f-float32(math.Round(float64(f))))) < 1e-3
+	}
+	// H is made of straight edges only, so every on-curve point is an edge
+	// of both axes.
+	check := func(hinting enums.FontHinting, fitX bool) {
+		hinted := hintOutline(outline, typeface, hinting, origin, -scale)
+		for _, s := range hinted {
+			if s.verb == enums.PathVerbClose {
+				continue
+			}
+			p := s.pts[0]
+			if !onGrid(p.Y, 0) {
+				t.Errorf("hinting %v: y = %v, not on the pixel grid", hinting, p.Y)
+			}
+			if fitX && !onGrid(p.X, origin.X) {
+				t.Errorf("hinting %v: x = %v, not on the subpixel origin's grid", hinting, p.X)
+			}
+		}
+	}
+	check(enums.FontHintingSlight, false)
+	check(enums.FontHintingNormal, true)
+
+	if got := hintOutline(outline, typeface, enums.FontHintingNone, origin, -scale); &got[0] != &outline[0] {
+		t.Error("hinting none changed the outline")
+	}
+}
@@ -0,0 +1,449 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"golang.org/x/image/vector"
+)
+
+// TextRenderMode selects how outline glyphs are drawn.
+type TextRenderMode uint8
+
+const (
+	// TextRenderModePath fills each glyph outline as a path. Glyphs scale
+	// and transform exactly, which suits large and animated text.
+	TextRenderModePath TextRenderMode = iota
+	// TextRenderModeMask rasterizes glyphs into a shared atlas in device
+	// space and draws them as images. It honors the font's subpixel and
+	// edging settings and is sharper for small UI text. Hinted fonts are
+	// grid-fitted by an autohinter; TrueType instructions are not run.
+	TextRenderModeMask
+)
+
+// GlyphMaskOptions tunes the coverage of rasterized glyph masks.
+type GlyphMaskOptions struct {
+	// Gamma is applied to the coverage; values above 1 darken the
+	// antialiased edges. 0 means 1.
+	Gamma float32
+	// Contrast boosts partial coverage towards opaque, in the range [0, 1].
+	Contrast float32
+}
+
+// DefaultGlyphMaskOptions are the mask options of a new canvas. They
+// slightly darken thin stems, similar to Skia's default text contrast.
+var DefaultGlyphMaskOptions = GlyphMaskOptions{Gamma: 1.2, Contrast: 0.2}
+
+const (
+	// glyphSubpixelSteps is the number of quantized subpixel offsets per
+	// pixel, matching Skia's glyph cache.
+	glyphSubpixelSteps = 4
+	// maxGlyphMaskSize is the largest glyph, in device pixels, drawn as a
+	// mask. Larger glyphs are filled as paths.
+	maxGlyphMaskSize = 256
+	// glyphAtlasPageSize is the width and height of an atlas page.
+	glyphAtlasPageSize = 512
+	// maxGlyphAtlasPages bounds the atlas; it is flushed when full.
+	maxGlyphAtlasPages = 4
+	// glyphAtlasUploadPixels is the area of new glyphs a page accumulates
+	// before it is uploaded again. Until then new glyphs are drawn from
+	// their own small images.
+	glyphAtlasUploadPixels = glyphAtlasPageSize * glyphAtlasPageSize / 16
+	// glyphMatrixPrecision quantizes the glyph matrix in cache keys.
+	glyphMatrixPrecision = 1 << 12
+)
+
+// SetTextRenderMode selects between path and mask rendering of glyphs.
+func (c *canvas) SetTextRenderMode(mode TextRenderMode) {
+	c.textRenderMode = mode
+}
+
+// SetGlyphMaskOptions sets the gamma and contrast used for glyph masks.
+func (c *canvas) SetGlyphMaskOptions(opts GlyphMaskOptions) {
+	c.glyphMaskOptions = opts
+}
+
+// glyphMaskKey identifies a rasterized glyph. The color is part of the key
+// since Gio cannot tint images, so masks are stored pre-colored.
+type glyphMaskKey struct {
+	typeface   uint32
+	glyph      uint16
+	matrix     [4]int32
+	subX, subY uint8
+	hinting    enums.FontHinting
+	edging     enums.FontEdging
+	color      color.NRGBA
+	options    GlyphMaskOptions
+}
+
+// glyphMask is the location of a glyph in the atlas.
+type glyphMask struct {
+	page int
+	// rect is the glyph's area in the page.
+	rect image.Rectangle
+	// origin is the offset of rect's top left corner from the glyph origin,
+	// in device pixels.
+	origin image.Point
+	// gen is the page generation the glyph was added in. The page's image
+	// op contains the glyph once the page has moved past it.
+	gen int
+	// own holds the glyph alone while it is missing from the page's image
+	// op.
+	own *gpaint.ImageOp
+}
+
+// glyphAtlasPage is an atlas page. Glyphs are rasterized into img, which
+// no image op ever references; imageOp is a snapshot of it, so ops already
+// issued never see later writes.
+type glyphAtlasPage struct {
+	img     *image.RGBA
+	imageOp gpaint.ImageOp
+	// gen counts the snapshots taken of img.
+	gen int
+	// pending is the area of glyphs added since the last snapshot.
+	pending int
+	shelves []glyphAtlasShelf
+}
+
+// glyphAtlasShelf is a row of glyphs of similar height.
+type glyphAtlasShelf struct {
+	y, height, x int
+}
+
+var glyphAtlas = struct {
+	sync.Mutex
+	pages []*glyphAtlasPage
+	masks map[glyphMaskKey]glyphMask
+}{masks: make(map[glyphMaskKey]glyphMask)}
+
+// drawGlyphMask draws an outline glyph from the glyph atlas. It reports
+// false if the glyph should be drawn as a path instead.
+func (c *canvas) drawGlyphMask(skFont interfaces.SkFont, glyphID uint16, matrix SkMatrix, paint SkPaint) bool {
+	internalPaint := skPaintToPaint(paint)
+	if !internalPaint.Fill || internalPaint.Stroke.Width > 0 || skFont.IsEmbolden() {
+		return false
+	}
+	typeface := skFont.Typeface()
+	ctx := &c.stack[len(c.stack)-1]
+	dev := impl.NewMatrixIdentity()
+	dev.SetConcat(ctx.matrix(), matrix)
+	if dev.HasPerspective() {
+		return false
+	}
+
+	a, b := float32(dev.GetScaleX()), float32(dev.GetSkewY())
+	cc, d := float32(dev.GetSkewX()), float32(dev.GetScaleY())
+	tx, ty := float32(dev.GetTranslateX()), float32(dev.GetTranslateY())
+	upem := float32(typeface.UnitsPerEm())
+	if upem <= 0 {
+		return false
+	}
+	extent := upem * float32(math.Sqrt(math.Abs(float64(a*d-b*cc))))
+	if extent <= 0 || extent > maxGlyphMaskSize {
+		return false
+	}
+
+	// Hinting grid-fits the outline with the autohinter of glyph_hint.go
+	// and only applies to axis-aligned text. Full hinting also snaps the
+	// horizontal origin to whole pixels.
+	axisAligned := b == 0 && cc == 0
+	hinting := skFont.Hinting()
+	if skFont.IsForceAutoHinting() && hinting == enums.FontHintingNone {
+		hinting = enums.FontHintingSlight
+	}
+	if !axisAligned {
+		hinting = enums.FontHintingNone
+	}
+
+	// Quantize the origin to subpixel steps; the integer part is applied at
+	// draw time so the mask is shared between positions.
+	subpixelX := skFont.IsSubpixel() && hinting < enums.FontHintingFull
+	subpixelY := skFont.IsSubpixel() && !axisAligned
+	ox, subX := quantizeGlyphOrigin(tx, subpixelX)
+	oy, subY := quantizeGlyphOrigin(ty, subpixelY)
+
+	key := glyphMaskKey{
+		typeface: typeface.UniqueID(),
+		glyph:    glyphID,
+		matrix:   [4]int32{quantizeGlyphScale(a), quantizeGlyphScale(b), quantizeGlyphScale(cc), quantizeGlyphScale(d)},
+		subX:     subX,
+		subY:     subY,
+		hinting:  hinting,
+		edging:   skFont.Edging(),
+		color:    internalPaint.Color,
+		options:  c.glyphMaskOptions,
+	}
+
+	glyphAtlas.Lock()
+	mask, ok := glyphAtlas.masks[key]
+	if !ok {
+		mask, ok = rasterizeGlyphMask(key, typeface, [4]float32{a, b, cc, d})
+		if !ok {
+			glyphAtlas.Unlock()
+			return false
+		}
+	}
+	if mask.rect.Empty() {
+		// Blank glyphs such as spaces.
+		glyphAtlas.Unlock()
+		return true
+	}
+	page := glyphAtlas.pages[mask.page]
+	if mask.gen >= page.gen && page.pending >= glyphAtlasUploadPixels {
+		page.snapshot()
+	}
+	var imageOp gpaint.ImageOp
+	src := mask.rect
+	if mask.gen < page.gen {
+		imageOp = page.imageOp
+		if mask.own != nil {
+			mask.own = nil
+			glyphAtlas.masks[key] = mask
+		}
+	} else {
+		imageOp = *mask.own
+		src = src.Sub(src.Min)
+	}
+	glyphAtlas.Unlock()
+
+	// Masks are in device space, so only the clips are applied.
+	for _, cl := range ctx.clips {
+		stack := cl.op.Push(c.ops)
+		defer stack.Pop()
+	}
+	pos := image.Pt(ox, oy).Add(mask.origin)
+	defer op.Offset(pos).Push(c.ops).Pop()
+	defer clip.Rect{Max: src.Size()}.Push(c.ops).Pop()
+	defer op.Offset(src.Min.Mul(-1)).Push(c.ops).Pop()
+	imageOp.Add(c.ops)
+	gpaint.PaintOp{}.Add(c.ops)
+	return true
+}
+
+// quantizeGlyphOrigin splits a device coordinate into its integer pixel and
+// subpixel step. Without subpixel positioning it rounds to whole pixels.
+func quantizeGlyphOrigin(v float32, subpixel bool) (int, uint8) {
+	if !subpixel {
+		return int(math.Round(float64(v))), 0
+	}
+	// Round to the nearest step rather than truncating.
+	steps := math.Round(float64(v) * glyphSubpixelSteps)
+	whole := math.Floor(steps / glyphSubpixelSteps)
+	return int(whole), uint8(steps - whole*glyphSubpixelSteps)
+}
+
+func quantizeGlyphScale(v float32) int32 {
+	return int32(math.Round(float64(v) * glyphMatrixPrecision))
+}
+
+// rasterizeGlyphMask renders the glyph for key and stores it in the atlas.
+// m is the linear part of the device matrix. The atlas must be locked.
+func rasterizeGlyphMask(key glyphMaskKey, typeface interfaces.SkTypeface, m [4]float32) (glyphMask, bool) {
+	glyphPath, err := typeface.GetGlyphPath(key.glyph)
+	if err != nil || glyphPath == nil {
+		// Glyphs without outlines, such as spaces, draw nothing.
+		glyphAtlas.masks[key] = glyphMask{}
+		return glyphMask{}, true
+	}
+
+	// The outline is flattened in font units, with the tolerance scaled to
+	// pixels, and mapped to device space for hinting.
+	scale := max(float32(math.Hypot(float64(m[0]), float64(m[1]))), float32(math.Hypot(float64(m[2]), float64(m[3]))))
+	var outline recordedPath
+	convertPath(glyphPath, conicTolerance/Scalar(max(scale, 1e-6)), &outline)
+	dx := float32(key.subX) / glyphSubpixelSteps
+	dy := float32(key.subY) / glyphSubpixelSteps
+	for i := range outline {
+		for j, p := range outline[i].pts {
+			outline[i].pts[j] = f32.Pt(m[0]*p.X+m[2]*p.Y+dx, m[1]*p.X+m[3]*p.Y+dy)
+		}
+	}
+	outline = hintOutline(outline, typeface, key.hinting, f32.Pt(dx, dy), m[3])
+
+	// The control point hull bounds the glyph.
+	var minX, minY, maxX, maxY float32 = math.MaxFloat32, math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32
+	for _, s := range outline {
+		n := 0
+		switch s.verb {
+		case enums.PathVerbMove, enums.PathVerbLine:
+			n = 1
+		case enums.PathVerbQuad:
+			n = 2
+		case enums.PathVerbCubic:
+			n = 3
+		}
+		for _, p := range s.pts[:n] {
+			minX, minY = min(minX, p.X), min(minY, p.Y)
+			maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+		}
+	}
+	if minX > maxX {
+		glyphAtlas.masks[key] = glyphMask{}
+		return glyphMask{}, true
+	}
+	origin := image.Pt(int(math.Floor(float64(minX)))-1, int(math.Floor(float64(minY)))-1)
+	size := image.Pt(int(math.Ceil(float64(maxX)))+1-origin.X, int(math.Ceil(float64(maxY)))+1-origin.Y)
+	if size.X <= 0 || size.Y <= 0 || size.X > glyphAtlasPageSize || size.Y > glyphAtlasPageSize {
+		return glyphMask{}, false
+	}
+
+	r := vector.NewRasterizer(size.X, size.Y)
+	ox, oy := float32(origin.X), float32(origin.Y)
+	outline.replay(rasterSink{r: r, xform: func(p f32.Point) (float32, float32) {
+		return p.X - ox, p.Y - oy
+	}})
+	coverage := image.NewAlpha(image.Rect(0, 0, size.X, size.Y))
+	r.Draw(coverage, coverage.Bounds(), image.Opaque, image.Point{})
+
+	pageIdx, rect, ok := allocateGlyphRect(size)
+	if !ok {
+		return glyphMask{}, false
+	}
+	page := glyphAtlas.pages[pageIdx]
+	lut := glyphCoverageLUT(key.options, key.edging)
+	glyph := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			cov := lut[coverage.Pix[y*coverage.Stride+x]]
+			if cov == 0 {
+				continue
+			}
+			col := key.color
+			col.A = uint8((uint32(col.A)*uint32(cov) + 127) / 255)
+			glyph.SetRGBA(x, y, premulRGBA(col))
+		}
+	}
+	draw.Draw(page.img, rect, glyph, image.Point{}, draw.Src)
+	page.pending += size.X * size.Y
+
+	// The glyph is drawn from its own image, uploading only its pixels,
+	// until the page is snapshotted.
+	own := gpaint.NewImageOp(glyph)
+	own.Filter = gpaint.FilterNearest
+	mask := glyphMask{page: pageIdx, rect: rect, origin: origin, gen: page.gen, own: &own}
+	glyphAtlas.masks[key] = mask
+	return mask, true
+}
+
+// rasterSink feeds path segments to a rasterizer, mapping them to its
+// pixel space.
+type rasterSink struct {
+	r     *vector.Rasterizer
+	xform func(f32.Point) (float32, float32)
+}
+
+func (s rasterSink) MoveTo(to f32.Point) {
+	s.r.MoveTo(s.xform(to))
+}
+
+func (s rasterSink) LineTo(to f32.Point) {
+	s.r.LineTo(s.xform(to))
+}
+
+func (s rasterSink) QuadTo(ctrl, to f32.Point) {
+	cx, cy := s.xform(ctrl)
+	x, y := s.xform(to)
+	s.r.QuadTo(cx, cy, x, y)
+}
+
+func (s rasterSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	c0x, c0y := s.xform(ctrl0)
+	c1x, c1y := s.xform(ctrl1)
+	x, y := s.xform(to)
+	s.r.CubeTo(c0x, c0y, c1x, c1y, x, y)
+}
+
+func (s rasterSink) Close() {
+	s.r.ClosePath()
+}
+
+// allocateGlyphRect reserves a size area in the atlas, flushing it when
+// all pages are full. The atlas must be locked.
+func allocateGlyphRect(size image.Point) (int, image.Rectangle, bool) {
+	for i, page := range glyphAtlas.pages {
+		if r, ok := page.allocate(size); ok {
+			return i, r, true
+		}
+	}
+	if n := len(glyphAtlas.pages); n > 0 && glyphAtlas.pages[n-1].pending > 0 {
+		// The last page is full; upload its remaining glyphs at once.
+		glyphAtlas.pages[n-1].snapshot()
+	}
+	if len(glyphAtlas.pages) >= maxGlyphAtlasPages {
+		// Start over with fresh pages; earlier frames may still reference
+		// the old images, so they are not reused.
+		glyphAtlas.pages = nil
+		glyphAtlas.masks = make(map[glyphMaskKey]glyphMask)
+	}
+	page := &glyphAtlasPage{img: image.NewRGBA(image.Rect(0, 0, glyphAtlasPageSize, glyphAtlasPageSize))}
+	glyphAtlas.pages = append(glyphAtlas.pages, page)
+	r, ok := page.allocate(size)
+	return len(glyphAtlas.pages) - 1, r, ok
+}
+
+// snapshot uploads a copy of the page, so later glyphs never write into an
+// image referenced by an issued op.
+func (p *glyphAtlasPage) snapshot() {
+	img := image.NewRGBA(p.img.Rect)
+	copy(img.Pix, p.img.Pix)
+	p.imageOp = gpaint.NewImageOp(img)
+	p.imageOp.Filter = gpaint.FilterNearest
+	p.gen++
+	p.pending = 0
+}
+
+// allocate places size on the first shelf that fits, opening a new shelf
+// if needed. A one pixel gutter keeps neighbouring glyphs apart.
+func (p *glyphAtlasPage) allocate(size image.Point) (image.Rectangle, bool) {
+	w, h := size.X+1, size.Y+1
+	for i := range p.shelves {
+		s := &p.shelves[i]
+		if h <= s.height && s.x+w <= glyphAtlasPageSize {
+			r := image.Rectangle{Min: image.Pt(s.x, s.y), Max: image.Pt(s.x+size.X, s.y+size.Y)}
+			s.x += w
+			return r, true
+		}
+	}
+	y := 0
+	if n := len(p.shelves); n > 0 {
+		y = p.shelves[n-1].y + p.shelves[n-1].height
+	}
+	if y+h > glyphAtlasPageSize || w > glyphAtlasPageSize {
+		return image.Rectangle{}, false
+	}
+	p.shelves = append(p.shelves, glyphAtlasShelf{y: y, height: h, x: w})
+	return image.Rectangle{Min: image.Pt(0, y), Max: image.Pt(size.X, y+size.Y)}, true
+}
+
+// glyphCoverageLUT maps raw coverage to adjusted coverage.
+func glyphCoverageLUT(opts GlyphMaskOptions, edging enums.FontEdging) *[256]uint8 {
+	var lut [256]uint8
+	gamma := float64(opts.Gamma)
+	if gamma <= 0 {
+		gamma = 1
+	}
+	contrast := float64(clampUnit(opts.Contrast))
+	for i := range lut {
+		if edging == enums.FontEdgingAlias {
+			if i >= 128 {
+				lut[i] = 255
+			}
+			continue
+		}
+		v := math.Pow(float64(i)/255, 1/gamma)
+		v += contrast * v * (1 - v)
+		lut[i] = uint8(math.Min(v, 1)*255 + 0.5)
+	}
+	return &lut
+}
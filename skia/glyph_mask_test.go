@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/go-text/typesetting/font"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func goRegularTypeface(t *testing.T) *impl.Typeface {
+	t.Helper()
+	face, err := font.ParseTTF(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatalf("Failed to parse goregular font: %v", err)
+	}
+	return impl.NewTypefaceWithTypefaceFace("Go", models.FontStyle{}, face)
+}
+
+func TestQuantizeGlyphOrigin(t *testing.T) {
+	tests := []struct {
+		v        float32
+		subpixel bool
+		whole    int
+		step     uint8
+	}{
+		{10.4, false, 10, 0},
+		{10.6, false, 11, 0},
+		{10.3, true, 10, 1},
+		{10.9, true, 11, 0},
+		{-0.3, true, -1, 3},
+	}
+	for _, tt := range tests {
+		whole, step := quantizeGlyphOrigin(tt.v, tt.subpixel)
+		if whole != tt.whole || step != tt.step {
+			t.Errorf("quantizeGlyphOrigin(%v, %v) = %d, %d; want %d, %d", tt.v, tt.subpixel, whole, step, tt.whole, tt.step)
+		}
+	}
+}
+
+func TestGlyphAtlasPage_Allocate(t *testing.T) {
+	p := &glyphAtlasPage{}
+	a, ok := p.allocate(image.Pt(10, 12))
+	if !ok || a.Min != (image.Point{}) {
+		t.Fatalf("first allocation: got %v, %v", a, ok)
+	}
+	b, ok := p.allocate(image.Pt(8, 8))
+	if !ok || b.Min.Y != 0 || b.Min.X != 11 {
+		t.Errorf("smaller glyph should share the shelf: got %v", b)
+	}
+	c, ok := p.allocate(image.Pt(8, 20))
+	if !ok || c.Min.Y != 13 {
+		t.Errorf("taller glyph should open a new shelf: got %v", c)
+	}
+	if _, ok := p.allocate(image.Pt(glyphAtlasPageSize, 1)); ok {
+		t.Error("allocation wider than the page should fail")
+	}
+}
+
+func TestGlyphCoverageLUT(t *testing.T) {
+	alias := glyphCoverageLUT(GlyphMaskOptions{}, enums.FontEdgingAlias)
+	if alias[127] != 0 || alias[128] != 255 {
+		t.Errorf("aliased coverage should be thresholded: %d, %d", alias[127], alias[128])
+	}
+
+	linear := glyphCoverageLUT(GlyphMaskOptions{Gamma: 1}, enums.FontEdgingAntiAlias)
+	if linear[0] != 0 || linear[128] != 128 || linear[255] != 255 {
+		t.Errorf("identity options should keep coverage: %d, %d, %d", linear[0], linear[128], linear[255])
+	}
+
+	boosted := glyphCoverageLUT(DefaultGlyphMaskOptions, enums.FontEdgingAntiAlias)
+	if boosted[128] <= 128 || boosted[255] != 255 {
+		t.Errorf("default options should boost partial coverage: %d", boosted[128])
+	}
+}
+
+func TestCanvas_TextRenderModeMask(t *testing.T) {
+	ops := new(op.Ops)
+	canvas := NewCanvas(ops).(Extended)
+	canvas.SetTextRenderMode(TextRenderModeMask)
+
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 12)
+	font.SetSubpixel(true)
+	col := color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+	canvas.DrawString("H", 10.3, 20, font, NewPaintFill(col))
+
+	glyphAtlas.Lock()
+	defer glyphAtlas.Unlock()
+	found := 0
+	for key, mask := range glyphAtlas.masks {
+		if key.typeface != font.Typeface().UniqueID() {
+			continue
+		}
+		found++
+		if key.color != col {
+			t.Errorf("mask color: got %v, want %v", key.color, col)
+		}
+		if key.subX != 1 {
+			t.Errorf("mask subpixel step: got %d, want 1", key.subX)
+		}
+		if !mask.rect.Empty() && mask.page >= len(glyphAtlas.pages) {
+			t.Errorf("mask refers to missing page %d", mask.page)
+		}
+	}
+	if found == 0 {
+		t.Fatal("expected glyph masks in the atlas")
+	}
+}
+
+func TestCanvas_TextRenderModeMask_LargeTextUsesPaths(t *testing.T) {
+	ops := new(op.Ops)
+	canvas := NewCanvas(ops).(Extended)
+	canvas.SetTextRenderMode(TextRenderModeMask)
+
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 400)
+	canvas.DrawString("W", 0, 400, font, NewPaintFill(color.NRGBA{A: 255}))
+
+	glyphAtlas.Lock()
+	defer glyphAtlas.Unlock()
+	for key := range glyphAtlas.masks {
+		if key.typeface == font.Typeface().UniqueID() {
+			t.Fatal("glyphs above the mask size limit should be filled as paths")
+		}
+	}
+}
+
+func TestCanvas_TextRenderModeMask_UploadsNewGlyphsAlone(t *testing.T) {
+	ops := new(op.Ops)
+	canvas := NewCanvas(ops).(Extended)
+	canvas.SetTextRenderMode(TextRenderModeMask)
+
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 13)
+	col := color.NRGBA{R: 90, A: 255}
+	canvas.DrawString("A", 0, 20, font, NewPaintFill(col))
+
+	glyphAtlas.Lock()
+	var key glyphMaskKey
+	var mask glyphMask
+	for k, m := range glyphAtlas.masks {
+		if k.typeface == font.Typeface().UniqueID() && k.color == col && !m.rect.Empty() {
+			key, mask = k, m
+		}
+	}
+	if mask.own == nil {
+		glyphAtlas.Unlock()
+		t.Fatal("a new glyph should be drawn from its own image")
+	}
+	page := glyphAtlas.pages[mask.page]
+	if mask.gen < page.gen {
+		t.Error("a new glyph should not be part of the page snapshot yet")
+	}
+	page.pending = glyphAtlasUploadPixels
+	glyphAtlas.Unlock()
+
+	// Once enough glyphs are pending, the page is uploaded and the glyph
+	// drops its own image.
+	canvas.DrawString("A", 0, 20, font, NewPaintFill(col))
+	glyphAtlas.Lock()
+	defer glyphAtlas.Unlock()
+	mask = glyphAtlas.masks[key]
+	if mask.gen >= page.gen || mask.own != nil || page.pending != 0 {
+		t.Errorf("page should have been snapshotted: gen %d, page gen %d, pending %d", mask.gen, page.gen, page.pending)
+	}
+}
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import "github.com/zodimo/gio-skia/pkg/stroke"
+
+// Hairlines. A stroke of width 0 is one device pixel wide whatever the
+// transform, so hairlines are always stroked in device space.
+
+// hairlineWidth is the width of a hairline in device pixels.
+const hairlineWidth = 1
+
+// isHairline reports whether paint strokes hairlines.
+func isHairline(paint Paint) bool {
+	return !paint.Fill && paint.Stroke.Width == 0
+}
+
+// hairlineOpts returns the device space stroke options for a hairline
+// drawn with opts. Joins are round, as if drawn with a one pixel pen.
+func hairlineOpts(opts stroke.StrokeOpts) stroke.StrokeOpts {
+	return stroke.StrokeOpts{
+		Width: hairlineWidth,
+		Miter: 4,
+		Cap:   opts.Cap,
+		Join:  stroke.RoundJoin,
+	}
+}
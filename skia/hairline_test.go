@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/op"
+	andyStroke "github.com/andybalholm/stroke"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// contourBounds returns the bounds of the control points of contours.
+func contourBounds(contours [][]andyStroke.Segment) models.Rect {
+	b := models.Rect{Left: math.MaxFloat32, Top: math.MaxFloat32, Right: -math.MaxFloat32, Bottom: -math.MaxFloat32}
+	for _, contour := range contours {
+		for _, seg := range contour {
+			for _, p := range []andyStroke.Point{seg.Start, seg.CP1, seg.CP2, seg.End} {
+				b.Left, b.Right = min(b.Left, Scalar(p.X)), max(b.Right, Scalar(p.X))
+				b.Top, b.Bottom = min(b.Top, Scalar(p.Y)), max(b.Bottom, Scalar(p.Y))
+			}
+		}
+	}
+	return b
+}
+
+func TestHairline_OneDevicePixel(t *testing.T) {
+	line := impl.NewSkPath(enums.PathFillTypeWinding)
+	line.MoveTo(0, 0)
+	line.LineTo(10, 0)
+
+	tests := []struct {
+		name  string
+		setup func(c Canvas)
+		want  models.Rect
+	}{
+		{"identity", func(c Canvas) {}, models.Rect{Left: 0, Top: -0.5, Right: 10, Bottom: 0.5}},
+		{"scaled", func(c Canvas) { c.Scale(10, 10) }, models.Rect{Left: 0, Top: -0.5, Right: 100, Bottom: 0.5}},
+		{"shrunk", func(c Canvas) { c.Scale(0.1, 0.1) }, models.Rect{Left: 0, Top: -0.5, Right: 1, Bottom: 0.5}},
+		{"rotated", func(c Canvas) {
+			c.Scale(4, 4)
+			c.Rotate(90)
+		}, models.Rect{Left: -0.5, Top: 0, Right: 0.5, Bottom: 40}},
+	}
+	for _, tt := range tests {
+		c := NewCanvas(new(op.Ops)).(*canvas)
+		tt.setup(c)
+		got := contourBounds(c.deviceStrokeContours(line, hairlineOpts(stroke.StrokeOpts{})))
+		for i, v := range []Scalar{got.Left - tt.want.Left, got.Top - tt.want.Top, got.Right - tt.want.Right, got.Bottom - tt.want.Bottom} {
+			if !near(v, 0, 1e-3) {
+				t.Errorf("%s: bounds %+v, want %+v (edge %d)", tt.name, got, tt.want, i)
+				break
+			}
+		}
+	}
+}
+
+func TestHairline_Perspective(t *testing.T) {
+	line := impl.NewSkPath(enums.PathFillTypeWinding)
+	line.MoveTo(0, 0)
+	line.LineTo(10, 0)
+
+	c := NewCanvas(new(op.Ops)).(*canvas)
+	c.Concat(impl.NewMatrixAll(4, 0, 0, 0, 4, 0, 0.01, 0, 1))
+	got := contourBounds(c.deviceStrokeContours(line, hairlineOpts(stroke.StrokeOpts{})))
+	if h := got.Bottom - got.Top; !near(h, 1, 1e-3) {
+		t.Errorf("hairline under perspective is %v pixels thick", h)
+	}
+}
+
+func TestHairline_ZeroWidthStrokeIsDrawn(t *testing.T) {
+	paint := NewPaint()
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(0)
+	if !isHairline(skPaintToPaint(paint)) {
+		t.Fatal("zero width stroke should be a hairline")
+	}
+	paint.SetStyle(enums.PaintStyleFill)
+	if isHairline(skPaintToPaint(paint)) {
+		t.Error("fills are not hairlines")
+	}
+}
@@ -30,7 +30,8 @@ func affine2DToSkMatrix(affine f32.Affine2D) SkMatrix {
 }
 
 // skMatrixToAffine2D converts SkMatrix to f32.Affine2D.
-// Only works for affine matrices (no perspective).
+// The perspective components are dropped; the canvas keeps perspective
+// matrices alongside their affine part.
 func skMatrixToAffine2D(matrix SkMatrix) f32.Affine2D {
 	// Extract the 6 affine values from the 3x3 matrix
 	// f32.NewAffine2D takes (sx, hx, ox, hy, sy, oy)
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+)
+
+// DefaultImageCacheLimit is the default number of bytes of converted
+// images kept by the image cache.
+const DefaultImageCacheLimit = 64 << 20
+
+// imageCacheKey identifies a converted image. Alpha-only images are
+// colored by the paint, and Gio cannot tint images, so they are stored
+// once per color.
+type imageCacheKey struct {
+	id      uint32
+	tint    color.NRGBA
+	variant imageVariant
+}
+
+// imageVariant describes the processing of an image derived from a
+// converted image for drawing. The zero value is the converted image.
+type imageVariant struct {
+	// crop is the part of the image kept, if not empty.
+	crop image.Rectangle
+	// tint is the color the cropped image is multiplied with, if not
+	// zero. The color filter applies after it.
+	tint color.NRGBA
+	// filter is the ID of the color filter applied, if not zero.
+	filter uint32
+	// size is the size the image is resampled to, if not zero.
+	size image.Point
+	// cubic selects cubic resampling with the B and C parameters over box
+	// filtering.
+	cubic bool
+	b, c  float32
+}
+
+// imageCacheEntry is a converted image. Its image op is reused across
+// draws so that Gio keeps the uploaded texture.
+type imageCacheEntry struct {
+	img     *image.RGBA
+	imageOp gpaint.ImageOp
+}
+
+// imageCache holds converted images by SkImage unique ID, evicting the
+// least recently drawn when over its limit in bytes. SkImages are
+// immutable, so an ID always names the same pixels.
+var imageCache = newLRUCache[imageCacheKey, imageCacheEntry](DefaultImageCacheLimit)
+
+// cachedImage returns skImg converted to RGBA, and an image op for it.
+// Alpha-only images are colored with the paint's color, or black if paint
+// is nil. The image must not be modified. It returns nil if the pixels
+// cannot be read.
+func cachedImage(skImg interfaces.SkImage, paint SkPaint) (*image.RGBA, gpaint.ImageOp) {
+	key := imageBaseKey(skImg, paint)
+	return cachedImageKey(key, func() *image.RGBA {
+		img := skImageToRGBA(skImg)
+		if img != nil && key.tint.A != 0 {
+			img = tintAlphaMask(img, key.tint)
+		}
+		return img
+	})
+}
+
+// imageBaseKey returns the cache key of skImg converted for drawing with
+// paint.
+func imageBaseKey(skImg interfaces.SkImage, paint SkPaint) imageCacheKey {
+	key := imageCacheKey{id: skImg.UniqueID()}
+	if skImg.ImageInfo().ColorType() == enums.ColorTypeAlpha8 {
+		key.tint = color.NRGBA{A: 255}
+		if paint != nil {
+			key.tint = color4fToNRGBA(paint.GetColor())
+			key.tint.A = 255
+		}
+	}
+	return key
+}
+
+// cachedImageKey returns the image cached under key, calling build to make
+// it if it is not cached.
+func cachedImageKey(key imageCacheKey, build func() *image.RGBA) (*image.RGBA, gpaint.ImageOp) {
+	if e, ok := imageCache.Get(key); ok {
+		return e.img, e.imageOp
+	}
+	// A concurrent build of the same image is harmless.
+	img := build()
+	if img == nil {
+		return nil, gpaint.ImageOp{}
+	}
+	e := imageCacheEntry{img: img, imageOp: gpaint.NewImageOp(img)}
+	imageCache.Put(key, e, len(img.Pix))
+	return e.img, e.imageOp
+}
+
+// SetImageCacheLimit sets the number of bytes of converted images kept
+// between draws, evicting images if needed, and returns the previous
+// limit. A limit of 0 disables the cache.
+func SetImageCacheLimit(bytes int) int {
+	prev := imageCache.Budget()
+	imageCache.SetBudget(max(bytes, 0))
+	return prev
+}
+
+// ImageCacheUsage returns the number of bytes of converted images in the
+// cache.
+func ImageCacheUsage() int {
+	return imageCache.Used()
+}
+
+// PurgeImageCache drops every converted image, for example when the
+// application is in the background.
+func PurgeImageCache() {
+	imageCache.Purge()
+}
+
+// PurgeImage drops the converted copies of img, if any. Call it when an
+// image will not be drawn again to release its memory early.
+func PurgeImage(img interfaces.SkImage) {
+	if img == nil {
+		return
+	}
+	id := img.UniqueID()
+	imageCache.RemoveFunc(func(key imageCacheKey) bool { return key.id == id })
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"testing"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// testImage returns an opaque square image of size by size pixels.
+func testImage(size int) interfaces.SkImage {
+	info := models.NewImageInfo(size, size, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	pixels := make([]byte, size*size*4)
+	for i := 3; i < len(pixels); i += 4 {
+		pixels[i] = 255
+	}
+	return impl.NewRasterImage(info, pixels, size*4)
+}
+
+func TestImageCache(t *testing.T) {
+	PurgeImageCache()
+	defer SetImageCacheLimit(SetImageCacheLimit(3 * 10 * 10 * 4))
+	defer PurgeImageCache()
+
+	a, b, c, d := testImage(10), testImage(10), testImage(10), testImage(10)
+	imgA, _ := cachedImage(a, nil)
+	if again, _ := cachedImage(a, nil); again != imgA {
+		t.Error("cached image was converted again")
+	}
+	cachedImage(b, nil)
+	cachedImage(c, nil)
+	if got := ImageCacheUsage(); got != 3*400 {
+		t.Errorf("usage %d, want %d", got, 3*400)
+	}
+
+	// Drawing a again makes b the least recently used image.
+	cachedImage(a, nil)
+	cachedImage(d, nil)
+	if got := ImageCacheUsage(); got != 3*400 {
+		t.Errorf("usage %d after eviction, want %d", got, 3*400)
+	}
+	if again, _ := cachedImage(a, nil); again != imgA {
+		t.Error("recently used image was evicted")
+	}
+
+	PurgeImage(a)
+	if again, _ := cachedImage(a, nil); again == imgA {
+		t.Error("purged image was not converted again")
+	}
+
+	// Images larger than the cache are converted but not kept.
+	PurgeImageCache()
+	big := testImage(20)
+	first, _ := cachedImage(big, nil)
+	if second, _ := cachedImage(big, nil); first == nil || second == first || ImageCacheUsage() != 0 {
+		t.Error("image larger than the limit was cached")
+	}
+
+	cachedImage(a, nil)
+	if prev := SetImageCacheLimit(0); prev != 3*400 || ImageCacheUsage() != 0 {
+		t.Errorf("limit %d, usage %d after disabling the cache", prev, ImageCacheUsage())
+	}
+}
@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// skImageToRGBA converts a SkImage to the premultiplied image.RGBA Gio
+// draws. Pixels are read in the image's own color type and converted;
+// alpha-only images become black masks. It returns nil if the pixels cannot
+// be read or the color type is unknown.
+func skImageToRGBA(skImg interfaces.SkImage) *image.RGBA {
+	info := skImg.ImageInfo()
+	width, height := info.Width(), info.Height()
+	rowBytes := info.MinRowBytes()
+	if width <= 0 || height <= 0 || rowBytes <= 0 {
+		return nil
+	}
+	src := make([]byte, rowBytes*height)
+	if !skImg.ReadPixels(info, src, rowBytes, 0, 0) {
+		return nil
+	}
+	return pixelsToRGBA(info, src, rowBytes)
+}
+
+// ImageToRGBA returns a copy of img's pixels converted to premultiplied
+// RGBA, or nil if they cannot be read. Alpha-only images become black
+// masks.
+func ImageToRGBA(img interfaces.SkImage) *image.RGBA {
+	if img == nil {
+		return nil
+	}
+	return skImageToRGBA(img)
+}
+
+// pixelsToRGBA converts pixels described by info, with rows rowBytes
+// apart, to a premultiplied image. Opaque images have their alpha forced to
+// one and unpremultiplied images are premultiplied. It returns nil for
+// unknown color types.
+func pixelsToRGBA(info models.ImageInfo, src []byte, rowBytes int) *image.RGBA {
+	width, height := info.Width(), info.Height()
+	bpp := info.BytesPerPixel()
+	if bpp == 0 || len(src) < rowBytes*(height-1)+width*bpp {
+		return nil
+	}
+	decode := pixelDecoder(info.ColorType())
+	if decode == nil {
+		return nil
+	}
+	alphaType := info.AlphaType()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := src[y*rowBytes:]
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < width; x++ {
+			c := decode(row[x*bpp : (x+1)*bpp])
+			switch alphaType {
+			case enums.AlphaTypeOpaque:
+				c.a = 1
+			case enums.AlphaTypeUnpremul:
+				c = rgbaF{c.r * c.a, c.g * c.a, c.b * c.a, c.a}
+			}
+			p := c.rgba()
+			out[4*x], out[4*x+1], out[4*x+2], out[4*x+3] = p.R, p.G, p.B, p.A
+		}
+	}
+	return dst
+}
+
+// pixelDecoder returns a function decoding one pixel of colorType, or nil
+// if the color type is unknown. Color types without alpha decode opaque.
+func pixelDecoder(colorType enums.ColorType) func(p []byte) rgbaF {
+	u8 := func(v uint8) float32 { return float32(v) / 255 }
+	switch colorType {
+	case enums.ColorTypeAlpha8:
+		return func(p []byte) rgbaF { return rgbaF{a: u8(p[0])} }
+	case enums.ColorTypeGray8:
+		return func(p []byte) rgbaF { g := u8(p[0]); return rgbaF{g, g, g, 1} }
+	case enums.ColorTypeRGB565:
+		return func(p []byte) rgbaF {
+			v := binary.LittleEndian.Uint16(p)
+			return rgbaF{float32(v>>11) / 31, float32(v>>5&0x3f) / 63, float32(v&0x1f) / 31, 1}
+		}
+	case enums.ColorTypeARGB4444:
+		// Despite its name, Skia stores red in the high bits.
+		return func(p []byte) rgbaF {
+			v := binary.LittleEndian.Uint16(p)
+			return rgbaF{float32(v>>12) / 15, float32(v>>8&0xf) / 15, float32(v>>4&0xf) / 15, float32(v&0xf) / 15}
+		}
+	case enums.ColorTypeRGBA8888:
+		return func(p []byte) rgbaF { return rgbaF{u8(p[0]), u8(p[1]), u8(p[2]), u8(p[3])} }
+	case enums.ColorTypeRGB888x:
+		return func(p []byte) rgbaF { return rgbaF{u8(p[0]), u8(p[1]), u8(p[2]), 1} }
+	case enums.ColorTypeBGRA8888:
+		return func(p []byte) rgbaF { return rgbaF{u8(p[2]), u8(p[1]), u8(p[0]), u8(p[3])} }
+	case enums.ColorTypeRGBA1010102, enums.ColorTypeRGB101010x:
+		opaque := colorType == enums.ColorTypeRGB101010x
+		return func(p []byte) rgbaF {
+			v := binary.LittleEndian.Uint32(p)
+			c := rgbaF{float32(v&0x3ff) / 1023, float32(v>>10&0x3ff) / 1023, float32(v>>20&0x3ff) / 1023, float32(v>>30) / 3}
+			if opaque {
+				c.a = 1
+			}
+			return c
+		}
+	case enums.ColorTypeRGBAF16Norm, enums.ColorTypeRGBAF16:
+		return func(p []byte) rgbaF {
+			h := func(i int) float32 { return float16ToFloat32(binary.LittleEndian.Uint16(p[2*i:])) }
+			return rgbaF{h(0), h(1), h(2), h(3)}.clamp()
+		}
+	case enums.ColorTypeRGBAF32:
+		return func(p []byte) rgbaF {
+			f := func(i int) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(p[4*i:])) }
+			return rgbaF{f(0), f(1), f(2), f(3)}.clamp()
+		}
+	}
+	return nil
+}
+
+// RGBAToPixels converts the premultiplied image src to pixels described by
+// info, with rows rowBytes apart: the reverse of reading an SkImage.
+// Unpremultiplied color types are unpremultiplied, and color types without
+// alpha drop it. It reports false if info is not the size of src, dst is too
+// small or the color type is unknown.
+func RGBAToPixels(src *image.RGBA, info models.ImageInfo, dst []byte, rowBytes int) bool {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	bpp := info.BytesPerPixel()
+	if info.Width() != width || info.Height() != height || bpp == 0 || rowBytes < width*bpp {
+		return false
+	}
+	if height > 0 && len(dst) < rowBytes*(height-1)+width*bpp {
+		return false
+	}
+	encode := pixelEncoder(info.ColorType())
+	if encode == nil {
+		return false
+	}
+	unpremul := info.AlphaType() == enums.AlphaTypeUnpremul
+	for y := 0; y < height; y++ {
+		row := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+		out := dst[y*rowBytes:]
+		for x := 0; x < width; x++ {
+			c := toRGBAF(color.RGBA{R: row[4*x], G: row[4*x+1], B: row[4*x+2], A: row[4*x+3]})
+			if unpremul && c.a > 0 {
+				c = rgbaF{c.r / c.a, c.g / c.a, c.b / c.a, c.a}.clamp()
+			}
+			encode(c, out[x*bpp:(x+1)*bpp])
+		}
+	}
+	return true
+}
+
+// pixelEncoder returns a function encoding a color into one pixel of
+// colorType, or nil if the color type is unknown. It is the reverse of
+// pixelDecoder; gray is the luma of the color.
+func pixelEncoder(colorType enums.ColorType) func(c rgbaF, p []byte) {
+	q := func(v float32, max uint32) uint32 { return uint32(v*float32(max) + 0.5) }
+	u8 := func(v float32) uint8 { return uint8(q(v, 255)) }
+	switch colorType {
+	case enums.ColorTypeAlpha8:
+		return func(c rgbaF, p []byte) { p[0] = u8(c.a) }
+	case enums.ColorTypeGray8:
+		return func(c rgbaF, p []byte) { p[0] = u8(0.2126*c.r + 0.7152*c.g + 0.0722*c.b) }
+	case enums.ColorTypeRGB565:
+		return func(c rgbaF, p []byte) {
+			binary.LittleEndian.PutUint16(p, uint16(q(c.r, 31)<<11|q(c.g, 63)<<5|q(c.b, 31)))
+		}
+	case enums.ColorTypeARGB4444:
+		return func(c rgbaF, p []byte) {
+			binary.LittleEndian.PutUint16(p, uint16(q(c.r, 15)<<12|q(c.g, 15)<<8|q(c.b, 15)<<4|q(c.a, 15)))
+		}
+	case enums.ColorTypeRGBA8888:
+		return func(c rgbaF, p []byte) { p[0], p[1], p[2], p[3] = u8(c.r), u8(c.g), u8(c.b), u8(c.a) }
+	case enums.ColorTypeRGB888x:
+		return func(c rgbaF, p []byte) { p[0], p[1], p[2], p[3] = u8(c.r), u8(c.g), u8(c.b), 255 }
+	case enums.ColorTypeBGRA8888:
+		return func(c rgbaF, p []byte) { p[0], p[1], p[2], p[3] = u8(c.b), u8(c.g), u8(c.r), u8(c.a) }
+	case enums.ColorTypeRGBA1010102, enums.ColorTypeRGB101010x:
+		opaque := colorType == enums.ColorTypeRGB101010x
+		return func(c rgbaF, p []byte) {
+			a := q(c.a, 3)
+			if opaque {
+				a = 3
+			}
+			binary.LittleEndian.PutUint32(p, q(c.r, 1023)|q(c.g, 1023)<<10|q(c.b, 1023)<<20|a<<30)
+		}
+	case enums.ColorTypeRGBAF16Norm, enums.ColorTypeRGBAF16:
+		return func(c rgbaF, p []byte) {
+			for i, v := range [4]float32{c.r, c.g, c.b, c.a} {
+				binary.LittleEndian.PutUint16(p[2*i:], float32ToFloat16(v))
+			}
+		}
+	case enums.ColorTypeRGBAF32:
+		return func(c rgbaF, p []byte) {
+			for i, v := range [4]float32{c.r, c.g, c.b, c.a} {
+				binary.LittleEndian.PutUint32(p[4*i:], math.Float32bits(v))
+			}
+		}
+	}
+	return nil
+}
+
+// clamp clamps the components of c to [0, 1], mapping NaN to 0.
+func (c rgbaF) clamp() rgbaF {
+	f := func(v float32) float32 {
+		if !(v > 0) {
+			return 0
+		}
+		return min(v, 1)
+	}
+	return rgbaF{f(c.r), f(c.g), f(c.b), f(c.a)}
+}
+
+// clampPremul clamps c to a valid premultiplied color: alpha to [0, 1] and
+// the color components to [0, alpha]. Cubic filters ring past both bounds.
+func (c rgbaF) clampPremul() rgbaF {
+	c = c.clamp()
+	return rgbaF{min(c.r, c.a), min(c.g, c.a), min(c.b, c.a), c.a}
+}
+
+// float16ToFloat32 converts an IEEE 754 half precision float.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		// Zero or subnormal.
+		v := float32(frac) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1f:
+		// Infinity or NaN.
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | frac<<13)
+}
+
+// float32ToFloat16 converts a float in [0, 1] to IEEE 754 half precision,
+// rounding to nearest.
+func float32ToFloat16(f float32) uint16 {
+	if !(f > 0) {
+		return 0
+	}
+	bits := math.Float32bits(f)
+	exp := int(bits>>23&0xff) - 127 + 15
+	if exp <= 0 {
+		// Subnormal.
+		return uint16(f*(1<<24) + 0.5)
+	}
+	// Round the 23 bit fraction to 10 bits; a carry into the exponent is
+	// still correct.
+	return uint16(exp<<10) + uint16((bits&0x7fffff+0x1000)>>13)
+}
+
+// tintAlphaMask returns a copy of the alpha-only image mask colored with
+// the opaque color tint, as Skia colors alpha images with the paint.
+func tintAlphaMask(mask *image.RGBA, tint color.NRGBA) *image.RGBA {
+	dst := image.NewRGBA(mask.Rect)
+	for i := 3; i < len(mask.Pix); i += 4 {
+		a := uint32(mask.Pix[i])
+		dst.Pix[i-3] = uint8((uint32(tint.R)*a + 127) / 255)
+		dst.Pix[i-2] = uint8((uint32(tint.G)*a + 127) / 255)
+		dst.Pix[i-1] = uint8((uint32(tint.B)*a + 127) / 255)
+		dst.Pix[i] = uint8(a)
+	}
+	return dst
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestPixelsToRGBA_ColorTypes(t *testing.T) {
+	le16 := func(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+	le32 := func(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+	f16 := func(vs ...uint16) []byte {
+		var b []byte
+		for _, v := range vs {
+			b = binary.LittleEndian.AppendUint16(b, v)
+		}
+		return b
+	}
+	f32 := func(vs ...float32) []byte {
+		var b []byte
+		for _, v := range vs {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+		}
+		return b
+	}
+	for _, tc := range []struct {
+		name      string
+		colorType enums.ColorType
+		alphaType enums.AlphaType
+		pixel     []byte
+		want      color.RGBA
+	}{
+		{"alpha8", enums.ColorTypeAlpha8, enums.AlphaTypePremul, []byte{128}, color.RGBA{A: 128}},
+		{"gray8", enums.ColorTypeGray8, enums.AlphaTypeOpaque, []byte{200}, color.RGBA{R: 200, G: 200, B: 200, A: 255}},
+		{"rgb565", enums.ColorTypeRGB565, enums.AlphaTypeOpaque, le16(0xf800 | 0x3f<<5), color.RGBA{R: 255, G: 255, A: 255}},
+		{"argb4444", enums.ColorTypeARGB4444, enums.AlphaTypePremul, le16(0x0f0f), color.RGBA{G: 255, A: 255}},
+		{"rgba8888", enums.ColorTypeRGBA8888, enums.AlphaTypePremul, []byte{10, 20, 30, 40}, color.RGBA{R: 10, G: 20, B: 30, A: 40}},
+		{"rgba8888 unpremul", enums.ColorTypeRGBA8888, enums.AlphaTypeUnpremul, []byte{255, 0, 100, 128}, color.RGBA{R: 128, B: 50, A: 128}},
+		{"rgba8888 opaque", enums.ColorTypeRGBA8888, enums.AlphaTypeOpaque, []byte{10, 20, 30, 0}, color.RGBA{R: 10, G: 20, B: 30, A: 255}},
+		{"rgb888x", enums.ColorTypeRGB888x, enums.AlphaTypeOpaque, []byte{1, 2, 3, 0}, color.RGBA{R: 1, G: 2, B: 3, A: 255}},
+		{"bgra8888", enums.ColorTypeBGRA8888, enums.AlphaTypePremul, []byte{30, 20, 10, 255}, color.RGBA{R: 10, G: 20, B: 30, A: 255}},
+		{"rgba1010102", enums.ColorTypeRGBA1010102, enums.AlphaTypePremul, le32(0x3ff | 3<<30), color.RGBA{R: 255, A: 255}},
+		{"rgb101010x", enums.ColorTypeRGB101010x, enums.AlphaTypeOpaque, le32(0x3ff << 20), color.RGBA{B: 255, A: 255}},
+		// Half floats 1.0, 0.5, 2.0 (clamped) and 1.0.
+		{"f16", enums.ColorTypeRGBAF16, enums.AlphaTypePremul, f16(0x3c00, 0x3800, 0x4000, 0x3c00), color.RGBA{R: 255, G: 128, B: 255, A: 255}},
+		{"f16 unpremul", enums.ColorTypeRGBAF16Norm, enums.AlphaTypeUnpremul, f16(0x3c00, 0, 0, 0x3800), color.RGBA{R: 128, A: 128}},
+		{"f32", enums.ColorTypeRGBAF32, enums.AlphaTypePremul, f32(0.25, -1, float32(math.NaN()), 1), color.RGBA{R: 64, A: 255}},
+	} {
+		info := models.NewImageInfo(1, 1, tc.colorType, tc.alphaType)
+		img := pixelsToRGBA(info, tc.pixel, len(tc.pixel))
+		if img == nil {
+			t.Errorf("%s: not converted", tc.name)
+			continue
+		}
+		if got := img.RGBAAt(0, 0); got != tc.want {
+			t.Errorf("%s: pixel %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if pixelsToRGBA(models.NewImageInfo(1, 1, enums.ColorTypeUnknown, enums.AlphaTypePremul), []byte{0}, 1) != nil {
+		t.Error("unknown color type converted")
+	}
+}
+
+func TestSkImageToRGBA_RowBytes(t *testing.T) {
+	// A 2x2 BGRA image with padded rows.
+	info := models.NewImageInfo(2, 2, enums.ColorTypeBGRA8888, enums.AlphaTypeOpaque)
+	pixels := []byte{
+		0, 0, 255, 0, 0, 255, 0, 0, 9, 9, 9, 9,
+		255, 0, 0, 0, 255, 255, 255, 0, 9, 9, 9, 9,
+	}
+	img := skImageToRGBA(impl.NewRasterImage(info, pixels, 12))
+	if img == nil {
+		t.Fatal("image not converted")
+	}
+	want := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}, {R: 255, G: 255, B: 255, A: 255}}
+	for i, w := range want {
+		if got := img.RGBAAt(i%2, i/2); got != w {
+			t.Errorf("pixel %d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestFloat16ToFloat32(t *testing.T) {
+	for h, want := range map[uint16]float32{
+		0x0000: 0,
+		0x3c00: 1,
+		0xc000: -2,
+		0x3555: 0.333251953125,
+		0x0001: 1.0 / (1 << 24),
+		0x7bff: 65504,
+	} {
+		if got := float16ToFloat32(h); got != want {
+			t.Errorf("float16ToFloat32(%#04x) = %v, want %v", h, got, want)
+		}
+	}
+	if !math.IsInf(float64(float16ToFloat32(0x7c00)), 1) || !math.IsNaN(float64(float16ToFloat32(0x7e00))) {
+		t.Error("infinity or NaN not preserved")
+	}
+}
+
+func TestCachedImage_AlphaMask(t *testing.T) {
+	PurgeImageCache()
+	defer PurgeImageCache()
+	info := models.NewImageInfo(1, 1, enums.ColorTypeAlpha8, enums.AlphaTypePremul)
+	mask := impl.NewRasterImage(info, []byte{128}, 1)
+
+	img, _ := cachedImage(mask, nil)
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{A: 128}) {
+		t.Errorf("untinted mask %v", got)
+	}
+	// The paint colors the mask; its alpha is applied when drawing.
+	img, _ = cachedImage(mask, NewPaintFill(color.NRGBA{R: 255, G: 100, A: 10}))
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{R: 128, G: 50, A: 128}) {
+		t.Errorf("tinted mask %v", got)
+	}
+	PurgeImage(mask)
+	if ImageCacheUsage() != 0 {
+		t.Error("tinted masks not purged with their image")
+	}
+}
+
+func TestRGBAToPixels_RoundTrip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	copy(src.Pix, []byte{255, 0, 0, 255, 32, 64, 96, 128})
+	for _, tc := range []struct {
+		colorType enums.ColorType
+		alphaType enums.AlphaType
+		// tol is the precision of the color type.
+		tol int
+		// opaque color types drop alpha.
+		opaque bool
+	}{
+		{enums.ColorTypeRGBA8888, enums.AlphaTypePremul, 0, false},
+		{enums.ColorTypeRGBA8888, enums.AlphaTypeUnpremul, 1, false},
+		{enums.ColorTypeBGRA8888, enums.AlphaTypePremul, 0, false},
+		{enums.ColorTypeRGB888x, enums.AlphaTypeOpaque, 0, true},
+		{enums.ColorTypeRGB565, enums.AlphaTypeOpaque, 5, true},
+		{enums.ColorTypeARGB4444, enums.AlphaTypePremul, 9, false},
+		{enums.ColorTypeRGBA1010102, enums.AlphaTypePremul, 43, false},
+		{enums.ColorTypeRGBAF16, enums.AlphaTypePremul, 0, false},
+		{enums.ColorTypeRGBAF16Norm, enums.AlphaTypeUnpremul, 1, false},
+		{enums.ColorTypeRGBAF32, enums.AlphaTypePremul, 0, false},
+	} {
+		info := models.NewImageInfo(2, 1, tc.colorType, tc.alphaType)
+		dst := make([]byte, info.MinRowBytes())
+		if !RGBAToPixels(src, info, dst, len(dst)) {
+			t.Errorf("color type %d: not converted", tc.colorType)
+			continue
+		}
+		got := pixelsToRGBA(info, dst, len(dst))
+		for x := 0; x < 2; x++ {
+			want := src.RGBAAt(x, 0)
+			if tc.opaque {
+				want.A = 255
+			}
+			g := got.RGBAAt(x, 0)
+			if absInt(int(g.R)-int(want.R)) > tc.tol || absInt(int(g.G)-int(want.G)) > tc.tol ||
+				absInt(int(g.B)-int(want.B)) > tc.tol || absInt(int(g.A)-int(want.A)) > tc.tol {
+				t.Errorf("color type %d alpha type %d: pixel %d = %v, want %v", tc.colorType, tc.alphaType, x, g, want)
+			}
+		}
+	}
+
+	gray := make([]byte, 2)
+	if !RGBAToPixels(src, models.NewImageInfo(2, 1, enums.ColorTypeGray8, enums.AlphaTypeOpaque), gray, 2) || gray[0] != 54 {
+		t.Errorf("gray of red = %d, want 54", gray[0])
+	}
+	if RGBAToPixels(src, models.NewImageInfo(3, 1, enums.ColorTypeRGBA8888, enums.AlphaTypePremul), make([]byte, 12), 12) {
+		t.Error("converted to a different size")
+	}
+	if RGBAToPixels(src, models.NewImageInfo(2, 1, enums.ColorTypeRGBA8888, enums.AlphaTypePremul), make([]byte, 4), 8) {
+		t.Error("converted into a short buffer")
+	}
+}
+
+func TestFloat32ToFloat16(t *testing.T) {
+	for _, f := range []float32{0, 1, 0.5, 0.25, 1.0 / 3, 0.001, 1e-6} {
+		if got := float16ToFloat32(float32ToFloat16(f)); math.Abs(float64(got-f)) > float64(f)/1000+1e-7 {
+			t.Errorf("%v round trips to %v", f, got)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"math"
+
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// maxResampledImageSize bounds the width and height of images resampled on
+// the CPU. Larger draws are scaled further by the GPU.
+const maxResampledImageSize = 2048
+
+// defaultImageSampling is the sampling of DrawImage and DrawImageRect,
+// which filter linearly as they always have.
+var defaultImageSampling = models.SamplingOptions{FilterMode: enums.FilterModeLinear}
+
+// DrawImageWithSampling draws image at (left, top) at its natural size,
+// sampled with sampling, mirroring SkCanvas::drawImage.
+func (c *canvas) DrawImageWithSampling(image interfaces.SkImage, left, top Scalar, sampling models.SamplingOptions, paint SkPaint) {
+	if image == nil {
+		return
+	}
+	w, h := Scalar(image.Width()), Scalar(image.Height())
+	src := models.Rect{Right: w, Bottom: h}
+	dst := models.Rect{Left: left, Top: top, Right: left + w, Bottom: top + h}
+	c.DrawImageRectWithSampling(image, &src, dst, sampling, paint, enums.SrcRectConstraintFast)
+}
+
+// DrawImageRectWithSampling draws the src part of image, or all of it if
+// src is nil, scaled into dst, mirroring SkCanvas::drawImageRect.
+//
+// Nearest and linear filtering map onto Gio's image filters. Mipmapped
+// sampling of minified images draws a box filtered level of the image and
+// cubic sampling resamples the image by the power of two scale at or above
+// its device scale, which the GPU reduces linearly; both are done on the
+// CPU and cached. With the strict constraint, filtering never samples
+// pixels outside src, at the cost of a cached copy of each src drawn.
+//
+// The paint's alpha and color filter apply. Gio composites source-over
+// only, so blend modes other than Dst, which draws nothing, are drawn
+// source-over.
+func (c *canvas) DrawImageRectWithSampling(image interfaces.SkImage, src *models.Rect, dst models.Rect, sampling models.SamplingOptions, paint SkPaint, constraint enums.SrcRectConstraint) {
+	if image == nil {
+		return
+	}
+	srcRect := models.Rect{Right: Scalar(image.Width()), Bottom: Scalar(image.Height())}
+	if src != nil {
+		srcRect = *src
+	}
+	if isEmptyRect(srcRect) || isEmptyRect(dst) || c.cullDraw(dst, 0) {
+		return
+	}
+	alpha := float32(1)
+	if paint != nil {
+		if paint.GetBlendModeOr(enums.BlendModeSrcOver) == enums.BlendModeDst {
+			return
+		}
+		alpha = float32(paint.GetAlphaf())
+	}
+	imgOp, srcRect, ok := c.imageForDraw(image, srcRect, dst, sampling, paint, constraint)
+	if !ok || alpha <= 0 {
+		return
+	}
+	if alpha < 1 {
+		opacity := gpaint.PushOpacity(c.ops, alpha)
+		defer opacity.Pop()
+	}
+	c.drawImageOpRect(imgOp, srcRect, dst)
+}
+
+// imageForDraw returns the image op to draw the src part of skImg into dst
+// with, and src in the coordinates of the op's image. The image is cropped,
+// color filtered and resampled as sampling, paint and constraint require.
+func (c *canvas) imageForDraw(skImg interfaces.SkImage, src, dst models.Rect, sampling models.SamplingOptions, paint SkPaint, constraint enums.SrcRectConstraint) (gpaint.ImageOp, models.Rect, bool) {
+	key := imageBaseKey(skImg, paint)
+	img, imgOp := cachedImage(skImg, paint)
+	if img == nil {
+		return gpaint.ImageOp{}, src, false
+	}
+	// derive replaces img with the variant v of the converted image, built
+	// from the current img.
+	derive := func(v imageVariant, build func(parent *image.RGBA) *image.RGBA) bool {
+		parent := img
+		key.variant = v
+		img, imgOp = cachedImageKey(key, func() *image.RGBA { return build(parent) })
+		return img != nil
+	}
+	v := key.variant
+	mipmap := sampling.MipmapMode != enums.MipmapModeNone && !sampling.UseCubic
+	smooth := sampling.FilterMode == enums.FilterModeLinear || sampling.UseCubic || mipmap
+
+	// Cubic resampling only covers src. Otherwise crop to src, so that
+	// filtering clamps to its edges, if the constraint is strict.
+	crop := image.Rect(
+		int(math.Floor(float64(src.Left))), int(math.Floor(float64(src.Top))),
+		int(math.Ceil(float64(src.Right))), int(math.Ceil(float64(src.Bottom))),
+	).Intersect(img.Rect)
+	if (sampling.UseCubic || (smooth && constraint == enums.SrcRectConstraintStrict)) && crop != img.Rect && !crop.Empty() {
+		v.crop = crop
+		if !derive(v, func(parent *image.RGBA) *image.RGBA { return cropImage(parent, crop) }) {
+			return gpaint.ImageOp{}, src, false
+		}
+		src = offsetRect(src, -Scalar(crop.Min.X), -Scalar(crop.Min.Y))
+	}
+
+	if f, ok := paintColorFilter(paint); ok {
+		v.filter = f.filterID()
+		if !derive(v, func(parent *image.RGBA) *image.RGBA { return filterImage(parent, f) }) {
+			return gpaint.ImageOp{}, src, false
+		}
+	}
+
+	// The device size of the image decides its resampling.
+	m := c.stack[len(c.stack)-1].matrix()
+	d0 := perspectiveMap(m, models.Point{X: dst.Left, Y: dst.Top})
+	dx := perspectiveMap(m, models.Point{X: dst.Right, Y: dst.Top})
+	dy := perspectiveMap(m, models.Point{X: dst.Left, Y: dst.Bottom})
+	rx := float64(pointLength(subPoint(dx, d0)) / (src.Right - src.Left))
+	ry := float64(pointLength(subPoint(dy, d0)) / (src.Bottom - src.Top))
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	switch {
+	case sampling.UseCubic:
+		// Quantizing the scale spares resampling again on every frame of
+		// an animated zoom.
+		size := image.Pt(int(math.Ceil(float64(w)*pow2Scale(rx))), int(math.Ceil(float64(h)*pow2Scale(ry))))
+		size.X = min(max(size.X, 1), maxResampledImageSize)
+		size.Y = min(max(size.Y, 1), maxResampledImageSize)
+		if size == img.Rect.Size() {
+			break
+		}
+		v.size, v.cubic, v.b, v.c = size, true, sampling.CubicB, sampling.CubicC
+		if !derive(v, func(parent *image.RGBA) *image.RGBA {
+			return resampleCubic(parent, size, sampling.CubicB, sampling.CubicC)
+		}) {
+			return gpaint.ImageOp{}, src, false
+		}
+		src = scaleRect(src, Scalar(size.X)/Scalar(w), Scalar(size.Y)/Scalar(h))
+	case mipmap:
+		level := mipmapLevel(max(rx, ry), sampling.MipmapMode)
+		if level == 0 {
+			break
+		}
+		size := image.Pt(w, h)
+		for i := 0; i < level; i++ {
+			size = image.Pt((size.X+1)/2, (size.Y+1)/2)
+		}
+		v.size = size
+		if !derive(v, func(parent *image.RGBA) *image.RGBA { return downsampleImage(parent, level) }) {
+			return gpaint.ImageOp{}, src, false
+		}
+		src = scaleRect(src, Scalar(size.X)/Scalar(w), Scalar(size.Y)/Scalar(h))
+	}
+
+	if !smooth {
+		imgOp.Filter = gpaint.FilterNearest
+	}
+	return imgOp, src, true
+}
+
+// pow2Scale returns the smallest power of two at or above scale.
+func pow2Scale(scale float64) float64 {
+	if !(scale > 0) || math.IsInf(scale, 0) {
+		return 1
+	}
+	return math.Exp2(math.Ceil(math.Log2(scale)))
+}
+
+// cachedFilteredImage returns skImg converted as cachedImage does, with the
+// paint's color filter applied if the canvas can evaluate it.
+func cachedFilteredImage(skImg interfaces.SkImage, paint SkPaint) (*image.RGBA, gpaint.ImageOp) {
+	img, imgOp := cachedImage(skImg, paint)
+	f, ok := paintColorFilter(paint)
+	if img == nil || !ok {
+		return img, imgOp
+	}
+	key := imageBaseKey(skImg, paint)
+	key.variant.filter = f.filterID()
+	return cachedImageKey(key, func() *image.RGBA { return filterImage(img, f) })
+}
+
+// mipmapLevel returns the mipmap level to draw an image scaled by scale
+// with: each level halves the previous one. The nearest mode picks the
+// closest level, the linear mode the finer of the two around scale, which
+// is then filtered linearly.
+func mipmapLevel(scale float64, mode enums.MipmapMode) int {
+	if !(scale > 0) || scale >= 1 {
+		return 0
+	}
+	l := math.Log2(1 / scale)
+	if mode == enums.MipmapModeNearest {
+		l = math.Round(l)
+	}
+	return min(int(l), 16)
+}
+
+func offsetRect(r models.Rect, dx, dy Scalar) models.Rect {
+	return models.Rect{Left: r.Left + dx, Top: r.Top + dy, Right: r.Right + dx, Bottom: r.Bottom + dy}
+}
+
+func scaleRect(r models.Rect, sx, sy Scalar) models.Rect {
+	return models.Rect{Left: r.Left * sx, Top: r.Top * sy, Right: r.Right * sx, Bottom: r.Bottom * sy}
+}
+
+// cropImage returns a copy of the r part of img, with its origin at zero.
+func cropImage(img *image.RGBA, r image.Rectangle) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := 0; y < r.Dy(); y++ {
+		copy(dst.Pix[y*dst.Stride:], img.Pix[img.PixOffset(r.Min.X, r.Min.Y+y):img.PixOffset(r.Max.X, r.Min.Y+y)])
+	}
+	return dst
+}
+
+// downsampleImage halves img level times, averaging each 2x2 block. Odd
+// sizes round up, repeating the last row or column.
+func downsampleImage(img *image.RGBA, level int) *image.RGBA {
+	for i := 0; i < level; i++ {
+		w, h := img.Rect.Dx(), img.Rect.Dy()
+		if w <= 1 && h <= 1 {
+			break
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, (w+1)/2, (h+1)/2))
+		for y := 0; y < dst.Rect.Dy(); y++ {
+			y0, y1 := img.Rect.Min.Y+2*y, img.Rect.Min.Y+min(2*y+1, h-1)
+			for x := 0; x < dst.Rect.Dx(); x++ {
+				x0, x1 := img.Rect.Min.X+2*x, img.Rect.Min.X+min(2*x+1, w-1)
+				var sum [4]uint32
+				for _, p := range [4]image.Point{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}} {
+					o := img.PixOffset(p.X, p.Y)
+					for k := range sum {
+						sum[k] += uint32(img.Pix[o+k])
+					}
+				}
+				o := dst.PixOffset(x, y)
+				for k := range sum {
+					dst.Pix[o+k] = uint8((sum[k] + 2) / 4)
+				}
+			}
+		}
+		img = dst
+	}
+	return img
+}
+
+// cubicWeight is the Mitchell-Netravali cubic filter with parameters b and
+// c, as used by SkCubicResampler.
+func cubicWeight(x, b, c float32) float32 {
+	x = float32(math.Abs(float64(x)))
+	switch {
+	case x < 1:
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	case x < 2:
+		return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	}
+	return 0
+}
+
+// resampleCubic returns img resampled to size with the cubic filter b, c.
+// When minifying the filter is widened to cover the source pixels. Edges
+// clamp.
+func resampleCubic(img *image.RGBA, size image.Point, b, c float32) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	src := make([]rgbaF, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src[y*w+x] = toRGBAF(img.RGBAAt(img.Rect.Min.X+x, img.Rect.Min.Y+y))
+		}
+	}
+	// pass resamples n rows of in from length from to length to. at
+	// returns the index in in of pixel i of a row and out stores pixel i of
+	// a resampled row.
+	pass := func(in []rgbaF, from, to, n int, at func(row, i int) int, out func(row, i int, c rgbaF)) {
+		scale := float32(to) / float32(from)
+		widen := max(1, 1/scale)
+		support := 2 * widen
+		for i := 0; i < to; i++ {
+			center := (float32(i)+0.5)/scale - 0.5
+			lo := int(math.Floor(float64(center - support)))
+			hi := int(math.Ceil(float64(center + support)))
+			for row := 0; row < n; row++ {
+				var sum rgbaF
+				var total float32
+				for j := lo; j <= hi; j++ {
+					wgt := cubicWeight((float32(j)-center)/widen, b, c)
+					if wgt == 0 {
+						continue
+					}
+					p := in[at(row, min(max(j, 0), from-1))]
+					sum = rgbaF{sum.r + p.r*wgt, sum.g + p.g*wgt, sum.b + p.b*wgt, sum.a + p.a*wgt}
+					total += wgt
+				}
+				if total != 0 {
+					sum = sum.scale(1 / total)
+				}
+				out(row, i, sum)
+			}
+		}
+	}
+	horizontal := make([]rgbaF, size.X*h)
+	pass(src, w, size.X, h,
+		func(row, i int) int { return row*w + i },
+		func(row, i int, c rgbaF) { horizontal[row*size.X+i] = c })
+	dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	pass(horizontal, h, size.Y, size.X,
+		func(col, i int) int { return i*size.X + col },
+		func(col, i int, c rgbaF) { dst.SetRGBA(col, i, c.clampPremul().rgba()) })
+	return dst
+}
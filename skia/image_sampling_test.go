@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestMipmapLevel(t *testing.T) {
+	for _, tc := range []struct {
+		scale float64
+		mode  enums.MipmapMode
+		want  int
+	}{
+		{1, enums.MipmapModeLinear, 0},
+		{2, enums.MipmapModeLinear, 0},
+		{0.6, enums.MipmapModeLinear, 0},
+		{0.6, enums.MipmapModeNearest, 1},
+		{0.25, enums.MipmapModeLinear, 2},
+		{0.2, enums.MipmapModeLinear, 2},
+		{0.2, enums.MipmapModeNearest, 2},
+		{0.15, enums.MipmapModeNearest, 3},
+		{0, enums.MipmapModeNearest, 0},
+	} {
+		if got := mipmapLevel(tc.scale, tc.mode); got != tc.want {
+			t.Errorf("mipmapLevel(%v, %d) = %d, want %d", tc.scale, tc.mode, got, tc.want)
+		}
+	}
+}
+
+func TestCropImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.SetRGBA(2, 1, color.RGBA{R: 255, A: 255})
+	crop := cropImage(img, image.Rect(1, 1, 3, 3))
+	if crop.Rect != image.Rect(0, 0, 2, 2) || crop.RGBAAt(1, 0) != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("crop %v, pixel %v", crop.Rect, crop.RGBAAt(1, 0))
+	}
+}
+
+func TestDownsampleImage(t *testing.T) {
+	// Columns alternate between white and transparent.
+	img := image.NewRGBA(image.Rect(0, 0, 5, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x += 2 {
+			img.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+	half := downsampleImage(img, 1)
+	if half.Rect.Size() != image.Pt(3, 2) {
+		t.Fatalf("size %v", half.Rect.Size())
+	}
+	if got := half.RGBAAt(0, 0); got != (color.RGBA{R: 128, G: 128, B: 128, A: 128}) {
+		t.Errorf("averaged pixel %v", got)
+	}
+	// The odd last column repeats.
+	if got := half.RGBAAt(2, 1); got.A != 255 {
+		t.Errorf("edge pixel %v", got)
+	}
+	if got := downsampleImage(img, 5).Rect.Size(); got != image.Pt(1, 1) {
+		t.Errorf("deepest level size %v", got)
+	}
+}
+
+func TestResampleCubic(t *testing.T) {
+	// A flat image stays flat whatever the scale.
+	img := image.NewRGBA(image.Rect(0, 0, 6, 6))
+	flat := color.RGBA{R: 40, G: 80, B: 120, A: 200}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = flat.R, flat.G, flat.B, flat.A
+	}
+	for _, size := range []image.Point{{12, 3}, {2, 20}, {6, 6}} {
+		out := resampleCubic(img, size, 1.0/3, 1.0/3)
+		if out.Rect.Size() != size {
+			t.Fatalf("size %v, want %v", out.Rect.Size(), size)
+		}
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				if got := out.RGBAAt(x, y); absInt(int(got.R)-int(flat.R)) > 1 || absInt(int(got.A)-int(flat.A)) > 1 {
+					t.Fatalf("size %v: pixel (%d, %d) = %v, want %v", size, x, y, got, flat)
+				}
+			}
+		}
+	}
+
+	// Magnifying an edge with Catmull-Rom overshoots, but stays clamped and
+	// premultiplied.
+	edge := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	edge.SetRGBA(1, 0, color.RGBA{R: 255, A: 255})
+	out := resampleCubic(edge, image.Pt(8, 1), 0, 0.5)
+	if got := out.RGBAAt(0, 0); got.A != 0 {
+		t.Errorf("left end %v", got)
+	}
+	if got := out.RGBAAt(7, 0); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("right end %v", got)
+	}
+}
+
+func TestClampPremul(t *testing.T) {
+	// Ringing past a transparent edge leaves color without alpha.
+	got := rgbaF{r: 0.4, g: -0.1, b: 1.2, a: 0.25}.clampPremul()
+	if want := (rgbaF{r: 0.25, g: 0, b: 0.25, a: 0.25}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := (rgbaF{r: 0.5, a: 1.5}).clampPremul(); got != (rgbaF{r: 0.5, a: 1}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestColorFilters(t *testing.T) {
+	gray := NewColorMatrixFilter([20]float32{
+		0.3, 0.59, 0.11, 0, 0,
+		0.3, 0.59, 0.11, 0, 0,
+		0.3, 0.59, 0.11, 0, 0,
+		0, 0, 0, 1, 0,
+	}).(colorFilterer)
+	if !gray.IsAlphaUnchanged() {
+		t.Error("grayscale matrix should keep alpha")
+	}
+	// Half transparent red is filtered unpremultiplied.
+	got := gray.filterColor(toRGBAF(color.RGBA{R: 128, A: 128})).rgba()
+	if got != (color.RGBA{R: 38, G: 38, B: 38, A: 128}) {
+		t.Errorf("grayscale red = %v", got)
+	}
+
+	tint := NewBlendModeColorFilter(color.NRGBA{B: 255, A: 255}, enums.BlendModeSrcIn).(colorFilterer)
+	if got := tint.filterColor(toRGBAF(color.RGBA{R: 100, A: 100})).rgba(); got != (color.RGBA{B: 100, A: 100}) {
+		t.Errorf("src-in tint = %v", got)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{G: 255, A: 255})
+	if got := filterImage(img, tint).RGBAAt(0, 0); got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("filtered image pixel %v", got)
+	}
+}
+
+func TestCanvas_DrawImageRectWithSampling(t *testing.T) {
+	PurgeImageCache()
+	defer PurgeImageCache()
+	img := testImage(16)
+	src := models.Rect{Left: 2, Top: 2, Right: 10, Bottom: 10}
+	c := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+
+	// The Dst blend mode draws nothing, so the image is not even converted.
+	dst := NewPaint()
+	dst.SetBlendMode(enums.BlendModeDst)
+	c.DrawImageRectWithSampling(img, &src, models.Rect{Right: 50, Bottom: 50}, defaultImageSampling, dst, enums.SrcRectConstraintStrict)
+	if ImageCacheUsage() != 0 {
+		t.Error("image converted for a Dst draw")
+	}
+
+	// Strict linear sampling draws a crop of src; fast sampling does not.
+	base := 16 * 16 * 4
+	c.DrawImageRectWithSampling(img, &src, models.Rect{Right: 50, Bottom: 50}, defaultImageSampling, nil, enums.SrcRectConstraintFast)
+	if got := ImageCacheUsage(); got != base {
+		t.Errorf("fast draw cached %d bytes, want %d", got, base)
+	}
+	c.DrawImageRectWithSampling(img, &src, models.Rect{Right: 50, Bottom: 50}, defaultImageSampling, nil, enums.SrcRectConstraintStrict)
+	if got := ImageCacheUsage(); got != base+8*8*4 {
+		t.Errorf("strict draw cached %d bytes, want %d", got, base+8*8*4)
+	}
+
+	// Mipmaps apply when minifying; cubic sampling resamples to the device
+	// size.
+	PurgeImageCache()
+	c.DrawImageRectWithSampling(img, nil, models.Rect{Right: 4, Bottom: 4}, models.NewSamplingOptionsMipmap(enums.FilterModeLinear, enums.MipmapModeNearest), nil, enums.SrcRectConstraintFast)
+	if got := ImageCacheUsage(); got != base+4*4*4 {
+		t.Errorf("mipmapped draw cached %d bytes, want %d", got, base+4*4*4)
+	}
+	PurgeImageCache()
+	c.Scale(2, 2)
+	c.DrawImageRectWithSampling(img, nil, models.Rect{Right: 16, Bottom: 16}, models.SamplingOptions{UseCubic: true, CubicB: 1.0 / 3, CubicC: 1.0 / 3}, nil, enums.SrcRectConstraintFast)
+	if got := ImageCacheUsage(); got != base+32*32*4 {
+		t.Errorf("cubic draw cached %d bytes, want %d", got, base+32*32*4)
+	}
+
+	// A color filter derives a filtered image; paint alpha is applied when
+	// drawing.
+	PurgeImageCache()
+	paint := NewPaint()
+	paint.SetAlphaf(0.5)
+	paint.SetColorFilter(NewBlendModeColorFilter(color.NRGBA{R: 255, A: 255}, enums.BlendModeSrcIn))
+	c.DrawImage(img, 0, 0, paint)
+	if got := ImageCacheUsage(); got != 2*base {
+		t.Errorf("filtered draw cached %d bytes, want %d", got, 2*base)
+	}
+	if c.CulledDraws() != 0 {
+		t.Errorf("visible images culled")
+	}
+}
+
+func TestCanvas_ImageDrawsShareUploads(t *testing.T) {
+	PurgeImageCache()
+	defer PurgeImageCache()
+	img := testImage(16)
+	base := 16 * 16 * 4
+	c := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+
+	// Sprites drawn with DrawImageRect share the converted image.
+	for i := 0; i < 4; i++ {
+		src := models.Rect{Left: Scalar(4 * i), Right: Scalar(4*i + 4), Bottom: 4}
+		c.DrawImageRect(img, &src, models.Rect{Left: Scalar(10 * i), Right: Scalar(10*i + 8), Bottom: 8}, nil)
+	}
+	if got := ImageCacheUsage(); got != base {
+		t.Errorf("sprites cached %d bytes, want %d", got, base)
+	}
+
+	// Cubic sampling resamples by powers of two, so a zoom between 2 and
+	// 4 resamples once.
+	cubic := models.SamplingOptions{UseCubic: true, CubicB: 1.0 / 3, CubicC: 1.0 / 3}
+	for _, scale := range []Scalar{2.2, 2.9, 3.7} {
+		c.Save()
+		c.Scale(scale, scale)
+		c.DrawImageRectWithSampling(img, nil, models.Rect{Right: 16, Bottom: 16}, cubic, nil, enums.SrcRectConstraintFast)
+		c.Restore()
+	}
+	if got := ImageCacheUsage(); got != base+64*64*4 {
+		t.Errorf("zoomed cubic draws cached %d bytes, want %d", got, base+64*64*4)
+	}
+
+	// Lattices and atlases apply the color filter.
+	PurgeImageCache()
+	paint := NewPaint()
+	paint.SetColorFilter(NewBlendModeColorFilter(color.NRGBA{R: 255, A: 255}, enums.BlendModeSrcIn))
+	c.DrawImageNine(img, models.IRect{Left: 4, Top: 4, Right: 12, Bottom: 12}, models.Rect{Right: 40, Bottom: 40}, enums.FilterModeLinear, paint)
+	if got := ImageCacheUsage(); got != 2*base {
+		t.Errorf("filtered lattice cached %d bytes, want %d", got, 2*base)
+	}
+	PurgeImageCache()
+	c.DrawAtlas(img, []models.RSXform{{SCos: 1}}, []models.Rect{{Right: 8, Bottom: 8}}, nil, enums.BlendModeModulate, defaultImageSampling, nil, paint)
+	if got := ImageCacheUsage(); got != 2*base {
+		t.Errorf("filtered atlas cached %d bytes, want %d", got, 2*base)
+	}
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// LatticeRectType selects how a lattice cell is drawn, mirroring
+// SkCanvas::Lattice::RectType.
+type LatticeRectType uint8
+
+const (
+	// LatticeRectTypeDefault draws the image in the cell.
+	LatticeRectTypeDefault LatticeRectType = iota
+	// LatticeRectTypeTransparent leaves the cell empty.
+	LatticeRectTypeTransparent
+	// LatticeRectTypeFixedColor fills the cell with its color.
+	LatticeRectTypeFixedColor
+)
+
+// Lattice divides an image into a grid of cells, mirroring
+// SkCanvas::Lattice. The divisions split the image into alternating fixed
+// and scalable columns and rows, starting with a fixed one unless the first
+// division lies on the image edge. Fixed cells keep their size and
+// scalable cells share the rest of the destination.
+type Lattice struct {
+	// XDivs and YDivs are increasing image coordinates of the divisions.
+	XDivs, YDivs []int
+	// RectTypes optionally holds the type of each cell, row by row, for
+	// (len(XDivs)+1)*(len(YDivs)+1) cells.
+	RectTypes []LatticeRectType
+	// Bounds optionally limits the lattice to part of the image.
+	Bounds *models.IRect
+	// Colors holds the color of each cell of type
+	// LatticeRectTypeFixedColor, indexed like RectTypes.
+	Colors []color.NRGBA
+}
+
+// latticeSegment is a column or row of a lattice.
+type latticeSegment struct {
+	src0, src1 Scalar
+	dst0, dst1 Scalar
+}
+
+// latticeSegments splits the image span [start, end) at divs and places the
+// parts into [dst0, dst1). It reports false if divs are not increasing
+// inside the span.
+func latticeSegments(divs []int, start, end int, dst0, dst1 Scalar) ([]latticeSegment, bool) {
+	bounds := make([]int, 0, len(divs)+2)
+	bounds = append(bounds, start)
+	for _, d := range divs {
+		if d < bounds[len(bounds)-1] || d > end || (len(bounds) > 1 && d == bounds[len(bounds)-1]) {
+			return nil, false
+		}
+		bounds = append(bounds, d)
+	}
+	bounds = append(bounds, end)
+	// A division on the start leaves the first fixed part empty.
+	scalable := func(i int) bool { return i%2 == 1 }
+
+	var fixed, stretch Scalar
+	for i := 0; i+1 < len(bounds); i++ {
+		size := Scalar(bounds[i+1] - bounds[i])
+		if scalable(i) {
+			stretch += size
+		} else {
+			fixed += size
+		}
+	}
+	length := dst1 - dst0
+	fixedScale, stretchScale := Scalar(1), Scalar(0)
+	switch {
+	case length < fixed:
+		fixedScale = length / fixed
+	case stretch > 0:
+		stretchScale = (length - fixed) / stretch
+	}
+
+	segs := make([]latticeSegment, 0, len(bounds)-1)
+	pos := dst0
+	for i := 0; i+1 < len(bounds); i++ {
+		size := Scalar(bounds[i+1] - bounds[i])
+		if scalable(i) {
+			size *= stretchScale
+		} else {
+			size *= fixedScale
+		}
+		segs = append(segs, latticeSegment{
+			src0: Scalar(bounds[i]), src1: Scalar(bounds[i+1]),
+			dst0: pos, dst1: pos + size,
+		})
+		pos += size
+	}
+	return segs, true
+}
+
+// DrawImageNine draws image stretched into dst, mirroring
+// SkCanvas::drawImageNine. The corners outside center keep their size, the
+// edges stretch along one axis and center stretches along both. If center
+// is empty or not inside the image, the whole image is drawn into dst.
+func (c *canvas) DrawImageNine(image interfaces.SkImage, center models.IRect, dst models.Rect, filter enums.FilterMode, paint SkPaint) {
+	if image == nil {
+		return
+	}
+	if center.Left >= center.Right || center.Top >= center.Bottom || center.Left < 0 || center.Top < 0 ||
+		center.Right > int32(image.Width()) || center.Bottom > int32(image.Height()) {
+		c.DrawImageRectWithSampling(image, nil, dst, models.SamplingOptions{FilterMode: filter}, paint, enums.SrcRectConstraintFast)
+		return
+	}
+	lattice := Lattice{
+		XDivs: []int{int(center.Left), int(center.Right)},
+		YDivs: []int{int(center.Top), int(center.Bottom)},
+	}
+	c.DrawImageLattice(image, lattice, dst, filter, paint)
+}
+
+// DrawImageLattice draws image divided by lattice and stretched into dst,
+// mirroring SkCanvas::drawImageLattice. Nothing is drawn if the lattice is
+// invalid. The image is converted once for all cells and the paint's alpha
+// and color filter apply to all of them.
+func (c *canvas) DrawImageLattice(image interfaces.SkImage, lattice Lattice, dst models.Rect, filter enums.FilterMode, paint SkPaint) {
+	if image == nil || isEmptyRect(dst) || c.cullDraw(dst, 0) {
+		return
+	}
+	bounds := models.IRect{Right: int32(image.Width()), Bottom: int32(image.Height())}
+	if lattice.Bounds != nil {
+		bounds = *lattice.Bounds
+		if bounds.Left < 0 || bounds.Top < 0 || bounds.Right > int32(image.Width()) || bounds.Bottom > int32(image.Height()) || bounds.Left >= bounds.Right || bounds.Top >= bounds.Bottom {
+			return
+		}
+	}
+	cols, ok := latticeSegments(lattice.XDivs, int(bounds.Left), int(bounds.Right), dst.Left, dst.Right)
+	if !ok {
+		return
+	}
+	rows, ok := latticeSegments(lattice.YDivs, int(bounds.Top), int(bounds.Bottom), dst.Top, dst.Bottom)
+	if !ok {
+		return
+	}
+	cells := len(cols) * len(rows)
+	if (lattice.RectTypes != nil && len(lattice.RectTypes) != cells) || (lattice.Colors != nil && len(lattice.Colors) != cells) {
+		return
+	}
+	alpha := float32(1)
+	if paint != nil {
+		if paint.GetBlendModeOr(enums.BlendModeSrcOver) == enums.BlendModeDst {
+			return
+		}
+		alpha = float32(paint.GetAlphaf())
+	}
+	img, imgOp := cachedFilteredImage(image, paint)
+	if img == nil || alpha <= 0 {
+		return
+	}
+	if alpha < 1 {
+		opacity := gpaint.PushOpacity(c.ops, alpha)
+		defer opacity.Pop()
+	}
+	if filter != enums.FilterModeLinear {
+		imgOp.Filter = gpaint.FilterNearest
+	}
+
+	for j, row := range rows {
+		for i, col := range cols {
+			cell := j*len(cols) + i
+			src := models.Rect{Left: col.src0, Top: row.src0, Right: col.src1, Bottom: row.src1}
+			d := models.Rect{Left: col.dst0, Top: row.dst0, Right: col.dst1, Bottom: row.dst1}
+			if isEmptyRect(src) || isEmptyRect(d) {
+				continue
+			}
+			rectType := LatticeRectTypeDefault
+			if lattice.RectTypes != nil {
+				rectType = lattice.RectTypes[cell]
+			}
+			switch rectType {
+			case LatticeRectTypeTransparent:
+			case LatticeRectTypeFixedColor:
+				if lattice.Colors != nil {
+					c.DrawRect(d, NewPaintFill(filterNRGBA(paint, lattice.Colors[cell])))
+				}
+			default:
+				c.drawImageOpRect(imgOp, src, d)
+			}
+		}
+	}
+}
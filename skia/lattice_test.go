@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestLatticeSegments(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		divs       []int
+		dst0, dst1 Scalar
+		want       []latticeSegment
+	}{
+		{
+			name: "nine patch stretched",
+			divs: []int{4, 6}, dst1: 30,
+			want: []latticeSegment{{0, 4, 0, 4}, {4, 6, 4, 26}, {6, 10, 26, 30}},
+		},
+		{
+			name: "nine patch shrunk",
+			divs: []int{4, 6}, dst0: 10, dst1: 14,
+			want: []latticeSegment{{0, 4, 10, 12}, {4, 6, 12, 12}, {6, 10, 12, 14}},
+		},
+		{
+			// A division on the edge makes the first part scalable.
+			name: "scalable first",
+			divs: []int{0, 2, 8}, dst1: 22,
+			want: []latticeSegment{{0, 0, 0, 0}, {0, 2, 0, 8}, {2, 8, 8, 14}, {8, 10, 14, 22}},
+		},
+	} {
+		got, ok := latticeSegments(tc.divs, 0, 10, tc.dst0, tc.dst1)
+		if !ok || len(got) != len(tc.want) {
+			t.Errorf("%s: segments %+v, %v, want %+v", tc.name, got, ok, tc.want)
+			continue
+		}
+		for i := range got {
+			g, w := got[i], tc.want[i]
+			if !near(g.src0, w.src0, 1e-4) || !near(g.src1, w.src1, 1e-4) || !near(g.dst0, w.dst0, 1e-4) || !near(g.dst1, w.dst1, 1e-4) {
+				t.Errorf("%s: segments %+v, want %+v", tc.name, got, tc.want)
+				break
+			}
+		}
+	}
+
+	for _, divs := range [][]int{{5, 3}, {2, 2}, {11}, {-1}} {
+		if _, ok := latticeSegments(divs, 0, 10, 0, 20); ok {
+			t.Errorf("divisions %v should be rejected", divs)
+		}
+	}
+}
+
+func TestCanvas_DrawImageLattice(t *testing.T) {
+	info := models.NewImageInfo(10, 10, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	img := impl.NewRasterImage(info, make([]byte, 10*10*4), 10*4)
+	dst := models.Rect{Left: 2, Top: 2, Right: 40, Bottom: 30}
+
+	c := NewCanvasWithSize(new(op.Ops), 50, 50).(Extended)
+	c.DrawImageNine(img, models.IRect{Left: 3, Top: 3, Right: 7, Bottom: 7}, dst, enums.FilterModeLinear, nil)
+	c.DrawImageLattice(img, Lattice{
+		XDivs:     []int{5},
+		YDivs:     []int{5},
+		RectTypes: []LatticeRectType{LatticeRectTypeDefault, LatticeRectTypeTransparent, LatticeRectTypeFixedColor, LatticeRectTypeDefault},
+		Colors:    make([]color.NRGBA, 4),
+	}, dst, enums.FilterModeNearest, nil)
+	if c.CulledDraws() != 0 {
+		t.Errorf("visible lattice culled")
+	}
+
+	c.DrawImageLattice(img, Lattice{XDivs: []int{5}, RectTypes: make([]LatticeRectType, 3)}, dst, enums.FilterModeNearest, nil)
+	c.DrawImageNine(img, models.IRect{Left: 3, Right: 7, Bottom: 7}, models.Rect{Left: 100, Top: 100, Right: 120, Bottom: 120}, enums.FilterModeLinear, nil)
+	if c.CulledDraws() != 1 {
+		t.Errorf("offscreen lattice should be culled once, culled %d", c.CulledDraws())
+	}
+}
+
+func TestCanvas_DrawImageNine_InvalidCenter(t *testing.T) {
+	PurgeImageCache()
+	defer PurgeImageCache()
+	img := testImage(10)
+	c := NewCanvasWithSize(new(op.Ops), 50, 50).(Extended)
+	// Empty centers and centers outside the image draw the whole image.
+	for _, center := range []models.IRect{
+		{Left: 3, Top: 3, Right: 3, Bottom: 7},
+		{Left: 3, Top: 3, Right: 12, Bottom: 7},
+	} {
+		PurgeImageCache()
+		c.DrawImageNine(img, center, models.Rect{Right: 40, Bottom: 40}, enums.FilterModeLinear, nil)
+		if ImageCacheUsage() == 0 {
+			t.Errorf("center %+v: image not drawn", center)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a concurrency safe least recently used cache bounded by the
+// total cost of its entries.
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	budget  int
+	used    int
+	order   *list.List
+	entries map[K]*list.Element
+	// onEvict, if set, is called for every entry that leaves the cache.
+	onEvict func(K, V)
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	cost  int
+}
+
+func newLRUCache[K comparable, V any](budget int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		budget:  budget,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value for key and marks it as recently used.
+func (c *lruCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*lruEntry[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Put stores value under key, evicting the least recently used entries
+// until the cache fits its budget. Entries costing more than the whole
+// budget are not stored.
+func (c *lruCache[K, V]) Put(key K, value V, cost int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.removeElement(e)
+	}
+	if cost > c.budget {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, cost: cost})
+	c.used += cost
+	c.trim(c.budget)
+}
+
+// Remove drops key from the cache.
+func (c *lruCache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.removeElement(e)
+	}
+}
+
+// RemoveFunc drops every key for which remove returns true.
+func (c *lruCache[K, V]) RemoveFunc(remove func(K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if remove(key) {
+			c.removeElement(e)
+		}
+	}
+}
+
+// Budget returns the budget.
+func (c *lruCache[K, V]) Budget() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.budget
+}
+
+// SetBudget changes the budget, evicting entries if needed.
+func (c *lruCache[K, V]) SetBudget(budget int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.budget = budget
+	c.trim(budget)
+}
+
+// Purge drops every entry.
+func (c *lruCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trim(0)
+}
+
+// Len returns the number of entries.
+func (c *lruCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Used returns the total cost of the entries.
+func (c *lruCache[K, V]) Used() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+// trim evicts entries until the used cost is at most limit.
+func (c *lruCache[K, V]) trim(limit int) {
+	for c.used > limit || (limit == 0 && c.order.Len() > 0) {
+		e := c.order.Back()
+		if e == nil {
+			return
+		}
+		c.removeElement(e)
+	}
+}
+
+func (c *lruCache[K, V]) removeElement(e *list.Element) {
+	entry := e.Value.(*lruEntry[K, V])
+	c.order.Remove(e)
+	delete(c.entries, entry.key)
+	c.used -= entry.cost
+	if c.onEvict != nil {
+		c.onEvict(entry.key, entry.value)
+	}
+}
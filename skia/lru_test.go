@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import "testing"
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[string, int](3)
+	var evicted []string
+	c.onEvict = func(k string, _ int) { evicted = append(evicted, k) }
+
+	c.Put("a", 1, 1)
+	c.Put("b", 2, 1)
+	c.Put("c", 3, 1)
+	c.Get("a")
+	c.Put("d", 4, 1)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Error("a should still be cached")
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("evicted %v, want [b]", evicted)
+	}
+}
+
+func TestLRUCache_Cost(t *testing.T) {
+	c := newLRUCache[int, string](10)
+	c.Put(1, "one", 4)
+	c.Put(2, "two", 4)
+	c.Put(3, "three", 4)
+	if c.Used() != 8 || c.Len() != 2 {
+		t.Errorf("used %d with %d entries, want 8 with 2", c.Used(), c.Len())
+	}
+	c.Put(4, "huge", 11)
+	if _, ok := c.Get(4); ok {
+		t.Error("entries larger than the budget should not be stored")
+	}
+
+	c.Put(2, "two again", 2)
+	if c.Used() != 6 {
+		t.Errorf("replacing an entry should update the cost, used %d", c.Used())
+	}
+
+	c.SetBudget(3)
+	if c.Len() != 1 || c.Used() != 2 {
+		t.Errorf("after shrinking: %d entries using %d", c.Len(), c.Used())
+	}
+	c.Purge()
+	if c.Len() != 0 || c.Used() != 0 {
+		t.Error("purge should empty the cache")
+	}
+}
+
+func TestLRUCache_RemoveFunc(t *testing.T) {
+	c := newLRUCache[int, string](10)
+	for i := 1; i <= 4; i++ {
+		c.Put(i, "", 2)
+	}
+	c.RemoveFunc(func(k int) bool { return k%2 == 0 })
+	if c.Len() != 2 || c.Used() != 4 {
+		t.Errorf("%d entries using %d after removal, want 2 using 4", c.Len(), c.Used())
+	}
+	if _, ok := c.Get(2); ok {
+		t.Error("2 should have been removed")
+	}
+	if c.Budget() != 10 {
+		t.Errorf("budget %d, want 10", c.Budget())
+	}
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+const (
+	// patchPartitionSize is the device length of a patch edge per
+	// subdivision.
+	patchPartitionSize = 10
+	// maxPatchVertices bounds the vertices of a tessellated patch so that
+	// they can be indexed with uint16.
+	maxPatchVertices = 1 << 16
+)
+
+// patchCorners are the indices of the corners of a patch within its
+// cubics, from the top-left corner clockwise.
+var patchCorners = [4]int{0, 3, 6, 9}
+
+// patchEdges returns the top, right, bottom and left edges of a patch. Top
+// and bottom run left to right, left and right run top to bottom.
+func patchEdges(cubics [12]models.Point) (top, right, bottom, left [4]models.Point) {
+	c := cubics
+	top = [4]models.Point{c[0], c[1], c[2], c[3]}
+	right = [4]models.Point{c[3], c[4], c[5], c[6]}
+	bottom = [4]models.Point{c[9], c[8], c[7], c[6]}
+	left = [4]models.Point{c[0], c[11], c[10], c[9]}
+	return
+}
+
+// evalCubic returns the point at t on the cubic Bézier curve p.
+func evalCubic(p [4]models.Point, t Scalar) models.Point {
+	mt := 1 - t
+	a, b, c, d := mt*mt*mt, 3*mt*mt*t, 3*mt*t*t, t*t*t
+	return models.Point{
+		X: a*p[0].X + b*p[1].X + c*p[2].X + d*p[3].X,
+		Y: a*p[0].Y + b*p[1].Y + c*p[2].Y + d*p[3].Y,
+	}
+}
+
+// patchLevelOfDetail returns the number of columns and rows to split a
+// patch into, from the device lengths of its edges under m. It returns 0, 0
+// if the patch is not finite.
+func patchLevelOfDetail(cubics [12]models.Point, m SkMatrix) (lodX, lodY int) {
+	var dev [12]models.Point
+	for i, p := range cubics {
+		dev[i] = perspectiveMap(m, p)
+	}
+	top, right, bottom, left := patchEdges(dev)
+	// The control polygon bounds the length of each edge.
+	length := func(p [4]models.Point) Scalar {
+		return pointLength(subPoint(p[1], p[0])) + pointLength(subPoint(p[2], p[1])) + pointLength(subPoint(p[3], p[2]))
+	}
+	lx := float64(max(length(top), length(bottom)))
+	ly := float64(max(length(left), length(right)))
+	if math.IsNaN(lx) || math.IsNaN(ly) || math.IsInf(lx, 0) || math.IsInf(ly, 0) {
+		return 0, 0
+	}
+	lodX = max(int(math.Ceil(lx/patchPartitionSize)), 1)
+	lodY = max(int(math.Ceil(ly/patchPartitionSize)), 1)
+	if (lodX+1)*(lodY+1) > maxPatchVertices {
+		s := math.Sqrt(float64(maxPatchVertices) / float64((lodX+1)*(lodY+1)))
+		lodX = max(int(float64(lodX)*s)-1, 1)
+		lodY = max(int(float64(lodY)*s)-1, 1)
+	}
+	// Scaling cannot shrink an axis below 1, so thin patches may still
+	// exceed the bound; shorten the longer axis until they fit.
+	for (lodX+1)*(lodY+1) > maxPatchVertices {
+		if lodX >= lodY {
+			lodX = max(maxPatchVertices/(lodY+1)-1, 1)
+		} else {
+			lodY = max(maxPatchVertices/(lodX+1)-1, 1)
+		}
+	}
+	return lodX, lodY
+}
+
+// patchVertices tessellates the Coons patch bounded by cubics into a grid
+// of lodX by lodY cells. Corner colors and texture coordinates, if not nil,
+// are interpolated bilinearly, colors unpremultiplied as Skia does by
+// default.
+func patchVertices(cubics [12]models.Point, colors *[4]color.NRGBA, texCoords *[4]models.Point, lodX, lodY int) *Vertices {
+	top, right, bottom, left := patchEdges(cubics)
+	n := (lodX + 1) * (lodY + 1)
+	pos := make([]models.Point, 0, n)
+	var tex []models.Point
+	if texCoords != nil {
+		tex = make([]models.Point, 0, n)
+	}
+	var cols []color.NRGBA
+	if colors != nil {
+		cols = make([]color.NRGBA, 0, n)
+	}
+	c0, c1, c2, c3 := cubics[patchCorners[0]], cubics[patchCorners[1]], cubics[patchCorners[2]], cubics[patchCorners[3]]
+	for j := 0; j <= lodY; j++ {
+		v := Scalar(j) / Scalar(lodY)
+		l, r := evalCubic(left, v), evalCubic(right, v)
+		for i := 0; i <= lodX; i++ {
+			u := Scalar(i) / Scalar(lodX)
+			t, b := evalCubic(top, u), evalCubic(bottom, u)
+			// The sum of the ruled surfaces between opposite edges, less
+			// the bilinear surface between the corners.
+			w0, w1, w2, w3 := (1-u)*(1-v), u*(1-v), u*v, (1-u)*v
+			pos = append(pos, models.Point{
+				X: (1-v)*t.X + v*b.X + (1-u)*l.X + u*r.X - (w0*c0.X + w1*c1.X + w2*c2.X + w3*c3.X),
+				Y: (1-v)*t.Y + v*b.Y + (1-u)*l.Y + u*r.Y - (w0*c0.Y + w1*c1.Y + w2*c2.Y + w3*c3.Y),
+			})
+			if texCoords != nil {
+				tc := texCoords
+				tex = append(tex, models.Point{
+					X: w0*tc[0].X + w1*tc[1].X + w2*tc[2].X + w3*tc[3].X,
+					Y: w0*tc[0].Y + w1*tc[1].Y + w2*tc[2].Y + w3*tc[3].Y,
+				})
+			}
+			if colors != nil {
+				mix := func(a, b, c, d uint8) uint8 {
+					return uint8(clampUnit(float32(w0)*float32(a)/255+float32(w1)*float32(b)/255+float32(w2)*float32(c)/255+float32(w3)*float32(d)/255)*255 + 0.5)
+				}
+				cc := colors
+				cols = append(cols, color.NRGBA{
+					R: mix(cc[0].R, cc[1].R, cc[2].R, cc[3].R),
+					G: mix(cc[0].G, cc[1].G, cc[2].G, cc[3].G),
+					B: mix(cc[0].B, cc[1].B, cc[2].B, cc[3].B),
+					A: mix(cc[0].A, cc[1].A, cc[2].A, cc[3].A),
+				})
+			}
+		}
+	}
+	indices := make([]uint16, 0, 6*lodX*lodY)
+	for j := 0; j < lodY; j++ {
+		for i := 0; i < lodX; i++ {
+			a := uint16(j*(lodX+1) + i)
+			b, c, d := a+1, a+uint16(lodX)+2, a+uint16(lodX)+1
+			indices = append(indices, a, b, c, a, c, d)
+		}
+	}
+	return NewVertices(VertexModeTriangles, pos, tex, cols, indices)
+}
+
+// DrawPatch draws the Coons patch bounded by cubics, mirroring
+// SkCanvas::drawPatch. cubics holds four cubic curves sharing end points,
+// clockwise from the top-left corner: top, right, bottom and left. colors
+// and texCoords are optional and hold the values at the corners, in the
+// same order; mode blends the paint's shader onto the colors as in
+// DrawVertices. The patch is tessellated more finely the larger it is on
+// the device.
+func (c *canvas) DrawPatch(cubics [12]models.Point, colors *[4]color.NRGBA, texCoords *[4]models.Point, mode enums.BlendMode, paint SkPaint) {
+	bounds := models.Rect{Left: cubics[0].X, Top: cubics[0].Y, Right: cubics[0].X, Bottom: cubics[0].Y}
+	for _, p := range cubics[1:] {
+		bounds = models.Rect{
+			Left:   min(bounds.Left, p.X),
+			Top:    min(bounds.Top, p.Y),
+			Right:  max(bounds.Right, p.X),
+			Bottom: max(bounds.Bottom, p.Y),
+		}
+	}
+	// The control points bound the patch.
+	if c.cullDraw(bounds, 0) {
+		return
+	}
+	lodX, lodY := patchLevelOfDetail(cubics, c.stack[len(c.stack)-1].matrix())
+	if lodX == 0 {
+		return
+	}
+	// The tessellation is made for this draw, so its raster is not cached.
+	c.drawVertices(patchVertices(cubics, colors, texCoords, lodX, lodY), mode, paint, false)
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// rectPatch returns the cubics of a patch with straight edges covering the
+// rectangle from (0, 0) to (w, h).
+func rectPatch(w, h Scalar) [12]models.Point {
+	return [12]models.Point{
+		{X: 0, Y: 0}, {X: w / 3, Y: 0}, {X: 2 * w / 3, Y: 0},
+		{X: w, Y: 0}, {X: w, Y: h / 3}, {X: w, Y: 2 * h / 3},
+		{X: w, Y: h}, {X: 2 * w / 3, Y: h}, {X: w / 3, Y: h},
+		{X: 0, Y: h}, {X: 0, Y: 2 * h / 3}, {X: 0, Y: h / 3},
+	}
+}
+
+func TestPatchVertices_Flat(t *testing.T) {
+	tex := [4]models.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}
+	v := patchVertices(rectPatch(40, 20), nil, &tex, 4, 2)
+	if v == nil || v.VertexCount() != 15 || v.IndexCount() != 48 {
+		t.Fatalf("vertices %+v", v)
+	}
+	// A patch with straight edges is a regular grid.
+	for j := 0; j <= 2; j++ {
+		for i := 0; i <= 4; i++ {
+			k := j*5 + i
+			p, tc := v.positions[k], v.texCoords[k]
+			if !near(p.X, Scalar(i)*10, 1e-3) || !near(p.Y, Scalar(j)*10, 1e-3) {
+				t.Errorf("vertex (%d, %d) at %v", i, j, p)
+			}
+			if !near(tc.X, Scalar(i)/4, 1e-4) || !near(tc.Y, Scalar(j)/2, 1e-4) {
+				t.Errorf("vertex (%d, %d) texture coordinate %v", i, j, tc)
+			}
+		}
+	}
+}
+
+func TestPatchVertices_Curved(t *testing.T) {
+	// Bowing the top edge up moves the interior of the patch with it,
+	// fading toward the bottom.
+	cubics := rectPatch(30, 30)
+	cubics[1].Y, cubics[2].Y = -12, -12
+	v := patchVertices(cubics, nil, nil, 2, 2)
+	top, middle, bottom := v.positions[1], v.positions[4], v.positions[7]
+	if !near(top.Y, -9, 1e-3) || !near(middle.Y, 15-4.5, 1e-3) || !near(bottom.Y, 30, 1e-3) {
+		t.Errorf("center column at %v, %v, %v", top, middle, bottom)
+	}
+}
+
+func TestPatchLevelOfDetail(t *testing.T) {
+	cubics := rectPatch(100, 20)
+	if x, y := patchLevelOfDetail(cubics, impl.NewMatrixIdentity()); x != 10 || y != 2 {
+		t.Errorf("identity level of detail %d, %d", x, y)
+	}
+	if x, y := patchLevelOfDetail(cubics, impl.NewMatrixScale(3, 0.1)); x != 30 || y != 1 {
+		t.Errorf("scaled level of detail %d, %d", x, y)
+	}
+	if x, y := patchLevelOfDetail(rectPatch(1e6, 1e6), impl.NewMatrixIdentity()); (x+1)*(y+1) > maxPatchVertices {
+		t.Errorf("huge patch level of detail %d, %d", x, y)
+	}
+
+	// A very wide, thin patch only has one row to spare.
+	x, y := patchLevelOfDetail(rectPatch(70000*patchPartitionSize, 1), impl.NewMatrixIdentity())
+	if (x+1)*(y+1) > maxPatchVertices || y != 1 {
+		t.Fatalf("wide patch level of detail %d, %d", x, y)
+	}
+	v := patchVertices(rectPatch(70000*patchPartitionSize, 1), nil, nil, x, y)
+	// Wrapped indices would never reach the last vertex.
+	top := 0
+	for _, i := range v.indices {
+		top = max(top, int(i))
+	}
+	if n := len(v.positions); top != n-1 {
+		t.Errorf("largest index %d of %d vertices", top, n)
+	}
+}
+
+func TestPatch_Colors(t *testing.T) {
+	colors := [4]color.NRGBA{{R: 255, A: 255}, {R: 255, A: 255}, {B: 255, A: 255}, {B: 255, A: 255}}
+	v := patchVertices(rectPatch(20, 20), &colors, nil, 2, 2)
+	dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	rasterVertices(dst, v, enums.BlendModeModulate, NewPaint(), impl.NewMatrixIdentity())
+	if got := dst.RGBAAt(10, 0); got.R < 240 || got.B > 15 {
+		t.Errorf("top = %v, want red", got)
+	}
+	if got := dst.RGBAAt(10, 19); got.B < 240 || got.R > 15 {
+		t.Errorf("bottom = %v, want blue", got)
+	}
+	if got := dst.RGBAAt(3, 10); absInt(int(got.R)-int(got.B)) > 15 {
+		t.Errorf("middle = %v, want an even mix", got)
+	}
+}
+
+func TestCanvas_DrawPatch(t *testing.T) {
+	colors := [4]color.NRGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}, {A: 255}}
+	c := NewCanvasWithSize(new(op.Ops), 50, 50).(Extended)
+	c.DrawPatch(rectPatch(40, 40), nil, nil, enums.BlendModeModulate, NewPaint())
+	c.DrawPatch(rectPatch(40, 40), &colors, nil, enums.BlendModeModulate, NewPaint())
+	if c.CulledDraws() != 0 {
+		t.Errorf("visible patch culled")
+	}
+	c.Translate(200, 0)
+	c.DrawPatch(rectPatch(40, 40), &colors, nil, enums.BlendModeModulate, NewPaint())
+	if c.CulledDraws() != 1 {
+		t.Errorf("offscreen patch should be culled")
+	}
+}
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"unsafe"
+
+	"gioui.org/f32"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Converted path cache. Converting and stroking a path is far more
+// expensive than replaying the result, so the segments to fill for a path,
+// its outline if stroked, are kept in local coordinates and reused while
+// the path and paint are unchanged, whatever the transform.
+
+// pathCacheBudget is the number of bytes of converted segments kept for
+// reuse.
+const pathCacheBudget = 8 << 20
+
+// pathKey identifies converted segments. SkPath has no generation ID, so
+// the path is identified by a hash of its contents; an edited path hashes
+// differently and misses the cache. Hits are verified against the
+// contents stored with the entry.
+type pathKey struct {
+	hash   uint64
+	verbs  int
+	points int
+	// tol is the conversion tolerance rounded down to a power of two, so
+	// small changes of scale reuse the entry.
+	tol   Scalar
+	fill  bool
+	width float32
+	miter float32
+	cap   stroke.CapStyle
+	join  stroke.JoinStyle
+	// dash holds the dash intervals as little-endian float32 bits.
+	dash  string
+	dash0 float32
+}
+
+// cachedOutline is the converted segments of a path and the path they
+// were made from.
+type cachedOutline struct {
+	path    pathContents
+	outline recordedPath
+}
+
+var pathCache = newLRUCache[pathKey, cachedOutline](pathCacheBudget)
+
+// pathContents holds the verbs, points and conic weights of a path.
+type pathContents struct {
+	verbs   []enums.PathVerb
+	points  []models.Point
+	weights []Scalar
+}
+
+func readPathContents(path SkPath) pathContents {
+	p := pathContents{
+		verbs:   make([]enums.PathVerb, path.CountVerbs()),
+		points:  make([]models.Point, path.CountPoints()),
+		weights: path.ConicWeights(),
+	}
+	path.GetVerbs(p.verbs)
+	path.GetPoints(p.points)
+	p.weights = append([]Scalar(nil), p.weights...)
+	return p
+}
+
+// hash returns the 64-bit FNV-1a hash of the contents.
+func (p pathContents) hash() uint64 {
+	const prime = 1099511628211
+	h := uint64(14695981039346656037)
+	word := func(v uint32) {
+		for i := 0; i < 4; i++ {
+			h = (h ^ uint64(byte(v>>(8*i)))) * prime
+		}
+	}
+	for _, v := range p.verbs {
+		h = (h ^ uint64(v)) * prime
+	}
+	for _, pt := range p.points {
+		word(math.Float32bits(float32(pt.X)))
+		word(math.Float32bits(float32(pt.Y)))
+	}
+	for _, w := range p.weights {
+		word(math.Float32bits(float32(w)))
+	}
+	return h
+}
+
+func (p pathContents) equal(q pathContents) bool {
+	return slices.Equal(p.verbs, q.verbs) && slices.Equal(p.points, q.points) && slices.Equal(p.weights, q.weights)
+}
+
+// cost returns the memory used by p in bytes.
+func (p pathContents) cost() int {
+	return cap(p.verbs)*int(unsafe.Sizeof(enums.PathVerb(0))) +
+		cap(p.points)*int(unsafe.Sizeof(models.Point{})) +
+		cap(p.weights)*int(unsafe.Sizeof(Scalar(0)))
+}
+
+// pathSegment is a segment of a recordedPath.
+type pathSegment struct {
+	verb enums.PathVerb
+	pts  [3]f32.Point
+}
+
+// recordedPath is a list of normalized segments that can be replayed into
+// any pathSink.
+type recordedPath []pathSegment
+
+func (r *recordedPath) MoveTo(to f32.Point) {
+	*r = append(*r, pathSegment{verb: enums.PathVerbMove, pts: [3]f32.Point{to}})
+}
+
+func (r *recordedPath) LineTo(to f32.Point) {
+	*r = append(*r, pathSegment{verb: enums.PathVerbLine, pts: [3]f32.Point{to}})
+}
+
+func (r *recordedPath) QuadTo(ctrl, to f32.Point) {
+	*r = append(*r, pathSegment{verb: enums.PathVerbQuad, pts: [3]f32.Point{ctrl, to}})
+}
+
+func (r *recordedPath) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	*r = append(*r, pathSegment{verb: enums.PathVerbCubic, pts: [3]f32.Point{ctrl0, ctrl1, to}})
+}
+
+func (r *recordedPath) Close() {
+	*r = append(*r, pathSegment{verb: enums.PathVerbClose})
+}
+
+// replay emits the recorded segments to sink.
+func (r recordedPath) replay(sink pathSink) {
+	for _, s := range r {
+		switch s.verb {
+		case enums.PathVerbMove:
+			sink.MoveTo(s.pts[0])
+		case enums.PathVerbLine:
+			sink.LineTo(s.pts[0])
+		case enums.PathVerbQuad:
+			sink.QuadTo(s.pts[0], s.pts[1])
+		case enums.PathVerbCubic:
+			sink.CubeTo(s.pts[0], s.pts[1], s.pts[2])
+		case enums.PathVerbClose:
+			sink.Close()
+		}
+	}
+}
+
+// area returns the signed area of the polygons through the points of r,
+// control points included, positive for clockwise contours in y-down
+// coordinates. Its sign gives the direction of a path.
+func (r recordedPath) area() float32 {
+	var area float32
+	var start, pen f32.Point
+	edge := func(to f32.Point) {
+		area += pen.X*to.Y - to.X*pen.Y
+		pen = to
+	}
+	for _, s := range r {
+		switch s.verb {
+		case enums.PathVerbMove:
+			edge(start)
+			start, pen = s.pts[0], s.pts[0]
+		case enums.PathVerbLine:
+			edge(s.pts[0])
+		case enums.PathVerbQuad:
+			edge(s.pts[0])
+			edge(s.pts[1])
+		case enums.PathVerbCubic:
+			edge(s.pts[0])
+			edge(s.pts[1])
+			edge(s.pts[2])
+		}
+	}
+	edge(start)
+	return area / 2
+}
+
+// contours returns the contours of r, each starting with its move.
+func (r recordedPath) contours() []recordedPath {
+	var contours []recordedPath
+	for len(r) > 0 {
+		n := 1
+		for n < len(r) && r[n].verb != enums.PathVerbMove {
+			n++
+		}
+		contours = append(contours, r[:n])
+		r = r[n:]
+	}
+	return contours
+}
+
+// reversed returns r with every contour traversed backwards.
+func (r recordedPath) reversed() recordedPath {
+	out := make(recordedPath, 0, len(r))
+	for _, contour := range r.contours() {
+		closed := contour[len(contour)-1].verb == enums.PathVerbClose
+		if closed {
+			contour = contour[:len(contour)-1]
+		}
+		if contour[0].verb != enums.PathVerbMove {
+			continue
+		}
+		end := func(s pathSegment) f32.Point {
+			switch s.verb {
+			case enums.PathVerbQuad:
+				return s.pts[1]
+			case enums.PathVerbCubic:
+				return s.pts[2]
+			}
+			return s.pts[0]
+		}
+		out.MoveTo(end(contour[len(contour)-1]))
+		for i := len(contour) - 1; i > 0; i-- {
+			s, to := contour[i], end(contour[i-1])
+			switch s.verb {
+			case enums.PathVerbLine:
+				out.LineTo(to)
+			case enums.PathVerbQuad:
+				out.QuadTo(s.pts[0], to)
+			case enums.PathVerbCubic:
+				out.CubeTo(s.pts[1], s.pts[0], to)
+			}
+		}
+		if closed {
+			out.Close()
+		}
+	}
+	return out
+}
+
+// cost returns the memory used by r in bytes.
+func (r recordedPath) cost() int {
+	return cap(r) * int(unsafe.Sizeof(pathSegment{}))
+}
+
+// convertPaintPath emits to sink the segments to fill for path drawn with
+// paint: the path itself for fills and its stroked outline otherwise,
+// converted within tol local units. The segments are cached if cache is
+// set; paths drawn once, such as positioned glyphs, should not be.
+func convertPaintPath(path SkPath, paint Paint, tol Scalar, cache bool, sink pathSink) {
+	tol = Scalar(math.Ldexp(1, math.Ilogb(float64(tol))))
+	if !cache {
+		appendPaintPath(sink, path, paint, tol)
+		return
+	}
+	cachedPaintPath(path, paint, tol).replay(sink)
+}
+
+// appendPaintPath emits to sink the segments to fill for path drawn with
+// paint, converted within tol local units.
+func appendPaintPath(sink pathSink, path SkPath, paint Paint, tol Scalar) {
+	if paint.Fill {
+		convertPath(path, tol, sink)
+		return
+	}
+	appendStrokedContours(sink, stroke.StrokedContours(toStrokePath(path, tol), paint.Stroke))
+}
+
+// cachedPaintPath returns the segments to fill for path drawn with paint,
+// converted within tol local units, from the cache if possible.
+func cachedPaintPath(path SkPath, paint Paint, tol Scalar) recordedPath {
+	contents := readPathContents(path)
+	key := makePathKey(contents, paint, tol)
+	if e, ok := pathCache.Get(key); ok && e.path.equal(contents) {
+		return e.outline
+	}
+	var r recordedPath
+	appendPaintPath(&r, path, paint, tol)
+	r = r[:len(r):len(r)]
+	pathCache.Put(key, cachedOutline{path: contents, outline: r}, r.cost()+contents.cost())
+	return r
+}
+
+func makePathKey(contents pathContents, paint Paint, tol Scalar) pathKey {
+	key := pathKey{
+		hash:   contents.hash(),
+		verbs:  len(contents.verbs),
+		points: len(contents.points),
+		tol:    tol,
+		fill:   paint.Fill,
+	}
+	if paint.Fill {
+		return key
+	}
+	key.width, key.miter = paint.Stroke.Width, paint.Stroke.Miter
+	key.cap, key.join = paint.Stroke.Cap, paint.Stroke.Join
+	if len(paint.Stroke.Dash) > 0 {
+		var buf []byte
+		for _, d := range paint.Stroke.Dash {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(d))
+		}
+		key.dash, key.dash0 = string(buf), paint.Stroke.Dash0
+	}
+	return key
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"reflect"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestPathCache_ReusedAcrossTransforms(t *testing.T) {
+	pathCache.Purge()
+	defer pathCache.Purge()
+
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(0, 0, 40, enums.PathDirectionCW)
+	paint := NewPaint()
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(4)
+
+	canvas := NewCanvas(new(op.Ops))
+	canvas.DrawPath(path, paint)
+	canvas.Translate(100, 50)
+	canvas.Rotate(30)
+	canvas.DrawPath(path, paint)
+
+	// A new path with the same contents is the same geometry.
+	same := impl.NewSkPath(enums.PathFillTypeWinding)
+	same.AddCircle(0, 0, 40, enums.PathDirectionCW)
+	canvas.DrawPath(same, paint)
+	if n := pathCache.Len(); n != 1 {
+		t.Fatalf("expected 1 cached path, got %d", n)
+	}
+
+	paint.SetStrokeWidth(6)
+	canvas.DrawPath(path, paint)
+	if n := pathCache.Len(); n != 2 {
+		t.Errorf("stroke width should key the cache, got %d entries", n)
+	}
+
+	// Fills are cached apart from strokes, whatever the stroke settings.
+	paint.SetStyle(enums.PaintStyleFill)
+	canvas.DrawPath(path, paint)
+	paint.SetStrokeWidth(4)
+	canvas.DrawPath(path, paint)
+	if n := pathCache.Len(); n != 3 {
+		t.Errorf("fills should be cached once, got %d entries", n)
+	}
+	paint.SetStyle(enums.PaintStyleStroke)
+
+	path.LineTo(100, 100)
+	canvas.DrawPath(path, paint)
+	if n := pathCache.Len(); n != 4 {
+		t.Errorf("edited path should miss the cache, got %d entries", n)
+	}
+
+	// A large change of scale converts again at a finer tolerance.
+	canvas.Scale(16, 16)
+	canvas.DrawPath(path, paint)
+	if n := pathCache.Len(); n != 5 {
+		t.Errorf("scaled path should miss the cache, got %d entries", n)
+	}
+}
+
+func TestPathCache_SkipsGlyphs(t *testing.T) {
+	pathCache.Purge()
+	defer pathCache.Purge()
+
+	paint := NewPaint()
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(1)
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 20)
+	canvas := NewCanvas(new(op.Ops))
+	for x := Scalar(0); x < 5; x++ {
+		canvas.DrawString("moving", x, 20, font, paint)
+	}
+	if n := pathCache.Len(); n != 0 {
+		t.Errorf("glyph strokes should not be cached, got %d entries", n)
+	}
+}
+
+// strokePaint is a 2 unit wide stroke.
+var strokePaint = Paint{Stroke: stroke.StrokeOpts{Width: 2, Miter: 4}}
+
+func TestPathCache_MatchesConversion(t *testing.T) {
+	pathCache.Purge()
+	defer pathCache.Purge()
+
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddOval(models.Rect{Left: 0, Top: 0, Right: 50, Bottom: 30}, enums.PathDirectionCW)
+
+	var want recordedPath
+	convertPaintPath(path, strokePaint, 0.25, false, &want)
+	for i := 0; i < 2; i++ {
+		var got recordedPath
+		convertPaintPath(path, strokePaint, 0.25, true, &got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("draw %d: cached segments differ from conversion", i)
+		}
+	}
+}
+
+func TestPathCache_VerifiesHits(t *testing.T) {
+	pathCache.Purge()
+	defer pathCache.Purge()
+
+	a := impl.NewSkPath(enums.PathFillTypeWinding)
+	a.AddRect(models.Rect{Left: 0, Top: 0, Right: 10, Bottom: 10}, enums.PathDirectionCW, 0)
+	b := impl.NewSkPath(enums.PathFillTypeWinding)
+	b.AddRect(models.Rect{Left: 0, Top: 0, Right: 50, Bottom: 50}, enums.PathDirectionCW, 0)
+
+	// Store b's outline under a's key, as a hash collision would.
+	key := makePathKey(readPathContents(a), strokePaint, 1)
+	wrong := cachedPaintPath(b, strokePaint, 1)
+	pathCache.Put(key, cachedOutline{path: readPathContents(b), outline: wrong}, 1)
+
+	var want recordedPath
+	convertPaintPath(a, strokePaint, 1, false, &want)
+	if got := cachedPaintPath(a, strokePaint, 1); !reflect.DeepEqual(got, want) {
+		t.Error("colliding entry was used for a different path")
+	}
+}
+
+func TestPathCache_Budget(t *testing.T) {
+	pathCache.Purge()
+	defer func() {
+		pathCache.SetBudget(pathCacheBudget)
+		pathCache.Purge()
+	}()
+
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRect(models.Rect{Left: 0, Top: 0, Right: 10, Bottom: 10}, enums.PathDirectionCW, 0)
+	cachedPaintPath(path, strokePaint, 1)
+	cost := pathCache.Used()
+	pathCache.SetBudget(2 * cost)
+
+	for i := 0; i < 4; i++ {
+		path.Offset(1, 0)
+		cachedPaintPath(path, strokePaint, 1)
+	}
+	if used := pathCache.Used(); used > 2*cost {
+		t.Errorf("cache uses %d bytes, budget %d", used, 2*cost)
+	}
+	if n := pathCache.Len(); n != 2 {
+		t.Errorf("expected the 2 most recent paths, got %d", n)
+	}
+}
+
+func TestRecordedPath_Reversed(t *testing.T) {
+	var r recordedPath
+	r.MoveTo(f32.Pt(0, 0))
+	r.LineTo(f32.Pt(10, 0))
+	r.CubeTo(f32.Pt(12, 2), f32.Pt(12, 8), f32.Pt(10, 10))
+	r.QuadTo(f32.Pt(5, 12), f32.Pt(0, 10))
+	r.Close()
+	r.MoveTo(f32.Pt(20, 0))
+	r.LineTo(f32.Pt(30, 0))
+
+	var want recordedPath
+	want.MoveTo(f32.Pt(0, 10))
+	want.QuadTo(f32.Pt(5, 12), f32.Pt(10, 10))
+	want.CubeTo(f32.Pt(12, 8), f32.Pt(12, 2), f32.Pt(10, 0))
+	want.LineTo(f32.Pt(0, 0))
+	want.Close()
+	want.MoveTo(f32.Pt(30, 0))
+	want.LineTo(f32.Pt(20, 0))
+
+	got := r.reversed()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reversed = %v, want %v", got, want)
+	}
+	if a, b := r.area(), got.area(); a <= 0 || b != -a {
+		t.Errorf("areas %v and %v, want a positive area and its negation", a, b)
+	}
+}
@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/op/clip"
+	andyStroke "github.com/andybalholm/stroke"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Path conversion. Every use of SkPath geometry — fills, strokes, clips and
+// perspective mapping — walks the path through convertPath, so they all see
+// the same segments.
+
+// conicTolerance is the largest distance in device pixels between a conic
+// and the quadratics approximating it.
+const conicTolerance = 0.25
+
+// pathSink receives normalized path segments. *clip.Path implements it.
+type pathSink interface {
+	MoveTo(to f32.Point)
+	LineTo(to f32.Point)
+	QuadTo(ctrl, to f32.Point)
+	CubeTo(ctrl0, ctrl1, to f32.Point)
+	Close()
+}
+
+var _ pathSink = (*clip.Path)(nil)
+
+// convertPath emits the segments of path to sink. The output is normalized:
+//   - every contour starts with MoveTo, including contours that continue
+//     after Close without one, which start where the closed contour did;
+//   - contours without segments are dropped;
+//   - segments that do not move the pen are dropped;
+//   - conics are approximated by quadratics within tol;
+//   - Close follows only contours with segments. It implies a line back to
+//     the start of the contour.
+func convertPath(path SkPath, tol Scalar, sink pathSink) {
+	if path == nil || path.IsEmpty() {
+		return
+	}
+	verbs := make([]enums.PathVerb, path.CountVerbs())
+	path.GetVerbs(verbs)
+	points := make([]models.Point, path.CountPoints())
+	path.GetPoints(points)
+	iter := impl.NewPathIter(points, verbs, path.ConicWeights())
+
+	var (
+		start, pen models.Point
+		// open is set between a contour's MoveTo and its Close.
+		open bool
+		// started is set once the contour's MoveTo has been emitted.
+		started bool
+	)
+	begin := func() {
+		if !started {
+			sink.MoveTo(f32Pt(start))
+			started = true
+		}
+	}
+	for rec := iter.Next(); rec != nil; rec = iter.Next() {
+		pts := rec.Points
+		if len(pts) == 0 {
+			continue
+		}
+		switch rec.Verb {
+		case enums.PathVerbMove:
+			start, pen = pts[0], pts[0]
+			open, started = true, false
+			continue
+		case enums.PathVerbClose:
+			if started {
+				sink.Close()
+			}
+			pen = start
+			open, started = false, false
+			continue
+		}
+		if !open {
+			// Implicit moveTo after close.
+			start = pen
+			open, started = true, false
+		}
+		switch rec.Verb {
+		case enums.PathVerbLine:
+			if pts[1] == pen {
+				continue
+			}
+			begin()
+			sink.LineTo(f32Pt(pts[1]))
+			pen = pts[1]
+		case enums.PathVerbQuad:
+			if pts[1] == pen && pts[2] == pen {
+				continue
+			}
+			begin()
+			sink.QuadTo(f32Pt(pts[1]), f32Pt(pts[2]))
+			pen = pts[2]
+		case enums.PathVerbConic:
+			if pts[1] == pen && pts[2] == pen {
+				continue
+			}
+			begin()
+			for _, q := range conicToQuads(pen, pts[1], pts[2], rec.ConicWeight, tol) {
+				sink.QuadTo(f32Pt(q[1]), f32Pt(q[2]))
+			}
+			pen = pts[2]
+		case enums.PathVerbCubic:
+			if pts[1] == pen && pts[2] == pen && pts[3] == pen {
+				continue
+			}
+			begin()
+			sink.CubeTo(f32Pt(pts[1]), f32Pt(pts[2]), f32Pt(pts[3]))
+			pen = pts[3]
+		}
+	}
+}
+
+func f32Pt(p models.Point) f32.Point {
+	return f32.Pt(float32(p.X), float32(p.Y))
+}
+
+// strokePathSink builds the stroker's representation of a path. The
+// stroker treats contours ending at their start as closed, so Close adds
+// the closing line.
+type strokePathSink struct {
+	path       stroke.Path
+	start, pen f32.Point
+}
+
+func (s *strokePathSink) MoveTo(to f32.Point) {
+	s.path.Segments = append(s.path.Segments, stroke.MoveTo(to))
+	s.start, s.pen = to, to
+}
+
+func (s *strokePathSink) LineTo(to f32.Point) {
+	s.path.Segments = append(s.path.Segments, stroke.LineTo(to))
+	s.pen = to
+}
+
+func (s *strokePathSink) QuadTo(ctrl, to f32.Point) {
+	s.path.Segments = append(s.path.Segments, stroke.QuadTo(ctrl, to))
+	s.pen = to
+}
+
+func (s *strokePathSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	s.path.Segments = append(s.path.Segments, stroke.CubeTo(ctrl0, ctrl1, to))
+	s.pen = to
+}
+
+func (s *strokePathSink) Close() {
+	if s.pen != s.start {
+		s.LineTo(s.start)
+	}
+}
+
+// toStrokePath converts path to the stroker's representation.
+func toStrokePath(path SkPath, tol Scalar) stroke.Path {
+	var s strokePathSink
+	convertPath(path, tol, &s)
+	return s.path
+}
+
+// skPathSink appends segments to an SkPath.
+type skPathSink struct {
+	path SkPath
+}
+
+func (s skPathSink) MoveTo(to f32.Point) {
+	s.path.MoveTo(Scalar(to.X), Scalar(to.Y))
+}
+
+func (s skPathSink) LineTo(to f32.Point) {
+	s.path.LineTo(Scalar(to.X), Scalar(to.Y))
+}
+
+func (s skPathSink) QuadTo(ctrl, to f32.Point) {
+	s.path.QuadTo(Scalar(ctrl.X), Scalar(ctrl.Y), Scalar(to.X), Scalar(to.Y))
+}
+
+func (s skPathSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	s.path.CubicTo(Scalar(ctrl0.X), Scalar(ctrl0.Y), Scalar(ctrl1.X), Scalar(ctrl1.Y), Scalar(to.X), Scalar(to.Y))
+}
+
+func (s skPathSink) Close() {
+	s.path.Close()
+}
+
+// appendStrokedContours emits the contours produced by the stroker as
+// closed contours.
+func appendStrokedContours(sink pathSink, contours [][]andyStroke.Segment) {
+	for _, contour := range contours {
+		for i, seg := range contour {
+			if i == 0 {
+				sink.MoveTo(f32.Point(seg.Start))
+			}
+			sink.CubeTo(f32.Point(seg.CP1), f32.Point(seg.CP2), f32.Point(seg.End))
+		}
+		if len(contour) > 0 {
+			sink.Close()
+		}
+	}
+}
+
+// appendOpenContours emits the contours produced by the dasher without
+// closing them. Straight segments are emitted as lines.
+func appendOpenContours(sink pathSink, contours [][]andyStroke.Segment) {
+	for _, contour := range contours {
+		for i, seg := range contour {
+			if i == 0 {
+				sink.MoveTo(f32.Point(seg.Start))
+			}
+			if isLinearSegment(seg) {
+				sink.LineTo(f32.Point(seg.End))
+			} else {
+				sink.CubeTo(f32.Point(seg.CP1), f32.Point(seg.CP2), f32.Point(seg.End))
+			}
+		}
+	}
+}
+
+// isLinearSegment reports whether the control points of seg lie on the
+// line between its ends, in order.
+func isLinearSegment(seg andyStroke.Segment) bool {
+	const eps = 1e-4
+	d := f32.Point(seg.End).Sub(f32.Point(seg.Start))
+	l2 := d.X*d.X + d.Y*d.Y
+	if l2 == 0 {
+		return seg.CP1 == seg.Start && seg.CP2 == seg.Start
+	}
+	for _, cp := range [2]andyStroke.Point{seg.CP1, seg.CP2} {
+		v := f32.Point(cp).Sub(f32.Point(seg.Start))
+		cross := v.X*d.Y - v.Y*d.X
+		dot := v.X*d.X + v.Y*d.Y
+		if cross*cross > eps*eps*l2*l2 || dot < 0 || dot > l2 {
+			return false
+		}
+	}
+	return true
+}
+
+// pathTolerance returns the tolerance in local units that corresponds to
+// tol device pixels under the current transform.
+func (c *canvas) pathTolerance(tol Scalar) Scalar {
+	return tol / c.resScale()
+}
+
+// resScale returns the largest scale of the current affine transform, the
+// number of device pixels a local unit can cover.
+func (c *canvas) resScale() Scalar {
+	sx, hx, _, hy, sy, _ := c.stack[len(c.stack)-1].xform.Elems()
+	scale := Scalar(math.Max(math.Hypot(float64(sx), float64(hy)), math.Hypot(float64(hx), float64(sy))))
+	if !(scale > 0) {
+		return 1
+	}
+	return scale
+}
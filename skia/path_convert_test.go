@@ -0,0 +1,327 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"gioui.org/f32"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// randomPath is a path built from random verbs on a coarse grid, so that
+// degenerate segments, lines after close and empty contours are common.
+type randomPath struct {
+	path SkPath
+	desc string
+}
+
+func (randomPath) Generate(r *rand.Rand, size int) reflect.Value {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	var desc strings.Builder
+	pt := func() (Scalar, Scalar) { return Scalar(r.Intn(4) * 10), Scalar(r.Intn(4) * 10) }
+	for i := r.Intn(size + 1); i >= 0; i-- {
+		switch r.Intn(6) {
+		case 0:
+			x, y := pt()
+			path.MoveTo(x, y)
+			fmt.Fprintf(&desc, "M%v,%v ", x, y)
+		case 1:
+			x, y := pt()
+			path.LineTo(x, y)
+			fmt.Fprintf(&desc, "L%v,%v ", x, y)
+		case 2:
+			x1, y1 := pt()
+			x2, y2 := pt()
+			path.QuadTo(x1, y1, x2, y2)
+			fmt.Fprintf(&desc, "Q%v,%v,%v,%v ", x1, y1, x2, y2)
+		case 3:
+			x1, y1 := pt()
+			x2, y2 := pt()
+			w := Scalar(0.25 + 2*r.Float64())
+			path.ConicTo(x1, y1, x2, y2, w)
+			fmt.Fprintf(&desc, "K%v,%v,%v,%v,%v ", x1, y1, x2, y2, w)
+		case 4:
+			x1, y1 := pt()
+			x2, y2 := pt()
+			x3, y3 := pt()
+			path.CubicTo(x1, y1, x2, y2, x3, y3)
+			fmt.Fprintf(&desc, "C%v,%v,%v,%v,%v,%v ", x1, y1, x2, y2, x3, y3)
+		case 5:
+			path.Close()
+			desc.WriteString("Z ")
+		}
+	}
+	return reflect.ValueOf(randomPath{path: path, desc: desc.String()})
+}
+
+func (p randomPath) String() string {
+	return p.desc
+}
+
+// pathEvent is a segment recorded by recordingSink.
+type pathEvent struct {
+	verb string
+	pts  []f32.Point
+}
+
+// recordingSink records the segments it receives.
+type recordingSink struct {
+	events []pathEvent
+}
+
+func (s *recordingSink) MoveTo(to f32.Point) {
+	s.events = append(s.events, pathEvent{"M", []f32.Point{to}})
+}
+
+func (s *recordingSink) LineTo(to f32.Point) {
+	s.events = append(s.events, pathEvent{"L", []f32.Point{to}})
+}
+
+func (s *recordingSink) QuadTo(ctrl, to f32.Point) {
+	s.events = append(s.events, pathEvent{"Q", []f32.Point{ctrl, to}})
+}
+
+func (s *recordingSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	s.events = append(s.events, pathEvent{"C", []f32.Point{ctrl0, ctrl1, to}})
+}
+
+func (s *recordingSink) Close() {
+	s.events = append(s.events, pathEvent{verb: "Z"})
+}
+
+// polylines flattens recorded events to one polyline per contour, with
+// closes as explicit lines.
+func polylines(events []pathEvent) [][]f32.Point {
+	var lines [][]f32.Point
+	var start f32.Point
+	for _, e := range events {
+		if e.verb == "M" {
+			start = e.pts[0]
+			lines = append(lines, []f32.Point{start})
+			continue
+		}
+		cur := &lines[len(lines)-1]
+		pen := (*cur)[len(*cur)-1]
+		if e.verb == "Z" {
+			if pen != start {
+				*cur = append(*cur, start)
+			}
+			continue
+		}
+		if e.verb == "L" {
+			*cur = append(*cur, e.pts[0])
+			continue
+		}
+		seg := curveSegment{pts: [4]models.Point{skPoint(pen)}}
+		for i, p := range e.pts {
+			seg.pts[i+1] = skPoint(p)
+		}
+		switch e.verb {
+		case "Q":
+			seg.verb = enums.PathVerbQuad
+		case "C":
+			seg.verb = enums.PathVerbCubic
+		}
+		const steps = 64
+		for i := 1; i <= steps; i++ {
+			p, _ := seg.eval(Scalar(i) / steps)
+			*cur = append(*cur, f32Pt(p))
+		}
+	}
+	return lines
+}
+
+func polylineLength(lines [][]f32.Point) Scalar {
+	var l Scalar
+	for _, line := range lines {
+		for i := 1; i < len(line); i++ {
+			l += pointLength(skPoint(line[i].Sub(line[i-1])))
+		}
+	}
+	return l
+}
+
+func convertEvents(path SkPath) []pathEvent {
+	var rec recordingSink
+	convertPath(path, 0.01, &rec)
+	return rec.events
+}
+
+var quickConfig = &quick.Config{MaxCount: 500}
+
+func TestConvertPath_Normalized(t *testing.T) {
+	normalized := func(p randomPath) bool {
+		events := convertEvents(p.path)
+		var pen f32.Point
+		contourSegments := -1
+		for i, e := range events {
+			switch e.verb {
+			case "M":
+				if contourSegments == 0 {
+					t.Logf("empty contour before event %d", i)
+					return false
+				}
+				pen, contourSegments = e.pts[0], 0
+			case "Z":
+				if contourSegments <= 0 {
+					t.Logf("close without segments at event %d", i)
+					return false
+				}
+				contourSegments = -1
+			default:
+				if contourSegments < 0 {
+					t.Logf("segment without moveTo at event %d", i)
+					return false
+				}
+				moves := false
+				for _, q := range e.pts {
+					moves = moves || q != pen
+				}
+				if !moves {
+					t.Logf("degenerate segment at event %d", i)
+					return false
+				}
+				pen = e.pts[len(e.pts)-1]
+				contourSegments++
+			}
+		}
+		return contourSegments != 0
+	}
+	if err := quick.Check(normalized, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConvertPath_Idempotent(t *testing.T) {
+	idempotent := func(p randomPath) bool {
+		events := convertEvents(p.path)
+		again := impl.NewSkPath(enums.PathFillTypeWinding)
+		convertPath(p.path, 0.01, skPathSink{path: again})
+		return reflect.DeepEqual(events, convertEvents(again))
+	}
+	if err := quick.Check(idempotent, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConvertPath_StrokeFormMatchesGioForm(t *testing.T) {
+	same := func(p randomPath) bool {
+		want := polylines(convertEvents(p.path))
+
+		// Replay the stroker form, which has no close verb.
+		var rec recordingSink
+		for _, seg := range toStrokePath(p.path, 0.01).Segments {
+			a := seg.Args
+			switch seg {
+			case stroke.MoveTo(a[0]):
+				rec.MoveTo(a[0])
+			case stroke.LineTo(a[0]):
+				rec.LineTo(a[0])
+			case stroke.QuadTo(a[0], a[1]):
+				rec.QuadTo(a[0], a[1])
+			case stroke.CubeTo(a[0], a[1], a[2]):
+				rec.CubeTo(a[0], a[1], a[2])
+			}
+		}
+		return reflect.DeepEqual(want, polylines(rec.events))
+	}
+	if err := quick.Check(same, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConvertPath_PreservesLength(t *testing.T) {
+	sameLength := func(p randomPath) bool {
+		got := polylineLength(polylines(convertEvents(p.path)))
+		var want Scalar
+		for m := NewPathMeasure(p.path, false, 100); m.Contour() != nil; m.NextContour() {
+			want += m.Length()
+		}
+		// Both sides approximate curves by chords.
+		if !near(got, want, 0.01*want+0.05) {
+			t.Logf("converted length %v, measured %v", got, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(sameLength, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConvertPath_PerspectiveIdentity(t *testing.T) {
+	identity := impl.NewMatrixIdentity()
+	sameLength := func(p randomPath) bool {
+		want := polylineLength(polylines(convertEvents(p.path)))
+		got := polylineLength(polylines(convertEvents(mapPathPerspective(p.path, identity))))
+		return near(got, want, 0.01*want+0.05)
+	}
+	if err := quick.Check(sameLength, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConvertPath_Cases(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(p SkPath)
+		want  string
+	}{
+		{"empty contours", func(p SkPath) {
+			p.MoveTo(1, 1)
+			p.MoveTo(2, 2)
+			p.Close()
+		}, ""},
+		{"degenerate segments", func(p SkPath) {
+			p.MoveTo(0, 0)
+			p.LineTo(0, 0)
+			p.QuadTo(0, 0, 0, 0)
+			p.LineTo(10, 0)
+		}, "M(0,0) L(10,0)"},
+		{"implicit moveTo after close", func(p SkPath) {
+			p.MoveTo(0, 0)
+			p.LineTo(10, 0)
+			p.Close()
+			p.LineTo(0, 10)
+		}, "M(0,0) L(10,0) Z M(0,0) L(0,10)"},
+		{"unit conic weight", func(p SkPath) {
+			p.MoveTo(0, 0)
+			p.ConicTo(10, 0, 10, 10, 1)
+		}, "M(0,0) Q(10,0)(10,10)"},
+	}
+	for _, tt := range tests {
+		path := impl.NewSkPath(enums.PathFillTypeWinding)
+		tt.build(path)
+		var got []string
+		for _, e := range convertEvents(path) {
+			s := e.verb
+			for _, p := range e.pts {
+				s += fmt.Sprintf("(%v,%v)", p.X, p.Y)
+			}
+			got = append(got, s)
+		}
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, s, tt.want)
+		}
+	}
+
+	// Conics with other weights are split into quads ending on the conic.
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(0, 0, 100, enums.PathDirectionCW)
+	for _, e := range convertEvents(path) {
+		if e.verb != "Q" {
+			continue
+		}
+		if r := pointLength(skPoint(e.pts[1])); !near(r, 100, 1e-3) {
+			t.Fatalf("quad ends off the circle at radius %v", r)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// PathEffect is a path effect that rewrites geometry before it is filled
+// or stroked, mirroring SkPathEffect. Paints carrying one apply it when
+// drawing paths and in FillPathWithPaint.
+type PathEffect interface {
+	interfaces.PathEffect
+
+	// FilterPath returns the path to draw in place of src. It may change
+	// rec, for example to fill the geometry it produces. It reports false
+	// if it does not apply, in which case src is drawn unchanged.
+	FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool)
+}
+
+// maxDashCount bounds the dashes produced for a path, like Skia's
+// kMaxDashCount.
+const maxDashCount = 1000000
+
+// dashPathEffect splits contours into dashes.
+type dashPathEffect struct {
+	intervals []Scalar
+	phase     Scalar
+	length    Scalar
+}
+
+// NewDashPathEffect returns an effect that alternates on and off
+// intervals along each contour, starting phase into the pattern. It
+// returns nil if intervals has an odd or zero count, a negative entry or a
+// zero sum, like SkDashPathEffect::Make.
+func NewDashPathEffect(intervals []Scalar, phase Scalar) PathEffect {
+	if len(intervals) < 2 || len(intervals)%2 != 0 {
+		return nil
+	}
+	var length Scalar
+	for _, v := range intervals {
+		if !(v >= 0) || math.IsInf(float64(v), 0) {
+			return nil
+		}
+		length += v
+	}
+	if !(length > 0) || math.IsInf(float64(length), 0) || math.IsNaN(float64(phase)) || math.IsInf(float64(phase), 0) {
+		return nil
+	}
+	phase = Scalar(math.Mod(float64(phase), float64(length)))
+	if phase < 0 {
+		phase += length
+	}
+	return &dashPathEffect{intervals: append([]Scalar(nil), intervals...), phase: phase, length: length}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect. Dashes lie on the
+// path, so the bounds do not change.
+func (e *dashPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	return true
+}
+
+// FilterPath implements PathEffect. Dashing is done by the stroker's
+// dasher, so a dash effect and a dashed stroke produce the same dashes.
+func (e *dashPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil || rec.IsFill() {
+		return nil, false
+	}
+	for m := NewPathMeasure(src, false, rec.ResScale); m.Contour() != nil; m.NextContour() {
+		if m.Length()/e.length*Scalar(len(e.intervals)) > maxDashCount {
+			return nil, false
+		}
+	}
+	tol := rec.tolerance()
+	pattern := make([]float32, len(e.intervals))
+	for i, v := range e.intervals {
+		pattern[i] = float32(v)
+	}
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	appendOpenContours(skPathSink{path: dst}, stroke.DashedContours(toStrokePath(src, tol), pattern, float32(e.phase)))
+	return dst, true
+}
+
+// TrimMode selects the part of a path kept by a trim path effect.
+type TrimMode uint8
+
+const (
+	// TrimModeNormal keeps the part between start and stop.
+	TrimModeNormal TrimMode = iota
+	// TrimModeInverted keeps the parts before start and after stop.
+	TrimModeInverted
+)
+
+// trimPathEffect keeps part of the length of a path.
+type trimPathEffect struct {
+	start, stop Scalar
+	mode        TrimMode
+}
+
+// NewTrimPathEffect returns an effect keeping the part of the path between
+// the start and stop fractions of its total length, or the rest of it if
+// mode is TrimModeInverted. Animating stop from 0 to 1 draws the path. It
+// returns nil if the effect would keep the whole path, like
+// SkTrimPathEffect::Make.
+func NewTrimPathEffect(start, stop Scalar, mode TrimMode) PathEffect {
+	if math.IsNaN(float64(start)) || math.IsInf(float64(start), 0) || math.IsNaN(float64(stop)) || math.IsInf(float64(stop), 0) {
+		return nil
+	}
+	if start <= 0 && stop >= 1 && mode == TrimModeNormal {
+		return nil
+	}
+	start, stop = min(max(start, 0), 1), min(max(stop, 0), 1)
+	if start >= stop && mode == TrimModeInverted {
+		return nil
+	}
+	return &trimPathEffect{start: start, stop: stop, mode: mode}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect. The kept part lies on
+// the path.
+func (e *trimPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	return true
+}
+
+// FilterPath implements PathEffect.
+func (e *trimPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil {
+		return nil, false
+	}
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	if e.start >= e.stop {
+		return dst, true
+	}
+	var contours []*ContourMeasure
+	var length Scalar
+	for m := NewPathMeasure(src, false, rec.ResScale); m.Contour() != nil; m.NextContour() {
+		contours = append(contours, m.Contour())
+		length += m.Length()
+	}
+	// add appends the part between distances startD and stopD of the
+	// whole path.
+	add := func(startD, stopD Scalar, moveTo bool) {
+		var offset Scalar
+		for _, c := range contours {
+			l := c.Length()
+			if offset+l > startD && offset < stopD {
+				c.GetSegment(startD-offset, stopD-offset, dst, moveTo)
+				moveTo = true
+			}
+			offset += l
+		}
+	}
+	startD, stopD := e.start*length, e.stop*length
+	if e.mode == TrimModeNormal {
+		add(startD, stopD, true)
+	} else {
+		// The end is added before the start, so that on a closed contour
+		// they join.
+		add(stopD, length, true)
+		add(0, startD, false)
+	}
+	return dst, true
+}
+
+// sumPathEffect draws the results of two effects.
+type sumPathEffect struct {
+	first, second PathEffect
+}
+
+// NewSumPathEffect returns an effect drawing the results of both first and
+// second applied to the path. If either is nil the other is returned.
+func NewSumPathEffect(first, second PathEffect) PathEffect {
+	switch {
+	case first == nil:
+		return second
+	case second == nil:
+		return first
+	}
+	return &sumPathEffect{first: first, second: second}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect.
+func (e *sumPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	return false
+}
+
+// FilterPath implements PathEffect. Only the effects that apply contribute;
+// if neither does, src is drawn unchanged.
+func (e *sumPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	filtered := false
+	for _, effect := range [2]PathEffect{e.first, e.second} {
+		if p, ok := effect.FilterPath(src, rec); ok {
+			dst.AddPathNoOffset(p, enums.AddPathModeAppend)
+			filtered = true
+		}
+	}
+	return dst, filtered
+}
+
+// composePathEffect applies one effect to the result of another.
+type composePathEffect struct {
+	outer, inner PathEffect
+}
+
+// NewComposePathEffect returns an effect applying outer to the result of
+// inner. If either is nil the other is returned.
+func NewComposePathEffect(outer, inner PathEffect) PathEffect {
+	switch {
+	case outer == nil:
+		return inner
+	case inner == nil:
+		return outer
+	}
+	return &composePathEffect{outer: outer, inner: inner}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect.
+func (e *composePathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	return e.inner.ComputeFastBounds(bounds) && e.outer.ComputeFastBounds(bounds)
+}
+
+// FilterPath implements PathEffect. As in Skia, the result is that of
+// outer; if outer does not apply, src is drawn unchanged.
+func (e *composePathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if p, ok := e.inner.FilterPath(src, rec); ok {
+		src = p
+	}
+	return e.outer.FilterPath(src, rec)
+}
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"gioui.org/f32"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// cornerPathEffect rounds the corners between lines.
+type cornerPathEffect struct {
+	radius Scalar
+}
+
+// NewCornerPathEffect returns an effect replacing the corners between
+// lines with quadratic curves starting up to radius before them. It
+// returns nil if radius is not positive, like SkCornerPathEffect::Make.
+func NewCornerPathEffect(radius Scalar) PathEffect {
+	if !(radius > 0) || math.IsInf(float64(radius), 0) {
+		return nil
+	}
+	return &cornerPathEffect{radius: radius}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect. Rounded corners stay
+// inside the path's bounds.
+func (e *cornerPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	return true
+}
+
+// FilterPath implements PathEffect.
+func (e *cornerPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil {
+		return nil, false
+	}
+	var segs recordedPath
+	convertPath(src, rec.tolerance(), &segs)
+	dst := impl.NewSkPath(src.FillType())
+	sink := skPathSink{path: dst}
+	for len(segs) > 0 {
+		// Split off the next contour.
+		n := 1
+		for n < len(segs) && segs[n].verb != enums.PathVerbMove {
+			n++
+		}
+		e.roundContour(segs[:n], sink)
+		segs = segs[n:]
+	}
+	return dst, true
+}
+
+// cornerSegment is a segment of a contour with its start point.
+type cornerSegment struct {
+	pathSegment
+	from f32.Point
+}
+
+func (s cornerSegment) end() f32.Point {
+	switch s.verb {
+	case enums.PathVerbQuad:
+		return s.pts[1]
+	case enums.PathVerbCubic:
+		return s.pts[2]
+	}
+	return s.pts[0]
+}
+
+// roundContour emits the normalized contour c, starting with its MoveTo,
+// with the corners between its lines rounded.
+func (e *cornerPathEffect) roundContour(c recordedPath, sink pathSink) {
+	start := c[0].pts[0]
+	closed := c[len(c)-1].verb == enums.PathVerbClose
+	var segs []cornerSegment
+	pen := start
+	for _, s := range c[1:] {
+		if s.verb == enums.PathVerbClose {
+			continue
+		}
+		seg := cornerSegment{pathSegment: s, from: pen}
+		segs = append(segs, seg)
+		pen = seg.end()
+	}
+	if closed && pen != start {
+		segs = append(segs, cornerSegment{pathSegment: pathSegment{verb: enums.PathVerbLine, pts: [3]f32.Point{start}}, from: pen})
+	}
+	if len(segs) == 0 {
+		return
+	}
+
+	// step returns the offset along line s between its ends and the ends
+	// of the rounded corners at them.
+	step := func(s cornerSegment) f32.Point {
+		d := s.end().Sub(s.from)
+		l := float32(math.Hypot(float64(d.X), float64(d.Y)))
+		if l <= 2*float32(e.radius) {
+			return d.Mul(0.5)
+		}
+		return d.Mul(float32(e.radius) / l)
+	}
+	// rounded reports whether the corner at the start of segs[i] is
+	// rounded.
+	rounded := func(i int) bool {
+		if i == 0 && !closed {
+			return false
+		}
+		prev := segs[(i+len(segs)-1)%len(segs)]
+		return len(segs) > 1 && prev.verb == enums.PathVerbLine && segs[i].verb == enums.PathVerbLine
+	}
+
+	if rounded(0) {
+		sink.MoveTo(start.Add(step(segs[0])))
+	} else {
+		sink.MoveTo(start)
+	}
+	for i, s := range segs {
+		next := (i + 1) % len(segs)
+		last := i == len(segs)-1
+		cornerAfter := (!last || closed) && rounded(next)
+		switch s.verb {
+		case enums.PathVerbLine:
+			to := s.end()
+			if cornerAfter {
+				to = to.Sub(step(s))
+			}
+			sink.LineTo(to)
+		case enums.PathVerbQuad:
+			sink.QuadTo(s.pts[0], s.pts[1])
+		case enums.PathVerbCubic:
+			sink.CubeTo(s.pts[0], s.pts[1], s.pts[2])
+		}
+		if cornerAfter {
+			sink.QuadTo(s.end(), s.end().Add(step(segs[next])))
+		}
+	}
+	if closed {
+		sink.Close()
+	}
+}
+
+// discretePathEffect chops paths into jittered lines.
+type discretePathEffect struct {
+	segLength, deviation Scalar
+	seed                 uint32
+}
+
+// NewDiscretePathEffect returns an effect that chops paths into lines
+// about segLength long and moves their ends up to deviation across the
+// path, for a hand drawn look. The jitter is the same for the same seed
+// and path. It returns nil if segLength is not positive, like
+// SkDiscretePathEffect::Make.
+func NewDiscretePathEffect(segLength, deviation Scalar, seed uint32) PathEffect {
+	if !(segLength > 0) || math.IsInf(float64(segLength), 0) || math.IsNaN(float64(deviation)) || math.IsInf(float64(deviation), 0) {
+		return nil
+	}
+	return &discretePathEffect{segLength: segLength, deviation: deviation, seed: seed}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect. Points move up to
+// the deviation.
+func (e *discretePathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	if bounds != nil {
+		d := Scalar(math.Abs(float64(e.deviation)))
+		*bounds = bounds.MakeOutset(d, d)
+	}
+	return true
+}
+
+// maxReasonableIterations bounds the lines or stamps per contour, like
+// Skia's MAX_REASONABLE_ITERATIONS.
+const maxReasonableIterations = 100000
+
+// FilterPath implements PathEffect.
+func (e *discretePathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil {
+		return nil, false
+	}
+	var contours []*ContourMeasure
+	var total Scalar
+	for m := NewPathMeasure(src, rec.IsFill(), rec.ResScale); m.Contour() != nil; m.NextContour() {
+		contours = append(contours, m.Contour())
+		total += m.Length()
+	}
+	// As in Skia, the seed is mixed with the length so that different
+	// paths jitter differently.
+	rng := lcgRandom(e.seed ^ uint32(math.Round(float64(total))))
+	doFill := Scalar(0)
+	if rec.IsFill() {
+		doFill = 1
+	}
+
+	dst := impl.NewSkPath(src.FillType())
+	for _, c := range contours {
+		length := c.Length()
+		if e.segLength*(2+doFill) > length {
+			// Too short to jitter.
+			c.GetSegment(0, length, dst, true)
+			continue
+		}
+		n := min(int(math.Round(float64(length/e.segLength))), maxReasonableIterations)
+		delta := length / Scalar(n)
+		var distance Scalar
+		if c.IsClosed() {
+			n--
+			distance += delta / 2
+		}
+		perturb := func(d Scalar) (models.Point, bool) {
+			p, tan, ok := c.GetPosTan(d)
+			if !ok {
+				return p, false
+			}
+			s := rng.next() * e.deviation
+			return models.Point{X: p.X - tan.Y*s, Y: p.Y + tan.X*s}, true
+		}
+		if p, ok := perturb(distance); ok {
+			dst.MoveTo(p.X, p.Y)
+		}
+		for ; n > 0; n-- {
+			distance += delta
+			if p, ok := perturb(distance); ok {
+				dst.LineTo(p.X, p.Y)
+			}
+		}
+		if c.IsClosed() {
+			dst.Close()
+		}
+	}
+	return dst, true
+}
+
+// lcgRandom is a linear congruential generator, so jitter does not depend
+// on the standard library's generator.
+type lcgRandom uint32
+
+// next returns a value in [-1, 1).
+func (r *lcgRandom) next() Scalar {
+	*r = *r*1664525 + 1013904223
+	return Scalar(int32(*r)) / (1 << 31)
+}
@@ -0,0 +1,337 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"sort"
+
+	"gioui.org/f32"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Path1DStyle selects how a 1D path effect places its stamp.
+type Path1DStyle uint8
+
+const (
+	// Path1DStyleTranslate moves the stamp to each position.
+	Path1DStyleTranslate Path1DStyle = iota
+	// Path1DStyleRotate also rotates the stamp to the path's tangent.
+	Path1DStyleRotate
+	// Path1DStyleMorph bends the stamp along the path.
+	Path1DStyleMorph
+)
+
+// path1DPathEffect stamps a path along contours.
+type path1DPathEffect struct {
+	path    SkPath
+	advance Scalar
+	phase   Scalar
+	style   Path1DStyle
+}
+
+// NewPath1DPathEffect returns an effect that fills copies of path placed
+// every advance along each contour, starting phase into the pattern. It
+// returns nil if advance is not positive or path is empty, like
+// SkPath1DPathEffect::Make.
+func NewPath1DPathEffect(path SkPath, advance, phase Scalar, style Path1DStyle) PathEffect {
+	if !(advance > 0) || math.IsInf(float64(advance), 0) || math.IsNaN(float64(phase)) || math.IsInf(float64(phase), 0) {
+		return nil
+	}
+	if path == nil || path.IsEmpty() || style > Path1DStyleMorph {
+		return nil
+	}
+	// Convert the phase to the distance of the first stamp, as Skia does.
+	if phase < 0 {
+		phase = -phase
+		if phase > advance {
+			phase = Scalar(math.Mod(float64(phase), float64(advance)))
+		}
+	} else {
+		if phase > advance {
+			phase = Scalar(math.Mod(float64(phase), float64(advance)))
+		}
+		phase = advance - phase
+	}
+	if phase >= advance {
+		phase = 0
+	}
+	stamp := impl.NewSkPath(path.FillType())
+	stamp.AddPathNoOffset(path, enums.AddPathModeAppend)
+	return &path1DPathEffect{path: stamp, advance: advance, phase: phase, style: style}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect. Translated and
+// rotated stamps stay within their distance from the origin of the path;
+// morphed ones may not.
+func (e *path1DPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	if e.style == Path1DStyleMorph {
+		return false
+	}
+	if bounds != nil {
+		b := e.path.Bounds()
+		r := max(pointLength(models.Point{X: b.Left, Y: b.Top}), pointLength(models.Point{X: b.Right, Y: b.Top}),
+			pointLength(models.Point{X: b.Left, Y: b.Bottom}), pointLength(models.Point{X: b.Right, Y: b.Bottom}))
+		*bounds = bounds.MakeOutset(r, r)
+	}
+	return true
+}
+
+// FilterPath implements PathEffect. The stamps are filled.
+func (e *path1DPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil {
+		return nil, false
+	}
+	rec.SetFill()
+	var stamp recordedPath
+	if e.style == Path1DStyleMorph {
+		convertPath(e.path, rec.tolerance(), &stamp)
+	}
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	for m := NewPathMeasure(src, false, rec.ResScale); m.Contour() != nil; m.NextContour() {
+		c := m.Contour()
+		length := c.Length()
+		n := 0
+		for d := e.phase; d < length; d += e.advance {
+			if n++; n > maxReasonableIterations {
+				return nil, false
+			}
+			switch e.style {
+			case Path1DStyleTranslate:
+				if pos, _, ok := c.GetPosTan(d); ok {
+					dst.AddPath(e.path, pos.X, pos.Y, enums.AddPathModeAppend)
+				}
+			case Path1DStyleRotate:
+				if mat, ok := c.GetMatrix(d, PathMeasureGetPosAndTan); ok {
+					dst.AddPathMatrix(e.path, mat, enums.AddPathModeAppend)
+				}
+			case Path1DStyleMorph:
+				morphPath(dst, stamp, c, d)
+			}
+		}
+	}
+	return dst, true
+}
+
+// morphPath appends stamp bent along c, with its origin distance along it.
+// Lines become quadratics so that they can bend.
+func morphPath(dst SkPath, stamp recordedPath, c *ContourMeasure, distance Scalar) {
+	// morph maps p, taking x as a distance along c and y as an offset
+	// across it.
+	morph := func(p f32.Point) models.Point {
+		pos, tan, _ := c.GetPosTan(distance + Scalar(p.X))
+		y := Scalar(p.Y)
+		return models.Point{X: pos.X - tan.Y*y, Y: pos.Y + tan.X*y}
+	}
+	var pen f32.Point
+	for _, s := range stamp {
+		switch s.verb {
+		case enums.PathVerbMove:
+			p := morph(s.pts[0])
+			dst.MoveTo(p.X, p.Y)
+			pen = s.pts[0]
+		case enums.PathVerbLine:
+			mid, to := morph(pen.Add(s.pts[0]).Mul(0.5)), morph(s.pts[0])
+			dst.QuadTo(mid.X, mid.Y, to.X, to.Y)
+			pen = s.pts[0]
+		case enums.PathVerbQuad:
+			ctrl, to := morph(s.pts[0]), morph(s.pts[1])
+			dst.QuadTo(ctrl.X, ctrl.Y, to.X, to.Y)
+			pen = s.pts[1]
+		case enums.PathVerbCubic:
+			ctrl0, ctrl1, to := morph(s.pts[0]), morph(s.pts[1]), morph(s.pts[2])
+			dst.CubicTo(ctrl0.X, ctrl0.Y, ctrl1.X, ctrl1.Y, to.X, to.Y)
+			pen = s.pts[2]
+		case enums.PathVerbClose:
+			dst.Close()
+		}
+	}
+}
+
+// latticePathEffect fills the area of a path with a pattern repeated on a
+// lattice, the base of Skia's 2D path effects.
+type latticePathEffect struct {
+	matrix, inverse SkMatrix
+}
+
+func newLatticePathEffect(matrix SkMatrix) (latticePathEffect, bool) {
+	if matrix == nil {
+		return latticePathEffect{}, false
+	}
+	inverse, ok := matrix.Invert()
+	return latticePathEffect{matrix: matrix, inverse: inverse}, ok
+}
+
+// spans calls span for each row of lattice cells whose centers are inside
+// src under its fill rule, with the cell coordinates of the row's first
+// cell and the number of cells.
+func (e latticePathEffect) spans(src SkPath, span func(u, v, count int)) {
+	local := impl.NewSkPath(src.FillType())
+	local.AddPathMatrix(src, e.inverse, enums.AddPathModeAppend)
+	b := local.Bounds()
+	left, top := int(math.Floor(float64(b.Left))), int(math.Floor(float64(b.Top)))
+	right, bottom := int(math.Ceil(float64(b.Right))), int(math.Ceil(float64(b.Bottom)))
+	if left >= right || top >= bottom || right-left > maxReasonableIterations || bottom-top > maxReasonableIterations {
+		return
+	}
+	flat := flattenSink{tol: pathOpsTolerance}
+	convertPath(local, pathOpsTolerance, &flat)
+	flat.endContour()
+
+	fill := local.FillType()
+	inverse := fill == enums.PathFillTypeInverseWinding || fill == enums.PathFillTypeInverseEvenOdd
+	evenOdd := fill == enums.PathFillTypeEvenOdd || fill == enums.PathFillTypeInverseEvenOdd
+	inside := func(winding int) bool {
+		if evenOdd {
+			return winding%2 != 0
+		}
+		return winding != 0
+	}
+
+	type crossing struct {
+		x   float32
+		dir int
+	}
+	var row []crossing
+	// emit reports the cells with centers in [x0, x1).
+	emit := func(v int, x0, x1 float32) {
+		u0 := max(int(math.Ceil(float64(x0-0.5))), left)
+		u1 := min(int(math.Ceil(float64(x1-0.5))), right)
+		if u0 < u1 {
+			span(u0, v, u1-u0)
+		}
+	}
+	for v := top; v < bottom; v++ {
+		y := float32(v) + 0.5
+		row = row[:0]
+		for _, c := range flat.contours {
+			for i := range c {
+				// Contours are closed implicitly.
+				p0, p1 := c[i], c[(i+1)%len(c)]
+				dir := 1
+				if p0.Y > p1.Y {
+					p0, p1, dir = p1, p0, -1
+				}
+				if y < p0.Y || y >= p1.Y {
+					continue
+				}
+				x := p0.X + (y-p0.Y)/(p1.Y-p0.Y)*(p1.X-p0.X)
+				row = append(row, crossing{x: x, dir: dir})
+			}
+		}
+		sort.Slice(row, func(i, j int) bool { return row[i].x < row[j].x })
+
+		winding := 0
+		start := float32(left)
+		in := inverse
+		for _, c := range row {
+			winding += c.dir
+			now := inside(winding) != inverse
+			if now == in {
+				continue
+			}
+			if in {
+				emit(v, start, c.x)
+			}
+			start, in = c.x, now
+		}
+		if in {
+			emit(v, start, float32(right))
+		}
+	}
+}
+
+// cell returns the point of the lattice at the center of cell (u, v).
+func (e latticePathEffect) cell(u, v Scalar) models.Point {
+	x, y := e.matrix.MapXY(u+0.5, v+0.5)
+	return models.Point{X: x, Y: y}
+}
+
+// path2DPathEffect stamps a path on a lattice.
+type path2DPathEffect struct {
+	latticePathEffect
+	path SkPath
+}
+
+// NewPath2DPathEffect returns an effect that fills the area of a path with
+// copies of path placed on the lattice given by matrix: one at the mapped
+// center of each unit cell whose center is inside the path. It returns nil
+// if matrix is not invertible.
+func NewPath2DPathEffect(matrix SkMatrix, path SkPath) PathEffect {
+	lattice, ok := newLatticePathEffect(matrix)
+	if !ok || path == nil {
+		return nil
+	}
+	stamp := impl.NewSkPath(path.FillType())
+	stamp.AddPathNoOffset(path, enums.AddPathModeAppend)
+	return &path2DPathEffect{latticePathEffect: lattice, path: stamp}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect.
+func (e *path2DPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	return false
+}
+
+// FilterPath implements PathEffect.
+func (e *path2DPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil {
+		return nil, false
+	}
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	n := 0
+	e.spans(src, func(u, v, count int) {
+		for i := 0; i < count && n < maxReasonableIterations; i++ {
+			p := e.cell(Scalar(u+i), Scalar(v))
+			dst.AddPath(e.path, p.X, p.Y, enums.AddPathModeAppend)
+			n++
+		}
+	})
+	return dst, true
+}
+
+// line2DPathEffect hatches the area of a path.
+type line2DPathEffect struct {
+	latticePathEffect
+	width Scalar
+}
+
+// NewLine2DPathEffect returns an effect that hatches the area of a path
+// with lines width wide, along the rows of the lattice given by matrix. It
+// returns nil if matrix is not invertible or width is negative.
+func NewLine2DPathEffect(width Scalar, matrix SkMatrix) PathEffect {
+	lattice, ok := newLatticePathEffect(matrix)
+	if !ok || !(width >= 0) || math.IsInf(float64(width), 0) {
+		return nil
+	}
+	return &line2DPathEffect{latticePathEffect: lattice, width: width}
+}
+
+// ComputeFastBounds implements interfaces.PathEffect. The lines may reach
+// past the path by their width.
+func (e *line2DPathEffect) ComputeFastBounds(bounds *models.Rect) bool {
+	if bounds != nil {
+		*bounds = bounds.MakeOutset(e.width, e.width)
+	}
+	return true
+}
+
+// FilterPath implements PathEffect. The lines are stroked. As in Skia, rows
+// of a single cell have no line.
+func (e *line2DPathEffect) FilterPath(src SkPath, rec *StrokeRec) (SkPath, bool) {
+	if src == nil {
+		return nil, false
+	}
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	e.spans(src, func(u, v, count int) {
+		if count < 2 {
+			return
+		}
+		p0 := e.cell(Scalar(u), Scalar(v))
+		p1 := e.cell(Scalar(u+count), Scalar(v))
+		dst.MoveTo(p0.X, p0.Y)
+		dst.LineTo(p1.X, p1.Y)
+	})
+	rec.SetStrokeStyle(e.width)
+	return dst, true
+}
@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// contourLengths returns the lengths of the contours of p.
+func contourLengths(p SkPath) []Scalar {
+	var lengths []Scalar
+	for m := NewPathMeasure(p, false, 1); m.Contour() != nil; m.NextContour() {
+		lengths = append(lengths, m.Length())
+	}
+	return lengths
+}
+
+func strokeRec() StrokeRec {
+	return StrokeRec{Style: enums.PaintStyleStroke, Width: 1, Miter: 4, ResScale: 1}
+}
+
+func TestCornerPathEffect(t *testing.T) {
+	effect := NewCornerPathEffect(2)
+	rec := StrokeRec{Style: enums.PaintStyleFill, ResScale: 1}
+	got, ok := effect.FilterPath(rectPath(0, 0, 10, 10), &rec)
+	if !ok {
+		t.Fatal("corner effect should apply")
+	}
+	// Each corner loses the triangle between the quad and the corner,
+	// less the area under the quad: 2·2/2 - 2/3·(2·2/2) = 2/3. Flattening
+	// the quads loses a little more.
+	if area, want := pathArea(got), Scalar(100-4*2.0/3); !near(area, want, 0.15) {
+		t.Errorf("rounded square area %v, want %v", area, want)
+	}
+	if got.Bounds() != (models.Rect{Left: 0, Top: 0, Right: 10, Bottom: 10}) {
+		t.Errorf("bounds %+v", got.Bounds())
+	}
+	if pathContains(got, f32.Pt(0.1, 0.1)) || !pathContains(got, f32.Pt(5, 0.1)) {
+		t.Error("corners should be cut and edges kept")
+	}
+
+	// Open polylines keep their ends.
+	open := impl.NewSkPath(enums.PathFillTypeWinding)
+	open.MoveTo(0, 0)
+	open.LineTo(10, 0)
+	open.LineTo(10, 10)
+	got, _ = effect.FilterPath(open, &rec)
+	if got.CountPoints() != 5 {
+		t.Errorf("open polyline has %d points, want 5", got.CountPoints())
+	}
+	if p := got.Point(0); p != (models.Point{}) {
+		t.Errorf("open polyline starts at %+v", p)
+	}
+
+	if NewCornerPathEffect(0) != nil {
+		t.Error("zero radius should be rejected")
+	}
+}
+
+func TestCornerPathEffect_ZeroResScale(t *testing.T) {
+	// A zero record, as callers may build it, flattens at the default
+	// tolerance rather than an infinite one.
+	circle := impl.NewSkPath(enums.PathFillTypeWinding)
+	circle.AddCircle(0, 0, 10, enums.PathDirectionCW)
+	got, ok := NewCornerPathEffect(0.1).FilterPath(circle, &StrokeRec{})
+	if !ok {
+		t.Fatal("corner effect should apply")
+	}
+	if area, want := pathArea(got), Scalar(math.Pi*100); !near(area, want, 1) {
+		t.Errorf("circle area %v, want %v", area, want)
+	}
+}
+
+func TestDiscretePathEffect(t *testing.T) {
+	effect := NewDiscretePathEffect(5, 2, 7)
+	rec := strokeRec()
+	a, ok := effect.FilterPath(linePath(0, 0, 100, 0), &rec)
+	if !ok {
+		t.Fatal("discrete effect should apply")
+	}
+	b, _ := effect.FilterPath(linePath(0, 0, 100, 0), &rec)
+	if a.CountPoints() != 21 || a.CountPoints() != b.CountPoints() {
+		t.Fatalf("points %d and %d, want 21", a.CountPoints(), b.CountPoints())
+	}
+	moved := false
+	for i := 0; i < a.CountPoints(); i++ {
+		pa, pb := a.Point(i), b.Point(i)
+		if pa != pb {
+			t.Fatal("jitter should be deterministic")
+		}
+		if pa.Y < -2 || pa.Y > 2 {
+			t.Errorf("point %+v deviates too far", pa)
+		}
+		moved = moved || pa.Y != 0
+	}
+	if !moved {
+		t.Error("points should be jittered")
+	}
+	bounds := models.Rect{Left: 0, Top: 0, Right: 100, Bottom: 0}
+	if !effect.ComputeFastBounds(&bounds) || bounds != (models.Rect{Left: -2, Top: -2, Right: 102, Bottom: 2}) {
+		t.Errorf("fast bounds %+v", bounds)
+	}
+
+	// Short contours are copied.
+	short, _ := effect.FilterPath(linePath(0, 0, 8, 0), &rec)
+	if short.CountPoints() != 2 {
+		t.Errorf("short contour has %d points", short.CountPoints())
+	}
+}
+
+func TestPath1DPathEffect(t *testing.T) {
+	stamp := rectPath(-1, -1, 1, 1)
+	effect := NewPath1DPathEffect(stamp, 10, 0, Path1DStyleTranslate)
+	rec := strokeRec()
+	got, ok := effect.FilterPath(linePath(0, 0, 100, 0), &rec)
+	if !ok || !rec.IsFill() {
+		t.Fatalf("ok %v, fill %v", ok, rec.IsFill())
+	}
+	// Stamps at 0, 10, ..., 90.
+	if n := len(contourLengths(got)); n != 10 {
+		t.Errorf("%d stamps, want 10", n)
+	}
+	if area := pathArea(got); !near(area, 40, 1e-3) {
+		t.Errorf("stamped area %v, want 40", area)
+	}
+
+	// The phase moves the stamps back along the path.
+	effect = NewPath1DPathEffect(stamp, 10, 3, Path1DStyleRotate)
+	got, _ = effect.FilterPath(linePath(0, 0, 0, 100), &rec)
+	if b := got.Bounds(); !near(b.Top, 6, 1e-4) || !near(b.Left, -1, 1e-4) {
+		t.Errorf("first stamp bounds %+v", b)
+	}
+
+	// Positive y is to the right of the tangent, inside a clockwise circle.
+	effect = NewPath1DPathEffect(linePath(0, 1, 10, 1), 20, 0, Path1DStyleMorph)
+	circle := impl.NewSkPath(enums.PathFillTypeWinding)
+	circle.AddCircle(0, 0, 50, enums.PathDirectionCW)
+	got, _ = effect.FilterPath(circle, &rec)
+	for i := 0; i < got.CountPoints(); i++ {
+		if r := pointLength(got.Point(i)); r < 48.5 || r > 50 {
+			t.Fatalf("morphed point %+v at radius %v", got.Point(i), r)
+		}
+	}
+
+	if NewPath1DPathEffect(stamp, 0, 0, Path1DStyleTranslate) != nil {
+		t.Error("zero advance should be rejected")
+	}
+}
+
+func TestLine2DPathEffect(t *testing.T) {
+	// Horizontal lines every 4 units.
+	effect := NewLine2DPathEffect(1, impl.NewMatrixScale(4, 4))
+	rec := StrokeRec{Style: enums.PaintStyleFill, ResScale: 1}
+	got, ok := effect.FilterPath(rectPath(0, 0, 40, 20), &rec)
+	if !ok || rec.Style != enums.PaintStyleStroke || rec.Width != 1 {
+		t.Fatalf("ok %v, rec %+v", ok, rec)
+	}
+	lengths := contourLengths(got)
+	if len(lengths) != 5 {
+		t.Fatalf("%d lines, want 5", len(lengths))
+	}
+	for _, l := range lengths {
+		if !near(l, 40, 1e-3) {
+			t.Errorf("line lengths %v, want 40", lengths)
+			break
+		}
+	}
+
+	if NewLine2DPathEffect(1, impl.NewMatrixScale(0, 4)) != nil {
+		t.Error("singular matrix should be rejected")
+	}
+}
+
+func TestPath2DPathEffect(t *testing.T) {
+	dot := rectPath(-0.5, -0.5, 0.5, 0.5)
+	effect := NewPath2DPathEffect(impl.NewMatrixScale(10, 10), dot)
+	rec := StrokeRec{Style: enums.PaintStyleFill, ResScale: 1}
+	got, _ := effect.FilterPath(rectPath(0, 0, 40, 20), &rec)
+	if n := len(contourLengths(got)); n != 8 {
+		t.Errorf("%d stamps, want 8", n)
+	}
+
+	// Inverse fills stamp the cells of the bounds outside the path.
+	ring := rectPath(0, 0, 40, 40)
+	ring.AddRect(models.Rect{Left: 10, Top: 10, Right: 30, Bottom: 30}, enums.PathDirectionCCW, 0)
+	got, _ = effect.FilterPath(ring, &rec)
+	if n := len(contourLengths(got)); n != 12 {
+		t.Errorf("%d ring stamps, want 12", n)
+	}
+	ring.SetFillType(enums.PathFillTypeInverseWinding)
+	got, _ = effect.FilterPath(ring, &rec)
+	if n := len(contourLengths(got)); n != 4 {
+		t.Errorf("%d inverse ring stamps, want 4", n)
+	}
+}
+
+func TestTrimPathEffect(t *testing.T) {
+	rec := strokeRec()
+	got, _ := NewTrimPathEffect(0.25, 0.5, TrimModeNormal).FilterPath(linePath(0, 0, 100, 0), &rec)
+	if b := got.Bounds(); !near(b.Left, 25, 1e-3) || !near(b.Right, 50, 1e-3) {
+		t.Errorf("trimmed bounds %+v", b)
+	}
+
+	// Trimming spans contours.
+	two := linePath(0, 0, 100, 0)
+	two.AddPath(linePath(0, 10, 100, 10), 0, 0, enums.AddPathModeAppend)
+	got, _ = NewTrimPathEffect(0.25, 0.75, TrimModeNormal).FilterPath(two, &rec)
+	if lengths := contourLengths(got); len(lengths) != 2 || !near(lengths[0], 50, 1e-3) || !near(lengths[1], 50, 1e-3) {
+		t.Errorf("trimmed lengths %v", lengths)
+	}
+
+	// Inverted trims of closed contours join across the start.
+	got, _ = NewTrimPathEffect(0.25, 0.75, TrimModeInverted).FilterPath(rectPath(0, 0, 10, 10), &rec)
+	if lengths := contourLengths(got); len(lengths) != 1 || !near(lengths[0], 20, 1e-3) {
+		t.Errorf("inverted lengths %v", lengths)
+	}
+
+	if NewTrimPathEffect(0, 1, TrimModeNormal) != nil || NewTrimPathEffect(0.5, 0.5, TrimModeInverted) != nil {
+		t.Error("no-op trims should be nil")
+	}
+}
+
+func TestSumAndComposePathEffects(t *testing.T) {
+	dash := NewDashPathEffect([]Scalar{10, 10}, 0)
+	trim := NewTrimPathEffect(0, 0.5, TrimModeNormal)
+
+	rec := strokeRec()
+	got, ok := NewSumPathEffect(dash, trim).FilterPath(linePath(0, 0, 100, 0), &rec)
+	if lengths := contourLengths(got); !ok || len(lengths) != 6 {
+		t.Errorf("sum: ok %v, lengths %v, want 5 dashes and a trim", ok, lengths)
+	}
+
+	// Compose dashes the trimmed half.
+	got, _ = NewComposePathEffect(dash, trim).FilterPath(linePath(0, 0, 100, 0), &rec)
+	if lengths := contourLengths(got); len(lengths) != 3 {
+		t.Errorf("compose lengths %v, want 3 dashes", lengths)
+	}
+
+	if NewSumPathEffect(nil, dash) != dash || NewComposePathEffect(trim, nil) != trim {
+		t.Error("nil operands should return the other effect")
+	}
+}
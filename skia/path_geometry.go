@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Curve evaluation and subdivision shared by the path utilities.
+
+// curveSegment is a single line, quadratic or cubic segment. Conics are
+// converted to quadratics before they get here.
+type curveSegment struct {
+	verb enums.PathVerb
+	pts  [4]models.Point
+}
+
+// end returns the final point of the segment.
+func (s curveSegment) end() models.Point {
+	switch s.verb {
+	case enums.PathVerbLine:
+		return s.pts[1]
+	case enums.PathVerbQuad:
+		return s.pts[2]
+	default:
+		return s.pts[3]
+	}
+}
+
+// eval returns the point and derivative of the segment at t.
+func (s curveSegment) eval(t Scalar) (pos, tan models.Point) {
+	p := s.pts
+	switch s.verb {
+	case enums.PathVerbLine:
+		return lerpPoint(p[0], p[1], t), subPoint(p[1], p[0])
+	case enums.PathVerbQuad:
+		a, b := lerpPoint(p[0], p[1], t), lerpPoint(p[1], p[2], t)
+		pos, tan = lerpPoint(a, b, t), scalePoint(subPoint(b, a), 2)
+		if isZeroPoint(tan) {
+			tan = subPoint(p[2], p[0])
+		}
+		return pos, tan
+	default:
+		a, b, c := lerpPoint(p[0], p[1], t), lerpPoint(p[1], p[2], t), lerpPoint(p[2], p[3], t)
+		d, e := lerpPoint(a, b, t), lerpPoint(b, c, t)
+		pos, tan = lerpPoint(d, e, t), scalePoint(subPoint(e, d), 3)
+		if isZeroPoint(tan) {
+			// Coincident control points at an end; use the far ones.
+			if t < 0.5 {
+				tan = subPoint(p[2], p[0])
+			} else {
+				tan = subPoint(p[3], p[1])
+			}
+			if isZeroPoint(tan) {
+				tan = subPoint(p[3], p[0])
+			}
+		}
+		return pos, tan
+	}
+}
+
+// chop returns the part of the segment between t0 and t1.
+func (s curveSegment) chop(t0, t1 Scalar) curveSegment {
+	if t0 <= 0 && t1 >= 1 {
+		return s
+	}
+	out := s
+	switch s.verb {
+	case enums.PathVerbLine:
+		out.pts[0], out.pts[1] = lerpPoint(s.pts[0], s.pts[1], t0), lerpPoint(s.pts[0], s.pts[1], t1)
+	case enums.PathVerbQuad:
+		left := chopQuadLeft(s.pts, t1)
+		if t1 > 0 {
+			out.pts = chopQuadRight(left, t0/t1)
+		} else {
+			out.pts = [4]models.Point{left[0], left[0], left[0]}
+		}
+	default:
+		left := chopCubicLeft(s.pts, t1)
+		if t1 > 0 {
+			out.pts = chopCubicRight(left, t0/t1)
+		} else {
+			out.pts = [4]models.Point{left[0], left[0], left[0], left[0]}
+		}
+	}
+	return out
+}
+
+// flatness returns the largest distance of the control points from the
+// chord of the segment. Control points beyond the ends of the chord count,
+// so collinear curves that double back are not flat.
+func (s curveSegment) flatness() Scalar {
+	switch s.verb {
+	case enums.PathVerbQuad:
+		return distanceToSegment(s.pts[1], s.pts[0], s.pts[2])
+	case enums.PathVerbCubic:
+		return max(distanceToSegment(s.pts[1], s.pts[0], s.pts[3]), distanceToSegment(s.pts[2], s.pts[0], s.pts[3]))
+	}
+	return 0
+}
+
+func chopQuadLeft(p [4]models.Point, t Scalar) [4]models.Point {
+	a, b := lerpPoint(p[0], p[1], t), lerpPoint(p[1], p[2], t)
+	return [4]models.Point{p[0], a, lerpPoint(a, b, t)}
+}
+
+func chopQuadRight(p [4]models.Point, t Scalar) [4]models.Point {
+	a, b := lerpPoint(p[0], p[1], t), lerpPoint(p[1], p[2], t)
+	return [4]models.Point{lerpPoint(a, b, t), b, p[2]}
+}
+
+func chopCubicLeft(p [4]models.Point, t Scalar) [4]models.Point {
+	a, b, c := lerpPoint(p[0], p[1], t), lerpPoint(p[1], p[2], t), lerpPoint(p[2], p[3], t)
+	d, e := lerpPoint(a, b, t), lerpPoint(b, c, t)
+	return [4]models.Point{p[0], a, d, lerpPoint(d, e, t)}
+}
+
+func chopCubicRight(p [4]models.Point, t Scalar) [4]models.Point {
+	a, b, c := lerpPoint(p[0], p[1], t), lerpPoint(p[1], p[2], t), lerpPoint(p[2], p[3], t)
+	d, e := lerpPoint(a, b, t), lerpPoint(b, c, t)
+	return [4]models.Point{lerpPoint(d, e, t), e, c, p[3]}
+}
+
+// maxConicQuadPow2 bounds the subdivision of conics into quadratics.
+const maxConicQuadPow2 = 5
+
+// conicToQuads approximates the conic (p0, p1, p2, w) by quadratics within
+// tol, following SkConic::computeQuadPOW2 and chopIntoQuadsPOW2.
+func conicToQuads(p0, p1, p2 models.Point, w, tol Scalar) [][3]models.Point {
+	if w == 1 || !(w > 0) || math.IsInf(float64(w), 0) {
+		return [][3]models.Point{{p0, p1, p2}}
+	}
+	a := w - 1
+	k := a / (4 * (2 + a))
+	x := k * (p0.X - 2*p1.X + p2.X)
+	y := k * (p0.Y - 2*p1.Y + p2.Y)
+	errSq := x*x + y*y
+	pow2 := 0
+	for ; pow2 < maxConicQuadPow2 && errSq > tol*tol; pow2++ {
+		errSq *= 0.0625 // The error shrinks by 4 per subdivision.
+	}
+	quads := make([][3]models.Point, 0, 1<<pow2)
+	var split func(p0, p1, p2 models.Point, w Scalar, level int)
+	split = func(p0, p1, p2 models.Point, w Scalar, level int) {
+		if level == 0 {
+			quads = append(quads, [3]models.Point{p0, p1, p2})
+			return
+		}
+		scale := 1 / (1 + w)
+		left := scalePoint(addPoint(p0, scalePoint(p1, w)), scale)
+		right := scalePoint(addPoint(scalePoint(p1, w), p2), scale)
+		mid := scalePoint(addPoint(left, right), 0.5)
+		newW := Scalar(math.Sqrt(float64((1 + w) / 2)))
+		split(p0, left, mid, newW, level-1)
+		split(mid, right, p2, newW, level-1)
+	}
+	split(p0, p1, p2, w, pow2)
+	return quads
+}
+
+func lerpPoint(a, b models.Point, t Scalar) models.Point {
+	return models.Point{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}
+}
+
+func addPoint(a, b models.Point) models.Point {
+	return models.Point{X: a.X + b.X, Y: a.Y + b.Y}
+}
+
+func subPoint(a, b models.Point) models.Point {
+	return models.Point{X: a.X - b.X, Y: a.Y - b.Y}
+}
+
+func scalePoint(a models.Point, s Scalar) models.Point {
+	return models.Point{X: a.X * s, Y: a.Y * s}
+}
+
+// crossPoint returns the z component of the cross product of a and b.
+func crossPoint(a, b models.Point) Scalar {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func pointLength(a models.Point) Scalar {
+	return Scalar(math.Hypot(float64(a.X), float64(a.Y)))
+}
+
+func isZeroPoint(a models.Point) bool {
+	return a.X == 0 && a.Y == 0
+}
+
+// normalizePoint returns a unit vector in the direction of a, or zero.
+func normalizePoint(a models.Point) models.Point {
+	l := pointLength(a)
+	if l == 0 {
+		return models.Point{}
+	}
+	return scalePoint(a, 1/l)
+}
+
+// distanceToLine returns the distance from p to the line through a and b.
+func distanceToLine(p, a, b models.Point) Scalar {
+	d := subPoint(b, a)
+	l := pointLength(d)
+	if l == 0 {
+		return pointLength(subPoint(p, a))
+	}
+	return Scalar(math.Abs(float64(d.X*(p.Y-a.Y)-d.Y*(p.X-a.X)))) / l
+}
+
+// distanceToSegment returns the distance from p to the segment from a to b.
+func distanceToSegment(p, a, b models.Point) Scalar {
+	d := subPoint(b, a)
+	l2 := d.X*d.X + d.Y*d.Y
+	if l2 == 0 {
+		return pointLength(subPoint(p, a))
+	}
+	t := ((p.X-a.X)*d.X + (p.Y-a.Y)*d.Y) / l2
+	t = min(max(t, 0), 1)
+	return pointLength(subPoint(p, lerpPoint(a, b, t)))
+}
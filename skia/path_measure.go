@@ -0,0 +1,320 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"sort"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// PathMeasureMatrixFlags selects what GetMatrix includes.
+type PathMeasureMatrixFlags uint8
+
+const (
+	// PathMeasureGetPosition includes the translation to the point.
+	PathMeasureGetPosition PathMeasureMatrixFlags = 1 << iota
+	// PathMeasureGetTangent includes the rotation to the tangent.
+	PathMeasureGetTangent
+
+	PathMeasureGetPosAndTan = PathMeasureGetPosition | PathMeasureGetTangent
+)
+
+// maxMeasureDepth bounds the subdivision of a single curve.
+const maxMeasureDepth = 10
+
+// PathMeasure measures the contours of a path. It starts on the first
+// contour with a non-zero length; NextContour advances to the next one.
+// This mirrors SkPathMeasure.
+type PathMeasure struct {
+	contours []*ContourMeasure
+	index    int
+}
+
+// ContourMeasure holds the measurements of a single contour.
+// This mirrors SkContourMeasure.
+type ContourMeasure struct {
+	segments []curveSegment
+	samples  []measureSample
+	length   Scalar
+	closed   bool
+}
+
+// measureSample maps the distance at the end of a flattened piece of a
+// segment back to the curve parameter.
+type measureSample struct {
+	distance Scalar
+	segment  int
+	t0, t1   Scalar
+}
+
+// NewPathMeasure measures path. If forceClosed is set every contour is
+// treated as closed. resScale is the expected scale of the path on screen;
+// larger values measure curves more precisely.
+func NewPathMeasure(path SkPath, forceClosed bool, resScale Scalar) *PathMeasure {
+	m := &PathMeasure{}
+	if path == nil || path.IsEmpty() {
+		return m
+	}
+	if resScale <= 0 {
+		resScale = 1
+	}
+	tol := 0.5 / resScale
+
+	verbs := make([]enums.PathVerb, path.CountVerbs())
+	path.GetVerbs(verbs)
+	points := make([]models.Point, path.CountPoints())
+	path.GetPoints(points)
+	iter := impl.NewPathIter(points, verbs, path.ConicWeights())
+
+	var cur *ContourMeasure
+	var start, last models.Point
+	finish := func() {
+		if cur == nil {
+			return
+		}
+		if forceClosed && !cur.closed {
+			cur.addSegment(curveSegment{verb: enums.PathVerbLine, pts: [4]models.Point{last, start}}, tol)
+			cur.closed = true
+		}
+		if cur.length > 0 {
+			m.contours = append(m.contours, cur)
+		}
+		cur = nil
+	}
+	for rec := iter.Next(); rec != nil; rec = iter.Next() {
+		pts := rec.Points
+		if len(pts) == 0 {
+			continue
+		}
+		switch rec.Verb {
+		case enums.PathVerbMove:
+			finish()
+			cur = &ContourMeasure{}
+			start, last = pts[0], pts[0]
+			continue
+		case enums.PathVerbClose:
+			if cur != nil {
+				cur.addSegment(curveSegment{verb: enums.PathVerbLine, pts: [4]models.Point{last, start}}, tol)
+				cur.closed = true
+				last = start
+				finish()
+			}
+			continue
+		}
+		if cur == nil {
+			// Implicit moveTo after close.
+			cur = &ContourMeasure{}
+			start = last
+		}
+		switch rec.Verb {
+		case enums.PathVerbLine:
+			cur.addSegment(curveSegment{verb: enums.PathVerbLine, pts: [4]models.Point{pts[0], pts[1]}}, tol)
+			last = pts[1]
+		case enums.PathVerbQuad:
+			cur.addSegment(curveSegment{verb: enums.PathVerbQuad, pts: [4]models.Point{pts[0], pts[1], pts[2]}}, tol)
+			last = pts[2]
+		case enums.PathVerbConic:
+			for _, q := range conicToQuads(pts[0], pts[1], pts[2], rec.ConicWeight, tol) {
+				cur.addSegment(curveSegment{verb: enums.PathVerbQuad, pts: [4]models.Point{q[0], q[1], q[2]}}, tol)
+			}
+			last = pts[2]
+		case enums.PathVerbCubic:
+			cur.addSegment(curveSegment{verb: enums.PathVerbCubic, pts: [4]models.Point{pts[0], pts[1], pts[2], pts[3]}}, tol)
+			last = pts[3]
+		}
+	}
+	finish()
+	return m
+}
+
+// addSegment appends seg, flattening it to within tol for measuring.
+func (c *ContourMeasure) addSegment(seg curveSegment, tol Scalar) {
+	idx := len(c.segments)
+	c.segments = append(c.segments, seg)
+	before := len(c.samples)
+	c.flatten(seg, idx, 0, 1, tol, 0)
+	if len(c.samples) == before {
+		// Zero length segments do not contribute to the contour.
+		c.segments = c.segments[:idx]
+	}
+}
+
+func (c *ContourMeasure) flatten(seg curveSegment, idx int, t0, t1, tol Scalar, depth int) {
+	part := seg.chop(t0, t1)
+	if depth < maxMeasureDepth && part.flatness() > tol {
+		mid := (t0 + t1) / 2
+		c.flatten(seg, idx, t0, mid, tol, depth+1)
+		c.flatten(seg, idx, mid, t1, tol, depth+1)
+		return
+	}
+	d := pointLength(subPoint(part.end(), part.pts[0]))
+	if d <= 0 {
+		return
+	}
+	c.length += d
+	c.samples = append(c.samples, measureSample{distance: c.length, segment: idx, t0: t0, t1: t1})
+}
+
+// locate returns the segment and parameter at distance, which must be in
+// [0, length].
+func (c *ContourMeasure) locate(distance Scalar) (int, Scalar) {
+	i := sort.Search(len(c.samples), func(i int) bool { return c.samples[i].distance >= distance })
+	if i >= len(c.samples) {
+		i = len(c.samples) - 1
+	}
+	s := c.samples[i]
+	var prev Scalar
+	if i > 0 {
+		prev = c.samples[i-1].distance
+	}
+	f := Scalar(0)
+	if span := s.distance - prev; span > 0 {
+		f = (distance - prev) / span
+	}
+	f = min(max(f, 0), 1)
+	return s.segment, s.t0 + (s.t1-s.t0)*f
+}
+
+// Length returns the length of the contour.
+func (c *ContourMeasure) Length() Scalar {
+	return c.length
+}
+
+// IsClosed reports whether the contour is closed.
+func (c *ContourMeasure) IsClosed() bool {
+	return c.closed
+}
+
+// GetPosTan returns the position and unit tangent at distance along the
+// contour. The distance is pinned to [0, Length].
+func (c *ContourMeasure) GetPosTan(distance Scalar) (pos, tan models.Point, ok bool) {
+	if c == nil || len(c.samples) == 0 || distance != distance {
+		return models.Point{}, models.Point{}, false
+	}
+	distance = min(max(distance, 0), c.length)
+	seg, t := c.locate(distance)
+	pos, tan = c.segments[seg].eval(t)
+	return pos, normalizePoint(tan), true
+}
+
+// GetMatrix returns a matrix that maps the origin to the point at distance
+// and the x axis to the tangent there, as selected by flags.
+func (c *ContourMeasure) GetMatrix(distance Scalar, flags PathMeasureMatrixFlags) (SkMatrix, bool) {
+	pos, tan, ok := c.GetPosTan(distance)
+	if !ok {
+		return nil, false
+	}
+	m := impl.NewMatrixIdentity()
+	if flags&PathMeasureGetTangent != 0 {
+		m.SetAll(tan.X, -tan.Y, 0, tan.Y, tan.X, 0, 0, 0, 1)
+	}
+	if flags&PathMeasureGetPosition != 0 {
+		m.PostTranslate(pos.X, pos.Y)
+	}
+	return m, true
+}
+
+// GetSegment appends the part of the contour between startD and stopD to
+// dst. If startWithMoveTo is false the segment continues dst's last contour.
+// It reports false if the range is empty.
+func (c *ContourMeasure) GetSegment(startD, stopD Scalar, dst SkPath, startWithMoveTo bool) bool {
+	if c == nil || dst == nil || len(c.samples) == 0 {
+		return false
+	}
+	startD = max(startD, 0)
+	stopD = min(stopD, c.length)
+	if !(startD <= stopD) {
+		return false
+	}
+	seg0, t0 := c.locate(startD)
+	seg1, t1 := c.locate(stopD)
+
+	if startWithMoveTo {
+		p, _ := c.segments[seg0].eval(t0)
+		dst.MoveTo(p.X, p.Y)
+	}
+	if seg0 == seg1 {
+		if t0 < t1 || startD == stopD {
+			appendCurveSegment(dst, c.segments[seg0].chop(t0, t1))
+		}
+		return true
+	}
+	appendCurveSegment(dst, c.segments[seg0].chop(t0, 1))
+	for i := seg0 + 1; i < seg1; i++ {
+		appendCurveSegment(dst, c.segments[i])
+	}
+	appendCurveSegment(dst, c.segments[seg1].chop(0, t1))
+	return true
+}
+
+// appendCurveSegment appends seg to dst, without its start point.
+func appendCurveSegment(dst interfaces.SkPath, seg curveSegment) {
+	p := seg.pts
+	switch seg.verb {
+	case enums.PathVerbLine:
+		dst.LineTo(p[1].X, p[1].Y)
+	case enums.PathVerbQuad:
+		dst.QuadTo(p[1].X, p[1].Y, p[2].X, p[2].Y)
+	default:
+		dst.CubicTo(p[1].X, p[1].Y, p[2].X, p[2].Y, p[3].X, p[3].Y)
+	}
+}
+
+// current returns the contour being measured, or nil.
+func (m *PathMeasure) current() *ContourMeasure {
+	if m == nil || m.index >= len(m.contours) {
+		return nil
+	}
+	return m.contours[m.index]
+}
+
+// Contour returns the current contour, or nil if there are none left.
+func (m *PathMeasure) Contour() *ContourMeasure {
+	return m.current()
+}
+
+// Length returns the length of the current contour, or 0.
+func (m *PathMeasure) Length() Scalar {
+	if c := m.current(); c != nil {
+		return c.length
+	}
+	return 0
+}
+
+// IsClosed reports whether the current contour is closed.
+func (m *PathMeasure) IsClosed() bool {
+	c := m.current()
+	return c != nil && c.closed
+}
+
+// GetPosTan returns the position and unit tangent at distance along the
+// current contour.
+func (m *PathMeasure) GetPosTan(distance Scalar) (pos, tan models.Point, ok bool) {
+	return m.current().GetPosTan(distance)
+}
+
+// GetMatrix returns the matrix at distance along the current contour.
+func (m *PathMeasure) GetMatrix(distance Scalar, flags PathMeasureMatrixFlags) (SkMatrix, bool) {
+	c := m.current()
+	if c == nil {
+		return nil, false
+	}
+	return c.GetMatrix(distance, flags)
+}
+
+// GetSegment appends part of the current contour to dst.
+func (m *PathMeasure) GetSegment(startD, stopD Scalar, dst SkPath, startWithMoveTo bool) bool {
+	return m.current().GetSegment(startD, stopD, dst, startWithMoveTo)
+}
+
+// NextContour moves to the next contour, reporting false if there is none.
+func (m *PathMeasure) NextContour() bool {
+	if m == nil || m.index >= len(m.contours) {
+		return false
+	}
+	m.index++
+	return m.index < len(m.contours)
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"testing"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func near(a, b, tol Scalar) bool {
+	return Scalar(math.Abs(float64(a-b))) <= tol
+}
+
+func TestPathMeasure_Line(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(0, 0)
+	path.LineTo(30, 40)
+
+	m := NewPathMeasure(path, false, 1)
+	if got := m.Length(); !near(got, 50, 1e-4) {
+		t.Fatalf("length: got %v, want 50", got)
+	}
+	pos, tan, ok := m.GetPosTan(25)
+	if !ok || !near(pos.X, 15, 1e-4) || !near(pos.Y, 20, 1e-4) {
+		t.Errorf("pos: got %v, %v", pos, ok)
+	}
+	if !near(tan.X, 0.6, 1e-4) || !near(tan.Y, 0.8, 1e-4) {
+		t.Errorf("tan: got %v", tan)
+	}
+
+	// Distances are pinned to the contour.
+	pos, _, _ = m.GetPosTan(100)
+	if !near(pos.X, 30, 1e-4) || !near(pos.Y, 40, 1e-4) {
+		t.Errorf("pinned pos: got %v", pos)
+	}
+	if m.IsClosed() {
+		t.Error("open line reported as closed")
+	}
+}
+
+func TestPathMeasure_Circle(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(0, 0, 100, enums.PathDirectionCW)
+
+	m := NewPathMeasure(path, false, 1)
+	want := Scalar(2 * math.Pi * 100)
+	if got := m.Length(); !near(got, want, 0.5) {
+		t.Errorf("circle length: got %v, want %v", got, want)
+	}
+	if !m.IsClosed() {
+		t.Error("circle should be closed")
+	}
+	// Every point lies on the circle.
+	for d := Scalar(0); d < want; d += 37 {
+		pos, _, _ := m.GetPosTan(d)
+		if r := pointLength(pos); !near(r, 100, 0.1) {
+			t.Errorf("point at %v has radius %v", d, r)
+		}
+	}
+}
+
+func TestPathMeasure_Cubic(t *testing.T) {
+	// A cubic with collinear, evenly spaced control points is a straight
+	// line.
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(0, 0)
+	path.CubicTo(10, 0, 20, 0, 30, 0)
+
+	m := NewPathMeasure(path, false, 1)
+	if got := m.Length(); !near(got, 30, 1e-3) {
+		t.Errorf("length: got %v, want 30", got)
+	}
+	pos, tan, _ := m.GetPosTan(12)
+	if !near(pos.X, 12, 1e-2) || !near(tan.X, 1, 1e-4) {
+		t.Errorf("pos/tan: got %v, %v", pos, tan)
+	}
+}
+
+func TestPathMeasure_Contours(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(0, 0)
+	path.LineTo(10, 0)
+	path.MoveTo(50, 50) // zero length contour is skipped
+	path.MoveTo(0, 10)
+	path.LineTo(0, 30)
+
+	m := NewPathMeasure(path, false, 1)
+	if got := m.Length(); !near(got, 10, 1e-4) {
+		t.Errorf("first contour length: got %v", got)
+	}
+	if !m.NextContour() {
+		t.Fatal("expected a second contour")
+	}
+	if got := m.Length(); !near(got, 20, 1e-4) {
+		t.Errorf("second contour length: got %v", got)
+	}
+	if m.NextContour() {
+		t.Error("expected no third contour")
+	}
+	if m.Length() != 0 {
+		t.Error("length past the last contour should be 0")
+	}
+}
+
+func TestPathMeasure_ForceClosed(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(0, 0)
+	path.LineTo(10, 0)
+	path.LineTo(10, 10)
+
+	m := NewPathMeasure(path, true, 1)
+	want := 20 + Scalar(math.Sqrt(200))
+	if got := m.Length(); !near(got, want, 1e-3) || !m.IsClosed() {
+		t.Errorf("force closed: got %v, closed %v; want %v", got, m.IsClosed(), want)
+	}
+}
+
+func TestPathMeasure_GetSegment(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(0, 0)
+	path.LineTo(10, 0)
+	path.QuadTo(20, 0, 20, 10)
+	path.LineTo(20, 20)
+
+	m := NewPathMeasure(path, false, 1)
+	dst := impl.NewSkPath(enums.PathFillTypeWinding)
+	if !m.GetSegment(5, m.Length()-5, dst, true) {
+		t.Fatal("GetSegment failed")
+	}
+	seg := NewPathMeasure(dst, false, 1)
+	if got, want := seg.Length(), m.Length()-10; !near(got, want, 0.05) {
+		t.Errorf("segment length: got %v, want %v", got, want)
+	}
+	first, _, _ := seg.GetPosTan(0)
+	if !near(first.X, 5, 1e-3) || !near(first.Y, 0, 1e-3) {
+		t.Errorf("segment start: got %v", first)
+	}
+	last, _, _ := seg.GetPosTan(seg.Length())
+	if !near(last.X, 20, 1e-3) || !near(last.Y, 15, 1e-3) {
+		t.Errorf("segment end: got %v", last)
+	}
+
+	if m.GetSegment(10, 5, dst, true) {
+		t.Error("reversed range should fail")
+	}
+}
+
+func TestPathMeasure_GetMatrix(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(10, 10)
+	path.LineTo(10, 50)
+
+	m := NewPathMeasure(path, false, 1)
+	mat, ok := m.GetMatrix(20, PathMeasureGetPosAndTan)
+	if !ok {
+		t.Fatal("GetMatrix failed")
+	}
+	// The x axis maps to the downward tangent.
+	p := mat.MapPoint(models.Point{X: 1, Y: 0})
+	if !near(p.X, 10, 1e-4) || !near(p.Y, 31, 1e-4) {
+		t.Errorf("mapped point: got %v", p)
+	}
+}
+
+func TestConicToQuads(t *testing.T) {
+	// A quarter circle as a conic.
+	w := Scalar(math.Sqrt2 / 2)
+	quads := conicToQuads(models.Point{X: 100, Y: 0}, models.Point{X: 100, Y: 100}, models.Point{X: 0, Y: 100}, w, 0.25)
+	if len(quads) < 2 {
+		t.Fatalf("expected subdivision, got %d quad(s)", len(quads))
+	}
+	for _, q := range quads {
+		mid := lerpPoint(lerpPoint(q[0], q[1], 0.5), lerpPoint(q[1], q[2], 0.5), 0.5)
+		if r := pointLength(mid); !near(r, 100, 0.25) {
+			t.Errorf("quad midpoint radius %v", r)
+		}
+	}
+}
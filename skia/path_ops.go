@@ -0,0 +1,859 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"sort"
+
+	"gioui.org/f32"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Path boolean operations. Both operands are flattened to polygons whose
+// vertices are snapped to a grid fitted to their bounds, so coincident
+// points and edges compare equal. Edges are split where they meet,
+// duplicates are merged, and a sweep over the edges finds the winding
+// numbers of both operands on either side of every edge, which decide
+// whether the edge bounds the result. The kept edges are chained into
+// contours.
+//
+// Every edge remembers the segment it was flattened from and the span of
+// the segment it covers, so a run of kept edges along a curve is emitted
+// as the piece of the curve between its ends: results keep the curves of
+// the operands. Conics are converted to quadratics.
+
+// PathOp is a boolean operation combining two paths, matching SkPathOp.
+type PathOp uint8
+
+const (
+	// PathOpDifference keeps the first path minus the second.
+	PathOpDifference PathOp = iota
+	// PathOpIntersect keeps the area inside both paths.
+	PathOpIntersect
+	// PathOpUnion keeps the area inside either path.
+	PathOpUnion
+	// PathOpXOR keeps the area inside exactly one path.
+	PathOpXOR
+	// PathOpReverseDifference keeps the second path minus the first.
+	PathOpReverseDifference
+)
+
+const (
+	// pathOpsTolerance is the largest distance between a curve and the
+	// lines approximating it, in path units.
+	pathOpsTolerance = 1.0 / 64
+	// pathOpsRelTolerance bounds the tolerance relative to the extent of
+	// the operands, so small paths are approximated as closely as large
+	// ones.
+	pathOpsRelTolerance = 1.0 / (1 << 12)
+	// pathOpsGridSteps is the number of grid steps across the extent of
+	// the operands that vertices are snapped to. Cross products of grid
+	// coordinates stay exact in float64.
+	pathOpsGridSteps = 1 << 24
+	// maxPathOpsPasses bounds the passes splitting edges. Snapping an
+	// intersection can create a new one, so more than one may be needed.
+	maxPathOpsPasses = 8
+	// maxFlattenSteps bounds the lines approximating a single curve.
+	maxFlattenSteps = 1 << 10
+)
+
+// Op returns the result of combining one and two with op. It reports
+// false if either path has non-finite points.
+func Op(one, two SkPath, op PathOp) (SkPath, bool) {
+	return pathOp(one, two, op)
+}
+
+// Simplify returns a path covering the same area as path whose contours
+// do not intersect. It reports false if path has non-finite points.
+func Simplify(path SkPath) (SkPath, bool) {
+	return pathOp(path, nil, PathOpUnion)
+}
+
+// AsWinding returns a path covering the same area as path that uses the
+// winding fill rule, or its inverse. Paths already using it are returned
+// unchanged. If no contours cross or touch, they are kept as they are,
+// curves included, and reversed where their nesting requires it, as
+// SkPathOps' AsWinding does; otherwise the path is simplified. It reports
+// false if path has non-finite points.
+func AsWinding(path SkPath) (SkPath, bool) {
+	if path == nil || !path.IsFinite() {
+		return nil, false
+	}
+	switch path.FillType() {
+	case enums.PathFillTypeWinding, enums.PathFillTypeInverseWinding:
+		return path, true
+	}
+	if oriented, ok := orientContours(path); ok {
+		return oriented, true
+	}
+	return Simplify(path)
+}
+
+// opPoint is a vertex snapped to the grid.
+type opPoint struct {
+	x, y int64
+}
+
+func (p opPoint) less(q opPoint) bool {
+	return p.x < q.x || (p.x == q.x && p.y < q.y)
+}
+
+// opGrid maps path coordinates to grid points. The grid and the
+// flattening tolerance are fitted to the bounds of the operands.
+type opGrid struct {
+	origin models.Point
+	// scale is the number of grid steps per path unit, a power of two.
+	scale float64
+	tol   Scalar
+}
+
+func newOpGrid(bounds models.Rect) opGrid {
+	extent := float64(max(bounds.Right-bounds.Left, bounds.Bottom-bounds.Top))
+	if !(extent > 0) {
+		extent = 1
+	}
+	return opGrid{
+		origin: models.Point{X: bounds.Left, Y: bounds.Top},
+		scale:  math.Exp2(math.Floor(math.Log2(pathOpsGridSteps / extent))),
+		tol:    Scalar(min(pathOpsTolerance, extent*pathOpsRelTolerance)),
+	}
+}
+
+func (g opGrid) snap(p models.Point) opPoint {
+	return opPoint{
+		x: int64(math.Round(float64(p.X-g.origin.X) * g.scale)),
+		y: int64(math.Round(float64(p.Y-g.origin.Y) * g.scale)),
+	}
+}
+
+func (g opGrid) point(p opPoint) models.Point {
+	return models.Point{
+		X: g.origin.X + Scalar(float64(p.x)/g.scale),
+		Y: g.origin.Y + Scalar(float64(p.y)/g.scale),
+	}
+}
+
+// opEdge is an edge of the planar graph, running from a to b with a less
+// than b. Kept edges are directed along the contours of the result
+// instead.
+type opEdge struct {
+	a, b opPoint
+	// wind is the winding contribution of each operand: the number of its
+	// edges running from a to b minus those running from b to a.
+	wind [2]int
+	// seg indexes the segment the edge was flattened from, and ta and tb
+	// are the parameters of a and b on it.
+	seg    int
+	ta, tb Scalar
+}
+
+func (e opEdge) reversed() opEdge {
+	return opEdge{a: e.b, b: e.a, wind: e.wind, seg: e.seg, ta: e.tb, tb: e.ta}
+}
+
+// opSides holds the winding numbers of both operands on either side of an
+// edge.
+type opSides struct {
+	left, right [2]int
+}
+
+// pathOp combines one and two with op. two may be nil.
+func pathOp(one, two SkPath, op PathOp) (SkPath, bool) {
+	operands := [2]SkPath{one, two}
+	var rules [2]enums.PathFillType
+	var bounds models.Rect
+	first := true
+	for i, p := range operands {
+		if p == nil {
+			continue
+		}
+		if !p.IsFinite() {
+			return nil, false
+		}
+		rules[i] = p.FillType()
+		if b := p.Bounds(); first {
+			bounds, first = b, false
+		} else {
+			bounds = models.Rect{Left: min(bounds.Left, b.Left), Top: min(bounds.Top, b.Top), Right: max(bounds.Right, b.Right), Bottom: max(bounds.Bottom, b.Bottom)}
+		}
+	}
+	g := newOpGrid(bounds)
+	var edges []opEdge
+	var segs []curveSegment
+	for i, p := range operands {
+		if p == nil {
+			continue
+		}
+		var rec recordedPath
+		convertPath(p, g.tol, &rec)
+		edges, segs = appendOpEdges(edges, segs, rec, i, g)
+	}
+	edges = mergeOpEdges(splitOpEdges(edges))
+
+	inside := func(w [2]int) bool {
+		var in [2]bool
+		for i, rule := range rules {
+			switch rule {
+			case enums.PathFillTypeWinding:
+				in[i] = w[i] != 0
+			case enums.PathFillTypeEvenOdd:
+				in[i] = w[i]&1 != 0
+			case enums.PathFillTypeInverseWinding:
+				in[i] = w[i] == 0
+			case enums.PathFillTypeInverseEvenOdd:
+				in[i] = w[i]&1 == 0
+			}
+			if operands[i] == nil {
+				in[i] = false
+			}
+		}
+		switch op {
+		case PathOpDifference:
+			return in[0] && !in[1]
+		case PathOpIntersect:
+			return in[0] && in[1]
+		case PathOpUnion:
+			return in[0] || in[1]
+		case PathOpXOR:
+			return in[0] != in[1]
+		case PathOpReverseDifference:
+			return in[1] && !in[0]
+		}
+		return false
+	}
+	// If the result covers the area far from every edge, the boundary of
+	// its complement is built and the result is an inverse fill.
+	inverse := inside([2]int{})
+
+	var kept []opEdge
+	for i, s := range opEdgeSides(edges) {
+		inLeft, inRight := inside(s.left) != inverse, inside(s.right) != inverse
+		switch {
+		case inRight && !inLeft:
+			kept = append(kept, edges[i])
+		case inLeft && !inRight:
+			kept = append(kept, edges[i].reversed())
+		}
+	}
+
+	fillType := enums.PathFillTypeWinding
+	if inverse {
+		fillType = enums.PathFillTypeInverseWinding
+	}
+	result := impl.NewSkPath(fillType)
+	for _, contour := range chainOpEdges(kept) {
+		appendOpContour(result, contour, segs, g)
+	}
+	return result, true
+}
+
+// opContourSegments returns the segments of the contours of rec. Every
+// contour is closed by a line if needed, as when filling.
+func opContourSegments(rec recordedPath) [][]curveSegment {
+	var contours [][]curveSegment
+	for _, c := range rec.contours() {
+		if c[0].verb != enums.PathVerbMove {
+			continue
+		}
+		start := skPoint(c[0].pts[0])
+		pen := start
+		var segs []curveSegment
+		for _, s := range c[1:] {
+			seg := curveSegment{pts: [4]models.Point{pen}}
+			switch s.verb {
+			case enums.PathVerbLine:
+				seg.verb = enums.PathVerbLine
+				seg.pts[1] = skPoint(s.pts[0])
+			case enums.PathVerbQuad:
+				seg.verb = enums.PathVerbQuad
+				seg.pts[1], seg.pts[2] = skPoint(s.pts[0]), skPoint(s.pts[1])
+			case enums.PathVerbCubic:
+				seg.verb = enums.PathVerbCubic
+				seg.pts[1], seg.pts[2], seg.pts[3] = skPoint(s.pts[0]), skPoint(s.pts[1]), skPoint(s.pts[2])
+			default:
+				continue
+			}
+			segs = append(segs, seg)
+			pen = seg.end()
+		}
+		if pen != start {
+			segs = append(segs, curveSegment{verb: enums.PathVerbLine, pts: [4]models.Point{pen, start}})
+		}
+		if len(segs) > 0 {
+			contours = append(contours, segs)
+		}
+	}
+	return contours
+}
+
+// appendOpEdges appends the edges of the flattened contours of rec,
+// tagged as operand i, and the segments they were flattened from.
+func appendOpEdges(edges []opEdge, segs []curveSegment, rec recordedPath, i int, g opGrid) ([]opEdge, []curveSegment) {
+	for _, contour := range opContourSegments(rec) {
+		for _, seg := range contour {
+			idx := len(segs)
+			segs = append(segs, seg)
+			n := segmentSteps(seg, g.tol)
+			p, tp := g.snap(seg.pts[0]), Scalar(0)
+			for k := 1; k <= n; k++ {
+				t := Scalar(k) / Scalar(n)
+				pos := seg.end()
+				if k < n {
+					pos, _ = seg.eval(t)
+				}
+				q := g.snap(pos)
+				if q == p {
+					continue
+				}
+				e := opEdge{a: p, b: q, seg: idx, ta: tp, tb: t}
+				e.wind[i] = 1
+				if q.less(p) {
+					e = e.reversed()
+					e.wind[i] = -1
+				}
+				edges = append(edges, e)
+				p, tp = q, t
+			}
+		}
+	}
+	return edges, segs
+}
+
+// findOpSplits returns the points where each edge must be split so that
+// edges meet only at their ends, by edge index.
+func findOpSplits(edges []opEdge) map[int][]opPoint {
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return edges[order[i]].a.x < edges[order[j]].a.x })
+
+	splits := make(map[int][]opPoint)
+	for oi, i := range order {
+		e := edges[i]
+		for _, j := range order[oi+1:] {
+			f := edges[j]
+			if f.a.x > e.b.x {
+				break
+			}
+			if max(e.a.y, e.b.y) < min(f.a.y, f.b.y) || max(f.a.y, f.b.y) < min(e.a.y, e.b.y) {
+				continue
+			}
+			onE, onF := intersectOpEdges(e, f)
+			if len(onE) > 0 {
+				splits[i] = append(splits[i], onE...)
+			}
+			if len(onF) > 0 {
+				splits[j] = append(splits[j], onF...)
+			}
+		}
+	}
+	return splits
+}
+
+// splitOpEdges splits edges wherever they meet, so that edges intersect
+// only at their ends.
+func splitOpEdges(edges []opEdge) []opEdge {
+	for pass := 0; pass < maxPathOpsPasses; pass++ {
+		splits := findOpSplits(edges)
+		if len(splits) == 0 {
+			return edges
+		}
+		var next []opEdge
+		for i, e := range edges {
+			pts, ok := splits[i]
+			if !ok {
+				next = append(next, e)
+				continue
+			}
+			sort.Slice(pts, func(i, j int) bool { return pts[i].less(pts[j]) })
+			// The parameter of a split point is interpolated along the
+			// edge, which is within the tolerance of the segment.
+			dx, dy := float64(e.b.x-e.a.x), float64(e.b.y-e.a.y)
+			param := func(p opPoint) Scalar {
+				f := (float64(p.x-e.a.x)*dx + float64(p.y-e.a.y)*dy) / (dx*dx + dy*dy)
+				return e.ta + (e.tb-e.ta)*Scalar(f)
+			}
+			start, ts := e.a, e.ta
+			for _, p := range append(pts, e.b) {
+				// Snapping can move a split point past the end of the
+				// edge; such points are skipped.
+				if !start.less(p) || e.b.less(p) {
+					continue
+				}
+				tp := e.tb
+				if p != e.b {
+					tp = param(p)
+				}
+				next = append(next, opEdge{a: start, b: p, wind: e.wind, seg: e.seg, ta: ts, tb: tp})
+				start, ts = p, tp
+			}
+		}
+		edges = next
+	}
+	return edges
+}
+
+// intersectOpEdges returns the points where e and f meet that are inside
+// e and inside f, excluding their ends.
+func intersectOpEdges(e, f opEdge) (onE, onF []opPoint) {
+	px, py := float64(e.a.x), float64(e.a.y)
+	rx, ry := float64(e.b.x-e.a.x), float64(e.b.y-e.a.y)
+	qx, qy := float64(f.a.x), float64(f.a.y)
+	sx, sy := float64(f.b.x-f.a.x), float64(f.b.y-f.a.y)
+
+	denom := rx*sy - ry*sx
+	wx, wy := qx-px, qy-py
+	if denom == 0 {
+		if wx*ry-wy*rx != 0 {
+			// Parallel but not collinear.
+			return nil, nil
+		}
+		// Collinear: split each edge at the ends of the other inside it.
+		for _, p := range [2]opPoint{f.a, f.b} {
+			if e.a.less(p) && p.less(e.b) {
+				onE = append(onE, p)
+			}
+		}
+		for _, p := range [2]opPoint{e.a, e.b} {
+			if f.a.less(p) && p.less(f.b) {
+				onF = append(onF, p)
+			}
+		}
+		return onE, onF
+	}
+	t := (wx*sy - wy*sx) / denom
+	u := (wx*ry - wy*rx) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return nil, nil
+	}
+	p := opPoint{x: int64(math.Round(px + t*rx)), y: int64(math.Round(py + t*ry))}
+	if p != e.a && p != e.b {
+		onE = append(onE, p)
+	}
+	if p != f.a && p != f.b {
+		onF = append(onF, p)
+	}
+	return onE, onF
+}
+
+// mergeOpEdges merges identical edges, summing their winding, and drops
+// edges that no longer change the winding. Merged edges keep the segment
+// of the first.
+func mergeOpEdges(edges []opEdge) []opEdge {
+	index := make(map[[2]opPoint]int)
+	var merged []opEdge
+	for _, e := range edges {
+		k := [2]opPoint{e.a, e.b}
+		if i, ok := index[k]; ok {
+			merged[i].wind[0] += e.wind[0]
+			merged[i].wind[1] += e.wind[1]
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, e)
+	}
+	kept := merged[:0]
+	for _, e := range merged {
+		if e.wind != [2]int{} {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// opEdgeSides returns the winding numbers of both operands on the left
+// and right of every edge, looking along it from a to b in y-down
+// coordinates. Edges meet only at their ends, so a sweep from left to
+// right keeps the edges it crosses in one order from top to bottom, and
+// the winding below an edge is the sum of the windings of the edges above
+// it and its own.
+func opEdgeSides(edges []opEdge) []opSides {
+	sides := make([]opSides, len(edges))
+	var starts, ends, verticals []int
+	xs := make([]int64, 0, 2*len(edges))
+	for i, e := range edges {
+		if e.a.x == e.b.x {
+			verticals = append(verticals, i)
+		} else {
+			starts = append(starts, i)
+			ends = append(ends, i)
+		}
+		xs = append(xs, e.a.x, e.b.x)
+	}
+	sort.Slice(starts, func(i, j int) bool { return edges[starts[i]].a.x < edges[starts[j]].a.x })
+	sort.Slice(ends, func(i, j int) bool { return edges[ends[i]].b.x < edges[ends[j]].b.x })
+	sort.Slice(verticals, func(i, j int) bool { return edges[verticals[i]].a.x < edges[verticals[j]].a.x })
+	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+
+	yAt := func(e opEdge, x float64) float64 {
+		return float64(e.a.y) + (x-float64(e.a.x))*float64(e.b.y-e.a.y)/float64(e.b.x-e.a.x)
+	}
+	// active holds the edges spanning the current slab, top to bottom.
+	var active []int
+	fresh := make([]bool, len(edges))
+	for k, x := range xs {
+		if k > 0 && x == xs[k-1] {
+			continue
+		}
+		// Vertical edges at x see the edges of the slab to their left.
+		// The winding there is that left of the downward edge, its right.
+		for len(verticals) > 0 && edges[verticals[0]].a.x == x {
+			i := verticals[0]
+			verticals = verticals[1:]
+			e := edges[i]
+			my := float64(e.a.y+e.b.y) / 2
+			var w [2]int
+			for _, j := range active {
+				if yAt(edges[j], float64(x)) >= my {
+					break
+				}
+				w[0] += edges[j].wind[0]
+				w[1] += edges[j].wind[1]
+			}
+			sides[i] = opSides{left: [2]int{w[0] - e.wind[0], w[1] - e.wind[1]}, right: w}
+		}
+		if len(ends) > 0 && edges[ends[0]].b.x == x {
+			for len(ends) > 0 && edges[ends[0]].b.x == x {
+				ends = ends[1:]
+			}
+			n := 0
+			for _, j := range active {
+				if edges[j].b.x != x {
+					active[n] = j
+					n++
+				}
+			}
+			active = active[:n]
+		}
+		if len(starts) == 0 || edges[starts[0]].a.x != x {
+			continue
+		}
+		// Order the new edges by their height in the middle of the slab
+		// they start.
+		next := x + 1
+		for _, nx := range xs[k+1:] {
+			if nx > x {
+				next = nx
+				break
+			}
+		}
+		xm := (float64(x) + float64(next)) / 2
+		for len(starts) > 0 && edges[starts[0]].a.x == x {
+			i := starts[0]
+			starts = starts[1:]
+			y := yAt(edges[i], xm)
+			pos := sort.Search(len(active), func(j int) bool { return yAt(edges[active[j]], xm) > y })
+			active = append(active, 0)
+			copy(active[pos+1:], active[pos:])
+			active[pos] = i
+			fresh[i] = true
+		}
+		// Above is left of an edge running right.
+		var w [2]int
+		for _, j := range active {
+			e := edges[j]
+			if fresh[j] {
+				fresh[j] = false
+				sides[j] = opSides{left: w, right: [2]int{w[0] + e.wind[0], w[1] + e.wind[1]}}
+			}
+			w[0] += e.wind[0]
+			w[1] += e.wind[1]
+		}
+	}
+	return sides
+}
+
+// chainOpEdges links directed edges into closed contours, turning as far
+// right as possible at shared vertices so that touching regions become
+// separate contours.
+func chainOpEdges(edges []opEdge) [][]opEdge {
+	out := make(map[opPoint][]int)
+	for i, e := range edges {
+		out[e.a] = append(out[e.a], i)
+	}
+	used := make([]bool, len(edges))
+	var contours [][]opEdge
+	for first := range edges {
+		if used[first] {
+			continue
+		}
+		used[first] = true
+		start := edges[first].a
+		contour := []opEdge{edges[first]}
+		cur := first
+		for edges[cur].b != start {
+			e := edges[cur]
+			next := -1
+			best := math.Inf(-1)
+			for _, j := range out[e.b] {
+				if used[j] {
+					continue
+				}
+				if a := turnAngle(e, edges[j]); a > best {
+					next, best = j, a
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			cur = next
+			contour = append(contour, edges[cur])
+		}
+		contours = append(contours, contour)
+	}
+	return contours
+}
+
+// turnAngle returns the angle turned from e to f, positive for right turns
+// in y-down coordinates.
+func turnAngle(e, f opEdge) float64 {
+	dx0, dy0 := float64(e.b.x-e.a.x), float64(e.b.y-e.a.y)
+	dx1, dy1 := float64(f.b.x-f.a.x), float64(f.b.y-f.a.y)
+	return math.Atan2(dx0*dy1-dy0*dx1, dx0*dx1+dy0*dy1)
+}
+
+// appendOpContour appends the closed contour through edges to path. Runs
+// of edges along one curve are emitted as the piece of the curve they
+// cover, and runs of collinear lines as one line. Contours without area
+// are dropped.
+func appendOpContour(path SkPath, edges []opEdge, segs []curveSegment, g opGrid) {
+	joins := func(e, f opEdge) bool {
+		if segs[e.seg].verb != enums.PathVerbLine || segs[f.seg].verb != enums.PathVerbLine {
+			return e.seg == f.seg && e.tb == f.ta
+		}
+		// Lines join if the second goes straight on.
+		dx0, dy0 := float64(e.b.x-e.a.x), float64(e.b.y-e.a.y)
+		dx1, dy1 := float64(f.b.x-f.a.x), float64(f.b.y-f.a.y)
+		return dx0*dy1-dy0*dx1 == 0 && dx0*dx1+dy0*dy1 > 0
+	}
+	// Start at the start of a run.
+	n := len(edges)
+	start := -1
+	for i := range edges {
+		if !joins(edges[(i+n-1)%n], edges[i]) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return
+	}
+	type run struct{ first, last opEdge }
+	var runs []run
+	curved := false
+	for k := 0; k < n; k++ {
+		e := edges[(start+k)%n]
+		if k > 0 && joins(runs[len(runs)-1].last, e) {
+			runs[len(runs)-1].last = e
+			continue
+		}
+		runs = append(runs, run{first: e, last: e})
+		curved = curved || segs[e.seg].verb != enums.PathVerbLine
+	}
+	if len(runs) < 2 || (len(runs) < 3 && !curved) {
+		return
+	}
+	p := g.point(runs[0].first.a)
+	path.MoveTo(p.X, p.Y)
+	for i, r := range runs {
+		seg := segs[r.first.seg]
+		from, to := g.point(r.first.a), g.point(r.last.b)
+		if seg.verb == enums.PathVerbLine {
+			// Closing draws the last line.
+			if i < len(runs)-1 {
+				path.LineTo(to.X, to.Y)
+			}
+			continue
+		}
+		// The piece ends exactly at the snapped vertices.
+		piece := chopCurveSegment(seg, r.first.ta, r.last.tb)
+		piece.pts[0] = from
+		if piece.verb == enums.PathVerbQuad {
+			piece.pts[2] = to
+		} else {
+			piece.pts[3] = to
+		}
+		appendCurveSegment(path, piece)
+	}
+	path.Close()
+}
+
+// chopCurveSegment returns the part of seg from t0 to t1, reversed if t1
+// is less than t0.
+func chopCurveSegment(seg curveSegment, t0, t1 Scalar) curveSegment {
+	if t0 <= t1 {
+		return seg.chop(t0, t1)
+	}
+	piece := seg.chop(t1, t0)
+	last := 2
+	if piece.verb == enums.PathVerbCubic {
+		last = 3
+	}
+	for i, j := 0, last; i < j; i, j = i+1, j-1 {
+		piece.pts[i], piece.pts[j] = piece.pts[j], piece.pts[i]
+	}
+	return piece
+}
+
+// orientContours returns path with the winding fill rule, each contour
+// winding opposite to the contour directly enclosing it and the outermost
+// clockwise. It reports false if contours cross or touch, when their
+// nesting does not describe the area.
+func orientContours(path SkPath) (SkPath, bool) {
+	g := newOpGrid(path.Bounds())
+	var rec recordedPath
+	convertPath(path, g.tol, &rec)
+	contours := rec.contours()
+	contourEdges := make([][]opEdge, len(contours))
+	var all []opEdge
+	degree := make(map[opPoint]int)
+	for i, c := range contours {
+		contourEdges[i], _ = appendOpEdges(nil, nil, c, 0, g)
+		for _, e := range contourEdges[i] {
+			degree[e.a]++
+			degree[e.b]++
+		}
+		all = append(all, contourEdges[i]...)
+	}
+	for _, d := range degree {
+		if d > 2 {
+			return nil, false
+		}
+	}
+	if len(findOpSplits(all)) > 0 {
+		return nil, false
+	}
+
+	fillType := enums.PathFillTypeWinding
+	if path.IsInverseFillType() {
+		fillType = enums.PathFillTypeInverseWinding
+	}
+	result := impl.NewSkPath(fillType)
+	sink := skPathSink{path: result}
+	for i, c := range contours {
+		edges := contourEdges[i]
+		if len(edges) == 0 {
+			continue
+		}
+		// The depth of a contour is the number of contours around the
+		// middle of its first edge.
+		e := edges[0]
+		mx, my := float64(e.a.x+e.b.x)/2, float64(e.a.y+e.b.y)/2
+		depth := 0
+		for j, other := range contourEdges {
+			if j != i && opPolygonContains(other, mx, my) {
+				depth++
+			}
+		}
+		var area float64
+		for _, e := range edges {
+			area += float64(e.wind[0]) * (float64(e.a.x)*float64(e.b.y) - float64(e.b.x)*float64(e.a.y))
+		}
+		// Clockwise contours have positive area in y-down coordinates.
+		if (area < 0) == (depth%2 == 0) {
+			c = c.reversed()
+		}
+		c.replay(sink)
+	}
+	return result, true
+}
+
+// opPolygonContains reports whether the polygon of edges contains the
+// point (x, y) in grid coordinates under the even-odd rule.
+func opPolygonContains(edges []opEdge, x, y float64) bool {
+	in := false
+	for _, e := range edges {
+		// Cast a ray up; ends are half open so vertices on it count once.
+		if float64(e.a.x) > x || x >= float64(e.b.x) {
+			continue
+		}
+		t := (x - float64(e.a.x)) / float64(e.b.x-e.a.x)
+		if float64(e.a.y)+t*float64(e.b.y-e.a.y) < y {
+			in = !in
+		}
+	}
+	return in
+}
+
+// flattenSink approximates segments by lines within tol, collecting one
+// polygon per contour.
+type flattenSink struct {
+	tol      Scalar
+	contours [][]f32.Point
+	cur      []f32.Point
+}
+
+func (s *flattenSink) endContour() {
+	if len(s.cur) > 1 {
+		s.contours = append(s.contours, s.cur)
+	}
+	s.cur = nil
+}
+
+func (s *flattenSink) pen() f32.Point {
+	return s.cur[len(s.cur)-1]
+}
+
+func (s *flattenSink) MoveTo(to f32.Point) {
+	s.endContour()
+	s.cur = []f32.Point{to}
+}
+
+func (s *flattenSink) LineTo(to f32.Point) {
+	s.cur = append(s.cur, to)
+}
+
+func (s *flattenSink) QuadTo(ctrl, to f32.Point) {
+	s.flatten(curveSegment{verb: enums.PathVerbQuad, pts: [4]models.Point{skPoint(s.pen()), skPoint(ctrl), skPoint(to)}})
+}
+
+func (s *flattenSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	s.flatten(curveSegment{verb: enums.PathVerbCubic, pts: [4]models.Point{skPoint(s.pen()), skPoint(ctrl0), skPoint(ctrl1), skPoint(to)}})
+}
+
+func (s *flattenSink) flatten(seg curveSegment) {
+	n := segmentSteps(seg, s.tol)
+	for i := 1; i < n; i++ {
+		p, _ := seg.eval(Scalar(i) / Scalar(n))
+		s.cur = append(s.cur, f32Pt(p))
+	}
+	s.cur = append(s.cur, f32Pt(seg.end()))
+}
+
+func (s *flattenSink) Close() {
+	s.endContour()
+}
+
+// segmentSteps returns the number of chords approximating seg within tol.
+func segmentSteps(seg curveSegment, tol Scalar) int {
+	p := seg.pts
+	second := func(a, b, c models.Point) Scalar {
+		return pointLength(addPoint(subPoint(a, scalePoint(b, 2)), c))
+	}
+	var dd Scalar
+	switch seg.verb {
+	case enums.PathVerbQuad:
+		// A quadratic deviates from its chords by at most
+		// |p0-2p1+p2|/(4n²).
+		dd = second(p[0], p[1], p[2])
+	case enums.PathVerbCubic:
+		// A cubic deviates from its chords by at most
+		// 3·max|p(i)-2p(i+1)+p(i+2)|/(4n²).
+		dd = 3 * max(second(p[0], p[1], p[2]), second(p[1], p[2], p[3]))
+	default:
+		return 1
+	}
+	n := int(math.Ceil(math.Sqrt(float64(dd / (4 * tol)))))
+	return min(max(n, 1), maxFlattenSteps)
+}
+
+// pathOpsRect returns a path covering r.
+func pathOpsRect(r models.Rect) SkPath {
+	p := impl.NewSkPath(enums.PathFillTypeWinding)
+	p.AddRect(r, enums.PathDirectionCW, 0)
+	return p
+}
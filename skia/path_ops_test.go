@@ -0,0 +1,323 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func rectPath(l, t, r, b Scalar) SkPath {
+	return pathOpsRect(models.Rect{Left: l, Top: t, Right: r, Bottom: b})
+}
+
+func flattenForTest(path SkPath) [][]f32.Point {
+	f := flattenSink{tol: pathOpsTolerance}
+	convertPath(path, pathOpsTolerance, &f)
+	f.endContour()
+	return f.contours
+}
+
+// pathArea returns the area covered by a path with consistently oriented,
+// non-intersecting contours.
+func pathArea(path SkPath) Scalar {
+	var area float64
+	for _, c := range flattenForTest(path) {
+		for i := range c {
+			p, q := c[i], c[(i+1)%len(c)]
+			area += float64(p.X*q.Y - q.X*p.Y)
+		}
+	}
+	return Scalar(math.Abs(area) / 2)
+}
+
+// pathContains reports whether path covers p under its fill rule.
+func pathContains(path SkPath, p f32.Point) bool {
+	w := 0
+	for _, c := range flattenForTest(path) {
+		for i := range c {
+			a, b := c[i], c[(i+1)%len(c)]
+			if (a.Y <= p.Y) == (b.Y <= p.Y) {
+				continue
+			}
+			x := a.X + (p.Y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+			if x < p.X {
+				if b.Y > a.Y {
+					w++
+				} else {
+					w--
+				}
+			}
+		}
+	}
+	var in bool
+	switch path.FillType() {
+	case enums.PathFillTypeWinding, enums.PathFillTypeInverseWinding:
+		in = w != 0
+	default:
+		in = w&1 != 0
+	}
+	return in != path.IsInverseFillType()
+}
+
+// nearEdge reports whether p is within d of an edge of path.
+func nearEdge(path SkPath, p f32.Point, d float32) bool {
+	for _, c := range flattenForTest(path) {
+		for i := range c {
+			a, b := skPoint(c[i]), skPoint(c[(i+1)%len(c)])
+			if distanceToSegment(skPoint(p), a, b) < Scalar(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestOp_Rects(t *testing.T) {
+	a := rectPath(0, 0, 10, 10)
+	b := rectPath(5, 5, 15, 15)
+	tests := []struct {
+		op   PathOp
+		want Scalar
+	}{
+		{PathOpDifference, 75},
+		{PathOpIntersect, 25},
+		{PathOpUnion, 175},
+		{PathOpXOR, 150},
+		{PathOpReverseDifference, 75},
+	}
+	for _, tt := range tests {
+		got, ok := Op(a, b, tt.op)
+		if !ok {
+			t.Fatalf("op %d failed", tt.op)
+		}
+		if area := pathArea(got); !near(area, tt.want, 1e-3) {
+			t.Errorf("op %d: area %v, want %v", tt.op, area, tt.want)
+		}
+	}
+}
+
+func TestOp_CoincidentEdges(t *testing.T) {
+	a := rectPath(0, 0, 10, 10)
+	b := rectPath(10, 0, 20, 10)
+	union, _ := Op(a, b, PathOpUnion)
+	if n := union.CountPoints(); n != 4 {
+		t.Errorf("union of adjacent squares has %d points, want a single rectangle", n)
+	}
+	if area := pathArea(union); !near(area, 200, 1e-3) {
+		t.Errorf("union area %v, want 200", area)
+	}
+	if inter, _ := Op(a, b, PathOpIntersect); !inter.IsEmpty() {
+		t.Errorf("adjacent squares should not intersect, got %d points", inter.CountPoints())
+	}
+
+	// Identical paths.
+	if diff, _ := Op(a, a, PathOpDifference); !diff.IsEmpty() {
+		t.Error("a path minus itself should be empty")
+	}
+	if xor, _ := Op(a, a, PathOpXOR); !xor.IsEmpty() {
+		t.Error("a path xor itself should be empty")
+	}
+	if same, _ := Op(a, a, PathOpUnion); !near(pathArea(same), 100, 1e-3) {
+		t.Error("a path union itself should be unchanged")
+	}
+
+	// Overlapping collinear edges.
+	c := rectPath(3, 10, 7, 20)
+	u, _ := Op(a, c, PathOpUnion)
+	if area := pathArea(u); !near(area, 140, 1e-3) {
+		t.Errorf("T shape area %v, want 140", area)
+	}
+}
+
+func TestOp_Curves(t *testing.T) {
+	a := impl.NewSkPath(enums.PathFillTypeWinding)
+	a.AddCircle(0, 0, 50, enums.PathDirectionCW)
+	b := impl.NewSkPath(enums.PathFillTypeWinding)
+	b.AddCircle(50, 0, 50, enums.PathDirectionCCW)
+
+	// Two circles of radius r whose centers are r apart overlap in a lens
+	// of area (2π/3 - √3/2)r².
+	r := 50.0
+	lens := (2*math.Pi/3 - math.Sqrt(3)/2) * r * r
+	disc := math.Pi * r * r
+	inter, _ := Op(a, b, PathOpIntersect)
+	if area := float64(pathArea(inter)); math.Abs(area-lens) > 0.002*lens {
+		t.Errorf("lens area %v, want %v", area, lens)
+	}
+	union, _ := Op(a, b, PathOpUnion)
+	if area := float64(pathArea(union)); math.Abs(area-(2*disc-lens)) > 0.002*disc {
+		t.Errorf("union area %v, want %v", area, 2*disc-lens)
+	}
+}
+
+// countVerbs returns the number of verbs of path by kind.
+func countVerbs(path SkPath) map[enums.PathVerb]int {
+	verbs := make([]enums.PathVerb, path.CountVerbs())
+	path.GetVerbs(verbs)
+	counts := make(map[enums.PathVerb]int)
+	for _, v := range verbs {
+		counts[v]++
+	}
+	return counts
+}
+
+func TestOp_KeepsCurves(t *testing.T) {
+	// The same lens at two scales: results keep the curves of the
+	// operands, and small paths are as precise as large ones.
+	for _, r := range []Scalar{50, 0.05} {
+		a := impl.NewSkPath(enums.PathFillTypeWinding)
+		a.AddCircle(0, 0, r, enums.PathDirectionCW)
+		b := impl.NewSkPath(enums.PathFillTypeWinding)
+		b.AddCircle(r, 0, r, enums.PathDirectionCW)
+		union, _ := Op(a, b, PathOpUnion)
+		// Conics become quads; no lines are left of the flattening.
+		if verbs := countVerbs(union); verbs[enums.PathVerbLine] != 0 || verbs[enums.PathVerbQuad] == 0 {
+			t.Errorf("r=%v: union verbs %v, want quads only", r, verbs)
+		}
+		rr := float64(r)
+		want := (2*math.Pi - (2*math.Pi/3 - math.Sqrt(3)/2)) * rr * rr
+		if area := float64(pathArea(union)); math.Abs(area-want) > 0.002*want {
+			t.Errorf("r=%v: union area %v, want %v", r, area, want)
+		}
+	}
+}
+
+func TestAsWinding_Nested(t *testing.T) {
+	// A disc with a hole holding an island, all drawn clockwise.
+	p := impl.NewSkPath(enums.PathFillTypeEvenOdd)
+	p.AddCircle(0, 0, 30, enums.PathDirectionCW)
+	p.AddCircle(0, 0, 20, enums.PathDirectionCW)
+	p.AddCircle(0, 0, 10, enums.PathDirectionCW)
+	got, ok := AsWinding(p)
+	if !ok || got.FillType() != enums.PathFillTypeWinding {
+		t.Fatalf("AsWinding: ok %v", ok)
+	}
+	// The contours are kept, only the hole is reversed.
+	if verbs := countVerbs(got); verbs[enums.PathVerbLine] != 0 || verbs[enums.PathVerbClose] != 3 {
+		t.Errorf("verbs %v, want the three circles", verbs)
+	}
+	tests := []struct {
+		p    f32.Point
+		want bool
+	}{
+		{f32.Pt(25, 0), true},
+		{f32.Pt(15, 0), false},
+		{f32.Pt(5, 0), true},
+		{f32.Pt(35, 0), false},
+	}
+	for _, tt := range tests {
+		if in := pathContains(got, tt.p); in != tt.want {
+			t.Errorf("point %v inside %v, want %v", tt.p, in, tt.want)
+		}
+	}
+}
+
+func TestOp_FillTypes(t *testing.T) {
+	// Two overlapping squares in one path.
+	overlap := func(fillType enums.PathFillType) SkPath {
+		p := impl.NewSkPath(fillType)
+		p.AddRect(models.Rect{Right: 10, Bottom: 10}, enums.PathDirectionCW, 0)
+		p.AddRect(models.Rect{Left: 5, Top: 5, Right: 15, Bottom: 15}, enums.PathDirectionCW, 0)
+		return p
+	}
+	simple, _ := Simplify(overlap(enums.PathFillTypeEvenOdd))
+	if area := pathArea(simple); !near(area, 150, 1e-3) {
+		t.Errorf("even-odd area %v, want 150", area)
+	}
+	simple, _ = Simplify(overlap(enums.PathFillTypeWinding))
+	if area := pathArea(simple); !near(area, 175, 1e-3) {
+		t.Errorf("winding area %v, want 175", area)
+	}
+
+	winding, _ := AsWinding(overlap(enums.PathFillTypeEvenOdd))
+	if ft := winding.FillType(); ft != enums.PathFillTypeWinding {
+		t.Errorf("AsWinding fill type %v", ft)
+	}
+	if area := pathArea(winding); !near(area, 150, 1e-3) {
+		t.Errorf("AsWinding area %v, want 150", area)
+	}
+
+	// An inverse operand covers everything outside it.
+	inv := rectPath(0, 0, 10, 10)
+	inv.SetFillType(enums.PathFillTypeInverseWinding)
+	got, _ := Op(inv, rectPath(5, 5, 15, 15), PathOpIntersect)
+	if area := pathArea(got); got.IsInverseFillType() || !near(area, 75, 1e-3) {
+		t.Errorf("inverse intersect: inverse %v, area %v, want 75", got.IsInverseFillType(), area)
+	}
+	got, _ = Op(inv, rectPath(5, 5, 15, 15), PathOpUnion)
+	// Everything but the part of the square outside the other.
+	if !got.IsInverseFillType() || !near(pathArea(got), 75, 1e-3) {
+		t.Errorf("inverse union: inverse %v, area %v, want 75", got.IsInverseFillType(), pathArea(got))
+	}
+}
+
+func TestOp_NonFinite(t *testing.T) {
+	bad := impl.NewSkPath(enums.PathFillTypeWinding)
+	bad.MoveTo(0, 0)
+	bad.LineTo(Scalar(math.Inf(1)), 0)
+	bad.LineTo(0, 10)
+	if _, ok := Op(bad, rectPath(0, 0, 1, 1), PathOpUnion); ok {
+		t.Error("non-finite paths should fail")
+	}
+}
+
+func TestOp_Containment(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	shape := func() SkPath {
+		p := impl.NewSkPath(enums.PathFillType(r.Intn(4)))
+		for i := 0; i < 1+r.Intn(3); i++ {
+			x, y := Scalar(r.Intn(60)), Scalar(r.Intn(60))
+			if r.Intn(2) == 0 {
+				p.AddCircle(x, y, Scalar(5+r.Intn(30)), enums.PathDirection(r.Intn(2)))
+			} else {
+				p.AddRect(models.Rect{Left: x, Top: y, Right: x + Scalar(5+r.Intn(40)), Bottom: y + Scalar(5+r.Intn(40))}, enums.PathDirection(r.Intn(2)), 0)
+			}
+		}
+		return p
+	}
+	// Indexed by op.
+	combine := []func(a, b bool) bool{
+		func(a, b bool) bool { return a && !b },
+		func(a, b bool) bool { return a && b },
+		func(a, b bool) bool { return a || b },
+		func(a, b bool) bool { return a != b },
+		func(a, b bool) bool { return b && !a },
+	}
+	for i := 0; i < 40; i++ {
+		a, b := shape(), shape()
+		for op, want := range combine {
+			got, ok := Op(a, b, PathOp(op))
+			if !ok {
+				t.Fatalf("case %d: op %d failed", i, op)
+			}
+			for j := 0; j < 50; j++ {
+				p := f32.Pt(float32(r.Float64()*120-20), float32(r.Float64()*120-20))
+				if nearEdge(a, p, 0.05) || nearEdge(b, p, 0.05) {
+					continue
+				}
+				if in := pathContains(got, p); in != want(pathContains(a, p), pathContains(b, p)) {
+					t.Fatalf("case %d, op %d: point %v inside result %v", i, op, p, in)
+				}
+			}
+		}
+	}
+}
+
+func TestCanvas_ClipDifference(t *testing.T) {
+	canvas := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+	canvas.ClipRect(models.Rect{Left: 10, Top: 10, Right: 60, Bottom: 60}, enums.ClipOpIntersect, true)
+	canvas.ClipRect(models.Rect{Left: 20, Top: 20, Right: 30, Bottom: 30}, enums.ClipOpDifference, true)
+	if got := canvas.GetDeviceClipBounds(); got != (models.IRect{Left: 10, Top: 10, Right: 60, Bottom: 60}) {
+		t.Errorf("clip bounds %+v", got)
+	}
+	if canvas.IsClipRect() || canvas.IsClipEmpty() {
+		t.Error("difference clip should be a non-empty complex clip")
+	}
+}
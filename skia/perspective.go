@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Perspective transforms. Gio only supports affine transforms, so geometry
+// under a perspective matrix is mapped to device space on the CPU and drawn
+// with the identity transform.
+
+const (
+	// perspectiveTolerance is the largest distance in device pixels between
+	// a projected curve and its flattened approximation.
+	perspectiveTolerance = 0.25
+	// maxPerspectiveDepth bounds the subdivision of a single segment.
+	maxPerspectiveDepth = 10
+	// maxPerspectiveCells bounds the grid used to draw images.
+	maxPerspectiveCells = 32
+	// minPerspectiveW keeps points behind the viewer from dividing by zero.
+	minPerspectiveW = 1.0 / (1 << 14)
+)
+
+// matrix returns the full transform of the context.
+func (ctx *context) matrix() SkMatrix {
+	if ctx.persp != nil {
+		return ctx.persp
+	}
+	return affine2DToSkMatrix(ctx.xform)
+}
+
+// perspectiveMap maps p through the homogeneous transform m.
+func perspectiveMap(m SkMatrix, p models.Point) models.Point {
+	e := m.Get9()
+	x := e[0]*p.X + e[1]*p.Y + e[2]
+	y := e[3]*p.X + e[4]*p.Y + e[5]
+	w := e[6]*p.X + e[7]*p.Y + e[8]
+	if w < minPerspectiveW {
+		w = minPerspectiveW
+	}
+	return models.Point{X: x / w, Y: y / w}
+}
+
+// mapPathPerspective maps path through m. Lines stay lines; curves are
+// subdivided until their projection is flat within perspectiveTolerance.
+func mapPathPerspective(path SkPath, m SkMatrix) SkPath {
+	sink := &perspectiveSink{m: m, dst: impl.NewSkPath(path.FillType())}
+	convertPath(path, perspectiveTolerance, sink)
+	return sink.dst
+}
+
+// perspectiveSink maps path segments through a perspective matrix.
+type perspectiveSink struct {
+	m   SkMatrix
+	dst SkPath
+	pen models.Point
+}
+
+func (s *perspectiveSink) lineTo(p models.Point) {
+	p = perspectiveMap(s.m, p)
+	s.dst.LineTo(p.X, p.Y)
+}
+
+func (s *perspectiveSink) MoveTo(to f32.Point) {
+	s.pen = skPoint(to)
+	p := perspectiveMap(s.m, s.pen)
+	s.dst.MoveTo(p.X, p.Y)
+}
+
+func (s *perspectiveSink) LineTo(to f32.Point) {
+	s.pen = skPoint(to)
+	s.lineTo(s.pen)
+}
+
+func (s *perspectiveSink) QuadTo(ctrl, to f32.Point) {
+	seg := curveSegment{verb: enums.PathVerbQuad, pts: [4]models.Point{s.pen, skPoint(ctrl), skPoint(to)}}
+	flattenPerspective(seg, s.m, s.lineTo)
+	s.pen = skPoint(to)
+}
+
+func (s *perspectiveSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	seg := curveSegment{verb: enums.PathVerbCubic, pts: [4]models.Point{s.pen, skPoint(ctrl0), skPoint(ctrl1), skPoint(to)}}
+	flattenPerspective(seg, s.m, s.lineTo)
+	s.pen = skPoint(to)
+}
+
+func (s *perspectiveSink) Close() {
+	s.dst.Close()
+}
+
+func skPoint(p f32.Point) models.Point {
+	return models.Point{X: Scalar(p.X), Y: Scalar(p.Y)}
+}
+
+// flattenPerspective calls lineTo with points along seg, excluding its
+// start, such that the projected polyline is within tolerance of the
+// projected curve. The projection of a curve is a rational curve inside the
+// hull of its projected control points, so a piece is flat once its
+// projected control polygon is.
+func flattenPerspective(seg curveSegment, m SkMatrix, lineTo func(models.Point)) {
+	var split func(t0, t1 Scalar, depth int)
+	split = func(t0, t1 Scalar, depth int) {
+		part := seg.chop(t0, t1)
+		if depth < maxPerspectiveDepth {
+			projected := part
+			for i := range projected.pts {
+				projected.pts[i] = perspectiveMap(m, projected.pts[i])
+			}
+			if projected.flatness() > perspectiveTolerance {
+				mid := (t0 + t1) / 2
+				split(t0, mid, depth+1)
+				split(mid, t1, depth+1)
+				return
+			}
+		}
+		lineTo(part.end())
+	}
+	split(0, 1, 0)
+}
+
+// drawPathPerspective fills or strokes path under the perspective matrix
+// of the current context. Strokes are outlined in local space, so their
+// width foreshortens like the rest of the geometry.
+func (c *canvas) drawPathPerspective(path SkPath, paint Paint, cache bool) {
+	ctx := &c.stack[len(c.stack)-1]
+	outline := path
+	if !paint.Fill {
+		outline = impl.NewSkPath(enums.PathFillTypeWinding)
+		convertPaintPath(path, paint, c.pathTolerance(conicTolerance), cache, skPathSink{path: outline})
+	}
+	device := mapPathPerspective(outline, ctx.persp)
+	if device.IsEmpty() {
+		return
+	}
+	for _, cl := range ctx.clips {
+		stack := cl.op.Push(c.ops)
+		defer stack.Pop()
+	}
+	gpaint.FillShape(c.ops, paint.Color, c.buildPathClip(device))
+}
+
+// drawImagePerspective draws the src part of the image of imgOp into dst
+// under the perspective matrix of the current context. dst is split into a
+// grid of cells small enough that an affine mapping of each cell is within
+// tolerance of the projection.
+func (c *canvas) drawImagePerspective(imgOp gpaint.ImageOp, srcRect, dst models.Rect) {
+	ctx := &c.stack[len(c.stack)-1]
+	m := ctx.persp
+
+	// The affine approximation of a cell fitted to three corners misses
+	// the fourth by an error that shrinks with the square of the cell size.
+	p00 := perspectiveMap(m, models.Point{X: dst.Left, Y: dst.Top})
+	p10 := perspectiveMap(m, models.Point{X: dst.Right, Y: dst.Top})
+	p01 := perspectiveMap(m, models.Point{X: dst.Left, Y: dst.Bottom})
+	p11 := perspectiveMap(m, models.Point{X: dst.Right, Y: dst.Bottom})
+	miss := pointLength(subPoint(p11, subPoint(addPoint(p10, p01), p00)))
+	n := int(math.Ceil(math.Sqrt(float64(miss / perspectiveTolerance))))
+	n = min(max(n, 1), maxPerspectiveCells)
+
+	for _, cl := range ctx.clips {
+		stack := cl.op.Push(c.ops)
+		defer stack.Pop()
+	}
+
+	lerp := func(a, b Scalar, i int) Scalar { return a + (b-a)*Scalar(i)/Scalar(n) }
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			x0, x1 := lerp(dst.Left, dst.Right, i), lerp(dst.Left, dst.Right, i+1)
+			y0, y1 := lerp(dst.Top, dst.Bottom, j), lerp(dst.Top, dst.Bottom, j+1)
+			d00 := perspectiveMap(m, models.Point{X: x0, Y: y0})
+			d10 := perspectiveMap(m, models.Point{X: x1, Y: y0})
+			d01 := perspectiveMap(m, models.Point{X: x0, Y: y1})
+			d11 := perspectiveMap(m, models.Point{X: x1, Y: y1})
+			s0 := models.Point{X: lerp(srcRect.Left, srcRect.Right, i), Y: lerp(srcRect.Top, srcRect.Bottom, j)}
+			s1 := models.Point{X: lerp(srcRect.Left, srcRect.Right, i+1), Y: lerp(srcRect.Top, srcRect.Bottom, j+1)}
+
+			// Split the cell into triangles, each mapped exactly by an
+			// affine transform.
+			c.drawImageTriangle(imgOp, [3]models.Point{s0, {X: s1.X, Y: s0.Y}, s1}, [3]models.Point{d00, d10, d11})
+			c.drawImageTriangle(imgOp, [3]models.Point{s0, s1, {X: s0.X, Y: s1.Y}}, [3]models.Point{d00, d11, d01})
+		}
+	}
+}
+
+// drawImageTriangle draws the src triangle of the image mapped onto the
+// dst triangle in device space.
+func (c *canvas) drawImageTriangle(img gpaint.ImageOp, src, dst [3]models.Point) {
+	xform, ok := triangleAffine(src, dst)
+	if !ok {
+		return
+	}
+	var path clip.Path
+	path.Begin(c.ops)
+	path.MoveTo(f32.Pt(float32(dst[0].X), float32(dst[0].Y)))
+	path.LineTo(f32.Pt(float32(dst[1].X), float32(dst[1].Y)))
+	path.LineTo(f32.Pt(float32(dst[2].X), float32(dst[2].Y)))
+	path.Close()
+	clipStack := clip.Outline{Path: path.End()}.Op().Push(c.ops)
+	transformSave := op.Affine(xform).Push(c.ops)
+	img.Add(c.ops)
+	gpaint.PaintOp{}.Add(c.ops)
+	transformSave.Pop()
+	clipStack.Pop()
+}
+
+// triangleAffine returns the affine transform mapping the src triangle to
+// dst. It reports false if src is degenerate.
+func triangleAffine(src, dst [3]models.Point) (f32.Affine2D, bool) {
+	u, v := subPoint(src[1], src[0]), subPoint(src[2], src[0])
+	det := u.X*v.Y - u.Y*v.X
+	if det == 0 {
+		return f32.Affine2D{}, false
+	}
+	du, dv := subPoint(dst[1], dst[0]), subPoint(dst[2], dst[0])
+	// Solve [a b; c d] * [u v] = [du dv].
+	a := (du.X*v.Y - dv.X*u.Y) / det
+	b := (dv.X*u.X - du.X*v.X) / det
+	cc := (du.Y*v.Y - dv.Y*u.Y) / det
+	d := (dv.Y*u.X - du.Y*v.X) / det
+	tx := dst[0].X - a*src[0].X - b*src[0].Y
+	ty := dst[0].Y - cc*src[0].X - d*src[0].Y
+	return f32.NewAffine2D(float32(a), float32(b), float32(tx), float32(cc), float32(d), float32(ty)), true
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// tiltMatrix returns a perspective matrix that shrinks x and y as x grows.
+func tiltMatrix() SkMatrix {
+	return impl.NewMatrixAll(1, 0, 0, 0, 1, 0, 0.002, 0, 1)
+}
+
+func TestPerspectiveMap(t *testing.T) {
+	m := tiltMatrix()
+	p := perspectiveMap(m, models.Point{X: 500, Y: 100})
+	// w = 0.002*500 + 1 = 2
+	if !near(p.X, 250, 1e-4) || !near(p.Y, 50, 1e-4) {
+		t.Errorf("got %+v, want (250, 50)", p)
+	}
+	if x, y := m.MapXY(500, 100); !near(p.X, x, 1e-3) || !near(p.Y, y, 1e-3) {
+		t.Errorf("differs from SkMatrix.MapXY: %+v vs (%v, %v)", p, x, y)
+	}
+}
+
+func TestMapPathPerspective_KeepsLines(t *testing.T) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRect(models.Rect{Left: 0, Top: 0, Right: 500, Bottom: 100}, enums.PathDirectionCW, 0)
+	mapped := mapPathPerspective(path, tiltMatrix())
+
+	if mapped.CountPoints() != path.CountPoints() {
+		t.Fatalf("lines should not be subdivided: %d points, want %d", mapped.CountPoints(), path.CountPoints())
+	}
+	b := mapped.Bounds()
+	if !near(b.Right, 250, 1e-3) || !near(b.Bottom, 100, 1e-3) {
+		t.Errorf("unexpected bounds %+v", b)
+	}
+}
+
+func TestMapPathPerspective_Curves(t *testing.T) {
+	m := tiltMatrix()
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(200, 200, 150, enums.PathDirectionCW)
+	mapped := mapPathPerspective(path, m)
+
+	if mapped.CountPoints() <= path.CountPoints() {
+		t.Fatalf("curves should be flattened, got %d points", mapped.CountPoints())
+	}
+	// Every flattened point lies on the projected circle.
+	pts := make([]models.Point, mapped.CountPoints())
+	mapped.GetPoints(pts)
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatal("matrix should be invertible")
+	}
+	for _, p := range pts {
+		x, y := inv.MapXY(p.X, p.Y)
+		if r := pointLength(models.Point{X: x - 200, Y: y - 200}); !near(r, 150, 0.5) {
+			t.Fatalf("point %+v maps back to radius %v", p, r)
+		}
+	}
+}
+
+func TestTriangleAffine(t *testing.T) {
+	src := [3]models.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 20}}
+	dst := [3]models.Point{{X: 5, Y: 5}, {X: 25, Y: 10}, {X: 0, Y: 45}}
+	xform, ok := triangleAffine(src, dst)
+	if !ok {
+		t.Fatal("expected a transform")
+	}
+	for i := range src {
+		p := xform.Transform(f32.Pt(float32(src[i].X), float32(src[i].Y)))
+		if !near(Scalar(p.X), dst[i].X, 1e-4) || !near(Scalar(p.Y), dst[i].Y, 1e-4) {
+			t.Errorf("corner %d maps to %v, want %+v", i, p, dst[i])
+		}
+	}
+	if _, ok := triangleAffine([3]models.Point{{}, {X: 1, Y: 1}, {X: 2, Y: 2}}, dst); ok {
+		t.Error("degenerate source should fail")
+	}
+}
+
+func TestCanvas_PerspectiveConcat(t *testing.T) {
+	ops := new(op.Ops)
+	canvas := NewCanvas(ops).(*canvas)
+
+	canvas.Save()
+	canvas.Concat(tiltMatrix())
+	canvas.Translate(10, 0)
+	ctx := &canvas.stack[len(canvas.stack)-1]
+	if ctx.persp == nil {
+		t.Fatal("perspective should be kept")
+	}
+	if x, _ := ctx.matrix().MapXY(490, 0); !near(x, 250, 1e-3) {
+		t.Errorf("translate after perspective: x = %v, want 250", x)
+	}
+
+	paint := NewPaint()
+	canvas.DrawRect(models.Rect{Left: 0, Top: 0, Right: 100, Bottom: 100}, paint)
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(4)
+	canvas.DrawCircle(models.Point{X: 50, Y: 50}, 40, paint)
+	canvas.ClipRect(models.Rect{Left: 0, Top: 0, Right: 50, Bottom: 50}, enums.ClipOpIntersect, true)
+	img := createTestImage(8, 8, color.NRGBA{R: 255, A: 255})
+	canvas.drawRGBAImageRect(img, models.Rect{Right: 8, Bottom: 8}, models.Rect{Right: 80, Bottom: 80})
+	if n := len(canvas.stack[len(canvas.stack)-1].clips); n != 1 {
+		t.Errorf("expected 1 clip, got %d", n)
+	}
+
+	canvas.Restore()
+	if canvas.stack[len(canvas.stack)-1].persp != nil {
+		t.Error("Restore should drop the perspective")
+	}
+	canvas.Concat(tiltMatrix())
+	canvas.ResetMatrix()
+	if canvas.stack[len(canvas.stack)-1].persp != nil {
+		t.Error("ResetMatrix should drop the perspective")
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"gioui.org/op"
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Picture is a recorded sequence of draws, mirroring SkPicture. It holds
+// the Gio ops of the draws, so replaying it costs no more than adding a
+// call op. Unlike SkPicture it does not record draw commands: the draws
+// are resolved for the recording canvas's device space, so a picture is
+// meant to be replayed at identity scale; see DrawPicture.
+type Picture struct {
+	call     op.CallOp
+	cullRect models.Rect
+}
+
+// CullRect returns the bounds the picture was recorded with.
+func (p *Picture) CullRect() models.Rect {
+	return p.cullRect
+}
+
+// PictureRecorder records the draws of a canvas into a Picture, mirroring
+// SkPictureRecorder. The zero value is ready to use.
+type PictureRecorder struct {
+	ops    *op.Ops
+	macro  op.MacroOp
+	canvas *canvas
+	bounds models.Rect
+}
+
+// BeginRecording starts recording and returns the canvas to draw on. Draws
+// entirely outside bounds are culled. A recording in progress is
+// discarded.
+func (r *PictureRecorder) BeginRecording(bounds models.Rect) Canvas {
+	r.ops = new(op.Ops)
+	r.macro = op.Record(r.ops)
+	r.bounds = bounds
+	r.canvas = newCanvas(r.ops, bounds)
+	return r.canvas
+}
+
+// RecordingCanvas returns the canvas of the recording in progress, or nil.
+func (r *PictureRecorder) RecordingCanvas() Canvas {
+	if r.canvas == nil {
+		return nil
+	}
+	return r.canvas
+}
+
+// FinishRecordingAsPicture ends the recording and returns its picture, or
+// nil if no recording is in progress.
+func (r *PictureRecorder) FinishRecordingAsPicture() *Picture {
+	if r.canvas == nil {
+		return nil
+	}
+	p := &Picture{call: r.macro.Stop(), cullRect: r.bounds}
+	r.ops, r.canvas = nil, nil
+	return p
+}
+
+// DrawPicture replays picture transformed by matrix, if not nil, mirroring
+// SkCanvas::drawPicture. The paint's alpha applies to the whole picture.
+//
+// The picture's ops were resolved at recording time, one canvas unit per
+// pixel, and are replayed as they are. Translations are exact, but other
+// transforms scale the rendered result: hairlines and other device-space
+// widths, glyph masks, image filtering and curve flattening keep the
+// resolution of the recording, and draws culled while recording stay
+// culled. Replay pictures at identity scale, or record them at the scale
+// they are drawn at. Gio transforms ops affinely, so the perspective of the
+// transform is ignored.
+func (c *canvas) DrawPicture(picture *Picture, matrix SkMatrix, paint SkPaint) {
+	if picture == nil {
+		return
+	}
+	alpha := float32(1)
+	if paint != nil {
+		if paint.GetBlendModeOr(enums.BlendModeSrcOver) == enums.BlendModeDst {
+			return
+		}
+		alpha = float32(paint.GetAlphaf())
+	}
+	if alpha <= 0 {
+		return
+	}
+	count := c.GetSaveCount()
+	c.Save()
+	defer c.RestoreToCount(count)
+	if matrix != nil {
+		c.Concat(matrix)
+	}
+	if c.cullDraw(picture.cullRect, 0) {
+		return
+	}
+	pop := c.pushContext()
+	defer pop()
+	if alpha < 1 {
+		opacity := gpaint.PushOpacity(c.ops, alpha)
+		defer opacity.Pop()
+	}
+	picture.call.Add(c.ops)
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestPictureRecorder(t *testing.T) {
+	var r PictureRecorder
+	if r.RecordingCanvas() != nil || r.FinishRecordingAsPicture() != nil {
+		t.Fatal("idle recorder records")
+	}
+	bounds := models.Rect{Right: 50, Bottom: 50}
+	c := r.BeginRecording(bounds).(Extended)
+	if r.RecordingCanvas() != c {
+		t.Error("recording canvas differs")
+	}
+	c.DrawRect(models.Rect{Left: 10, Top: 10, Right: 20, Bottom: 20}, NewPaintFill(color.NRGBA{R: 255, A: 255}))
+	// Draws outside the bounds are culled.
+	c.DrawRect(models.Rect{Left: 60, Top: 60, Right: 70, Bottom: 70}, NewPaintFill(color.NRGBA{R: 255, A: 255}))
+	if c.CulledDraws() != 1 {
+		t.Errorf("%d draws culled, want 1", c.CulledDraws())
+	}
+	p := r.FinishRecordingAsPicture()
+	if p == nil || p.CullRect() != bounds {
+		t.Fatalf("picture %v", p)
+	}
+	if r.RecordingCanvas() != nil || r.FinishRecordingAsPicture() != nil {
+		t.Error("recorder still recording")
+	}
+}
+
+func TestCanvas_DrawPicture(t *testing.T) {
+	var r PictureRecorder
+	r.BeginRecording(models.Rect{Right: 50, Bottom: 50}).DrawCircle(models.Point{X: 25, Y: 25}, 20, NewPaintFill(color.NRGBA{B: 255, A: 255}))
+	p := r.FinishRecordingAsPicture()
+
+	c := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+	c.DrawPicture(p, nil, nil)
+	half := NewPaint()
+	half.SetAlphaf(0.5)
+	c.DrawPicture(p, impl.NewMatrixTranslate(50, 50), half)
+	if c.CulledDraws() != 0 {
+		t.Errorf("visible pictures culled")
+	}
+	// Moved off the canvas, the picture is culled by its bounds.
+	c.DrawPicture(p, impl.NewMatrixTranslate(120, 0), nil)
+	if c.CulledDraws() != 1 {
+		t.Errorf("%d draws culled, want 1", c.CulledDraws())
+	}
+	// The picture's transform does not leak.
+	if m := c.GetTotalMatrix(); !m.IsIdentity() {
+		t.Errorf("matrix %v after drawing pictures", m)
+	}
+
+	dst := NewPaint()
+	dst.SetBlendMode(enums.BlendModeDst)
+	c.DrawPicture(p, impl.NewMatrixTranslate(120, 0), dst)
+	c.DrawPicture(nil, nil, nil)
+	if c.CulledDraws() != 1 {
+		t.Errorf("invisible draws culled")
+	}
+}
@@ -0,0 +1,454 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"gioui.org/f32"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+	"golang.org/x/image/vector"
+)
+
+// CPU raster canvas.
+//
+// The raster canvas draws into an image on the CPU, as SkCanvas does on a
+// raster surface, for machines without a GPU and for exact pixel output.
+// Paths are outlined like on the Gio canvas, flattened and rasterized
+// with anti-aliasing; shaders, color filters and blend modes are
+// evaluated per pixel with the same code as the CPU paths of the Gio
+// canvas. Clips are coverage masks, so difference and anti-aliased clips
+// are exact.
+//
+// It implements Canvas only: the extras of Extended are Gio specific.
+// Glyphs are drawn from their outlines, so color and bitmap glyphs draw
+// in the paint's color.
+
+// Compile-time check that rasterCanvas implements Canvas interface
+var _ Canvas = (*rasterCanvas)(nil)
+
+type rasterCanvas struct {
+	dst   *image.RGBA
+	stack []rasterState
+}
+
+type rasterState struct {
+	matrix SkMatrix
+	// clip is the coverage of the clip over the bounds of dst, or nil if
+	// nothing is clipped. Clips make new masks, so states share them.
+	clip *image.Alpha
+	// bounds holds the pixels the clip may cover.
+	bounds image.Rectangle
+}
+
+// NewRasterCanvas returns a Canvas drawing into dst on the CPU. Draws
+// change dst at once. dst is premultiplied, like every image.RGBA.
+func NewRasterCanvas(dst *image.RGBA) Canvas {
+	return &rasterCanvas{
+		dst: dst,
+		stack: []rasterState{{
+			matrix: impl.NewMatrixIdentity(),
+			bounds: dst.Rect,
+		}},
+	}
+}
+
+func (c *rasterCanvas) top() *rasterState {
+	return &c.stack[len(c.stack)-1]
+}
+
+// ── State management ───────────────────────────────────────────────────
+
+func (c *rasterCanvas) Save() int {
+	c.stack = append(c.stack, *c.top())
+	return len(c.stack)
+}
+
+// SaveLayer is Save: draws are not composited through layers.
+func (c *rasterCanvas) SaveLayer(bounds *models.Rect, paint SkPaint) int {
+	return c.Save()
+}
+
+func (c *rasterCanvas) Restore() {
+	if len(c.stack) > 1 {
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+}
+
+func (c *rasterCanvas) RestoreToCount(saveCount int) {
+	for len(c.stack) > saveCount && len(c.stack) > 1 {
+		c.Restore()
+	}
+}
+
+func (c *rasterCanvas) GetSaveCount() int {
+	return len(c.stack)
+}
+
+func (c *rasterCanvas) Concat(matrix SkMatrix) {
+	top := c.top()
+	m := impl.NewMatrixIdentity()
+	m.SetConcat(top.matrix, matrix)
+	top.matrix = m
+}
+
+func (c *rasterCanvas) Translate(dx, dy Scalar) {
+	c.Concat(impl.NewMatrixTranslate(dx, dy))
+}
+
+func (c *rasterCanvas) Scale(sx, sy Scalar) {
+	c.Concat(impl.NewMatrixScale(sx, sy))
+}
+
+func (c *rasterCanvas) Rotate(degrees Scalar) {
+	c.Concat(impl.NewMatrixRotate(degrees))
+}
+
+func (c *rasterCanvas) Skew(sx, sy Scalar) {
+	c.Concat(impl.NewMatrixSkew(sx, sy))
+}
+
+func (c *rasterCanvas) ResetMatrix() {
+	c.top().matrix = impl.NewMatrixIdentity()
+}
+
+// ── Clipping ───────────────────────────────────────────────────
+
+func (c *rasterCanvas) ClipRect(rect models.Rect, clipOp enums.ClipOp, doAntiAlias bool) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRect(rect, enums.PathDirectionCW, 0)
+	c.ClipPath(path, clipOp, doAntiAlias)
+}
+
+func (c *rasterCanvas) ClipRRect(rrect models.RRect, clipOp enums.ClipOp, doAntiAlias bool) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRRect(rrect, enums.PathDirectionCW)
+	c.ClipPath(path, clipOp, doAntiAlias)
+}
+
+// ClipPath clips to path. Clips are always anti-aliased.
+func (c *rasterCanvas) ClipPath(path SkPath, clipOp enums.ClipOp, doAntiAlias bool) {
+	top := c.top()
+	dev := c.devicePath(path)
+	bounds := top.bounds
+	if clipOp == enums.ClipOpIntersect && !dev.IsInverseFillType() {
+		bounds = bounds.Intersect(roundOutRect(dev.Bounds()))
+	}
+	mask := image.NewAlpha(c.dst.Rect)
+	if !bounds.Empty() {
+		cov := rasterCoverage(dev, bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				a := uint32(cov.Pix[cov.PixOffset(x, y)])
+				if clipOp == enums.ClipOpDifference {
+					a = 255 - a
+				}
+				if top.clip != nil {
+					a = (a*uint32(top.clip.Pix[top.clip.PixOffset(x, y)]) + 127) / 255
+				}
+				mask.Pix[mask.PixOffset(x, y)] = uint8(a)
+			}
+		}
+	}
+	top.clip, top.bounds = mask, bounds
+}
+
+// ── Drawing Primitives ───────────────────────────────────────────────────
+
+func (c *rasterCanvas) DrawColor(color models.Color4f, mode enums.BlendMode) {
+	paint := NewPaint()
+	paint.SetColor(color)
+	paint.SetBlendMode(mode)
+	c.DrawPaint(paint)
+}
+
+func (c *rasterCanvas) Clear(color models.Color4f) {
+	c.DrawColor(color, enums.BlendModeSrc)
+}
+
+// DrawPaint fills the clip with paint, ignoring its style and path
+// effect.
+func (c *rasterCanvas) DrawPaint(paint SkPaint) {
+	c.shade(c.top().bounds, nil, paint)
+}
+
+func (c *rasterCanvas) DrawRect(rect models.Rect, paint SkPaint) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRect(rect, enums.PathDirectionCW, 0)
+	c.DrawPath(path, paint)
+}
+
+func (c *rasterCanvas) DrawRRect(rrect models.RRect, paint SkPaint) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRRect(rrect, enums.PathDirectionCW)
+	c.DrawPath(path, paint)
+}
+
+func (c *rasterCanvas) DrawDRRect(outer models.RRect, inner models.RRect, paint SkPaint) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddRRect(outer, enums.PathDirectionCW)
+	path.AddRRect(inner, enums.PathDirectionCCW)
+	c.DrawPath(path, paint)
+}
+
+func (c *rasterCanvas) DrawOval(oval models.Rect, paint SkPaint) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddOval(oval, enums.PathDirectionCW)
+	c.DrawPath(path, paint)
+}
+
+func (c *rasterCanvas) DrawArc(oval models.Rect, startAngle, sweepAngle Scalar, useCenter bool, paint SkPaint) {
+	if sweepAngle == 0 {
+		return
+	}
+	c.DrawPath(arcPath(oval, startAngle, sweepAngle, useCenter), paint)
+}
+
+func (c *rasterCanvas) DrawCircle(center models.Point, radius Scalar, paint SkPaint) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(center.X, center.Y, radius, enums.PathDirectionCW)
+	c.DrawPath(path, paint)
+}
+
+func (c *rasterCanvas) DrawPoints(mode enums.PointMode, points []models.Point, paint SkPaint) {
+	if len(points) < 1 {
+		return
+	}
+	c.DrawPath(pointsPath(mode, points, paint), paint)
+}
+
+func (c *rasterCanvas) DrawLine(p0, p1 models.Point, paint SkPaint) {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(p0.X, p0.Y)
+	path.LineTo(p1.X, p1.Y)
+	c.DrawPath(path, paint)
+}
+
+// DrawPath draws path with paint. Strokes are outlined in local space;
+// hairlines are stroked one pixel wide on the device.
+func (c *rasterCanvas) DrawPath(path SkPath, paint SkPaint) {
+	top := c.top()
+	fill, isFill := FillPathWithPaint(path, paint, matrixResScale(top.matrix))
+	dev := c.devicePath(fill)
+	if !isFill {
+		hairline := NewPaint()
+		hairline.SetStyle(enums.PaintStyleStroke)
+		hairline.SetStrokeWidth(1)
+		hairline.SetStrokeCap(paint.GetStrokeCap())
+		hairline.SetStrokeJoin(paint.GetStrokeJoin())
+		dev, _ = FillPathWithPaint(dev, hairline, 1)
+	}
+	if dev.IsEmpty() && !dev.IsInverseFillType() {
+		return
+	}
+	area := top.bounds
+	if !dev.IsInverseFillType() {
+		area = area.Intersect(roundOutRect(dev.Bounds()))
+	}
+	if area.Empty() {
+		return
+	}
+	c.shade(area, rasterCoverage(dev, area), paint)
+}
+
+// devicePath returns path mapped by the current matrix.
+func (c *rasterCanvas) devicePath(path SkPath) SkPath {
+	m := c.top().matrix
+	if m.HasPerspective() {
+		return mapPathPerspective(path, m)
+	}
+	dev := impl.NewSkPath(path.FillType())
+	dev.AddPathMatrix(path, m, enums.AddPathModeAppend)
+	return dev
+}
+
+// ── Image Drawing ───────────────────────────────────────────────────
+
+// DrawImage draws image at (left, top) at its natural size, filtered
+// linearly.
+func (c *rasterCanvas) DrawImage(image interfaces.SkImage, left, top Scalar, paint SkPaint) {
+	if image == nil {
+		return
+	}
+	dst := models.Rect{Left: left, Top: top, Right: left + Scalar(image.Width()), Bottom: top + Scalar(image.Height())}
+	c.DrawImageRect(image, nil, dst, paint)
+}
+
+// DrawImageRect draws the src part of image, or all of it if src is nil,
+// scaled into dst and filtered linearly. Alpha-only images are colored
+// with the paint's color; the paint's alpha, color filter and blend mode
+// apply.
+func (c *rasterCanvas) DrawImageRect(skImg interfaces.SkImage, src *models.Rect, dst models.Rect, paint SkPaint) {
+	if skImg == nil {
+		return
+	}
+	srcRect := models.Rect{Right: Scalar(skImg.Width()), Bottom: Scalar(skImg.Height())}
+	if src != nil {
+		srcRect = *src
+	}
+	if isEmptyRect(srcRect) || isEmptyRect(dst) {
+		return
+	}
+	img, _ := cachedImage(skImg, paint)
+	if img == nil {
+		return
+	}
+	sx := (dst.Right - dst.Left) / (srcRect.Right - srcRect.Left)
+	sy := (dst.Bottom - dst.Top) / (srcRect.Bottom - srcRect.Top)
+	local := impl.NewMatrixAll(sx, 0, dst.Left-srcRect.Left*sx, 0, sy, dst.Top-srcRect.Top*sy, 0, 0, 1)
+	shader := newImageShader(img, enums.TileModeClamp, enums.TileModeClamp, defaultImageSampling, local)
+	if shader == nil {
+		return
+	}
+	imagePaint := NewPaint()
+	imagePaint.SetShader(shader)
+	if paint != nil {
+		imagePaint.SetAlphaf(paint.GetAlphaf())
+		imagePaint.SetColorFilter(paint.GetColorFilter())
+		imagePaint.SetBlendMode(paint.GetBlendModeOr(enums.BlendModeSrcOver))
+	}
+	c.DrawRect(dst, imagePaint)
+}
+
+// ── Text ───────────────────────────────────────────────────
+
+func (c *rasterCanvas) DrawTextBlob(blob interfaces.SkTextBlob, x, y Scalar, paint SkPaint) {
+	forEachGlyph(blob, x, y, func(font interfaces.SkFont, glyphID uint16, matrix SkMatrix) {
+		glyphPath, err := font.Typeface().GetGlyphPath(glyphID)
+		if err != nil || glyphPath == nil {
+			return
+		}
+		path := impl.NewSkPath(glyphPath.FillType())
+		path.AddPathMatrix(glyphPath, matrix, enums.AddPathModeAppend)
+		c.DrawPath(path, paint)
+	})
+}
+
+func (c *rasterCanvas) DrawSimpleText(text []byte, encoding enums.TextEncoding, x, y Scalar, font interfaces.SkFont, paint SkPaint) {
+	if blob := simpleTextBlob(text, encoding, font); blob != nil {
+		c.DrawTextBlob(blob, x, y, paint)
+	}
+}
+
+func (c *rasterCanvas) DrawString(str string, x, y Scalar, font interfaces.SkFont, paint SkPaint) {
+	c.DrawSimpleText([]byte(str), enums.TextEncodingUTF8, x, y, font, paint)
+}
+
+// ── Shading ───────────────────────────────────────────────────
+
+// shade paints the area pixels of dst with paint, weighted by cov, a
+// coverage mask of area, or fully if cov is nil, and by the clip.
+func (c *rasterCanvas) shade(area image.Rectangle, cov *image.Alpha, paint SkPaint) {
+	top := c.top()
+	area = area.Intersect(top.bounds)
+	if area.Empty() {
+		return
+	}
+	mode := paint.GetBlendModeOr(enums.BlendModeSrcOver)
+	alpha := float32(paint.GetAlphaf())
+	filter, hasFilter := paintColorFilter(paint)
+	// Without a shader the source is the same for every pixel.
+	shader := paint.GetShader()
+	var inverse SkMatrix
+	if shader != nil {
+		if _, ok := shaderColor(shader, 0, 0); ok {
+			inverse, _ = top.matrix.Invert()
+		}
+	}
+	var solid rgbaF
+	if inverse == nil {
+		col := color4fToNRGBA(paint.GetColor())
+		col.A = 255
+		solid = toRGBAF(premulRGBA(col)).scale(alpha)
+		if hasFilter {
+			solid = filter.filterColor(solid)
+		}
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			k := float32(1)
+			if cov != nil {
+				k = float32(cov.Pix[cov.PixOffset(x, y)]) / 255
+			}
+			if top.clip != nil {
+				k *= float32(top.clip.Pix[top.clip.PixOffset(x, y)]) / 255
+			}
+			if k == 0 {
+				continue
+			}
+			s := solid
+			if inverse != nil {
+				p := perspectiveMap(inverse, models.Point{X: Scalar(x) + 0.5, Y: Scalar(y) + 0.5})
+				col, _ := shaderColor(shader, p.X, p.Y)
+				s = toRGBAF(col).scale(alpha)
+				if hasFilter {
+					s = filter.filterColor(s)
+				}
+			}
+			i := c.dst.PixOffset(x, y)
+			px := c.dst.Pix[i : i+4 : i+4]
+			d := toRGBAF(color.RGBA{R: px[0], G: px[1], B: px[2], A: px[3]})
+			b := blendRGBAF(mode, s, d)
+			out := rgbaF{d.r + (b.r-d.r)*k, d.g + (b.g-d.g)*k, d.b + (b.b-d.b)*k, d.a + (b.a-d.a)*k}.rgba()
+			px[0], px[1], px[2], px[3] = out.R, out.G, out.B, out.A
+		}
+	}
+}
+
+// rasterCoverage returns the anti-aliased coverage of path, in device
+// space, over area.
+func rasterCoverage(path SkPath, area image.Rectangle) *image.Alpha {
+	inverse := path.IsInverseFillType()
+	// The rasterizer fills with the winding rule only.
+	if winding, ok := AsWinding(path); ok {
+		path = winding
+	}
+	mask := image.NewAlpha(area)
+	r := vector.NewRasterizer(area.Dx(), area.Dy())
+	r.DrawOp = draw.Src
+	ox, oy := float32(area.Min.X), float32(area.Min.Y)
+	convertPath(path, conicTolerance, rasterSink{r: r, xform: func(p f32.Point) (float32, float32) {
+		return p.X - ox, p.Y - oy
+	}})
+	r.Draw(mask, area, image.Opaque, image.Point{})
+	if inverse {
+		for i, a := range mask.Pix {
+			mask.Pix[i] = 255 - a
+		}
+	}
+	return mask
+}
+
+// roundOutRect returns the pixels r touches.
+func roundOutRect(r models.Rect) image.Rectangle {
+	// Clamp to the range of int before converting.
+	const limit = 1 << 30
+	q := func(v float64) int {
+		if math.IsNaN(v) {
+			return 0
+		}
+		return int(min(max(v, -limit), limit))
+	}
+	return image.Rect(
+		q(math.Floor(float64(r.Left))), q(math.Floor(float64(r.Top))),
+		q(math.Ceil(float64(r.Right))), q(math.Ceil(float64(r.Bottom))),
+	)
+}
+
+// matrixResScale returns the largest scale of m along an axis, or 1 if it
+// is degenerate.
+func matrixResScale(m SkMatrix) Scalar {
+	scale := Scalar(math.Max(
+		math.Hypot(float64(m.GetScaleX()), float64(m.GetSkewY())),
+		math.Hypot(float64(m.GetSkewX()), float64(m.GetScaleY())),
+	))
+	if !(scale > 0) {
+		return 1
+	}
+	return scale
+}
@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestRasterCanvas_DrawRect(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	c := NewRasterCanvas(dst)
+	c.Translate(2, 2)
+	c.DrawRect(models.Rect{Right: 4, Bottom: 4.5}, NewPaintFill(color.NRGBA{R: 255, A: 255}))
+
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{3, 3, color.RGBA{R: 255, A: 255}},
+		{1, 3, color.RGBA{}},
+		{6, 3, color.RGBA{}},
+		// Half covered.
+		{3, 6, color.RGBA{R: 128, A: 128}},
+	}
+	for _, tt := range tests {
+		if got := dst.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestRasterCanvas_Hairline(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	c := NewRasterCanvas(dst)
+	// Hairlines stay a pixel wide whatever the scale.
+	c.Scale(4, 4)
+	c.DrawLine(models.Point{X: 0, Y: 1.125}, models.Point{X: 2.5, Y: 1.125}, NewPaintStroke(color.NRGBA{A: 255}, 0))
+	if got := dst.RGBAAt(5, 4); got.A != 255 {
+		t.Errorf("on the line: alpha %d, want 255", got.A)
+	}
+	if got := dst.RGBAAt(5, 5); got.A != 0 {
+		t.Errorf("below the line: alpha %d, want 0", got.A)
+	}
+}
+
+func TestRasterCanvas_Clip(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	c := NewRasterCanvas(dst)
+	c.Clear(models.Color4f{R: 1, G: 1, B: 1, A: 1})
+	c.Save()
+	c.ClipRect(models.Rect{Left: 1, Top: 1, Right: 9, Bottom: 9}, enums.ClipOpIntersect, true)
+	c.ClipRect(models.Rect{Left: 4, Top: 4, Right: 6, Bottom: 6}, enums.ClipOpDifference, true)
+	c.DrawColor(models.Color4f{B: 1, A: 1}, enums.BlendModeSrcOver)
+	c.Restore()
+
+	white, blue := color.RGBA{R: 255, G: 255, B: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, white},
+		{2, 2, blue},
+		{5, 5, white},
+		{8, 5, blue},
+		{9, 9, white},
+	}
+	for _, tt := range tests {
+		if got := dst.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// The clip is gone after Restore.
+	c.DrawColor(models.Color4f{A: 1}, enums.BlendModeSrc)
+	if got := dst.RGBAAt(5, 5); got != (color.RGBA{A: 255}) {
+		t.Errorf("after restore: got %v", got)
+	}
+}
+
+func TestRasterCanvas_BlendAndAlpha(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	c := NewRasterCanvas(dst)
+	c.Clear(models.Color4f{R: 1, A: 1})
+
+	paint := NewPaintFill(color.NRGBA{B: 255, A: 255})
+	paint.SetAlphaf(0.5)
+	c.DrawRect(models.Rect{Right: 2, Bottom: 4}, paint)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{R: 128, B: 128, A: 255}) {
+		t.Errorf("half blue over red: got %v", got)
+	}
+
+	c.DrawColor(models.Color4f{A: 1}, enums.BlendModeDstOut)
+	if got := dst.RGBAAt(3, 3); got != (color.RGBA{}) {
+		t.Errorf("dst-out: got %v", got)
+	}
+}
+
+func TestRasterCanvas_DrawImageRect(t *testing.T) {
+	defer PurgeImageCache()
+	info := models.NewImageInfo(2, 2, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	pixels := []byte{
+		0, 255, 0, 255, 0, 255, 0, 255,
+		0, 255, 0, 255, 0, 255, 0, 255,
+	}
+	img := impl.NewRasterImage(info, pixels, 2*4)
+
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	c := NewRasterCanvas(dst)
+	c.DrawImageRect(img, nil, models.Rect{Left: 2, Top: 2, Right: 8, Bottom: 8}, nil)
+	if got := dst.RGBAAt(5, 5); got != (color.RGBA{G: 255, A: 255}) {
+		t.Errorf("inside the image: got %v", got)
+	}
+	if got := dst.RGBAAt(1, 5); got != (color.RGBA{}) {
+		t.Errorf("outside the image: got %v", got)
+	}
+}
+
+func TestRasterCanvas_DrawString(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	c := NewRasterCanvas(dst)
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 16)
+	c.DrawString("H", 2, 16, font, NewPaintFill(color.NRGBA{A: 255}))
+	inked := 0
+	for i := 3; i < len(dst.Pix); i += 4 {
+		if dst.Pix[i] != 0 {
+			inked++
+		}
+	}
+	if inked == 0 {
+		t.Error("text drew nothing")
+	}
+}
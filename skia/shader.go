@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync/atomic"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// shaderSampler is implemented by shaders the canvas can evaluate on the
+// CPU.
+type shaderSampler interface {
+	// sample returns the premultiplied color of the shader at (x, y) in
+	// its local coordinates.
+	sample(x, y Scalar) color.RGBA
+}
+
+// shaderIDs hands out shader unique IDs.
+var shaderIDs atomic.Uint32
+
+// imageShader tiles an image.
+type imageShader struct {
+	img         *image.RGBA
+	tmx, tmy    enums.TileMode
+	sampling    models.SamplingOptions
+	localMatrix SkMatrix
+	// inverse maps local coordinates to image pixels.
+	inverse SkMatrix
+	id      uint32
+}
+
+// NewImageShader returns a shader that draws img, tiled with tmx and tmy
+// outside its bounds, as SkImage::makeShader does. localMatrix maps the
+// image into the shader's coordinates and may be nil. It returns nil if the
+// image cannot be read or localMatrix is not invertible.
+func NewImageShader(img interfaces.SkImage, tmx, tmy enums.TileMode, sampling models.SamplingOptions, localMatrix SkMatrix) interfaces.Shader {
+	if img == nil {
+		return nil
+	}
+	rgba, _ := cachedImage(img, nil)
+	if rgba == nil {
+		return nil
+	}
+	if shader := newImageShader(rgba, tmx, tmy, sampling, localMatrix); shader != nil {
+		return shader
+	}
+	return nil
+}
+
+func newImageShader(img *image.RGBA, tmx, tmy enums.TileMode, sampling models.SamplingOptions, localMatrix SkMatrix) *imageShader {
+	if localMatrix == nil {
+		localMatrix = impl.NewMatrixIdentity()
+	}
+	inverse, ok := localMatrix.Invert()
+	if !ok {
+		return nil
+	}
+	return &imageShader{
+		img:         img,
+		tmx:         tmx,
+		tmy:         tmy,
+		sampling:    sampling,
+		localMatrix: localMatrix,
+		inverse:     inverse,
+		id:          shaderIDs.Add(1),
+	}
+}
+
+func (s *imageShader) IsOpaque() bool {
+	if s.tmx == enums.TileModeDecal || s.tmy == enums.TileModeDecal {
+		return false
+	}
+	return s.img.Opaque()
+}
+
+func (s *imageShader) IsAImage(localMatrix *SkMatrix, tileMode []enums.TileMode) bool {
+	if localMatrix != nil {
+		*localMatrix = s.localMatrix
+	}
+	if len(tileMode) >= 2 {
+		tileMode[0], tileMode[1] = s.tmx, s.tmy
+	}
+	return true
+}
+
+func (s *imageShader) IsAImageSimple() bool {
+	return true
+}
+
+func (s *imageShader) MakeWithLocalMatrix(localMatrix SkMatrix) interfaces.Shader {
+	if localMatrix == nil {
+		return s
+	}
+	m := impl.NewMatrixIdentity()
+	m.SetConcat(localMatrix, s.localMatrix)
+	if shader := newImageShader(s.img, s.tmx, s.tmy, s.sampling, m); shader != nil {
+		return shader
+	}
+	return nil
+}
+
+// MakeWithColorFilter returns s: color filters on shaders are not
+// supported.
+func (s *imageShader) MakeWithColorFilter(filter interfaces.ColorFilter) interfaces.Shader {
+	return s
+}
+
+// MakeWithWorkingColorSpace returns s: drawing is not color managed.
+func (s *imageShader) MakeWithWorkingColorSpace(inputCS, outputCS *models.ColorSpace) interfaces.Shader {
+	return s
+}
+
+func (s *imageShader) UniqueID() uint32 {
+	return s.id
+}
+
+func (s *imageShader) IsConstant(color *models.Color4f) bool {
+	return false
+}
+
+func (s *imageShader) Type() enums.ShaderType {
+	return enums.ShaderTypeImage
+}
+
+func (s *imageShader) AsGradient(info *models.GradientInfo, localMatrix *SkMatrix) enums.GradientType {
+	return enums.GradientTypeNone
+}
+
+// MakeInvertAlpha returns a copy of the shader with the alpha of its image
+// inverted.
+func (s *imageShader) MakeInvertAlpha() interfaces.Shader {
+	img := image.NewRGBA(s.img.Rect)
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		c := unpremulRGBA(color.RGBA{R: s.img.Pix[i], G: s.img.Pix[i+1], B: s.img.Pix[i+2], A: s.img.Pix[i+3]})
+		c.A = 255 - c.A
+		p := premulRGBA(c)
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = p.R, p.G, p.B, p.A
+	}
+	return &imageShader{
+		img:         img,
+		tmx:         s.tmx,
+		tmy:         s.tmy,
+		sampling:    s.sampling,
+		localMatrix: s.localMatrix,
+		inverse:     s.inverse,
+		id:          shaderIDs.Add(1),
+	}
+}
+
+// MakeWithCTM returns s: shaders are always evaluated in the coordinates of
+// the draw.
+func (s *imageShader) MakeWithCTM(ctm SkMatrix) interfaces.Shader {
+	return s
+}
+
+// sample implements shaderSampler.
+func (s *imageShader) sample(x, y Scalar) color.RGBA {
+	ix, iy := s.inverse.MapXY(x, y)
+	b := s.img.Rect
+	if s.sampling.FilterMode != enums.FilterModeLinear && !s.sampling.UseCubic {
+		px, okx := tile(int(math.Floor(float64(ix))), b.Dx(), s.tmx)
+		py, oky := tile(int(math.Floor(float64(iy))), b.Dy(), s.tmy)
+		if !okx || !oky {
+			return color.RGBA{}
+		}
+		return s.img.RGBAAt(b.Min.X+px, b.Min.Y+py)
+	}
+	// Bilinear filtering between the four nearest pixel centers.
+	fx, fy := float64(ix)-0.5, float64(iy)-0.5
+	x0, y0 := math.Floor(fx), math.Floor(fy)
+	tx, ty := float32(fx-x0), float32(fy-y0)
+	at := func(x, y int) rgbaF {
+		px, okx := tile(x, b.Dx(), s.tmx)
+		py, oky := tile(y, b.Dy(), s.tmy)
+		if !okx || !oky {
+			return rgbaF{}
+		}
+		return toRGBAF(s.img.RGBAAt(b.Min.X+px, b.Min.Y+py))
+	}
+	lerp := func(a, b rgbaF, t float32) rgbaF {
+		return rgbaF{a.r + (b.r-a.r)*t, a.g + (b.g-a.g)*t, a.b + (b.b-a.b)*t, a.a + (b.a-a.a)*t}
+	}
+	ix0, iy0 := int(x0), int(y0)
+	top := lerp(at(ix0, iy0), at(ix0+1, iy0), tx)
+	bottom := lerp(at(ix0, iy0+1), at(ix0+1, iy0+1), tx)
+	return lerp(top, bottom, ty).rgba()
+}
+
+// tile maps the pixel index i into [0, n) with mode. It reports false for
+// indices outside a decal image.
+func tile(i, n int, mode enums.TileMode) (int, bool) {
+	if n <= 0 {
+		return 0, false
+	}
+	switch mode {
+	case enums.TileModeRepeat:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+	case enums.TileModeMirror:
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+	case enums.TileModeDecal:
+		return i, i >= 0 && i < n
+	default:
+		i = min(max(i, 0), n-1)
+	}
+	return i, true
+}
+
+// shaderColor returns the color of shader at (x, y) in its local
+// coordinates. It reports false for shaders the canvas cannot evaluate.
+func shaderColor(shader interfaces.Shader, x, y Scalar) (color.RGBA, bool) {
+	if s, ok := shader.(shaderSampler); ok {
+		return s.sample(x, y), true
+	}
+	var c models.Color4f
+	if shader != nil && shader.IsConstant(&c) {
+		return premulRGBA(color4fToNRGBA(c)), true
+	}
+	return color.RGBA{}, false
+}
+
+// color4fToNRGBA converts c to 8 bit components.
+func color4fToNRGBA(c models.Color4f) color.NRGBA {
+	q := func(v Scalar) uint8 { return uint8(clampUnit(float32(v))*255 + 0.5) }
+	return color.NRGBA{R: q(c.R), G: q(c.G), B: q(c.B), A: q(c.A)}
+}
+
+func unpremulRGBA(c color.RGBA) color.NRGBA {
+	if c.A == 0 {
+		return color.NRGBA{}
+	}
+	a := uint32(c.A)
+	return color.NRGBA{
+		R: uint8(min((uint32(c.R)*255+a/2)/a, 255)),
+		G: uint8(min((uint32(c.G)*255+a/2)/a, 255)),
+		B: uint8(min((uint32(c.B)*255+a/2)/a, 255)),
+		A: c.A,
+	}
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestTile(t *testing.T) {
+	for _, tc := range []struct {
+		mode enums.TileMode
+		i    int
+		want int
+		ok   bool
+	}{
+		{enums.TileModeClamp, -3, 0, true},
+		{enums.TileModeClamp, 7, 3, true},
+		{enums.TileModeRepeat, -1, 3, true},
+		{enums.TileModeRepeat, 9, 1, true},
+		{enums.TileModeMirror, 4, 3, true},
+		{enums.TileModeMirror, -1, 0, true},
+		{enums.TileModeMirror, 9, 1, true},
+		{enums.TileModeDecal, 4, 4, false},
+		{enums.TileModeDecal, 2, 2, true},
+	} {
+		if got, ok := tile(tc.i, 4, tc.mode); got != tc.want || ok != tc.ok {
+			t.Errorf("tile(%d, 4, %d) = %d, %v, want %d, %v", tc.i, tc.mode, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestImageShader_Sample(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+	img.SetRGBA(1, 0, color.RGBA{B: 255, A: 255})
+
+	s := newImageShader(img, enums.TileModeRepeat, enums.TileModeClamp, models.SamplingOptions{}, impl.NewMatrixScale(10, 10))
+	if got := s.sample(15, 5); got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("scaled sample = %v, want blue", got)
+	}
+	if got := s.sample(25, 5); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("repeated sample = %v, want red", got)
+	}
+
+	// Bilinear filtering mixes the pixels between their centers.
+	s = newImageShader(img, enums.TileModeClamp, enums.TileModeClamp, models.SamplingOptions{FilterMode: enums.FilterModeLinear}, nil)
+	if got := s.sample(1, 0.5); got != (color.RGBA{R: 128, B: 128, A: 255}) {
+		t.Errorf("filtered sample = %v", got)
+	}
+
+	if newImageShader(img, enums.TileModeClamp, enums.TileModeClamp, models.SamplingOptions{}, impl.NewMatrixScale(0, 1)) != nil {
+		t.Error("singular local matrix should be rejected")
+	}
+}
+
+func TestBlendRGBA(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	halfBlue := color.RGBA{B: 128, A: 128}
+	for _, tc := range []struct {
+		mode     enums.BlendMode
+		src, dst color.RGBA
+		want     color.RGBA
+	}{
+		{enums.BlendModeSrcOver, halfBlue, red, color.RGBA{R: 127, B: 128, A: 255}},
+		{enums.BlendModeDstOver, halfBlue, red, red},
+		{enums.BlendModeSrcIn, halfBlue, color.RGBA{}, color.RGBA{}},
+		{enums.BlendModeDstOut, halfBlue, red, color.RGBA{R: 127, A: 127}},
+		{enums.BlendModeModulate, red, color.RGBA{R: 128, G: 128, A: 255}, color.RGBA{R: 128, A: 255}},
+		{enums.BlendModeMultiply, red, color.RGBA{R: 128, G: 128, A: 255}, color.RGBA{R: 128, A: 255}},
+		{enums.BlendModeScreen, red, color.RGBA{G: 255, A: 255}, color.RGBA{R: 255, G: 255, A: 255}},
+		{enums.BlendModeDarken, red, color.RGBA{R: 128, G: 128, A: 255}, color.RGBA{R: 128, A: 255}},
+		{enums.BlendModeLuminosity, color.RGBA{R: 255, G: 255, B: 255, A: 255}, red, color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+	} {
+		if got := blendRGBA(tc.mode, tc.src, tc.dst); got != tc.want {
+			t.Errorf("mode %d: blend(%v, %v) = %v, want %v", tc.mode, tc.src, tc.dst, got, tc.want)
+		}
+	}
+}
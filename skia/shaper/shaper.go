@@ -1,6 +1,9 @@
 package shaper
 
 import (
+	"math"
+
+	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/zodimo/go-skia-support/skia/interfaces"
 	"github.com/zodimo/go-skia-support/skia/shaper"
 )
@@ -9,6 +12,45 @@ import (
 // allowing consumers to use it without importing support package directly if they prefer.
 type UseGoTextFace = shaper.UseGoTextFace
 
+// Feature is an OpenType feature applied to a byte range of the shaped text.
+// It is an alias to the type in go-skia-support.
+type Feature = shaper.Feature
+
+// Common OpenType feature tags.
+const (
+	FeatureLigatures             = "liga"
+	FeatureContextualLigatures   = "clig"
+	FeatureDiscretionaryLigature = "dlig"
+	FeatureKerning               = "kern"
+	FeatureTabularNumbers        = "tnum"
+	FeatureProportionalNumbers   = "pnum"
+	FeatureLiningNumbers         = "lnum"
+	FeatureOldstyleNumbers       = "onum"
+	FeatureSlashedZero           = "zero"
+	FeatureFractions             = "frac"
+	FeatureSmallCaps             = "smcp"
+	FeatureCapsToSmallCaps       = "c2sc"
+	FeatureStylisticAlternates   = "salt"
+)
+
+// NewFeature returns a feature that applies to the whole text.
+// tag must be a four character OpenType tag; value is 0 to disable the
+// feature, 1 to enable it, or an alternate index for features that have them.
+func NewFeature(tag string, value uint32) Feature {
+	return NewRangeFeature(tag, value, 0, math.MaxInt)
+}
+
+// NewRangeFeature returns a feature that applies to the bytes [start, end)
+// of the text. The text is split into separate runs at the range boundaries.
+func NewRangeFeature(tag string, value uint32, start, end int) Feature {
+	return Feature{
+		Tag:   uint32(ot.MustNewTag(tag)),
+		Value: value,
+		Start: start,
+		End:   end,
+	}
+}
+
 // ShaperImpl wraps the HarfbuzzShaper implementation from go-skia-support.
 type ShaperImpl struct {
 	*shaper.HarfbuzzShaper
@@ -23,7 +65,24 @@ func NewShaper() *ShaperImpl {
 
 // Shape shapes the text using the font and runHandler.
 // It matches the interface expected by gio-skia consumers, delegating to the support shaper.
-func (s *ShaperImpl) Shape(text string, font interfaces.SkFont, leftToRight bool, width float32, runHandler shaper.RunHandler) {
-	// Pass nil for descriptors/features as default
-	s.HarfbuzzShaper.Shape(text, font, leftToRight, width, runHandler, nil)
+// features may be nil to use the font's default features.
+func (s *ShaperImpl) Shape(text string, font interfaces.SkFont, leftToRight bool, width float32, runHandler shaper.RunHandler, features []Feature) {
+	s.HarfbuzzShaper.Shape(text, font, leftToRight, width, runHandler, clampFeatures(features, len(text)))
+}
+
+// clampFeatures limits feature ranges to the text, so open ended ranges
+// like those of NewFeature do not split runs past its end.
+func clampFeatures(features []Feature, n int) []Feature {
+	if len(features) == 0 {
+		return nil
+	}
+	out := make([]Feature, 0, len(features))
+	for _, f := range features {
+		f.Start = max(f.Start, 0)
+		f.End = min(f.End, n)
+		if f.Start < f.End {
+			out = append(out, f)
+		}
+	}
+	return out
 }
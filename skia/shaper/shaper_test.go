@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/zodimo/go-skia-support/skia/base"
 	"github.com/zodimo/go-skia-support/skia/interfaces"
 	"github.com/zodimo/go-skia-support/skia/models"
@@ -101,7 +102,7 @@ func TestShaper_Shape_Wrapper(t *testing.T) {
 	handler := &MockRunHandler{}
 
 	// Act
-	s.Shape("Hello", mockFont, true, 100.0, handler)
+	s.Shape("Hello", mockFont, true, 100.0, handler, nil)
 
 	// Assert
 	if !handler.BeginLineCalled {
@@ -128,3 +129,54 @@ func TestShaper_Shape_Wrapper(t *testing.T) {
 		t.Error("GlyphCount is 0, expected > 0 for 'Hello'")
 	}
 }
+
+func TestShaper_Shape_RangeFeatureSplitsRuns(t *testing.T) {
+	face, err := font.ParseTTF(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatalf("Failed to parse goregular font: %v", err)
+	}
+	mockFont := &MockFont{typeface: &MockTypeface{face: face}, size: 12.0}
+
+	s := NewShaper()
+	handler := &MockRunHandler{}
+	features := []Feature{NewRangeFeature(FeatureTabularNumbers, 1, 0, 5)}
+	s.Shape("12345 abc", mockFont, true, 0, handler, features)
+
+	if len(handler.Infos) < 2 {
+		t.Fatalf("expected the feature range to split the text into runs, got %d run(s)", len(handler.Infos))
+	}
+	if got := handler.Infos[0].Utf8Range.End; got != 5 {
+		t.Errorf("first run should end at the feature boundary, got %d", got)
+	}
+}
+
+func TestShaper_Shape_WholeTextFeature(t *testing.T) {
+	face, err := font.ParseTTF(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatalf("Failed to parse goregular font: %v", err)
+	}
+	mockFont := &MockFont{typeface: &MockTypeface{face: face}, size: 12.0}
+
+	s := NewShaper()
+	handler := &MockRunHandler{}
+	s.Shape("office", mockFont, true, 0, handler, []Feature{NewFeature(FeatureLigatures, 0)})
+
+	if len(handler.Infos) != 1 {
+		t.Errorf("a whole text feature should not split runs, got %d run(s)", len(handler.Infos))
+	}
+}
+
+func TestNewFeature(t *testing.T) {
+	f := NewFeature(FeatureSmallCaps, 1)
+	if f.Tag != uint32(ot.MustNewTag("smcp")) {
+		t.Errorf("unexpected tag %x", f.Tag)
+	}
+	if f.Start != 0 || f.Value != 1 {
+		t.Errorf("unexpected feature %+v", f)
+	}
+
+	clamped := clampFeatures([]Feature{f, NewRangeFeature(FeatureKerning, 0, 10, 20)}, 4)
+	if len(clamped) != 1 || clamped[0].End != 4 {
+		t.Errorf("unexpected clamped features %+v", clamped)
+	}
+}
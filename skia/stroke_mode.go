@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/op/clip"
+	gpaint "gioui.org/op/paint"
+	andyStroke "github.com/andybalholm/stroke"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+)
+
+// StrokeMode selects the space strokes are expanded in.
+type StrokeMode uint8
+
+const (
+	// StrokeModeLocal expands strokes in local coordinates, so the pen is
+	// transformed with the path, as in Skia. A non-uniform scale or skew
+	// makes the stroke width vary with direction.
+	StrokeModeLocal StrokeMode = iota
+	// StrokeModeDevice maps paths to device space before expanding them,
+	// so strokes keep their width in device pixels under any transform,
+	// like SVG's non-scaling-stroke.
+	StrokeModeDevice
+)
+
+// SetStrokeMode selects the space strokes are expanded in.
+func (c *canvas) SetStrokeMode(mode StrokeMode) {
+	c.strokeMode = mode
+}
+
+// deviceStroke returns the options to stroke paint with in device space,
+// and whether it is stroked in device space at all.
+func (c *canvas) deviceStroke(paint Paint) (stroke.StrokeOpts, bool) {
+	switch {
+	case isHairline(paint):
+		return hairlineOpts(paint.Stroke), true
+	case !paint.Fill && c.strokeMode == StrokeModeDevice:
+		return paint.Stroke, true
+	}
+	return stroke.StrokeOpts{}, false
+}
+
+// localInflation returns a distance in local units covering r device
+// pixels in every direction under the current transform.
+func (c *canvas) localInflation(r Scalar) Scalar {
+	ctx := &c.stack[len(c.stack)-1]
+	if ctx.persp != nil {
+		return math.MaxFloat32
+	}
+	// The norm of the inverse of [a b; c d] is at most its Frobenius norm,
+	// sqrt(a²+b²+c²+d²)/|det|.
+	a, b, _, cc, d, _ := ctx.xform.Elems()
+	det := math.Abs(float64(a*d - b*cc))
+	if det == 0 {
+		return math.MaxFloat32
+	}
+	return r * Scalar(math.Sqrt(float64(a*a+b*b+cc*cc+d*d))/det)
+}
+
+// affineSink maps segments through an affine transform before passing them
+// to sink.
+type affineSink struct {
+	m    f32.Affine2D
+	sink pathSink
+}
+
+func (s affineSink) MoveTo(to f32.Point) {
+	s.sink.MoveTo(s.m.Transform(to))
+}
+
+func (s affineSink) LineTo(to f32.Point) {
+	s.sink.LineTo(s.m.Transform(to))
+}
+
+func (s affineSink) QuadTo(ctrl, to f32.Point) {
+	s.sink.QuadTo(s.m.Transform(ctrl), s.m.Transform(to))
+}
+
+func (s affineSink) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	s.sink.CubeTo(s.m.Transform(ctrl0), s.m.Transform(ctrl1), s.m.Transform(to))
+}
+
+func (s affineSink) Close() {
+	s.sink.Close()
+}
+
+// drawDeviceStroke strokes path with opts in device space under the
+// current transform.
+func (c *canvas) drawDeviceStroke(path SkPath, paint Paint, opts stroke.StrokeOpts) {
+	contours := c.deviceStrokeContours(path, opts)
+	if len(contours) == 0 {
+		return
+	}
+	for _, cl := range c.stack[len(c.stack)-1].clips {
+		stack := cl.op.Push(c.ops)
+		defer stack.Pop()
+	}
+	var b clip.Path
+	b.Begin(c.ops)
+	appendStrokedContours(&b, contours)
+	gpaint.FillShape(c.ops, paint.Color, clip.Outline{Path: b.End()}.Op())
+}
+
+// deviceStrokeContours returns the outline of path stroked with opts in
+// device space. Conics are converted within conicTolerance device pixels,
+// so zoomed in curves stay smooth.
+func (c *canvas) deviceStrokeContours(path SkPath, opts stroke.StrokeOpts) [][]andyStroke.Segment {
+	ctx := &c.stack[len(c.stack)-1]
+	var device strokePathSink
+	if ctx.persp != nil {
+		convertPath(mapPathPerspective(path, ctx.persp), conicTolerance, &device)
+	} else {
+		convertPath(path, c.pathTolerance(conicTolerance), affineSink{m: ctx.xform, sink: &device})
+	}
+	return stroke.StrokedContours(device.path, opts)
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestStrokeModeDevice_ConstantWidth(t *testing.T) {
+	horizontal := impl.NewSkPath(enums.PathFillTypeWinding)
+	horizontal.MoveTo(0, 0)
+	horizontal.LineTo(10, 0)
+	vertical := impl.NewSkPath(enums.PathFillTypeWinding)
+	vertical.MoveTo(0, 0)
+	vertical.LineTo(0, 10)
+	opts := stroke.StrokeOpts{Width: 2, Miter: 4, Cap: stroke.FlatCap, Join: stroke.MiterJoin}
+
+	c := NewCanvas(new(op.Ops)).(*canvas)
+	c.SetStrokeMode(StrokeModeDevice)
+	c.Scale(8, 1)
+	c.Skew(0.5, 0)
+
+	h := contourBounds(c.deviceStrokeContours(horizontal, opts))
+	if got := h.Bottom - h.Top; !near(got, 2, 1e-3) {
+		t.Errorf("horizontal stroke is %v pixels thick, want 2", got)
+	}
+	// The vertical line is sheared, so measure its width across the line.
+	v := c.deviceStrokeContours(vertical, opts)
+	dir := models.Point{X: 8 * 0.5 * 10, Y: 10}
+	n := models.Point{X: dir.Y / pointLength(dir), Y: -dir.X / pointLength(dir)}
+	lo, hi := Scalar(math.MaxFloat32), Scalar(-math.MaxFloat32)
+	for _, contour := range v {
+		for _, seg := range contour {
+			d := Scalar(seg.Start.X)*n.X + Scalar(seg.Start.Y)*n.Y
+			lo, hi = min(lo, d), max(hi, d)
+		}
+	}
+	if got := hi - lo; !near(got, 2, 1e-3) {
+		t.Errorf("sheared stroke is %v pixels thick, want 2", got)
+	}
+}
+
+func TestStrokeModeDevice_ZoomedCurvesStaySmooth(t *testing.T) {
+	circle := impl.NewSkPath(enums.PathFillTypeWinding)
+	circle.AddCircle(0, 0, 1, enums.PathDirectionCW)
+
+	c := NewCanvas(new(op.Ops)).(*canvas)
+	c.Scale(200, 200)
+	contours := c.deviceStrokeContours(circle, stroke.StrokeOpts{Width: 2, Miter: 4})
+	if len(contours) == 0 {
+		t.Fatal("no outline")
+	}
+	for _, contour := range contours {
+		for _, seg := range contour {
+			for i := 0; i <= 8; i++ {
+				p := seg.Split2(float32(i)/8, float32(i)/8).Start
+				r := Scalar(math.Hypot(float64(p.X), float64(p.Y)))
+				if math.Abs(float64(r-200)) > 1+2*conicTolerance {
+					t.Fatalf("outline point at radius %v, want 199 or 201", r)
+				}
+			}
+		}
+	}
+}
+
+func TestStrokeModeDevice_Culling(t *testing.T) {
+	canvas := NewCanvasWithSize(new(op.Ops), 100, 100).(Extended)
+	canvas.SetStrokeMode(StrokeModeDevice)
+	paint := NewPaint()
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(20)
+
+	// Shrunk by 100, the line is 0.05 pixels left of the canvas, well
+	// within the 10 pixel half width of the stroke.
+	canvas.Scale(0.01, 0.01)
+	canvas.DrawLine(models.Point{X: -5, Y: 0}, models.Point{X: -5, Y: 5000}, paint)
+	if got := canvas.CulledDraws(); got != 0 {
+		t.Errorf("device stroke reaching into the canvas was culled, count %d", got)
+	}
+	canvas.DrawLine(models.Point{X: -10000, Y: 0}, models.Point{X: -10000, Y: 5000}, paint)
+	if got := canvas.CulledDraws(); got != 1 {
+		t.Errorf("device stroke outside the canvas was not culled, count %d", got)
+	}
+}
+
+func TestLocalInflation(t *testing.T) {
+	c := NewCanvas(new(op.Ops)).(*canvas)
+	c.Scale(4, 0.5)
+	// The shortest axis needs the most local units.
+	if got := c.localInflation(1); got < 2 {
+		t.Errorf("inflation %v does not cover one pixel along y", got)
+	}
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+)
+
+// StrokeRec describes how a path is filled or stroked, mirroring
+// SkStrokeRec. Path effects may change it, for example to turn a stroke
+// into a fill of the geometry they produce.
+type StrokeRec struct {
+	// Style is fill, stroke or stroke and fill. A stroke of width 0 is a
+	// hairline.
+	Style enums.PaintStyle
+	Width Scalar
+	Miter Scalar
+	Cap   enums.PaintCap
+	Join  enums.PaintJoin
+	// ResScale is the expected scale of the path on screen; larger values
+	// approximate curves more precisely.
+	ResScale Scalar
+}
+
+// NewStrokeRec returns the stroke described by paint.
+func NewStrokeRec(paint SkPaint, resScale Scalar) StrokeRec {
+	if !(resScale > 0) {
+		resScale = 1
+	}
+	return StrokeRec{
+		Style:    paint.GetStyle(),
+		Width:    paint.GetStrokeWidth(),
+		Miter:    paint.GetStrokeMiter(),
+		Cap:      paint.GetStrokeCap(),
+		Join:     paint.GetStrokeJoin(),
+		ResScale: resScale,
+	}
+}
+
+// IsFill reports whether the path is only filled.
+func (r *StrokeRec) IsFill() bool {
+	return r.Style == enums.PaintStyleFill
+}
+
+// IsHairline reports whether the path is stroked one device pixel wide.
+func (r *StrokeRec) IsHairline() bool {
+	return r.Style == enums.PaintStyleStroke && r.Width == 0
+}
+
+// SetFill makes the record fill the path.
+func (r *StrokeRec) SetFill() {
+	r.Style = enums.PaintStyleFill
+}
+
+// SetHairline makes the record stroke the path as a hairline.
+func (r *StrokeRec) SetHairline() {
+	r.Style = enums.PaintStyleStroke
+	r.Width = 0
+}
+
+// SetStrokeStyle makes the record stroke the path width wide, or as a
+// hairline if width is 0.
+func (r *StrokeRec) SetStrokeStyle(width Scalar) {
+	r.Style = enums.PaintStyleStroke
+	r.Width = width
+}
+
+// tolerance returns the curve flattening tolerance of the record, in path
+// units.
+func (r *StrokeRec) tolerance() Scalar {
+	if r.ResScale > 0 {
+		return conicTolerance / r.ResScale
+	}
+	return conicTolerance
+}
+
+// opts returns the stroker options of the record.
+func (r *StrokeRec) opts() stroke.StrokeOpts {
+	opts := stroke.StrokeOpts{Width: float32(r.Width), Miter: float32(r.Miter)}
+	switch r.Cap {
+	case enums.PaintCapRound:
+		opts.Cap = stroke.RoundCap
+	case enums.PaintCapSquare:
+		opts.Cap = stroke.SquareCap
+	default:
+		opts.Cap = stroke.FlatCap
+	}
+	switch r.Join {
+	case enums.PaintJoinRound:
+		opts.Join = stroke.RoundJoin
+	case enums.PaintJoinBevel:
+		opts.Join = stroke.BevelJoin
+	default:
+		opts.Join = stroke.MiterJoin
+	}
+	return opts
+}
+
+// applyTo updates the fill and stroke of p to match the record.
+func (r *StrokeRec) applyTo(p Paint) Paint {
+	p.Fill = r.Style == enums.PaintStyleFill || r.Style == enums.PaintStyleStrokeAndFill
+	if r.Style == enums.PaintStyleFill {
+		p.Stroke = stroke.StrokeOpts{}
+	} else {
+		p.Stroke = r.opts()
+	}
+	return p
+}
+
+// ApplyToPath returns the outline of src stroked as described, to be
+// filled with the winding rule. For stroke and fill, src is appended to the
+// outline. It reports false for fills and hairlines, which have no
+// outline.
+func (r *StrokeRec) ApplyToPath(src SkPath) (SkPath, bool) {
+	if src == nil || r.IsFill() || r.IsHairline() {
+		return nil, false
+	}
+	tol := r.tolerance()
+	outline := impl.NewSkPath(enums.PathFillTypeWinding)
+	appendStrokedContours(skPathSink{path: outline}, stroke.StrokedContours(toStrokePath(src, tol), r.opts()))
+	if r.Style != enums.PaintStyleStrokeAndFill {
+		return outline, true
+	}
+	// The outer contours of the outline wind clockwise. The path is
+	// appended winding the same way, as Skia's stroker does, so that under
+	// the winding fill rule it adds to the stroke rather than cancelling
+	// it.
+	var fill recordedPath
+	convertPath(src, tol, &fill)
+	if fill.area() < 0 {
+		fill = fill.reversed()
+	}
+	fill.replay(skPathSink{path: outline})
+	return outline, true
+}
+
+// FillPathWithPaint returns the geometry drawn by paint for src: src after
+// the paint's path effect, outlined if the paint strokes it. isFill reports
+// whether the result is filled; it is false for hairlines, whose result is
+// the path to stroke one pixel wide. resScale is the expected scale of the
+// path on screen. This mirrors skpathutils::FillPathWithPaint.
+func FillPathWithPaint(src SkPath, paint SkPaint, resScale Scalar) (SkPath, bool) {
+	rec := NewStrokeRec(paint, resScale)
+	path := applyPathEffect(src, paint, &rec)
+	if outline, ok := rec.ApplyToPath(path); ok {
+		return outline, true
+	}
+	dst := impl.NewSkPath(path.FillType())
+	dst.AddPathNoOffset(path, enums.AddPathModeAppend)
+	return dst, !rec.IsHairline()
+}
+
+// applyPathEffect returns src after the path effect of paint, updating rec.
+// Effects that are not PathEffects, and effects that fail, leave src as is.
+func applyPathEffect(src SkPath, paint interfaces.SkPaint, rec *StrokeRec) SkPath {
+	effect, ok := paint.GetPathEffect().(PathEffect)
+	if !ok || effect == nil {
+		return src
+	}
+	if dst, ok := effect.FilterPath(src, rec); ok {
+		return dst
+	}
+	return src
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"github.com/zodimo/gio-skia/pkg/stroke"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func linePath(x0, y0, x1, y1 Scalar) SkPath {
+	p := impl.NewSkPath(enums.PathFillTypeWinding)
+	p.MoveTo(x0, y0)
+	p.LineTo(x1, y1)
+	return p
+}
+
+func TestFillPathWithPaint_Styles(t *testing.T) {
+	rect := rectPath(0, 0, 10, 10)
+	paint := NewPaint()
+
+	got, isFill := FillPathWithPaint(rect, paint, 1)
+	if !isFill || got.CountPoints() != rect.CountPoints() || got.Bounds() != rect.Bounds() {
+		t.Errorf("fill: isFill %v, bounds %+v", isFill, got.Bounds())
+	}
+	got.Offset(5, 5)
+	if rect.Bounds().Left != 0 {
+		t.Error("result should be a copy of the source")
+	}
+
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(10)
+	paint.SetStrokeCap(enums.PaintCapButt)
+	got, isFill = FillPathWithPaint(linePath(0, 0, 100, 0), paint, 1)
+	if want := (models.Rect{Left: 0, Top: -5, Right: 100, Bottom: 5}); !isFill || got.Bounds() != want {
+		t.Errorf("stroke: isFill %v, bounds %+v, want %+v", isFill, got.Bounds(), want)
+	}
+
+	paint.SetStrokeWidth(0)
+	got, isFill = FillPathWithPaint(linePath(0, 0, 100, 0), paint, 1)
+	if isFill || got.CountPoints() != 2 {
+		t.Errorf("hairline: isFill %v, %d points", isFill, got.CountPoints())
+	}
+
+	paint.SetStyle(enums.PaintStyleStrokeAndFill)
+	paint.SetStrokeWidth(4)
+	paint.SetStrokeJoin(enums.PaintJoinMiter)
+	// The fill covers the inside whichever way the path winds.
+	ccw := impl.NewSkPath(enums.PathFillTypeWinding)
+	ccw.MoveTo(0, 0)
+	ccw.LineTo(0, 10)
+	ccw.LineTo(10, 10)
+	ccw.LineTo(10, 0)
+	ccw.Close()
+	for _, path := range []SkPath{rect, ccw} {
+		got, isFill = FillPathWithPaint(path, paint, 1)
+		if !isFill {
+			t.Error("stroke and fill: not a fill")
+		}
+		for _, p := range []f32.Point{{X: 5, Y: 5}, {X: 1, Y: 1}, {X: -1, Y: 5}, {X: 11.5, Y: 11.5}} {
+			if !pathContains(got, p) {
+				t.Errorf("stroke and fill does not cover %v", p)
+			}
+		}
+		if pathContains(got, f32.Pt(12.5, 5)) {
+			t.Error("stroke and fill covers beyond the stroke")
+		}
+	}
+}
+
+func TestFillPathWithPaint_Dash(t *testing.T) {
+	paint := NewPaint()
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetPathEffect(NewDashPathEffect([]Scalar{10, 10}, 5))
+
+	got, isFill := FillPathWithPaint(linePath(0, 0, 100, 0), paint, 1)
+	if isFill {
+		t.Error("dashed hairline should not be filled")
+	}
+	// The phase shortens the first dash: [0,5] [15,25] ... [95,100].
+	var lengths []Scalar
+	for m := NewPathMeasure(got, false, 1); m.Contour() != nil; m.NextContour() {
+		lengths = append(lengths, m.Length())
+	}
+	want := []Scalar{5, 10, 10, 10, 10, 5}
+	if len(lengths) != len(want) {
+		t.Fatalf("dash lengths %v, want %v", lengths, want)
+	}
+	for i := range want {
+		if !near(lengths[i], want[i], 1e-3) {
+			t.Errorf("dash lengths %v, want %v", lengths, want)
+			break
+		}
+	}
+
+	paint.SetStrokeWidth(2)
+	paint.SetStrokeCap(enums.PaintCapButt)
+	got, isFill = FillPathWithPaint(linePath(0, 0, 100, 0), paint, 1)
+	if area := pathArea(got); !isFill || !near(area, 100, 1e-2) {
+		t.Errorf("dashed stroke: isFill %v, area %v, want 100", isFill, area)
+	}
+
+	// Fills are not dashed.
+	paint.SetStyle(enums.PaintStyleFill)
+	got, _ = FillPathWithPaint(rectPath(0, 0, 10, 10), paint, 1)
+	if area := pathArea(got); !near(area, 100, 1e-3) {
+		t.Errorf("dashed fill area %v, want 100", area)
+	}
+}
+
+func TestDashPathEffect_JoinsClosedContours(t *testing.T) {
+	// The last dash of the 160 long outline, [120,160], runs into the
+	// first, [0,50], across the corner where the rect starts.
+	rec := strokeRec()
+	got, ok := NewDashPathEffect([]Scalar{50, 10}, 0).FilterPath(rectPath(0, 0, 40, 40), &rec)
+	lengths := contourLengths(got)
+	if !ok || len(lengths) != 2 || !near(lengths[0], 50, 1e-3) || !near(lengths[1], 90, 1e-3) {
+		t.Fatalf("dash lengths %v, want [50 90]", lengths)
+	}
+
+	// The dashes match those of a dashed stroke.
+	want := stroke.DashedContours(toStrokePath(rectPath(0, 0, 40, 40), 1), []float32{50, 10}, 0)
+	var segs recordedPath
+	appendOpenContours(&segs, want)
+	moves := 0
+	for _, s := range segs {
+		if s.verb == enums.PathVerbMove {
+			moves++
+		}
+	}
+	if moves != len(lengths) {
+		t.Errorf("dashed stroke has %d dashes, effect %d", moves, len(lengths))
+	}
+}
+
+func TestNewDashPathEffect_Invalid(t *testing.T) {
+	for _, intervals := range [][]Scalar{nil, {10}, {10, 5, 3}, {10, -1}, {0, 0}} {
+		if NewDashPathEffect(intervals, 0) != nil {
+			t.Errorf("intervals %v should be rejected", intervals)
+		}
+	}
+}
+
+func TestCanvas_DrawPathWithDash(t *testing.T) {
+	pathCache.Purge()
+	defer pathCache.Purge()
+
+	paint := NewPaint()
+	paint.SetStyle(enums.PaintStyleStroke)
+	paint.SetStrokeWidth(2)
+	paint.SetPathEffect(NewDashPathEffect([]Scalar{4, 4}, 0))
+	canvas := NewCanvas(new(op.Ops))
+	canvas.DrawLine(models.Point{X: 0, Y: 0}, models.Point{X: 100, Y: 0}, paint)
+
+	if n := pathCache.Len(); n != 1 {
+		t.Fatalf("expected 1 cached stroke, got %d", n)
+	}
+	// Without the effect the undashed line is a different stroke.
+	paint.SetPathEffect(nil)
+	canvas.DrawLine(models.Point{X: 0, Y: 0}, models.Point{X: 100, Y: 0}, paint)
+	if n := pathCache.Len(); n != 2 {
+		t.Errorf("dashed and solid lines should be cached separately, got %d entries", n)
+	}
+}
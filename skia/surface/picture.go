@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package surface
+
+import (
+	"errors"
+
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// MakeRenderTarget returns a GPU surface of the given size for rendering
+// content once and drawing it many times, mirroring
+// SkSurfaces::RenderTarget: draw into its canvas, then draw its
+// MakeImageSnapshot with DrawImage or DrawImageRect. Release the surface
+// once the snapshots are taken.
+func MakeRenderTarget(width, height int) (Surface, error) {
+	return NewGPU(width, height)
+}
+
+// MakePictureImage renders picture, transformed by matrix if not nil, into
+// an image of the given size, mirroring SkImages::DeferredFromPicture
+// except that the picture is rendered at once, on the GPU like every
+// surface. The image is premultiplied RGBA and can be drawn like any
+// other. The picture is replayed as recorded, at the resolution of the
+// recording, so matrix must be a translation: other matrices return an
+// error. See skia.Extended.DrawPicture.
+func MakePictureImage(picture *skia.Picture, size models.ISize, matrix skia.SkMatrix) (interfaces.SkImage, error) {
+	if picture == nil {
+		return nil, errors.New("surface: nil picture")
+	}
+	if matrix != nil && !matrix.IsTranslate() {
+		return nil, errors.New("surface: picture matrix is not a translation")
+	}
+	s, err := newSurfaceWithInfo(models.NewImageInfo(int(size.Width), int(size.Height), enums.ColorTypeRGBA8888, enums.AlphaTypePremul))
+	if err != nil {
+		return nil, err
+	}
+	defer s.Release()
+	s.GetCanvas().(skia.Extended).DrawPicture(picture, matrix, nil)
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	return snapshot(s.pixels, s.info), nil
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package surface
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestMakePictureImage(t *testing.T) {
+	var r skia.PictureRecorder
+	c := r.BeginRecording(models.Rect{Right: 10, Bottom: 10})
+	c.DrawRect(models.Rect{Right: 10, Bottom: 10}, skia.NewPaintFill(color.NRGBA{G: 255, A: 255}))
+	p := r.FinishRecordingAsPicture()
+
+	if _, err := MakePictureImage(nil, models.NewISize(4, 4), nil); err == nil {
+		t.Error("image of nil picture made")
+	}
+	if _, err := MakePictureImage(p, models.NewISize(20, 20), impl.NewMatrixScale(2, 2)); err == nil {
+		t.Error("image of scaled picture made")
+	}
+	img, err := MakePictureImage(p, models.NewISize(30, 20), impl.NewMatrixTranslate(15, 5))
+	if err != nil {
+		t.Skipf("no headless renderer: %v", err)
+	}
+	if img.Width() != 30 || img.Height() != 20 {
+		t.Fatalf("size %dx%d", img.Width(), img.Height())
+	}
+	pix := skia.ImageToRGBA(img)
+	if got := pix.RGBAAt(20, 10); got != (color.RGBA{G: 255, A: 255}) {
+		t.Errorf("picture pixel %v", got)
+	}
+	if got := pix.RGBAAt(5, 10); got.A != 0 {
+		t.Errorf("pixel outside the picture %v", got)
+	}
+
+	// The image draws like any other on a render target.
+	s, err := MakeRenderTarget(40, 20)
+	if err != nil {
+		t.Skipf("no headless renderer: %v", err)
+	}
+	defer s.Release()
+	s.GetCanvas().DrawImageRect(img, nil, models.Rect{Right: 15, Bottom: 10}, nil)
+	out := make([]byte, 4)
+	if !s.ReadPixels(models.NewImageInfo(1, 1, img.ColorType(), img.AlphaType()), out, 4, 10, 5) {
+		t.Fatal("pixels not read")
+	}
+	if out[1] < 250 || out[3] != 255 {
+		t.Errorf("drawn picture image pixel %v", out)
+	}
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package surface
+
+import (
+	"image"
+
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// rasterSurface draws on the CPU straight into its pixels, so flushing
+// has nothing to do.
+type rasterSurface struct {
+	canvas skia.Canvas
+	// info describes the pixels of snapshots.
+	info     models.ImageInfo
+	pixels   *image.RGBA
+	released bool
+}
+
+// NewRaster returns a surface of the given size that draws on the CPU,
+// mirroring SkSurfaces::Raster. Its snapshots are premultiplied RGBA.
+func NewRaster(width, height int) (Surface, error) {
+	return NewRasterWithInfo(models.NewImageInfo(width, height, enums.ColorTypeRGBA8888, enums.AlphaTypePremul))
+}
+
+// NewRasterWithInfo returns a raster surface with snapshots in the size,
+// color type and alpha type of info. Drawing is premultiplied RGBA
+// whatever the color type.
+func NewRasterWithInfo(info models.ImageInfo) (Surface, error) {
+	if err := checkInfo(info); err != nil {
+		return nil, err
+	}
+	pixels := image.NewRGBA(image.Rect(0, 0, info.Width(), info.Height()))
+	return &rasterSurface{
+		canvas: skia.NewRasterCanvas(pixels),
+		info:   info,
+		pixels: pixels,
+	}, nil
+}
+
+func (s *rasterSurface) Width() int {
+	return s.info.Width()
+}
+
+func (s *rasterSurface) Height() int {
+	return s.info.Height()
+}
+
+func (s *rasterSurface) GetCanvas() skia.Canvas {
+	return s.canvas
+}
+
+func (s *rasterSurface) Flush() error {
+	if s.released {
+		return errReleased
+	}
+	return nil
+}
+
+func (s *rasterSurface) MakeImageSnapshot() interfaces.SkImage {
+	if s.released {
+		return nil
+	}
+	return snapshot(s.pixels, s.info)
+}
+
+func (s *rasterSurface) ReadPixels(dstInfo models.ImageInfo, dst []byte, dstRowBytes, srcX, srcY int) bool {
+	return !s.released && readPixels(s.pixels, dstInfo, dst, dstRowBytes, srcX, srcY)
+}
+
+func (s *rasterSurface) Release() {
+	s.released = true
+	s.pixels = nil
+}
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package surface provides offscreen render targets for skia canvases,
+// mirroring SkSurface: draw with the canvas of a surface, then take a
+// snapshot image or read its pixels back.
+//
+// GPU surfaces render with Gio's headless GPU renderer, sharing a few
+// headless windows; NewGPUWithInfo only chooses the pixel format of their
+// snapshots. On Linux machines without a GPU, Mesa's software renderer
+// serves instead when the environment variable EGL_PLATFORM is set to
+// surfaceless. Raster surfaces draw on the CPU with skia.NewRasterCanvas,
+// needing no GPU at all, but their canvases lack the extras of
+// skia.Extended.
+package surface
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"sync"
+
+	"gioui.org/gpu/headless"
+	"gioui.org/op"
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Surface owns the pixels a canvas draws into.
+type Surface interface {
+	// Width and Height return the size of the surface in pixels.
+	Width() int
+	Height() int
+	// GetCanvas returns the canvas drawing into the surface. It is the same
+	// canvas on every call, and is clipped to the surface.
+	GetCanvas() skia.Canvas
+	// Flush renders the draws made so far into the surface's pixels.
+	Flush() error
+	// MakeImageSnapshot flushes the surface and returns an image of its
+	// pixels, or nil if rendering fails. Later draws do not change the
+	// image.
+	MakeImageSnapshot() interfaces.SkImage
+	// ReadPixels flushes the surface and copies the pixels of the
+	// rectangle of dstInfo's size at srcX, srcY into dst, with rows
+	// dstRowBytes apart, converted to dstInfo's color and alpha type. Only
+	// the part of the rectangle within the surface is copied. It reports
+	// false if nothing is copied.
+	ReadPixels(dstInfo models.ImageInfo, dst []byte, dstRowBytes, srcX, srcY int) bool
+	// Release frees the resources of the surface. It must not be used
+	// afterwards.
+	Release()
+}
+
+var (
+	errReleased = errors.New("surface: released")
+	errEmpty    = errors.New("surface: empty size")
+)
+
+// minWindowSize is the smallest headless window made.
+const minWindowSize = 64
+
+// renderer holds the headless windows surfaces render with, one per size
+// class. Releasing a headless window terminates the process's EGL display,
+// breaking every other window, so windows are only released together by
+// ReleaseRenderer.
+var renderer = struct {
+	sync.Mutex
+	windows map[image.Point]*headless.Window
+}{}
+
+// windowSize returns the size class of size: each side rounded up to a
+// power of two.
+func windowSize(size image.Point) image.Point {
+	class := func(n int) int {
+		c := minWindowSize
+		for c < n {
+			c *= 2
+		}
+		return c
+	}
+	return image.Pt(class(size.X), class(size.Y))
+}
+
+// render renders ops and reads the top-left corner of the result into dst.
+func render(ops *op.Ops, dst *image.RGBA) error {
+	renderer.Lock()
+	defer renderer.Unlock()
+	size := windowSize(dst.Rect.Size())
+	w := renderer.windows[size]
+	if w == nil {
+		nw, err := headless.NewWindow(size.X, size.Y)
+		if err != nil {
+			return err
+		}
+		if renderer.windows == nil {
+			renderer.windows = make(map[image.Point]*headless.Window)
+		}
+		renderer.windows[size] = nw
+		w = nw
+	}
+	if err := w.Frame(ops); err != nil {
+		return err
+	}
+	if w.Size() == dst.Rect.Size() {
+		return w.Screenshot(dst)
+	}
+	// Read the whole window; OpenGL reads parts from the bottom.
+	full := image.NewRGBA(image.Rectangle{Max: w.Size()})
+	if err := w.Screenshot(full); err != nil {
+		return err
+	}
+	draw.Draw(dst, dst.Rect, full, image.Point{}, draw.Src)
+	return nil
+}
+
+// ReleaseRenderer frees the headless windows shared by all surfaces, for
+// example once an export is done. The next flush makes new ones.
+func ReleaseRenderer() {
+	renderer.Lock()
+	defer renderer.Unlock()
+	for size, w := range renderer.windows {
+		w.Release()
+		delete(renderer.windows, size)
+	}
+}
+
+// surface renders the Gio ops recorded by its canvas. Gio renders an op
+// list in full, so every flush renders all draws since the surface was
+// made.
+type surface struct {
+	ops    op.Ops
+	canvas skia.Canvas
+	// info describes the pixels of snapshots.
+	info models.ImageInfo
+	// pixels holds the result of the last flush.
+	pixels   *image.RGBA
+	released bool
+}
+
+// NewGPU returns a surface of the given size, checking that the GPU can
+// render it. Its snapshots are premultiplied RGBA.
+func NewGPU(width, height int) (Surface, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errEmpty
+	}
+	s := newSurface(models.NewImageInfo(width, height, enums.ColorTypeRGBA8888, enums.AlphaTypePremul))
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewGPUWithInfo returns a surface with snapshots in the size, color type
+// and alpha type of info. Like NewGPU it renders on the GPU, but it does
+// not check the GPU until the first flush.
+func NewGPUWithInfo(info models.ImageInfo) (Surface, error) {
+	s, err := newSurfaceWithInfo(info)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newSurfaceWithInfo(info models.ImageInfo) (*surface, error) {
+	if err := checkInfo(info); err != nil {
+		return nil, err
+	}
+	return newSurface(info), nil
+}
+
+// checkInfo reports whether surfaces can have pixels described by info.
+func checkInfo(info models.ImageInfo) error {
+	if info.Width() <= 0 || info.Height() <= 0 {
+		return errEmpty
+	}
+	if info.BytesPerPixel() == 0 {
+		return errors.New("surface: unknown color type")
+	}
+	return nil
+}
+
+func newSurface(info models.ImageInfo) *surface {
+	s := &surface{info: info}
+	s.canvas = skia.NewCanvasWithSize(&s.ops, info.Width(), info.Height())
+	s.pixels = image.NewRGBA(image.Rect(0, 0, info.Width(), info.Height()))
+	return s
+}
+
+func (s *surface) Width() int {
+	return s.info.Width()
+}
+
+func (s *surface) Height() int {
+	return s.info.Height()
+}
+
+func (s *surface) GetCanvas() skia.Canvas {
+	return s.canvas
+}
+
+func (s *surface) Flush() error {
+	if s.released {
+		return errReleased
+	}
+	img := image.NewRGBA(s.pixels.Rect)
+	if err := render(&s.ops, img); err != nil {
+		return err
+	}
+	s.pixels = img
+	return nil
+}
+
+func (s *surface) MakeImageSnapshot() interfaces.SkImage {
+	if s.Flush() != nil {
+		return nil
+	}
+	return snapshot(s.pixels, s.info)
+}
+
+// snapshot returns an image of pixels in the format of info.
+func snapshot(pixels *image.RGBA, info models.ImageInfo) interfaces.SkImage {
+	rowBytes := info.MinRowBytes()
+	data := make([]byte, rowBytes*info.Height())
+	if !skia.RGBAToPixels(pixels, info, data, rowBytes) {
+		return nil
+	}
+	return impl.NewRasterImage(info, data, rowBytes)
+}
+
+func (s *surface) ReadPixels(dstInfo models.ImageInfo, dst []byte, dstRowBytes, srcX, srcY int) bool {
+	return s.Flush() == nil && readPixels(s.pixels, dstInfo, dst, dstRowBytes, srcX, srcY)
+}
+
+// readPixels implements Surface.ReadPixels for a surface with pixels.
+func readPixels(pixels *image.RGBA, dstInfo models.ImageInfo, dst []byte, dstRowBytes, srcX, srcY int) bool {
+	src := image.Rect(srcX, srcY, srcX+dstInfo.Width(), srcY+dstInfo.Height())
+	r := src.Intersect(pixels.Rect)
+	bpp := dstInfo.BytesPerPixel()
+	if r.Empty() || bpp == 0 {
+		return false
+	}
+	// Copy the visible part to its place in dst.
+	off := (r.Min.Y-srcY)*dstRowBytes + (r.Min.X-srcX)*bpp
+	if off >= len(dst) {
+		return false
+	}
+	info := models.NewImageInfo(r.Dx(), r.Dy(), dstInfo.ColorType(), dstInfo.AlphaType())
+	return skia.RGBAToPixels(pixels.SubImage(r).(*image.RGBA), info, dst[off:], dstRowBytes)
+}
+
+func (s *surface) Release() {
+	s.released = true
+	s.ops.Reset()
+	s.pixels = nil
+}
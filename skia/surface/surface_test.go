@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package surface
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/zodimo/gio-skia/skia"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// newSurfaces returns premultiplied RGBA surfaces and unpremultiplied BGRA
+// ones of the size, raster and, if the machine can render, GPU.
+func newSurfaces(t *testing.T, width, height int) map[string]Surface {
+	t.Helper()
+	bgraInfo := models.NewImageInfo(width, height, enums.ColorTypeBGRA8888, enums.AlphaTypeUnpremul)
+	surfaces := make(map[string]Surface)
+	t.Cleanup(func() {
+		for _, s := range surfaces {
+			s.Release()
+		}
+	})
+	raster, err := NewRaster(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surfaces["raster rgba"] = raster
+	rasterBGRA, err := NewRasterWithInfo(bgraInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surfaces["raster bgra"] = rasterBGRA
+	gpu, err := NewGPU(width, height)
+	if err != nil {
+		t.Logf("no headless renderer: %v", err)
+		return surfaces
+	}
+	surfaces["gpu rgba"] = gpu
+	bgra, err := NewGPUWithInfo(bgraInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surfaces["gpu bgra"] = bgra
+	return surfaces
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := NewGPU(0, 10); err == nil {
+		t.Error("empty GPU surface made")
+	}
+	if _, err := NewGPUWithInfo(models.NewImageInfo(10, 0, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)); err == nil {
+		t.Error("empty surface made")
+	}
+	if _, err := NewGPUWithInfo(models.NewImageInfo(10, 10, enums.ColorTypeUnknown, enums.AlphaTypePremul)); err == nil {
+		t.Error("surface of unknown color type made")
+	}
+	if _, err := NewRaster(10, 0); err == nil {
+		t.Error("empty raster surface made")
+	}
+	if _, err := NewRasterWithInfo(models.NewImageInfo(10, 10, enums.ColorTypeUnknown, enums.AlphaTypePremul)); err == nil {
+		t.Error("raster surface of unknown color type made")
+	}
+}
+
+func TestSurface_Draw(t *testing.T) {
+	for name, s := range newSurfaces(t, 20, 10) {
+		c := s.GetCanvas()
+		if c != s.GetCanvas() {
+			t.Errorf("%s: canvas changed", name)
+		}
+		c.DrawRect(models.Rect{Right: 10, Bottom: 10}, skia.NewPaintFill(color.NRGBA{R: 255, A: 255}))
+
+		snap := s.MakeImageSnapshot()
+		if snap == nil {
+			t.Fatalf("%s: no snapshot", name)
+		}
+		if snap.Width() != 20 || snap.Height() != 10 {
+			t.Errorf("%s: snapshot %dx%d", name, snap.Width(), snap.Height())
+		}
+		img := skia.ImageToRGBA(snap)
+		if got := img.RGBAAt(5, 5); got != (color.RGBA{R: 255, A: 255}) {
+			t.Errorf("%s: drawn pixel %v", name, got)
+		}
+		if got := img.RGBAAt(15, 5); got.A != 0 {
+			t.Errorf("%s: undrawn pixel %v", name, got)
+		}
+
+		// Later draws add to the surface but not to the snapshot.
+		c.DrawRect(models.Rect{Left: 10, Right: 20, Bottom: 10}, skia.NewPaintFill(color.NRGBA{B: 255, A: 255}))
+		if got := skia.ImageToRGBA(snap).RGBAAt(15, 5); got.A != 0 {
+			t.Errorf("%s: snapshot changed to %v", name, got)
+		}
+
+		// Read 4x4 pixels straddling the right edge as RGBA.
+		info := models.NewImageInfo(4, 4, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+		dst := make([]byte, 4*4*4)
+		if !s.ReadPixels(info, dst, 16, 18, 2) {
+			t.Fatalf("%s: pixels not read", name)
+		}
+		if got := dst[:4]; got[0] != 0 || got[2] != 255 || got[3] != 255 {
+			t.Errorf("%s: read pixel %v, want blue", name, got)
+		}
+		if got := dst[8:12]; got[3] != 0 {
+			t.Errorf("%s: pixel outside the surface written: %v", name, got)
+		}
+		if s.ReadPixels(info, dst, 16, 20, 0) {
+			t.Errorf("%s: pixels read outside the surface", name)
+		}
+	}
+}
+
+func TestSurface_Release(t *testing.T) {
+	info := models.NewImageInfo(4, 4, enums.ColorTypeRGBA8888, enums.AlphaTypePremul)
+	gpu, err := NewGPUWithInfo(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raster, err := NewRasterWithInfo(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Surface{gpu, raster} {
+		s.Release()
+		if s.Flush() == nil || s.MakeImageSnapshot() != nil {
+			t.Errorf("released %T rendered", s)
+		}
+		if s.ReadPixels(info, make([]byte, 64), 16, 0, 0) {
+			t.Errorf("released %T read", s)
+		}
+		s.Release()
+	}
+}
+
+func TestSurface_SharedRenderer(t *testing.T) {
+	small, err := NewGPU(4, 4)
+	if err != nil {
+		t.Skipf("no headless renderer: %v", err)
+	}
+	defer small.Release()
+	small.GetCanvas().DrawRect(models.Rect{Right: 4, Bottom: 4}, skia.NewPaintFill(color.NRGBA{G: 255, A: 255}))
+
+	// A larger surface renders in a window of its own size class and
+	// releasing it leaves the others working.
+	large, err := NewGPUWithInfo(models.NewImageInfo(64, 32, enums.ColorTypeRGBA8888, enums.AlphaTypePremul))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := large.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	large.Release()
+
+	snap := small.MakeImageSnapshot()
+	if snap == nil {
+		t.Fatal(small.Flush())
+	}
+	if got := skia.ImageToRGBA(snap).RGBAAt(3, 3); got != (color.RGBA{G: 255, A: 255}) {
+		t.Errorf("pixel %v after rendering a larger surface", got)
+	}
+	ReleaseRenderer()
+	if snap := small.MakeImageSnapshot(); snap == nil || skia.ImageToRGBA(snap).RGBAAt(0, 0).G != 255 {
+		t.Error("no rendering after releasing the renderer")
+	}
+}
+
+func TestWindowSize(t *testing.T) {
+	tests := []struct {
+		size, want image.Point
+	}{
+		{image.Pt(1, 1), image.Pt(minWindowSize, minWindowSize)},
+		{image.Pt(64, 65), image.Pt(64, 128)},
+		{image.Pt(1000, 300), image.Pt(1024, 512)},
+	}
+	for _, tt := range tests {
+		if got := windowSize(tt.size); got != tt.want {
+			t.Errorf("windowSize(%v) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
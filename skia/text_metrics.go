@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// Text measurement. These functions shape and measure text exactly as
+// DrawSimpleText draws it, and share its shaping cache, so measuring before
+// drawing does not shape twice.
+
+// shapeCacheSize is the number of shaped strings kept for reuse.
+const shapeCacheSize = 512
+
+// shapeKey identifies a shaped string. It holds every font setting that is
+// stored in the shaped blob, so a cached blob draws like a fresh one.
+type shapeKey struct {
+	text     string
+	typeface interfaces.SkTypeface
+	size     Scalar
+	scaleX   Scalar
+	skewX    Scalar
+	edging   enums.FontEdging
+	hinting  enums.FontHinting
+	flags    uint8
+}
+
+// shapedText is a shaped string and its advance.
+type shapedText struct {
+	blob    interfaces.SkTextBlob
+	advance Scalar
+}
+
+var shapeCache = newLRUCache[shapeKey, shapedText](shapeCacheSize)
+
+func makeShapeKey(text string, f interfaces.SkFont) shapeKey {
+	var flags uint8
+	for i, set := range []bool{f.IsSubpixel(), f.IsEmbolden(), f.IsForceAutoHinting(), f.IsBaselineSnap(), f.IsLinearMetrics(), f.IsEmbeddedBitmaps()} {
+		if set {
+			flags |= 1 << i
+		}
+	}
+	return shapeKey{
+		text:     text,
+		typeface: f.Typeface(),
+		size:     f.Size(),
+		scaleX:   f.ScaleX(),
+		skewX:    f.SkewX(),
+		edging:   f.Edging(),
+		hinting:  f.Hinting(),
+		flags:    flags,
+	}
+}
+
+// cloneFont returns a copy of f. The runs of a shaped blob refer to the font
+// they were shaped with, so cached blobs hold a copy the caller cannot
+// change.
+func cloneFont(f interfaces.SkFont) interfaces.SkFont {
+	if fi, ok := f.(*impl.Font); ok {
+		c := *fi
+		return &c
+	}
+	c := impl.NewFontWithTypefaceSizeScaleSkew(f.Typeface(), f.Size(), f.ScaleX(), f.SkewX())
+	c.SetEdging(f.Edging())
+	c.SetHinting(f.Hinting())
+	c.SetSubpixel(f.IsSubpixel())
+	c.SetEmbolden(f.IsEmbolden())
+	c.SetForceAutoHinting(f.IsForceAutoHinting())
+	c.SetBaselineSnap(f.IsBaselineSnap())
+	c.SetLinearMetrics(f.IsLinearMetrics())
+	c.SetEmbeddedBitmaps(f.IsEmbeddedBitmaps())
+	return c
+}
+
+// shapeTextCached returns text shaped on a single left-to-right line at the
+// origin, reusing earlier results. The blob's runs refer to a private copy
+// of f, so later changes to f do not affect cached or returned blobs.
+func shapeTextCached(text string, f interfaces.SkFont) shapedText {
+	key := makeShapeKey(text, f)
+	if s, ok := shapeCache.Get(key); ok {
+		return s
+	}
+	blob, advance := shapeTextAdvance(text, cloneFont(f))
+	s := shapedText{blob: blob, advance: advance}
+	shapeCache.Put(key, s, 1)
+	return s
+}
+
+// decodeText converts text in the given encoding to a string. Glyph IDs
+// cannot be decoded and report false. UTF-16 and UTF-32 are little endian,
+// matching SkFont.
+func decodeText(text []byte, encoding enums.TextEncoding) (string, bool) {
+	switch encoding {
+	case enums.TextEncodingUTF8:
+		return string(text), true
+	case enums.TextEncodingUTF16:
+		u16 := make([]uint16, len(text)/2)
+		for i := range u16 {
+			u16[i] = binary.LittleEndian.Uint16(text[2*i:])
+		}
+		return string(utf16.Decode(u16)), true
+	case enums.TextEncodingUTF32:
+		runes := make([]rune, len(text)/4)
+		for i := range runes {
+			r := rune(binary.LittleEndian.Uint32(text[4*i:]))
+			if !utf8.ValidRune(r) {
+				r = utf8.RuneError
+			}
+			runes[i] = r
+		}
+		return string(runes), true
+	}
+	return "", false
+}
+
+// glyphIDs converts glyph ID encoded text to glyphs.
+func glyphIDs(text []byte) []uint16 {
+	glyphs := make([]uint16, len(text)/2)
+	for i := range glyphs {
+		glyphs[i] = binary.LittleEndian.Uint16(text[2*i:])
+	}
+	return glyphs
+}
+
+// glyphIDBlob lays out glyphs by their advances, without shaping.
+func glyphIDBlob(glyphs []uint16, f interfaces.SkFont) (interfaces.SkTextBlob, Scalar) {
+	if len(glyphs) == 0 {
+		return nil, 0
+	}
+	widths := GetWidths(f, glyphs)
+	builder := impl.NewTextBlobBuilder()
+	run := builder.AllocRunPosH(f, len(glyphs), 0)
+	var x Scalar
+	for i, g := range glyphs {
+		run.Glyphs[i] = impl.GlyphID(g)
+		run.Positions[i] = x
+		x += widths[i]
+	}
+	builder.AddRun()
+	return builder.Make(), x
+}
+
+// MeasureText returns the advance width of text as DrawSimpleText draws
+// it, and its tight bounds relative to the origin of the baseline.
+func MeasureText(text []byte, encoding enums.TextEncoding, f interfaces.SkFont) (Scalar, models.Rect) {
+	if len(text) == 0 || f == nil {
+		return 0, models.Rect{}
+	}
+	var blob interfaces.SkTextBlob
+	var advance Scalar
+	if encoding == enums.TextEncodingGlyphID {
+		blob, advance = glyphIDBlob(glyphIDs(text), f)
+	} else {
+		str, _ := decodeText(text, encoding)
+		s := shapeTextCached(str, f)
+		blob, advance = s.blob, s.advance
+	}
+	return advance, textBlobTightBounds(blob)
+}
+
+// MeasureString is MeasureText for UTF-8 strings.
+func MeasureString(text string, f interfaces.SkFont) (Scalar, models.Rect) {
+	return MeasureText([]byte(text), enums.TextEncodingUTF8, f)
+}
+
+// textBlobTightBounds returns the union of the glyph bounds in blob.
+func textBlobTightBounds(blob interfaces.SkTextBlob) models.Rect {
+	tb, ok := blob.(*impl.TextBlob)
+	if !ok || tb == nil {
+		return models.Rect{}
+	}
+	var bounds models.Rect
+	empty := true
+	for i := 0; i < tb.RunCount(); i++ {
+		run := tb.Run(i)
+		if run == nil || run.Font == nil {
+			continue
+		}
+		ids := make([]uint16, len(run.Glyphs))
+		for j, g := range run.Glyphs {
+			ids[j] = uint16(g)
+		}
+		for j, r := range GetBounds(run.Font, ids) {
+			if j >= len(run.Positions) || r.Right <= r.Left || r.Bottom <= r.Top {
+				continue
+			}
+			p := run.Positions[j]
+			r = models.Rect{Left: r.Left + p.X, Top: r.Top + p.Y, Right: r.Right + p.X, Bottom: r.Bottom + p.Y}
+			if empty {
+				bounds, empty = r, false
+			} else {
+				bounds = models.Rect{
+					Left:   min(bounds.Left, r.Left),
+					Top:    min(bounds.Top, r.Top),
+					Right:  max(bounds.Right, r.Right),
+					Bottom: max(bounds.Bottom, r.Bottom),
+				}
+			}
+		}
+	}
+	return bounds
+}
+
+// fontScale returns the scale from font units to pixels, or 0 if the font
+// has no face.
+func fontScale(f interfaces.SkFont) (*font.Face, Scalar) {
+	face := goTextFace(f.Typeface())
+	if face == nil || face.Upem() == 0 {
+		return nil, 0
+	}
+	return face, f.Size() / Scalar(face.Upem())
+}
+
+// GetWidths returns the horizontal advances of glyphs, in pixels.
+func GetWidths(f interfaces.SkFont, glyphs []uint16) []Scalar {
+	if f == nil || len(glyphs) == 0 {
+		return nil
+	}
+	face, scale := fontScale(f)
+	if face == nil {
+		return f.GetWidths(glyphs)
+	}
+	widths := make([]Scalar, len(glyphs))
+	for i, g := range glyphs {
+		widths[i] = Scalar(face.HorizontalAdvance(font.GID(g))) * scale * f.ScaleX()
+	}
+	return widths
+}
+
+// GetBounds returns the tight bounds of glyphs relative to their origin,
+// in pixels with y down, including the font's scale and skew. Glyphs
+// without ink, such as spaces, have empty bounds.
+func GetBounds(f interfaces.SkFont, glyphs []uint16) []models.Rect {
+	if f == nil || len(glyphs) == 0 {
+		return nil
+	}
+	bounds := make([]models.Rect, len(glyphs))
+	face, scale := fontScale(f)
+	if face == nil {
+		return bounds
+	}
+	m := impl.NewMatrixScale(scale*f.ScaleX(), -scale)
+	if skew := f.SkewX(); skew != 0 {
+		m.PostSkew(skew, 0)
+	}
+	typeface := f.Typeface()
+	for i, g := range glyphs {
+		if p, err := typeface.GetGlyphPath(g); err == nil && p != nil && !p.IsEmpty() {
+			mapped := impl.NewSkPath(p.FillType())
+			mapped.AddPathMatrix(p, m, enums.AddPathModeAppend)
+			bounds[i] = mapped.ComputeTightBounds()
+			continue
+		}
+		// Color and bitmap glyphs report their extents.
+		if ext, ok := face.GlyphExtents(font.GID(g)); ok && ext.Width != 0 && ext.Height != 0 {
+			bounds[i] = m.MapRect(models.Rect{
+				Left:   Scalar(ext.XBearing),
+				Top:    Scalar(ext.YBearing + ext.Height),
+				Right:  Scalar(ext.XBearing + ext.Width),
+				Bottom: Scalar(ext.YBearing),
+			})
+		}
+	}
+	return bounds
+}
+
+// GetMetrics returns the metrics of f, in pixels with y down. Unlike
+// SkFont.GetMetrics of the support package it also reports the cap height,
+// x-height and the underline and strikeout metrics.
+func GetMetrics(f interfaces.SkFont) models.FontMetrics {
+	if f == nil {
+		return models.FontMetrics{}
+	}
+	metrics := f.GetMetrics()
+	face, scale := fontScale(f)
+	if face == nil {
+		return metrics
+	}
+	if extents, ok := face.FontHExtents(); ok {
+		metrics.Ascent = -Scalar(extents.Ascender) * scale
+		metrics.Descent = -Scalar(extents.Descender) * scale
+		metrics.Leading = Scalar(extents.LineGap) * scale
+	}
+	// The font bounding box is not exposed; report the line extents.
+	metrics.Top = metrics.Ascent
+	metrics.Bottom = metrics.Descent
+	metrics.Flags |= models.FontMetricsBoundsInvalidFlag
+
+	metrics.CapHeight = -Scalar(face.LineMetric(font.CapHeight)) * scale
+	metrics.XHeight = -Scalar(face.LineMetric(font.XHeight)) * scale
+	if t := face.LineMetric(font.UnderlineThickness); t > 0 {
+		metrics.UnderlineThickness = Scalar(t) * scale
+		metrics.UnderlinePosition = -Scalar(face.LineMetric(font.UnderlinePosition)) * scale
+		metrics.Flags |= models.FontMetricsUnderlineThicknessIsValidFlag | models.FontMetricsUnderlinePositionIsValidFlag
+	}
+	if t := face.LineMetric(font.StrikethroughThickness); t > 0 {
+		metrics.StrikeoutThickness = Scalar(t) * scale
+		metrics.StrikeoutPosition = -Scalar(face.LineMetric(font.StrikethroughPosition)) * scale
+		metrics.Flags |= models.FontMetricsStrikeoutThicknessIsValidFlag | models.FontMetricsStrikeoutPositionIsValidFlag
+	}
+	return metrics
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+func TestMeasureText_MatchesShaping(t *testing.T) {
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 20)
+
+	advance, bounds := MeasureString("Hello", font)
+	if advance <= 0 {
+		t.Fatalf("advance should be positive, got %v", advance)
+	}
+	if bounds.Left < 0 || bounds.Right > advance+1 || bounds.Top >= 0 || bounds.Bottom > 0.5 {
+		t.Errorf("unexpected bounds %+v for advance %v", bounds, advance)
+	}
+
+	// DrawSimpleText draws the cached shaping result.
+	s := shapeTextCached("Hello", font)
+	if s.advance != advance {
+		t.Errorf("cached advance %v differs from measured %v", s.advance, advance)
+	}
+	if again := shapeTextCached("Hello", font); again.blob != s.blob {
+		t.Error("expected the shaped blob to be reused")
+	}
+
+	// Without kerning pairs in "Hello" the advance is the sum of the widths,
+	// up to the 26.6 fixed point rounding of the shaper.
+	var ids []uint16
+	for _, r := range "Hello" {
+		ids = append(ids, font.UnicharToGlyph(r))
+	}
+	var sum Scalar
+	for _, w := range GetWidths(font, ids) {
+		sum += w
+	}
+	if !near(sum, advance, Scalar(len(ids))/64) {
+		t.Errorf("sum of widths %v differs from advance %v", sum, advance)
+	}
+}
+
+func TestShapeTextCached_FontChangedAfterCaching(t *testing.T) {
+	typeface := goRegularTypeface(t)
+	fontA := impl.NewFontWithTypefaceAndSize(typeface, 12)
+	first := shapeTextCached("cache me", fontA)
+	fontA.SetSize(40)
+
+	fontB := impl.NewFontWithTypefaceAndSize(typeface, 12)
+	second := shapeTextCached("cache me", fontB)
+	if second.blob != first.blob {
+		t.Fatal("expected an equal font to reuse the cached blob")
+	}
+	tb := second.blob.(*impl.TextBlob)
+	for i := 0; i < tb.RunCount(); i++ {
+		if size := tb.Run(i).Font.Size(); size != 12 {
+			t.Errorf("run %d has font size %v, want 12", i, size)
+		}
+	}
+
+	// The changed font gets its own shaping.
+	if larger := shapeTextCached("cache me", fontA); larger.advance <= second.advance {
+		t.Errorf("size 40 advance %v should exceed size 12 advance %v", larger.advance, second.advance)
+	}
+}
+
+func TestMeasureText_Encodings(t *testing.T) {
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 16)
+	want, _ := MeasureString("Gio ✓", font)
+
+	var u16 []byte
+	for _, c := range utf16.Encode([]rune("Gio ✓")) {
+		u16 = binary.LittleEndian.AppendUint16(u16, c)
+	}
+	if got, _ := MeasureText(u16, enums.TextEncodingUTF16, font); !near(got, want, 1e-3) {
+		t.Errorf("UTF-16 advance %v, want %v", got, want)
+	}
+
+	var u32 []byte
+	for _, r := range "Gio ✓" {
+		u32 = binary.LittleEndian.AppendUint32(u32, uint32(r))
+	}
+	if got, _ := MeasureText(u32, enums.TextEncodingUTF32, font); !near(got, want, 1e-3) {
+		t.Errorf("UTF-32 advance %v, want %v", got, want)
+	}
+
+	g := font.UnicharToGlyph('W')
+	glyphs := binary.LittleEndian.AppendUint16(binary.LittleEndian.AppendUint16(nil, g), g)
+	got, _ := MeasureText(glyphs, enums.TextEncodingGlyphID, font)
+	if w := GetWidths(font, []uint16{g})[0]; !near(got, 2*w, 1e-3) {
+		t.Errorf("glyph ID advance %v, want %v", got, 2*w)
+	}
+}
+
+func TestGetBounds(t *testing.T) {
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 100)
+	metrics := GetMetrics(font)
+
+	ids := []uint16{font.UnicharToGlyph('H'), font.UnicharToGlyph(' ')}
+	bounds := GetBounds(font, ids)
+	if !near(bounds[0].Top, metrics.CapHeight, 0.5) || !near(bounds[0].Bottom, 0, 0.5) {
+		t.Errorf("H bounds %+v should span the cap height %v", bounds[0], metrics.CapHeight)
+	}
+	if bounds[1] != (models.Rect{}) {
+		t.Errorf("space should have empty bounds, got %+v", bounds[1])
+	}
+
+	// Skew leans the top of the glyph to the right for negative values.
+	font.SetSkewX(-0.25)
+	skewed := GetBounds(font, ids[:1])
+	if skewed[0].Right <= bounds[0].Right {
+		t.Errorf("skewed bounds %+v should extend past %+v", skewed[0], bounds[0])
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 20)
+	m := GetMetrics(font)
+	if m.Ascent >= 0 || m.Descent <= 0 {
+		t.Errorf("ascent %v should be negative and descent %v positive", m.Ascent, m.Descent)
+	}
+	if m.CapHeight >= 0 || m.XHeight >= 0 || m.XHeight <= m.CapHeight {
+		t.Errorf("unexpected cap height %v and x-height %v", m.CapHeight, m.XHeight)
+	}
+	if ok, thickness := m.HasUnderlineThickness(); !ok || thickness <= 0 {
+		t.Errorf("expected a valid underline thickness, got %v, %v", ok, thickness)
+	}
+	if m.UnderlinePosition <= 0 {
+		t.Errorf("underline should be below the baseline, got %v", m.UnderlinePosition)
+	}
+	if m.StrikeoutPosition >= 0 {
+		t.Errorf("strikeout should be above the baseline, got %v", m.StrikeoutPosition)
+	}
+}
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/interfaces"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// TextAlign positions text relative to the offset along a path.
+type TextAlign uint8
+
+const (
+	// TextAlignLeft starts the text at the offset.
+	TextAlignLeft TextAlign = iota
+	// TextAlignCenter centers the text on the middle of the path, shifted
+	// by the offset.
+	TextAlignCenter
+	// TextAlignRight ends the text at the end of the path, shifted by the
+	// offset.
+	TextAlignRight
+)
+
+// TextOnPathOverflow controls glyphs that fall outside the path.
+type TextOnPathOverflow uint8
+
+const (
+	// TextOnPathOverflowHide drops glyphs whose center lies beyond either
+	// end of the path.
+	TextOnPathOverflowHide TextOnPathOverflow = iota
+	// TextOnPathOverflowExtend continues the path along its end tangents.
+	TextOnPathOverflowExtend
+	// TextOnPathOverflowWrap wraps around closed paths. Open paths hide
+	// overflowing glyphs.
+	TextOnPathOverflowWrap
+)
+
+// TextOnPathOptions configures DrawTextOnPathWithOptions.
+type TextOnPathOptions struct {
+	Align    TextAlign
+	Overflow TextOnPathOverflow
+	// VOffset moves the baseline away from the path, along the normal to
+	// the right of the path direction. Negative values move it to the left,
+	// which is above a path drawn left to right.
+	VOffset Scalar
+}
+
+// DrawTextOnPath draws text along the first contour of path, starting
+// offset units from the start of the path.
+func (c *canvas) DrawTextOnPath(text string, path SkPath, offset Scalar, font interfaces.SkFont, paint SkPaint) {
+	c.DrawTextOnPathWithOptions(text, path, offset, font, paint, TextOnPathOptions{})
+}
+
+// DrawTextOnPathWithOptions draws text along path with the given alignment
+// and overflow handling.
+func (c *canvas) DrawTextOnPathWithOptions(text string, path SkPath, offset Scalar, font interfaces.SkFont, paint SkPaint, opts TextOnPathOptions) {
+	for _, blob := range MakeTextBlobsOnPath(text, path, offset, font, opts) {
+		c.DrawTextBlob(blob, 0, 0, paint)
+	}
+}
+
+// MakeTextBlobsOnPath shapes text and places each glyph on path with an
+// RSXform, rotated to the path tangent at the glyph's center. It returns a
+// blob per font run, ready for DrawTextBlob at (0, 0).
+func MakeTextBlobsOnPath(text string, path SkPath, offset Scalar, font interfaces.SkFont, opts TextOnPathOptions) []interfaces.SkTextBlob {
+	if text == "" || path == nil || font == nil {
+		return nil
+	}
+	contour := NewPathMeasure(path, false, 1).Contour()
+	if contour == nil {
+		return nil
+	}
+	shaped, ok := shapeTextCached(text, font).blob.(*impl.TextBlob)
+	if !ok || shaped == nil {
+		return nil
+	}
+
+	type glyphRun struct {
+		font     interfaces.SkFont
+		glyphs   []impl.GlyphID
+		starts   []Scalar
+		advances []Scalar
+		baseline []Scalar
+	}
+	var runs []glyphRun
+	var total Scalar
+	for i := 0; i < shaped.RunCount(); i++ {
+		run := shaped.Run(i)
+		if run == nil || run.Font == nil || len(run.Positions) < len(run.Glyphs) {
+			continue
+		}
+		ids := make([]uint16, len(run.Glyphs))
+		for j, g := range run.Glyphs {
+			ids[j] = uint16(g)
+		}
+		r := glyphRun{font: run.Font, glyphs: run.Glyphs, advances: GetWidths(run.Font, ids)}
+		for j := range run.Glyphs {
+			r.starts = append(r.starts, run.Positions[j].X)
+			r.baseline = append(r.baseline, run.Positions[j].Y)
+			if j < len(r.advances) {
+				total = max(total, run.Positions[j].X+r.advances[j])
+			}
+		}
+		runs = append(runs, r)
+	}
+
+	length := contour.Length()
+	start := offset
+	switch opts.Align {
+	case TextAlignCenter:
+		start += (length - total) / 2
+	case TextAlignRight:
+		start += length - total
+	}
+	wrap := opts.Overflow == TextOnPathOverflowWrap && contour.IsClosed()
+
+	var blobs []interfaces.SkTextBlob
+	for _, r := range runs {
+		var glyphs []byte
+		var xforms []models.RSXform
+		for j, g := range r.glyphs {
+			var advance Scalar
+			if j < len(r.advances) {
+				advance = r.advances[j]
+			}
+			half := advance / 2
+			d := start + r.starts[j] + half
+
+			var pos, tan models.Point
+			switch {
+			case wrap:
+				d -= length * Scalar(math.Floor(float64(d/length)))
+				pos, tan, _ = contour.GetPosTan(d)
+			case d < 0 || d > length:
+				if opts.Overflow != TextOnPathOverflowExtend {
+					continue
+				}
+				// Continue along the tangent at the nearest end.
+				end := min(max(d, 0), length)
+				pos, tan, _ = contour.GetPosTan(end)
+				pos = addPoint(pos, scalePoint(tan, d-end))
+			default:
+				pos, tan, _ = contour.GetPosTan(d)
+			}
+
+			// Map the glyph's baseline center to pos, displaced along the
+			// normal by the vertical offset.
+			v := opts.VOffset + r.baseline[j]
+			xforms = append(xforms, models.RSXform{
+				SCos: tan.X,
+				SSin: tan.Y,
+				Tx:   pos.X - (tan.X*half + tan.Y*v),
+				Ty:   pos.Y - (tan.Y*half - tan.X*v),
+			})
+			glyphs = binary.LittleEndian.AppendUint16(glyphs, uint16(g))
+		}
+		if len(xforms) == 0 {
+			continue
+		}
+		if blob := impl.MakeTextBlobFromRSXform(glyphs, enums.TextEncodingGlyphID, xforms, r.font); blob != nil {
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs
+}
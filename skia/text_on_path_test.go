@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+)
+
+func collectRSXforms(t *testing.T, text string, length, offset Scalar, opts TextOnPathOptions) []impl.RSXform {
+	t.Helper()
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.MoveTo(0, 100)
+	path.LineTo(length, 100)
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 20)
+
+	var xforms []impl.RSXform
+	for _, blob := range MakeTextBlobsOnPath(text, path, offset, font, opts) {
+		tb := blob.(*impl.TextBlob)
+		for i := 0; i < tb.RunCount(); i++ {
+			xforms = append(xforms, tb.Run(i).RSXforms...)
+		}
+	}
+	return xforms
+}
+
+func TestMakeTextBlobsOnPath_StraightLine(t *testing.T) {
+	xforms := collectRSXforms(t, "abc", 500, 10, TextOnPathOptions{})
+	if len(xforms) != 3 {
+		t.Fatalf("expected 3 glyphs, got %d", len(xforms))
+	}
+	if !near(xforms[0].Tx, 10, 1e-3) || !near(xforms[0].Ty, 100, 1e-3) {
+		t.Errorf("first glyph origin: got (%v, %v), want (10, 100)", xforms[0].Tx, xforms[0].Ty)
+	}
+	for i, x := range xforms {
+		if !near(x.SCos, 1, 1e-4) || !near(x.SSin, 0, 1e-4) {
+			t.Errorf("glyph %d should not be rotated: %+v", i, x)
+		}
+		if i > 0 && x.Tx <= xforms[i-1].Tx {
+			t.Errorf("glyph %d is not after glyph %d", i, i-1)
+		}
+	}
+
+	// A positive vertical offset moves glyphs below a left to right path.
+	shifted := collectRSXforms(t, "abc", 500, 10, TextOnPathOptions{VOffset: 5})
+	if !near(shifted[0].Ty, 105, 1e-3) {
+		t.Errorf("vertical offset: got %v, want 105", shifted[0].Ty)
+	}
+}
+
+func TestMakeTextBlobsOnPath_Align(t *testing.T) {
+	left := collectRSXforms(t, "abc", 500, 0, TextOnPathOptions{Align: TextAlignLeft})
+	center := collectRSXforms(t, "abc", 500, 0, TextOnPathOptions{Align: TextAlignCenter})
+	right := collectRSXforms(t, "abc", 500, 0, TextOnPathOptions{Align: TextAlignRight})
+	if len(left) != 3 || len(center) != 3 || len(right) != 3 {
+		t.Fatalf("expected 3 glyphs each: %d %d %d", len(left), len(center), len(right))
+	}
+	shift := center[0].Tx - left[0].Tx
+	if !near(shift*2, right[0].Tx-left[0].Tx, 1e-2) {
+		t.Errorf("center shift %v should be half the right shift %v", shift, right[0].Tx-left[0].Tx)
+	}
+}
+
+func TestMakeTextBlobsOnPath_Overflow(t *testing.T) {
+	hidden := collectRSXforms(t, "abcdef", 30, 0, TextOnPathOptions{})
+	if len(hidden) == 0 || len(hidden) >= 6 {
+		t.Errorf("expected overflowing glyphs to be hidden, got %d", len(hidden))
+	}
+	extended := collectRSXforms(t, "abcdef", 30, 0, TextOnPathOptions{Overflow: TextOnPathOverflowExtend})
+	if len(extended) != 6 {
+		t.Errorf("expected all glyphs when extending, got %d", len(extended))
+	}
+	if last := extended[len(extended)-1]; last.Tx <= 30 || !near(last.Ty, 100, 1e-3) {
+		t.Errorf("extended glyph should continue along the path: %+v", last)
+	}
+}
+
+func TestCanvas_DrawTextOnPath(t *testing.T) {
+	ops := new(op.Ops)
+	canvas := NewCanvas(ops).(Extended)
+
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	path.AddCircle(100, 100, 80, enums.PathDirectionCW)
+	font := impl.NewFontWithTypefaceAndSize(goRegularTypeface(t), 16)
+	paint := NewPaintFill(color.NRGBA{A: 255})
+
+	// Should not panic
+	canvas.DrawTextOnPath("around the circle", path, 0, font, paint)
+	canvas.DrawTextOnPathWithOptions("wrapped", path, 480, font, paint, TextOnPathOptions{Overflow: TextOnPathOverflowWrap})
+	canvas.DrawTextOnPath("nil path", nil, 0, font, paint)
+}
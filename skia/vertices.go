@@ -0,0 +1,472 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/op"
+	gpaint "gioui.org/op/paint"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// VertexMode selects how vertices form triangles, mirroring
+// SkVertices::VertexMode.
+type VertexMode uint8
+
+const (
+	// VertexModeTriangles takes each three vertices as a triangle.
+	VertexModeTriangles VertexMode = iota
+	// VertexModeTriangleStrip makes a triangle of each vertex and the two
+	// before it.
+	VertexModeTriangleStrip
+	// VertexModeTriangleFan makes a triangle of each vertex, the one before
+	// it and the first.
+	VertexModeTriangleFan
+)
+
+// maxVerticesImageSize bounds the width and height of meshes rasterized on
+// the CPU.
+const maxVerticesImageSize = 4096
+
+// verticesRasterBudget is the number of bytes of rasterized meshes kept for
+// reuse.
+const verticesRasterBudget = 16 << 20
+
+// verticesRasterKey identifies a rasterized mesh. Vertices are immutable,
+// so a mesh is identified by its pointer.
+type verticesRasterKey struct {
+	vertices *Vertices
+	mode     enums.BlendMode
+	color    models.Color4f
+	// shader is the unique ID of the image shader, if any, and constant
+	// the color of a constant shader.
+	shader   uint32
+	constant models.Color4f
+	// filter is the ID of the color filter, if any.
+	filter uint32
+	matrix [9]Scalar
+	// rect is the device area rasterized.
+	rect image.Rectangle
+}
+
+// verticesRaster is a rasterized mesh and its image op.
+type verticesRaster struct {
+	img     *image.RGBA
+	imageOp gpaint.ImageOp
+}
+
+var verticesRasterCache = newLRUCache[verticesRasterKey, verticesRaster](verticesRasterBudget)
+
+// Vertices is an immutable triangle mesh, mirroring SkVertices.
+type Vertices struct {
+	mode      VertexMode
+	positions []models.Point
+	texCoords []models.Point
+	colors    []color.NRGBA
+	indices   []uint16
+	bounds    models.Rect
+}
+
+// NewVertices returns a mesh of triangles formed from positions as mode
+// describes. texCoords and colors are optional; if present they hold one
+// entry per position. indices is optional; if present the triangles are
+// formed from the positions it selects. The slices are copied. It returns
+// nil if the lengths do not match or an index is out of range, like
+// SkVertices::MakeCopy.
+func NewVertices(mode VertexMode, positions, texCoords []models.Point, colors []color.NRGBA, indices []uint16) *Vertices {
+	if mode > VertexModeTriangleFan {
+		return nil
+	}
+	if (texCoords != nil && len(texCoords) != len(positions)) || (colors != nil && len(colors) != len(positions)) {
+		return nil
+	}
+	for _, i := range indices {
+		if int(i) >= len(positions) {
+			return nil
+		}
+	}
+	v := &Vertices{
+		mode:      mode,
+		positions: append([]models.Point(nil), positions...),
+		texCoords: append([]models.Point(nil), texCoords...),
+		colors:    append([]color.NRGBA(nil), colors...),
+		indices:   append([]uint16(nil), indices...),
+	}
+	for i, p := range positions {
+		if i == 0 {
+			v.bounds = models.Rect{Left: p.X, Top: p.Y, Right: p.X, Bottom: p.Y}
+			continue
+		}
+		v.bounds = models.Rect{
+			Left:   min(v.bounds.Left, p.X),
+			Top:    min(v.bounds.Top, p.Y),
+			Right:  max(v.bounds.Right, p.X),
+			Bottom: max(v.bounds.Bottom, p.Y),
+		}
+	}
+	return v
+}
+
+// Mode returns how the vertices form triangles.
+func (v *Vertices) Mode() VertexMode {
+	return v.mode
+}
+
+// Bounds returns the bounds of the positions.
+func (v *Vertices) Bounds() models.Rect {
+	return v.bounds
+}
+
+// VertexCount returns the number of positions.
+func (v *Vertices) VertexCount() int {
+	return len(v.positions)
+}
+
+// IndexCount returns the number of indices, 0 if the mesh is not indexed.
+func (v *Vertices) IndexCount() int {
+	return len(v.indices)
+}
+
+// triangles calls fn with the vertex indices of each triangle.
+func (v *Vertices) triangles(fn func(a, b, c int)) {
+	n := len(v.positions)
+	vertex := func(i int) int { return i }
+	if len(v.indices) > 0 {
+		n = len(v.indices)
+		vertex = func(i int) int { return int(v.indices[i]) }
+	}
+	switch v.mode {
+	case VertexModeTriangles:
+		for i := 0; i+2 < n; i += 3 {
+			fn(vertex(i), vertex(i+1), vertex(i+2))
+		}
+	case VertexModeTriangleStrip:
+		for i := 0; i+2 < n; i++ {
+			fn(vertex(i), vertex(i+1), vertex(i+2))
+		}
+	case VertexModeTriangleFan:
+		for i := 1; i+1 < n; i++ {
+			fn(vertex(0), vertex(i), vertex(i+1))
+		}
+	}
+}
+
+// DrawVertices draws the triangles of vertices, mirroring
+// SkCanvas::drawVertices. Triangles are colored by the paint's shader
+// sampled at their texture coordinates, or at their positions if they have
+// none, and by their colors; mode blends the shader color onto the vertex
+// colors if both are present. Without either the paint color is used. The
+// result is filtered by the paint's color filter, modulated by the paint's
+// alpha and is not antialiased.
+//
+// Gio composites source-over only, so the paint's blend mode is
+// approximated: BlendModeDst draws nothing and every other mode draws
+// source-over, overlapping triangles included.
+//
+// Solid meshes are filled as a path and meshes that map an image shader
+// inside its bounds are drawn as textured triangles; anything else is
+// rasterized on the CPU. Rasterized meshes are reused while the mesh,
+// paint, transform and clip bounds are unchanged.
+func (c *canvas) DrawVertices(vertices *Vertices, mode enums.BlendMode, paint SkPaint) {
+	c.drawVertices(vertices, mode, paint, true)
+}
+
+// drawVertices implements DrawVertices. Rasterized meshes are cached if
+// cache is set; meshes built for a single draw should not be.
+func (c *canvas) drawVertices(vertices *Vertices, mode enums.BlendMode, paint SkPaint, cache bool) {
+	if vertices == nil || len(vertices.positions) == 0 || c.cullDraw(vertices.bounds, 0) {
+		return
+	}
+	if paint.GetBlendModeOr(enums.BlendModeSrcOver) == enums.BlendModeDst {
+		return
+	}
+	shader := paint.GetShader()
+	if _, ok := shaderColor(shader, 0, 0); !ok {
+		// Shaders the canvas cannot evaluate draw the paint color.
+		shader = nil
+	}
+	var constant models.Color4f
+	isConstant := shader != nil && shader.IsConstant(&constant)
+	if len(vertices.colors) == 0 && (shader == nil || isConstant) {
+		fill := NewPaint()
+		col := paint.GetColor()
+		if isConstant {
+			col = constant
+			col.A *= paint.GetAlphaf()
+		}
+		fill.SetColor(ColorToColor4f(filterNRGBA(paint, color4fToNRGBA(col))))
+		c.DrawPath(verticesPath(vertices), fill)
+		return
+	}
+	_, filtered := paintColorFilter(paint)
+	if img, ok := shader.(*imageShader); ok && len(vertices.colors) == 0 && !filtered && c.drawVerticesImage(vertices, img, float32(paint.GetAlphaf())) {
+		return
+	}
+	c.drawVerticesRaster(vertices, mode, paint, cache)
+}
+
+// verticesPath returns the area covered by the triangles of v. The
+// triangles are wound the same way, so that they add up under the nonzero
+// fill rule.
+func verticesPath(v *Vertices) SkPath {
+	path := impl.NewSkPath(enums.PathFillTypeWinding)
+	v.triangles(func(a, b, c int) {
+		p0, p1, p2 := v.positions[a], v.positions[b], v.positions[c]
+		if crossPoint(subPoint(p1, p0), subPoint(p2, p0)) < 0 {
+			p1, p2 = p2, p1
+		}
+		path.MoveTo(p0.X, p0.Y)
+		path.LineTo(p1.X, p1.Y)
+		path.LineTo(p2.X, p2.Y)
+		path.Close()
+	})
+	return path
+}
+
+// drawVerticesImage draws the triangles of v textured with the image of
+// shader. It reports false, without drawing, unless every triangle maps to
+// a non-degenerate triangle inside the image under an affine transform.
+func (c *canvas) drawVerticesImage(v *Vertices, shader *imageShader, alpha float32) bool {
+	if c.stack[len(c.stack)-1].persp != nil {
+		return false
+	}
+	tex := v.texCoords
+	if len(tex) == 0 {
+		tex = v.positions
+	}
+	w, h := Scalar(shader.img.Rect.Dx()), Scalar(shader.img.Rect.Dy())
+	src := make([]models.Point, len(tex))
+	for i, t := range tex {
+		x, y := shader.inverse.MapXY(t.X, t.Y)
+		if x < 0 || y < 0 || x > w || y > h {
+			return false
+		}
+		src[i] = models.Point{X: x, Y: y}
+	}
+	ok := true
+	v.triangles(func(a, b, cc int) {
+		degenerate := crossPoint(subPoint(src[b], src[a]), subPoint(src[cc], src[a])) == 0
+		empty := crossPoint(subPoint(v.positions[b], v.positions[a]), subPoint(v.positions[cc], v.positions[a])) == 0
+		ok = ok && (!degenerate || empty)
+	})
+	if !ok {
+		return false
+	}
+
+	pop := c.pushContext()
+	defer pop()
+	if alpha < 1 {
+		opacity := gpaint.PushOpacity(c.ops, alpha)
+		defer opacity.Pop()
+	}
+	imgOp := gpaint.NewImageOp(shader.img)
+	if shader.sampling.FilterMode != enums.FilterModeLinear && !shader.sampling.UseCubic {
+		imgOp.Filter = gpaint.FilterNearest
+	}
+	v.triangles(func(a, b, cc int) {
+		c.drawImageTriangle(imgOp, [3]models.Point{src[a], src[b], src[cc]}, [3]models.Point{v.positions[a], v.positions[b], v.positions[cc]})
+	})
+	return true
+}
+
+// drawVerticesRaster rasterizes v over the part of the clip it covers and
+// draws the result. The raster is cached if cache is set.
+func (c *canvas) drawVerticesRaster(v *Vertices, mode enums.BlendMode, paint SkPaint, cache bool) {
+	m := c.stack[len(c.stack)-1].matrix()
+	var dev models.Rect
+	for i, p := range v.positions {
+		d := perspectiveMap(m, p)
+		if i == 0 {
+			dev = models.Rect{Left: d.X, Top: d.Y, Right: d.X, Bottom: d.Y}
+			continue
+		}
+		dev = models.Rect{Left: min(dev.Left, d.X), Top: min(dev.Top, d.Y), Right: max(dev.Right, d.X), Bottom: max(dev.Bottom, d.Y)}
+	}
+	area := intersectRect(dev, c.deviceClipBounds())
+	r := image.Rect(
+		int(math.Floor(float64(area.Left))), int(math.Floor(float64(area.Top))),
+		int(math.Ceil(float64(area.Right))), int(math.Ceil(float64(area.Bottom))),
+	)
+	r.Max = image.Pt(min(r.Max.X, r.Min.X+maxVerticesImageSize), min(r.Max.Y, r.Min.Y+maxVerticesImageSize))
+	if r.Empty() {
+		return
+	}
+	key, cacheable := verticesRasterKeyFor(v, mode, paint, m, r)
+	if cache && cacheable {
+		if e, ok := verticesRasterCache.Get(key); ok {
+			c.drawDeviceImage(e.imageOp, r.Min)
+			return
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	toImage := impl.NewMatrixTranslate(Scalar(-r.Min.X), Scalar(-r.Min.Y))
+	toImage.PreConcat(m)
+	rasterVertices(img, v, mode, paint, toImage)
+	if f, ok := paintColorFilter(paint); ok {
+		img = filterImage(img, f)
+	}
+	imgOp := gpaint.NewImageOp(img)
+	if cache && cacheable {
+		verticesRasterCache.Put(key, verticesRaster{img: img, imageOp: imgOp}, len(img.Pix))
+	}
+	c.drawDeviceImage(imgOp, r.Min)
+}
+
+// verticesRasterKeyFor returns the cache key of v rasterized with m over
+// the device area r. It reports false if the paint's shader cannot be
+// identified.
+func verticesRasterKeyFor(v *Vertices, mode enums.BlendMode, paint SkPaint, m SkMatrix, r image.Rectangle) (verticesRasterKey, bool) {
+	key := verticesRasterKey{
+		vertices: v,
+		mode:     mode,
+		color:    paint.GetColor(),
+		matrix:   m.Get9(),
+		rect:     r,
+	}
+	if f, ok := paintColorFilter(paint); ok {
+		key.filter = f.filterID()
+	}
+	switch shader := paint.GetShader().(type) {
+	case nil:
+	case *imageShader:
+		key.shader = shader.UniqueID()
+	default:
+		if !shader.IsConstant(&key.constant) {
+			return key, false
+		}
+	}
+	return key, true
+}
+
+// rasterVertices draws the triangles of v into dst, with m mapping their
+// positions to the pixels of dst. It is the reference implementation of
+// DrawVertices: colors, texture coordinates and local positions are
+// interpolated with perspective correction, pixels are covered if their
+// centers are inside a triangle, with the top-left rule on edges, and each
+// pixel is composited source-over onto dst. The paint's blend mode is not
+// used; see DrawVertices.
+func rasterVertices(dst *image.RGBA, v *Vertices, mode enums.BlendMode, paint SkPaint, m SkMatrix) {
+	shader := paint.GetShader()
+	if _, ok := shaderColor(shader, 0, 0); !ok {
+		shader = nil
+	}
+	alpha := float32(paint.GetAlphaf())
+	paintColor := premulRGBA(color4fToNRGBA(paint.GetColor()))
+	e := m.Get9()
+
+	type vertex struct {
+		x, y float32
+		// invW is the reciprocal of the homogeneous coordinate.
+		invW float32
+	}
+	verts := make([]vertex, len(v.positions))
+	for i, p := range v.positions {
+		w := float32(e[6]*p.X + e[7]*p.Y + e[8])
+		w = max(w, minPerspectiveW)
+		verts[i] = vertex{
+			x:    float32(e[0]*p.X+e[1]*p.Y+e[2]) / w,
+			y:    float32(e[3]*p.X+e[4]*p.Y+e[5]) / w,
+			invW: 1 / w,
+		}
+	}
+	var colors []rgbaF
+	for _, col := range v.colors {
+		colors = append(colors, toRGBAF(premulRGBA(col)))
+	}
+	tex := v.texCoords
+	if len(tex) == 0 {
+		tex = v.positions
+	}
+
+	b := dst.Rect
+	v.triangles(func(i0, i1, i2 int) {
+		p0, p1, p2 := verts[i0], verts[i1], verts[i2]
+		area := (p1.x-p0.x)*(p2.y-p0.y) - (p1.y-p0.y)*(p2.x-p0.x)
+		if area == 0 || area != area {
+			return
+		}
+		if area < 0 {
+			i1, i2, p1, p2, area = i2, i1, p2, p1, -area
+		}
+		x0 := max(int(math.Floor(float64(min(p0.x, p1.x, p2.x)))), b.Min.X)
+		x1 := min(int(math.Ceil(float64(max(p0.x, p1.x, p2.x)))), b.Max.X)
+		y0 := max(int(math.Floor(float64(min(p0.y, p1.y, p2.y)))), b.Min.Y)
+		y1 := min(int(math.Ceil(float64(max(p0.y, p1.y, p2.y)))), b.Max.Y)
+
+		// edge returns the edge function of a→b at (x, y), positive inside,
+		// and whether the edge owns pixels centered on it.
+		edge := func(a, b vertex, x, y float32) (float32, bool) {
+			w := (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+			// In y-down coordinates with positive area, top edges run
+			// right and left edges run up.
+			topLeft := (a.y == b.y && b.x > a.x) || b.y < a.y
+			return w, topLeft
+		}
+		inside := func(w float32, topLeft bool) bool {
+			return w > 0 || (w == 0 && topLeft)
+		}
+		for y := y0; y < y1; y++ {
+			py := float32(y) + 0.5
+			for x := x0; x < x1; x++ {
+				px := float32(x) + 0.5
+				w0, tl0 := edge(p1, p2, px, py)
+				w1, tl1 := edge(p2, p0, px, py)
+				w2, tl2 := edge(p0, p1, px, py)
+				if !inside(w0, tl0) || !inside(w1, tl1) || !inside(w2, tl2) {
+					continue
+				}
+				// Perspective correct barycentric weights.
+				l0, l1, l2 := w0/area*p0.invW, w1/area*p1.invW, w2/area*p2.invW
+				sum := l0 + l1 + l2
+				l0, l1, l2 = l0/sum, l1/sum, l2/sum
+
+				var col rgbaF
+				var sc color.RGBA
+				if shader != nil {
+					t0, t1, t2 := tex[i0], tex[i1], tex[i2]
+					sx := Scalar(l0)*t0.X + Scalar(l1)*t1.X + Scalar(l2)*t2.X
+					sy := Scalar(l0)*t0.Y + Scalar(l1)*t1.Y + Scalar(l2)*t2.Y
+					sc, _ = shaderColor(shader, sx, sy)
+				}
+				switch {
+				case colors != nil:
+					c0, c1, c2 := colors[i0], colors[i1], colors[i2]
+					col = rgbaF{
+						l0*c0.r + l1*c1.r + l2*c2.r,
+						l0*c0.g + l1*c1.g + l2*c2.g,
+						l0*c0.b + l1*c1.b + l2*c2.b,
+						l0*c0.a + l1*c1.a + l2*c2.a,
+					}
+					if shader != nil {
+						col = blendRGBAF(mode, toRGBAF(sc), col)
+					}
+					col = col.scale(alpha)
+				case shader != nil:
+					col = toRGBAF(sc).scale(alpha)
+				default:
+					col = toRGBAF(paintColor)
+				}
+				dst.SetRGBA(x, y, blendRGBA(enums.BlendModeSrcOver, col.rgba(), dst.RGBAAt(x, y)))
+			}
+		}
+	})
+}
+
+// drawDeviceImage draws the image of imgOp with its top-left corner at the
+// device pixel origin, under the current clips but not the current
+// transform.
+func (c *canvas) drawDeviceImage(imgOp gpaint.ImageOp, origin image.Point) {
+	ctx := &c.stack[len(c.stack)-1]
+	for _, cl := range ctx.clips {
+		stack := cl.op.Push(c.ops)
+		defer stack.Pop()
+	}
+	offset := op.Offset(origin).Push(c.ops)
+	defer offset.Pop()
+	imgOp.Add(c.ops)
+	gpaint.PaintOp{}.Add(c.ops)
+}
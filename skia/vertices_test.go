@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+package skia
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/op"
+	"github.com/zodimo/go-skia-support/skia/enums"
+	"github.com/zodimo/go-skia-support/skia/impl"
+	"github.com/zodimo/go-skia-support/skia/models"
+)
+
+// quadVertices returns a fan of two triangles covering the square from
+// (0, 0) to (size, size), with texture coordinates from (0, 0) to (tex,
+// tex).
+func quadVertices(size, tex Scalar, colors []color.NRGBA) *Vertices {
+	pos := []models.Point{{X: 0, Y: 0}, {X: size, Y: 0}, {X: size, Y: size}, {X: 0, Y: size}}
+	texs := []models.Point{{X: 0, Y: 0}, {X: tex, Y: 0}, {X: tex, Y: tex}, {X: 0, Y: tex}}
+	return NewVertices(VertexModeTriangleFan, pos, texs, colors, nil)
+}
+
+func TestNewVertices_Invalid(t *testing.T) {
+	pos := []models.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}}
+	if NewVertices(VertexModeTriangles, pos, pos[:2], nil, nil) != nil {
+		t.Error("texture coordinates of the wrong length should be rejected")
+	}
+	if NewVertices(VertexModeTriangles, pos, nil, make([]color.NRGBA, 4), nil) != nil {
+		t.Error("colors of the wrong length should be rejected")
+	}
+	if NewVertices(VertexModeTriangles, pos, nil, nil, []uint16{0, 1, 3}) != nil {
+		t.Error("out of range indices should be rejected")
+	}
+	v := NewVertices(VertexModeTriangles, pos, nil, nil, []uint16{0, 1, 2})
+	if v == nil || v.Bounds() != (models.Rect{Right: 1, Bottom: 1}) || v.IndexCount() != 3 {
+		t.Errorf("vertices %+v", v)
+	}
+}
+
+func TestVertices_Triangles(t *testing.T) {
+	pos := make([]models.Point, 6)
+	for _, tc := range []struct {
+		mode    VertexMode
+		indices []uint16
+		want    [][3]int
+	}{
+		{VertexModeTriangles, nil, [][3]int{{0, 1, 2}, {3, 4, 5}}},
+		{VertexModeTriangleStrip, nil, [][3]int{{0, 1, 2}, {1, 2, 3}, {2, 3, 4}, {3, 4, 5}}},
+		{VertexModeTriangleFan, nil, [][3]int{{0, 1, 2}, {0, 2, 3}, {0, 3, 4}, {0, 4, 5}}},
+		{VertexModeTriangles, []uint16{5, 4, 3, 2}, [][3]int{{5, 4, 3}}},
+		{VertexModeTriangleFan, []uint16{2, 0, 1, 3}, [][3]int{{2, 0, 1}, {2, 1, 3}}},
+	} {
+		var got [][3]int
+		NewVertices(tc.mode, pos, nil, nil, tc.indices).triangles(func(a, b, c int) {
+			got = append(got, [3]int{a, b, c})
+		})
+		if len(got) != len(tc.want) {
+			t.Errorf("mode %d indices %v: triangles %v, want %v", tc.mode, tc.indices, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("mode %d indices %v: triangles %v, want %v", tc.mode, tc.indices, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRasterVertices_SharedEdges(t *testing.T) {
+	// Translucent triangles sharing an edge cover each pixel exactly once.
+	paint := NewPaintFill(color.NRGBA{R: 255, A: 128})
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	rasterVertices(dst, quadVertices(8, 8, nil), enums.BlendModeModulate, paint, impl.NewMatrixIdentity())
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if got := dst.RGBAAt(x, y); got.A != 128 {
+				t.Fatalf("pixel (%d, %d) = %v, want alpha 128", x, y, got)
+			}
+		}
+	}
+}
+
+func TestRasterVertices_Colors(t *testing.T) {
+	pos := []models.Point{{X: 0, Y: 0}, {X: 30, Y: 0}, {X: 0, Y: 30}}
+	colors := []color.NRGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	dst := image.NewRGBA(image.Rect(0, 0, 30, 30))
+	rasterVertices(dst, NewVertices(VertexModeTriangles, pos, nil, colors, nil), enums.BlendModeModulate, NewPaint(), impl.NewMatrixIdentity())
+
+	if got := dst.RGBAAt(0, 0); got.R < 240 || got.G > 15 || got.B > 15 {
+		t.Errorf("corner color %v, want red", got)
+	}
+	// Near the centroid the colors mix about equally.
+	if got := dst.RGBAAt(9, 9); got.A != 255 || absInt(int(got.R)-int(got.G)) > 15 || absInt(int(got.G)-int(got.B)) > 15 {
+		t.Errorf("centroid color %v, want gray", got)
+	}
+	if got := dst.RGBAAt(29, 29); got.A != 0 {
+		t.Errorf("pixel outside the triangle = %v", got)
+	}
+
+	// The paint's alpha modulates vertex colors.
+	paint := NewPaint()
+	paint.SetAlphaf(0.5)
+	dst = image.NewRGBA(image.Rect(0, 0, 30, 30))
+	rasterVertices(dst, NewVertices(VertexModeTriangles, pos, nil, colors, nil), enums.BlendModeModulate, paint, impl.NewMatrixIdentity())
+	if got := dst.RGBAAt(0, 0); got.A < 126 || got.A > 129 {
+		t.Errorf("half transparent corner %v", got)
+	}
+}
+
+func TestRasterVertices_TexCoords(t *testing.T) {
+	// A 2x2 image with a different color in each pixel.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	quadrants := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}, {R: 255, G: 255, B: 255, A: 255}}
+	img.SetRGBA(0, 0, quadrants[0])
+	img.SetRGBA(1, 0, quadrants[1])
+	img.SetRGBA(0, 1, quadrants[2])
+	img.SetRGBA(1, 1, quadrants[3])
+	paint := NewPaint()
+	paint.SetShader(newImageShader(img, enums.TileModeClamp, enums.TileModeClamp, models.SamplingOptions{}, nil))
+
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	rasterVertices(dst, quadVertices(8, 2, nil), enums.BlendModeModulate, paint, impl.NewMatrixIdentity())
+	for i, p := range []image.Point{{1, 1}, {6, 1}, {1, 6}, {6, 6}} {
+		if got := dst.RGBAAt(p.X, p.Y); got != quadrants[i] {
+			t.Errorf("pixel %v = %v, want %v", p, got, quadrants[i])
+		}
+	}
+
+	// Vertex colors modulate the shader.
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	red := color.NRGBA{R: 255, A: 255}
+	dst = image.NewRGBA(image.Rect(0, 0, 8, 8))
+	rasterVertices(dst, quadVertices(8, 2, []color.NRGBA{red, red, red, red}), enums.BlendModeModulate, paint, impl.NewMatrixIdentity())
+	if got := dst.RGBAAt(6, 6); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("modulated white = %v, want red", got)
+	}
+	dst = image.NewRGBA(image.Rect(0, 0, 8, 8))
+	rasterVertices(dst, quadVertices(8, 2, []color.NRGBA{white, white, white, white}), enums.BlendModeDst, paint, impl.NewMatrixIdentity())
+	if got := dst.RGBAAt(1, 1); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("dst mode = %v, want the vertex color", got)
+	}
+}
+
+func TestCanvas_DrawVertices(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	shader := newImageShader(img, enums.TileModeClamp, enums.TileModeClamp, models.SamplingOptions{}, nil)
+	red := color.NRGBA{R: 255, A: 255}
+	for _, tc := range []struct {
+		name     string
+		vertices *Vertices
+		shader   *imageShader
+	}{
+		{"solid", quadVertices(10, 4, nil), nil},
+		{"textured", quadVertices(10, 4, nil), shader},
+		{"tiled", quadVertices(10, 8, nil), shader},
+		{"colors", quadVertices(10, 4, []color.NRGBA{red, red, red, red}), shader},
+	} {
+		c := NewCanvasWithSize(new(op.Ops), 20, 20).(Extended)
+		paint := NewPaint()
+		if tc.shader != nil {
+			paint.SetShader(tc.shader)
+		}
+		c.DrawVertices(tc.vertices, enums.BlendModeModulate, paint)
+		if c.CulledDraws() != 0 {
+			t.Errorf("%s: visible mesh culled", tc.name)
+		}
+	}
+
+	c := NewCanvasWithSize(new(op.Ops), 20, 20).(Extended)
+	c.Translate(100, 100)
+	c.DrawVertices(quadVertices(10, 4, nil), enums.BlendModeModulate, NewPaint())
+	if c.CulledDraws() != 1 {
+		t.Errorf("offscreen mesh should be culled")
+	}
+}
+
+func TestCanvas_DrawVerticesRasterCache(t *testing.T) {
+	verticesRasterCache.Purge()
+	defer verticesRasterCache.Purge()
+
+	red := color.NRGBA{R: 255, A: 255}
+	heatmap := quadVertices(10, 4, []color.NRGBA{red, red, red, red})
+	c := NewCanvasWithSize(new(op.Ops), 20, 20).(Extended)
+	for i := 0; i < 3; i++ {
+		c.DrawVertices(heatmap, enums.BlendModeModulate, NewPaint())
+	}
+	if n := verticesRasterCache.Len(); n != 1 {
+		t.Fatalf("expected 1 cached raster, got %d", n)
+	}
+	c.Translate(2, 0)
+	c.DrawVertices(heatmap, enums.BlendModeModulate, NewPaint())
+	if n := verticesRasterCache.Len(); n != 2 {
+		t.Errorf("moved mesh should be rasterized again, got %d entries", n)
+	}
+
+	// Patches are tessellated for each draw and not cached.
+	var cubics [12]models.Point
+	for i := range cubics {
+		cubics[i] = models.Point{X: Scalar(i % 4 * 3), Y: Scalar(i / 4 * 3)}
+	}
+	c.DrawPatch(cubics, &[4]color.NRGBA{red, red, red, red}, nil, enums.BlendModeModulate, NewPaint())
+	if n := verticesRasterCache.Len(); n != 2 {
+		t.Errorf("patch raster should not be cached, got %d entries", n)
+	}
+
+	// BlendModeDst leaves the canvas unchanged.
+	dst := NewPaint()
+	dst.SetBlendMode(enums.BlendModeDst)
+	c.DrawVertices(quadVertices(10, 4, []color.NRGBA{red, red, red, red}), enums.BlendModeModulate, dst)
+	if n := verticesRasterCache.Len(); n != 2 {
+		t.Errorf("dst blend mode should not draw, got %d entries", n)
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}